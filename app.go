@@ -0,0 +1,16 @@
+package main
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/job"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// App 整个应用，除了 web 服务器，还有退出的时候需要收尾的组件
+type App struct {
+	server *gin.Engine
+	// 退出之前要把还没写回去的阅读数刷新出去
+	readCntAggregator *repository.ReadCntAggregator
+	// 定时任务
+	scheduler *job.Scheduler
+}
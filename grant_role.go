@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/spf13/pflag"
+)
+
+// 第一个管理员只能从命令行授予，比如
+// ./webook --config=config/dev.yaml grant-role --uid=1 --role=admin
+const cmdGrantRole = "grant-role"
+
+var (
+	grantRoleUid = pflag.Int64("uid", 0, cmdGrantRole+" 子命令：用户 id")
+	grantRoles   = pflag.StringSlice("role", nil,
+		cmdGrantRole+" 子命令：角色，可以传多个，会覆盖掉用户原本的角色")
+)
+
+// runGrantRole 改完角色之后作废用户手上的 access token，
+// 用户下一次请求会用 refresh token 换一个带新角色的 token，不需要重新登录
+func runGrantRole() {
+	if *grantRoleUid <= 0 {
+		log.Fatalln("缺少 --uid")
+	}
+	ctx := context.Background()
+	svc := InitRBACService()
+	err := svc.SetUserRoles(ctx, *grantRoleUid, *grantRoles)
+	if err != nil {
+		log.Fatalln("授予角色失败", err)
+	}
+	err = InitJWTHandler().InvalidateClaims(ctx, *grantRoleUid)
+	if err != nil {
+		// 角色已经改好了，重新执行一次是安全的
+		log.Fatalln("角色已经改好了，但是作废用户的 token 失败，请重试", err)
+	}
+	log.Printf("用户 %d 的角色已经改成 %v\n", *grantRoleUid, *grantRoles)
+}
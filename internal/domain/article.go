@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type Article struct {
+	Id      int64
+	Title   string
+	Content string
+	// Author 要从用户来
+	Author Author
+	Status ArticleStatus
+	Ctime  time.Time
+	Utime  time.Time
+}
+
+// Abstract 取文章内容的前一部分作为摘要
+func (a Article) Abstract() string {
+	cs := []rune(a.Content)
+	if len(cs) < 100 {
+		return a.Content
+	}
+	return string(cs[:100])
+}
+
+type ArticleStatus uint8
+
+const (
+	// ArticleStatusUnknown 这是一个未知状态，也就是文章还不存在
+	ArticleStatusUnknown ArticleStatus = iota
+	// ArticleStatusUnpublished 未发表
+	ArticleStatusUnpublished
+	// ArticleStatusPublished 已发表
+	ArticleStatusPublished
+	// ArticleStatusPrivate 仅自己可见
+	ArticleStatusPrivate
+	// ArticleStatusArchived 已归档，读者看不到，也不能再编辑
+	ArticleStatusArchived
+	// ArticleStatusDeleted 已删除，这是终态
+	ArticleStatusDeleted
+)
+
+// articleStatusTransitions 文章状态机，key 是当前状态，value 是允许迁移过去的状态
+// 自己迁移到自己，代表的是重复编辑或者重复发表
+var articleStatusTransitions = map[ArticleStatus][]ArticleStatus{
+	ArticleStatusUnknown: {ArticleStatusUnpublished, ArticleStatusPublished},
+	ArticleStatusUnpublished: {ArticleStatusUnpublished, ArticleStatusPublished,
+		ArticleStatusPrivate, ArticleStatusDeleted},
+	// 已发表的文章再编辑，制作库里面就又变回了未发表
+	ArticleStatusPublished: {ArticleStatusUnpublished, ArticleStatusPublished,
+		ArticleStatusPrivate, ArticleStatusArchived, ArticleStatusDeleted},
+	ArticleStatusPrivate: {ArticleStatusUnpublished, ArticleStatusPublished,
+		ArticleStatusPrivate, ArticleStatusArchived, ArticleStatusDeleted},
+	ArticleStatusArchived: {ArticleStatusPrivate, ArticleStatusDeleted},
+	ArticleStatusDeleted:  {},
+}
+
+func (s ArticleStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s ArticleStatus) String() string {
+	switch s {
+	case ArticleStatusUnpublished:
+		return "unpublished"
+	case ArticleStatusPublished:
+		return "published"
+	case ArticleStatusPrivate:
+		return "private"
+	case ArticleStatusArchived:
+		return "archived"
+	case ArticleStatusDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// CanTransitTo 能不能从 s 迁移到 to
+func (s ArticleStatus) CanTransitTo(to ArticleStatus) bool {
+	for _, st := range articleStatusTransitions[s] {
+		if st == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Sources 所有能够迁移到 s 的状态
+// 存储层用它来拼条件更新的 WHERE status IN (...)，这样检查和更新是原子的
+func (s ArticleStatus) Sources() []ArticleStatus {
+	res := make([]ArticleStatus, 0, len(articleStatusTransitions))
+	for from := ArticleStatusUnknown; from <= ArticleStatusDeleted; from++ {
+		if from.CanTransitTo(s) {
+			res = append(res, from)
+		}
+	}
+	return res
+}
+
+// ArticleStatusTransitionError 非法的状态迁移，比如说从已删除变成已发表
+type ArticleStatusTransitionError struct {
+	From ArticleStatus
+	To   ArticleStatus
+}
+
+func (e *ArticleStatusTransitionError) Error() string {
+	return fmt.Sprintf("文章状态不能从 %s 变为 %s", e.From, e.To)
+}
+
+// Author 在帖子这个领域内，是一个值对象
+type Author struct {
+	Id   int64
+	Name string
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArticleStatus_CanTransitTo(t *testing.T) {
+	testCases := []struct {
+		name string
+		from ArticleStatus
+		to   ArticleStatus
+		want bool
+	}{
+		{name: "新建草稿", from: ArticleStatusUnknown, to: ArticleStatusUnpublished, want: true},
+		{name: "直接发表", from: ArticleStatusUnknown, to: ArticleStatusPublished, want: true},
+		{name: "撤回", from: ArticleStatusPublished, to: ArticleStatusPrivate, want: true},
+		{name: "重新发表", from: ArticleStatusPrivate, to: ArticleStatusPublished, want: true},
+		{name: "归档后不能直接发表", from: ArticleStatusArchived, to: ArticleStatusPublished, want: false},
+		{name: "删除之后不能发表", from: ArticleStatusDeleted, to: ArticleStatusPublished, want: false},
+		{name: "删除之后不能编辑", from: ArticleStatusDeleted, to: ArticleStatusUnpublished, want: false},
+		{name: "不能凭空删除", from: ArticleStatusUnknown, to: ArticleStatusDeleted, want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.from.CanTransitTo(tc.to))
+		})
+	}
+}
+
+func TestArticleStatus_Sources(t *testing.T) {
+	assert.Equal(t, []ArticleStatus{
+		ArticleStatusUnpublished,
+		ArticleStatusPublished,
+		ArticleStatusPrivate,
+		ArticleStatusArchived,
+	}, ArticleStatusDeleted.Sources())
+	assert.Equal(t, []ArticleStatus{}, ArticleStatusUnknown.Sources())
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Collection 收藏夹
+type Collection struct {
+	Id   int64
+	Uid  int64
+	Name string
+
+	Ctime time.Time
+	Utime time.Time
+}
+
+// CollectionItem 收藏夹里面的一条收藏
+type CollectionItem struct {
+	Biz   string
+	BizId int64
+	// 收藏夹 ID，0 是默认收藏夹
+	Cid   int64
+	Ctime time.Time
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type Interactive struct {
 	// 阅读点赞收藏数量
 	ReadCnt    int64
@@ -9,3 +11,48 @@ type Interactive struct {
 	Liked     bool
 	Collected bool
 }
+
+// LikeRankWindow 点赞榜的时间窗口
+type LikeRankWindow string
+
+const (
+	LikeRankWindowDay  LikeRankWindow = "24h"
+	LikeRankWindowWeek LikeRankWindow = "7d"
+	LikeRankWindowAll  LikeRankWindow = "all"
+)
+
+func (w LikeRankWindow) Valid() bool {
+	switch w {
+	case LikeRankWindowDay, LikeRankWindowWeek, LikeRankWindowAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// LikeRankItem 点赞榜上的一项，LikeCnt 是窗口内的点赞数
+type LikeRankItem struct {
+	BizId   int64
+	LikeCnt int64
+}
+
+// UserBiz 用户点过赞或者收藏过的一个资源
+type UserBiz struct {
+	// 点赞或者收藏记录的 ID，分页的时候用来做游标
+	Id    int64
+	Biz   string
+	BizId int64
+	Utime time.Time
+}
+
+// InteractiveCntDiff 计数和点赞、收藏明细对不上的资源
+type InteractiveCntDiff struct {
+	Biz   string
+	BizId int64
+	// interactives 表里面记录的
+	LikeCnt    int64
+	CollectCnt int64
+	// 按照明细表统计出来的
+	ActualLikeCnt    int64
+	ActualCollectCnt int64
+}
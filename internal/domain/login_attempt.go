@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// LoginLockPolicy 连续登录失败之后怎么锁定
+type LoginLockPolicy struct {
+	// Threshold 在 Window 内失败这么多次就锁定
+	Threshold int64
+	Window    time.Duration
+	// LockDuration 第一次锁定的时长，锁定结束之后很快又被锁，时长翻倍，
+	// 最长 MaxLockDuration
+	LockDuration    time.Duration
+	MaxLockDuration time.Duration
+}
+
+// LoginAttempt 一个账号或者一个 IP 最近的登录失败情况
+type LoginAttempt struct {
+	Failures int64
+	// LockedFor 还要锁定多久，0 就是没有锁定
+	LockedFor time.Duration
+}
+
+// LoginAttemptStatus 综合账号和 IP 之后，这次登录要怎么处理
+type LoginAttemptStatus struct {
+	LockedFor time.Duration
+	// CaptchaRequired 失败次数有点多了，前端要让用户先过验证码
+	CaptchaRequired bool
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// OAuth2Identity 第三方登录拿到的用户身份
+type OAuth2Identity struct {
+	// Provider 供应商的名字，例如 wechat、google
+	Provider string
+	// Subject 用户在这个供应商里面的唯一标识，OIDC 是 sub，微信是 openid
+	Subject string
+	// UnionId 同一个供应商下面多个应用通用的标识，目前只有微信有
+	UnionId string
+
+	Email string
+	// EmailVerified 供应商确认过邮箱是用户本人的，没确认过的邮箱不能拿来找用户
+	EmailVerified bool
+	Nickname      string
+	Avatar        string
+	// Profile 供应商返回的原始用户信息，JSON，绑定的时候原样存下来
+	Profile string
+
+	// AccessToken 和 RefreshToken 是供应商发的令牌，存下来以后可以替用户调用供应商的接口。
+	// 目前只有微信会填。过期了用 UserIdentityService.AccessToken 刷新
+	AccessToken  string
+	RefreshToken string
+	TokenExpiry  time.Time
+}
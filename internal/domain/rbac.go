@@ -0,0 +1,35 @@
+package domain
+
+// 权限是 资源:操作 形式的字符串，会放进 access token 里面
+const (
+	PermissionUserRead        = "user:read"
+	PermissionUserBan         = "user:ban"
+	PermissionRoleManage      = "role:manage"
+	PermissionArticleTakedown = "article:takedown"
+)
+
+// 内置角色，建表的时候会初始化
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// BuiltinRoles 内置角色和它们的权限
+var BuiltinRoles = map[string][]string{
+	RoleAdmin: {PermissionUserRead, PermissionUserBan,
+		PermissionRoleManage, PermissionArticleTakedown},
+	// 内容审核，可以查用户和下架文章，不能封号
+	RoleModerator: {PermissionUserRead, PermissionArticleTakedown},
+}
+
+type Role struct {
+	Id          int64
+	Name        string
+	Permissions []string
+}
+
+// UserPermissions 一个用户所有角色的权限合在一起
+type UserPermissions struct {
+	Roles       []string
+	Permissions []string
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// 安全事件的类型
+const (
+	SecurityEventLogin       = "login"
+	SecurityEventLoginFailed = "login_failed"
+	SecurityEventRefresh     = "refresh"
+	SecurityEventLogout      = "logout"
+	// SecurityEventTwoFactorChallenge 密码对了，要求两步验证
+	SecurityEventTwoFactorChallenge = "2fa_challenge"
+	SecurityEventOAuthBind          = "oauth_bind"
+	SecurityEventPasswordChange     = "password_change"
+	// SecurityEventPasswordReset 忘记密码，用验证码重置
+	SecurityEventPasswordReset = "password_reset"
+	// SecurityEventStepUp token 绑定的设备对不上，再验证一次身份
+	SecurityEventStepUp = "step_up"
+)
+
+const (
+	SecurityResultSuccess = "success"
+	SecurityResultFailure = "failure"
+)
+
+// 登录方式，第三方登录用供应商的名字
+const (
+	LoginMethodPassword = "password"
+	LoginMethodSMS      = "sms"
+	LoginMethodEmail    = "email"
+	LoginMethodWechat   = "wechat"
+)
+
+// SecurityEvent 登录、刷新 token、退出登录之类和账号安全相关的事件
+type SecurityEvent struct {
+	Id  int64
+	Uid int64
+	// Account 登录失败的时候用户填的邮箱或者手机号，账号可能根本不存在。
+	// 第三方登录是供应商的用户标识
+	Account string
+	Type    string
+	// Method 登录方式，比如 password、sms、wechat
+	Method string
+	Result string
+	// Detail 失败的原因之类的补充信息
+	Detail    string
+	IP        string
+	UserAgent string
+	// Fingerprint 前端算的设备指纹，没有的话用 UserAgent 区分设备
+	Fingerprint string
+	// NewDevice 这个设备第一次登录
+	NewDevice bool
+	Ctime     time.Time
+}
@@ -0,0 +1,19 @@
+package domain
+
+// UserTOTP 用户的两步验证，Secret 是解密之后的
+type UserTOTP struct {
+	Uid    int64
+	Secret string
+	// Enabled 用户用动态码确认过之后才会生效
+	Enabled bool
+	// LastStep 最后一次用过的时间步，比它小的码都不能再用
+	LastStep int64
+}
+
+// TOTPEnrollment 开启两步验证的时候返回给用户，只展示这一次
+type TOTPEnrollment struct {
+	Secret string
+	// URI otpauth:// 开头，前端把它做成二维码
+	URI           string
+	RecoveryCodes []string
+}
@@ -3,14 +3,17 @@ package domain
 import "time"
 
 type User struct {
-	Id       int64
-	Email    string
-	Password string
+	Id    int64
+	Email string
+	// EmailVerified 用户证明过邮箱是自己的
+	EmailVerified bool
+	Password      string
 
 	Nickname string
 	// YYYY-MM-DD
 	Birthday time.Time
 	AboutMe  string
+	Avatar   string
 
 	Phone string
 
@@ -19,6 +22,8 @@ type User struct {
 
 	WechatInfo WechatInfo
 
+	Banned bool
+
 	//Addr Address
 }
 
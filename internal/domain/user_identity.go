@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// UserIdentity 用户绑定的第三方账号
+type UserIdentity struct {
+	Id       int64
+	Uid      int64
+	Provider string
+	Subject  string
+	UnionId  string
+	// Profile 供应商返回的原始用户信息，JSON
+	Profile string
+
+	AccessToken  string
+	RefreshToken string
+	TokenExpiry  time.Time
+	// Ctime 绑定的时间
+	Ctime time.Time
+}
@@ -0,0 +1,14 @@
+package startup
+
+import (
+	"gitee.com/geekbang/basic-go/webook/pkg/cryptox"
+)
+
+// InitCipher 测试环境用固定的密钥
+func InitCipher() *cryptox.AESGCM {
+	c, err := cryptox.NewAESGCM([]byte("test-only-data-encryption-key-32"))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
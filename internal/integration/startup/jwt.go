@@ -0,0 +1,23 @@
+package startup
+
+import (
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+)
+
+// InitJWTKeyRings 测试环境每种 token 只用一把固定的密钥
+func InitJWTKeyRings() *ijwt.KeyRings {
+	return &ijwt.KeyRings{
+		Access:    newKeyRing("k6CswdUm77WKcbM68UQUuxVsHSpTCwgK"),
+		Refresh:   newKeyRing("k6CswdUm77WKcbM68UQUuxVsHSpTCwgA"),
+		State:     newKeyRing("k6CswdUm77WKcbM68UQUuxVsHSpTCwgB"),
+		TwoFactor: newKeyRing("k6CswdUm77WKcbM68UQUuxVsHSpTCwgC"),
+	}
+}
+
+func newKeyRing(secret string) *ijwt.KeyRing {
+	ring, err := ijwt.NewKeyRing("test", []ijwt.Key{{Kid: "test", Secret: []byte(secret)}})
+	if err != nil {
+		panic(err)
+	}
+	return ring
+}
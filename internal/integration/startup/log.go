@@ -0,0 +1,7 @@
+package startup
+
+import "gitee.com/geekbang/basic-go/webook/pkg/logger"
+
+func InitLog() logger.LoggerV1 {
+	return logger.NewNopLogger()
+}
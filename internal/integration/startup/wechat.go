@@ -0,0 +1,11 @@
+package startup
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/wechat"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+// InitWechatService 测试环境不需要真的调用微信
+func InitWechatService(l logger.LoggerV1) wechat.Service {
+	return wechat.NewService("", "", "", l)
+}
@@ -8,6 +8,8 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"gitee.com/geekbang/basic-go/webook/internal/web"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	"gitee.com/geekbang/basic-go/webook/ioc"
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
@@ -17,24 +19,69 @@ func InitWebServer() *gin.Engine {
 	wire.Build(
 		// 第三方依赖
 		InitRedis, ioc.InitDB,
+		InitLog,
 		// DAO 部分
 		dao.NewUserDAO,
+		dao.NewArticleGORMDAO,
+		dao.NewGORMInteractiveDAO,
+		dao.NewGORMCollectionDAO,
+		dao.NewGORMRBACDAO,
+		dao.NewGORMTOTPDAO,
+		dao.NewGORMSecurityEventDAO,
+		dao.NewGORMUserIdentityDAO,
 
 		// cache 部分
 		cache.NewCodeCache, cache.NewUserCache,
+		cache.NewInteractiveRedisCache,
+		cache.NewLoginAttemptRedisCache,
+		cache.NewLikeRankRedisCache,
+		cache.NewRankingRedisCache,
+		cache.NewRankingLocalCache,
 
 		// repository 部分
 		repository.NewCachedUserRepository,
 		repository.NewCodeRepository,
+		repository.NewCachedArticleRepository,
+		repository.NewReadCntAggregator,
+		repository.NewCachedInteractiveRepository,
+		repository.NewCachedCollectionRepository,
+		repository.NewCachedRBACRepository,
+		repository.NewCachedTOTPRepository,
+		repository.NewCachedLoginAttemptRepository,
+		repository.NewCachedSecurityEventRepository,
+		repository.NewCachedUserIdentityRepository,
+		InitCipher,
+		repository.NewCachedRankingRepository,
 
 		// Service 部分
 		ioc.InitSMSService,
+		ioc.InitEmailService,
+		InitWechatService,
+		ioc.InitOAuth2Providers,
 		service.NewUserService,
 		service.NewCodeService,
+		service.NewArticleService,
+		service.NewInteractiveService,
+		service.NewCollectionService,
+		service.NewRBACService,
+		ioc.InitTOTPService,
+		service.NewLoginAttemptService,
+		service.NewSecurityEventService,
+		service.NewUserIdentityService,
+		service.NewLoggerNewDeviceNotifier,
+		service.NewBatchRankingService,
 
 		// handler 部分
 		web.NewUserHandler,
+		web.NewArticleHandler,
+		web.NewCollectionHandler,
+		InitJWTKeyRings,
+		ijwt.NewRedisJWTHandler,
+		web.NewOAuth2Handler,
+		web.NewJWKSHandler,
+		web.NewAdminHandler,
 
+		middleware.NewRouteAuthRegistry,
 		ioc.InitGinMiddlewares,
 		ioc.InitWebServer,
 	)
@@ -12,6 +12,8 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"gitee.com/geekbang/basic-go/webook/internal/web"
+	"gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	"gitee.com/geekbang/basic-go/webook/ioc"
 	"github.com/gin-gonic/gin"
 )
@@ -19,9 +21,16 @@ import (
 // Injectors from wire.go:
 
 func InitWebServer() *gin.Engine {
-	cmdable := ioc.InitRedis()
-	v := ioc.InitGinMiddlewares(cmdable)
-	db := ioc.InitDB()
+	cmdable := InitRedis()
+	keyRings := InitJWTKeyRings()
+	loggerV1 := InitLog()
+	db := ioc.InitDB(loggerV1)
+	rbacDAO := dao.NewGORMRBACDAO(db)
+	rbacRepository := repository.NewCachedRBACRepository(rbacDAO)
+	rbacService := service.NewRBACService(rbacRepository)
+	handler := jwt.NewRedisJWTHandler(cmdable, keyRings, rbacService)
+	routeAuthRegistry := middleware.NewRouteAuthRegistry()
+	v := ioc.InitGinMiddlewares(cmdable, handler, routeAuthRegistry, loggerV1)
 	userDAO := dao.NewUserDAO(db)
 	userCache := cache.NewUserCache(cmdable)
 	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
@@ -29,8 +38,46 @@ func InitWebServer() *gin.Engine {
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
 	smsService := ioc.InitSMSService()
-	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, codeService)
-	engine := ioc.InitWebServer(v, userHandler)
+	emailService := ioc.InitEmailService()
+	codeService := service.NewCodeService(codeRepository, smsService, emailService)
+	totpDAO := dao.NewGORMTOTPDAO(db)
+	aesgcm := InitCipher()
+	totpRepository := repository.NewCachedTOTPRepository(totpDAO, aesgcm)
+	totpService := ioc.InitTOTPService(totpRepository, userRepository, cmdable)
+	loginAttemptCache := cache.NewLoginAttemptRedisCache(cmdable)
+	loginAttemptRepository := repository.NewCachedLoginAttemptRepository(loginAttemptCache)
+	loginAttemptService := service.NewLoginAttemptService(loginAttemptRepository, userRepository)
+	securityEventDAO := dao.NewGORMSecurityEventDAO(db)
+	securityEventRepository := repository.NewCachedSecurityEventRepository(securityEventDAO)
+	newDeviceNotifier := service.NewLoggerNewDeviceNotifier(loggerV1)
+	securityEventService := service.NewSecurityEventService(securityEventRepository, userRepository, newDeviceNotifier, loggerV1)
+	userHandler := web.NewUserHandler(userService, handler, codeService, totpService, loginAttemptService, securityEventService)
+	articleDAO := dao.NewArticleGORMDAO(db)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, userRepository, loggerV1)
+	articleService := service.NewArticleService(articleRepository)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	readCntAggregator := repository.NewReadCntAggregator(interactiveDAO, interactiveCache, loggerV1)
+	likeRankCache := cache.NewLikeRankRedisCache(cmdable)
+	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, loggerV1, interactiveCache, readCntAggregator, likeRankCache)
+	interactiveService := service.NewInteractiveService(interactiveRepository)
+	rankingCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingCache, rankingLocalCache, loggerV1)
+	rankingService := service.NewBatchRankingService(articleService, interactiveService, rankingRepository)
+	articleHandler := web.NewArticleHandler(articleService, interactiveService, rankingService, loggerV1)
+	collectionDAO := dao.NewGORMCollectionDAO(db)
+	collectionRepository := repository.NewCachedCollectionRepository(collectionDAO, interactiveCache, loggerV1)
+	collectionService := service.NewCollectionService(collectionRepository)
+	collectionHandler := web.NewCollectionHandler(collectionService, loggerV1)
+	wechatService := InitWechatService(loggerV1)
+	providers := ioc.InitOAuth2Providers(wechatService)
+	userIdentityDAO := dao.NewGORMUserIdentityDAO(db)
+	userIdentityRepository := repository.NewCachedUserIdentityRepository(userIdentityDAO, userCache, aesgcm)
+	userIdentityService := service.NewUserIdentityService(userIdentityRepository, userRepository, providers)
+	oAuth2Handler := web.NewOAuth2Handler(providers, handler, keyRings, userIdentityService, securityEventService, totpService)
+	jwksHandler := web.NewJWKSHandler(keyRings)
+	adminHandler := web.NewAdminHandler(userService, articleService, rbacService, loginAttemptService, handler, loggerV1)
+	engine := ioc.InitWebServer(v, userHandler, articleHandler, collectionHandler, oAuth2Handler, jwksHandler, adminHandler, routeAuthRegistry)
 	return engine
 }
@@ -0,0 +1,77 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/redislock"
+)
+
+var _ Job = (*InteractiveReconcileJob)(nil)
+
+// InteractiveReconcileLockKey 定时核对和 reconcile-interactive 子命令抢的是同一把锁
+const InteractiveReconcileLockKey = "job:interactive_reconcile"
+
+// InteractiveReconcileJob 定时核对并修复点赞数和收藏数。
+// 每次运行都抢一次锁，跑完就释放，同一时刻只有一个实例在核对
+type InteractiveReconcileJob struct {
+	svc     service.InteractiveReconcileService
+	client  *redislock.Client
+	key     string
+	timeout time.Duration
+	l       logger.LoggerV1
+}
+
+// NewInteractiveReconcileJob timeout 是一次核对的超时时间
+func NewInteractiveReconcileJob(svc service.InteractiveReconcileService,
+	client *redislock.Client,
+	timeout time.Duration,
+	l logger.LoggerV1) *InteractiveReconcileJob {
+	return &InteractiveReconcileJob{
+		svc:     svc,
+		client:  client,
+		key:     InteractiveReconcileLockKey,
+		timeout: timeout,
+		l:       l,
+	}
+}
+
+func (r *InteractiveReconcileJob) Name() string {
+	return "interactive_reconcile"
+}
+
+func (r *InteractiveReconcileJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	lock, err := r.client.TryLock(ctx, r.key, r.timeout)
+	if errors.Is(err, redislock.ErrFailedToPreemptLock) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		uctx, ucancel := context.WithTimeout(context.Background(), time.Second)
+		defer ucancel()
+		if er := lock.Unlock(uctx); er != nil {
+			r.l.Error("释放核对任务的锁失败", logger.Field{Key: "error", Val: er})
+		}
+	}()
+	report, err := r.svc.Reconcile(ctx, true)
+	for _, diff := range report.Diffs {
+		r.l.Warn("互动计数和明细对不上",
+			logger.Field{Key: "biz", Val: diff.Biz},
+			logger.Field{Key: "biz_id", Val: diff.BizId},
+			logger.Field{Key: "like_cnt", Val: diff.LikeCnt},
+			logger.Field{Key: "actual_like_cnt", Val: diff.ActualLikeCnt},
+			logger.Field{Key: "collect_cnt", Val: diff.CollectCnt},
+			logger.Field{Key: "actual_collect_cnt", Val: diff.ActualCollectCnt})
+	}
+	r.l.Info("核对互动计数完成",
+		logger.Field{Key: "diffs", Val: len(report.Diffs)},
+		logger.Field{Key: "fixed", Val: report.Fixed})
+	return err
+}
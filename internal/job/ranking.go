@@ -0,0 +1,112 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/redislock"
+)
+
+var _ Job = (*RankingJob)(nil)
+
+// RankingJob 计算热榜。
+// 所有实例都会定时运行，但是只有拿到分布式锁的那个实例会真的计算。
+// 拿到锁之后一直续约、一直持有，直到续约失败或者 Close，避免每次都重新抢锁
+type RankingJob struct {
+	svc     service.RankingService
+	client  *redislock.Client
+	key     string
+	timeout time.Duration
+	l       logger.LoggerV1
+
+	// 保护 lock，Run 和续约失败的 goroutine 都会改它
+	localLock sync.Mutex
+	lock      *redislock.Lock
+}
+
+// NewRankingJob timeout 是一次计算的超时时间
+func NewRankingJob(svc service.RankingService,
+	client *redislock.Client,
+	timeout time.Duration,
+	l logger.LoggerV1) *RankingJob {
+	return &RankingJob{
+		svc:     svc,
+		client:  client,
+		key:     "job:ranking",
+		timeout: timeout,
+		l:       l,
+	}
+}
+
+func (r *RankingJob) Name() string {
+	return "ranking"
+}
+
+func (r *RankingJob) Run() error {
+	r.localLock.Lock()
+	lock := r.lock
+	if lock == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		var err error
+		// 过期时间比一次计算长，续约失败了也能撑到这次计算完
+		lock, err = r.client.TryLock(ctx, r.key, r.timeout)
+		cancel()
+		if errors.Is(err, redislock.ErrFailedToPreemptLock) {
+			// 别的实例在算
+			r.localLock.Unlock()
+			return nil
+		}
+		if err != nil {
+			r.localLock.Unlock()
+			return err
+		}
+		r.lock = lock
+		go r.autoRefresh(lock)
+	}
+	r.localLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	err := r.svc.TopN(ctx, lock.Token())
+	if errors.Is(err, service.ErrStaleFencingToken) {
+		// 锁已经被别的实例拿走了，这次算的结果没有写进去
+		r.l.Warn("热榜任务已经不再持有锁", logger.Field{Key: "token", Val: lock.Token()})
+		r.releaseLock(lock)
+		return nil
+	}
+	return err
+}
+
+func (r *RankingJob) autoRefresh(lock *redislock.Lock) {
+	err := lock.AutoRefresh(r.timeout/2, time.Second)
+	if err != nil {
+		// 续约失败，下一次 Run 的时候重新抢锁
+		r.l.Error("热榜任务续约失败", logger.Field{Key: "error", Val: err})
+		r.releaseLock(lock)
+	}
+}
+
+// releaseLock 只有 r.lock 还是这把锁的时候才清掉
+func (r *RankingJob) releaseLock(lock *redislock.Lock) {
+	r.localLock.Lock()
+	defer r.localLock.Unlock()
+	if r.lock == lock {
+		r.lock = nil
+	}
+}
+
+// Close 退出的时候主动释放锁，别的实例不用等锁过期就能接手
+func (r *RankingJob) Close(ctx context.Context) error {
+	r.localLock.Lock()
+	lock := r.lock
+	r.lock = nil
+	r.localLock.Unlock()
+	if lock == nil {
+		return nil
+	}
+	return lock.Unlock(ctx)
+}
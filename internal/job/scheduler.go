@@ -0,0 +1,91 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+// Scheduler 按照固定的间隔运行任务，同一个任务不会并发运行
+type Scheduler struct {
+	l     logger.LoggerV1
+	jobs  []scheduledJob
+	stop  chan struct{}
+	wg    sync.WaitGroup
+	start sync.Once
+}
+
+type scheduledJob struct {
+	job      Job
+	interval time.Duration
+}
+
+func NewScheduler(l logger.LoggerV1) *Scheduler {
+	return &Scheduler{
+		l:    l,
+		stop: make(chan struct{}),
+	}
+}
+
+// Add 要在 Start 之前调用
+func (s *Scheduler) Add(job Job, interval time.Duration) {
+	s.jobs = append(s.jobs, scheduledJob{job: job, interval: interval})
+}
+
+func (s *Scheduler) Start() {
+	s.start.Do(func() {
+		for _, sj := range s.jobs {
+			s.wg.Add(1)
+			go s.loop(sj)
+		}
+	})
+}
+
+// Stop 等正在运行的任务结束，再关闭需要收尾的任务，比如说释放分布式锁
+func (s *Scheduler) Stop(ctx context.Context) {
+	close(s.stop)
+	s.wg.Wait()
+	for _, sj := range s.jobs {
+		closer, ok := sj.job.(interface {
+			Close(ctx context.Context) error
+		})
+		if !ok {
+			continue
+		}
+		if err := closer.Close(ctx); err != nil {
+			s.l.Error("关闭任务失败",
+				logger.Field{Key: "name", Val: sj.job.Name()},
+				logger.Field{Key: "error", Val: err})
+		}
+	}
+}
+
+func (s *Scheduler) loop(sj scheduledJob) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(sj.interval)
+	defer ticker.Stop()
+	for {
+		s.run(sj.job)
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) run(job Job) {
+	start := time.Now()
+	err := job.Run()
+	if err != nil {
+		s.l.Error("运行任务失败",
+			logger.Field{Key: "name", Val: job.Name()},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	s.l.Debug("运行任务成功",
+		logger.Field{Key: "name", Val: job.Name()},
+		logger.Field{Key: "duration", Val: time.Since(start)})
+}
@@ -0,0 +1,7 @@
+package job
+
+// Job 定时任务
+type Job interface {
+	Name() string
+	Run() error
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+var ErrArticleNotFound = dao.ErrRecordNotFound
+
+type ArticleRepository interface {
+	Create(ctx context.Context, art domain.Article) (int64, error)
+	Update(ctx context.Context, art domain.Article) error
+	// Sync 存储并同步数据
+	Sync(ctx context.Context, art domain.Article) (int64, error)
+	SyncStatus(ctx context.Context, uid int64, id int64, status domain.ArticleStatus) error
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	GetById(ctx context.Context, id int64) (domain.Article, error)
+	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	// GetPubByIds 批量查询线上库，不会填充作者的名字
+	GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error)
+	// ListPub 分页查询 start 之后更新过的已发表文章，不会填充作者的名字
+	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error)
+}
+
+type CachedArticleRepository struct {
+	dao dao.ArticleDAO
+	// 线上库的文章要带上作者的名字，所以要查用户
+	userRepo UserRepository
+	l        logger.LoggerV1
+}
+
+func NewCachedArticleRepository(dao dao.ArticleDAO,
+	userRepo UserRepository,
+	l logger.LoggerV1) ArticleRepository {
+	return &CachedArticleRepository{
+		dao:      dao,
+		userRepo: userRepo,
+		l:        l,
+	}
+}
+
+func (c *CachedArticleRepository) Create(ctx context.Context, art domain.Article) (int64, error) {
+	return c.dao.Insert(ctx, c.toEntity(art))
+}
+
+func (c *CachedArticleRepository) Update(ctx context.Context, art domain.Article) error {
+	return c.dao.UpdateById(ctx, c.toEntity(art))
+}
+
+func (c *CachedArticleRepository) Sync(ctx context.Context, art domain.Article) (int64, error) {
+	return c.dao.Sync(ctx, c.toEntity(art))
+}
+
+func (c *CachedArticleRepository) SyncStatus(ctx context.Context, uid int64, id int64, status domain.ArticleStatus) error {
+	return c.dao.SyncStatus(ctx, uid, id, status.ToUint8())
+}
+
+func (c *CachedArticleRepository) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.GetByAuthor(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, c.toDomain(art))
+	}
+	return res, nil
+}
+
+func (c *CachedArticleRepository) GetById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := c.dao.GetById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	return c.toDomain(art), nil
+}
+
+func (c *CachedArticleRepository) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := c.dao.GetPubById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	res := c.toDomain(dao.Article(art))
+	// 读者要看到作者的名字
+	author, err := c.userRepo.FindById(ctx, art.AuthorId)
+	if err != nil {
+		// 查不到作者不影响读者看文章，记录一下就可以
+		c.l.Error("查询文章作者失败",
+			logger.Field{Key: "aid", Val: id},
+			logger.Field{Key: "error", Val: err})
+		return res, nil
+	}
+	res.Author.Name = author.Nickname
+	return res, nil
+}
+
+func (c *CachedArticleRepository) GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	if len(ids) == 0 {
+		return []domain.Article{}, nil
+	}
+	arts, err := c.dao.GetPubByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, c.toDomain(dao.Article(art)))
+	}
+	return res, nil
+}
+
+func (c *CachedArticleRepository) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.ListPub(ctx, start.UnixMilli(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, c.toDomain(dao.Article(art)))
+	}
+	return res, nil
+}
+
+func (c *CachedArticleRepository) toEntity(art domain.Article) dao.Article {
+	return dao.Article{
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		AuthorId: art.Author.Id,
+		Status:   art.Status.ToUint8(),
+	}
+}
+
+func (c *CachedArticleRepository) toDomain(art dao.Article) domain.Article {
+	return domain.Article{
+		Id:      art.Id,
+		Title:   art.Title,
+		Content: art.Content,
+		Author: domain.Author{
+			Id: art.AuthorId,
+		},
+		Status: domain.ArticleStatus(art.Status),
+		Ctime:  time.UnixMilli(art.Ctime),
+		Utime:  time.UnixMilli(art.Utime),
+	}
+}
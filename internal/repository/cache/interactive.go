@@ -1,12 +1,14 @@
 package cache
 
 import (
-	_ "embed"
 	"context"
+	_ "embed"
 	"fmt"
+	"strconv"
+	"time"
 
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
-	"github.com/go-redis/redis/v9"
+	"github.com/redis/go-redis/v9"
 )
 
 var (
@@ -14,56 +16,174 @@ var (
 	luaIncrCnt string
 )
 
-const fieldReadCnt = "read_cnt"
+const (
+	fieldReadCnt    = "read_cnt"
+	fieldLikeCnt    = "like_cnt"
+	fieldCollectCnt = "collect_cnt"
+)
 
 type InteractiveCache interface {
 	IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	// BatchIncrReadCntIfPresent 批量增加阅读数，三个切片一一对应
+	BatchIncrReadCntIfPresent(ctx context.Context, bizs []string, bizIds []int64, deltas []int64) error
 	IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error
 	DecrLikeCntIfPresent(ctx context.Context, biz string, id int64) error
 	IncrCollectCntIfPresent(ctx context.Context, biz string, id int64) error
+	DecrCollectCntIfPresent(ctx context.Context, biz string, id int64) error
 	Get(ctx context.Context, biz string, id int64) (domain.Interactive, error)
 	Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error
+	// GetByIds 只返回缓存里面有的，没有的 id 不在结果里面
+	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	BatchSet(ctx context.Context, biz string, intrs map[int64]domain.Interactive) error
+	// Del 删掉这些资源的缓存，下次查询的时候从数据库加载
+	Del(ctx context.Context, biz string, ids ...int64) error
 }
 
 type InteractiveRedisCache struct {
-	client redis.Cmdable
+	client     redis.Cmdable
+	expiration time.Duration
 }
 
 // DecrLikeCntIfPresent implements [InteractiveCache].
 func (i *InteractiveRedisCache) DecrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
-	panic("unimplemented")
+	key := i.key(biz, id)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldLikeCnt, -1).Err()
 }
 
-// Get implements [InteractiveCache].
+// Get 缓存里面没有的时候，返回 ErrKeyNotExist
 func (i *InteractiveRedisCache) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
-	panic("unimplemented")
+	key := i.key(biz, id)
+	res, err := i.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	if len(res) == 0 {
+		// HGetAll 在 key 不存在的时候不会返回 redis.Nil
+		return domain.Interactive{}, ErrKeyNotExist
+	}
+	return i.toDomain(res), nil
+}
+
+// GetByIds 用一个 pipeline 执行所有的 HGETALL
+func (i *InteractiveRedisCache) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	if len(ids) == 0 {
+		return map[int64]domain.Interactive{}, nil
+	}
+	pipe := i.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.HGetAll(ctx, i.key(biz, id)))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive, len(ids))
+	for idx, cmd := range cmds {
+		val := cmd.Val()
+		if len(val) == 0 {
+			continue
+		}
+		res[ids[idx]] = i.toDomain(val)
+	}
+	return res, nil
+}
+
+// BatchSet 用一个 pipeline 回写多个资源的缓存
+func (i *InteractiveRedisCache) BatchSet(ctx context.Context, biz string, intrs map[int64]domain.Interactive) error {
+	if len(intrs) == 0 {
+		return nil
+	}
+	pipe := i.client.Pipeline()
+	for id, intr := range intrs {
+		key := i.key(biz, id)
+		pipe.HSet(ctx, key,
+			fieldReadCnt, intr.ReadCnt,
+			fieldLikeCnt, intr.LikeCnt,
+			fieldCollectCnt, intr.CollectCnt)
+		pipe.Expire(ctx, key, i.expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 // IncrCollectCntIfPresent implements [InteractiveCache].
 func (i *InteractiveRedisCache) IncrCollectCntIfPresent(ctx context.Context, biz string, id int64) error {
-	panic("unimplemented")
+	key := i.key(biz, id)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldCollectCnt, 1).Err()
+}
+
+// DecrCollectCntIfPresent implements [InteractiveCache].
+func (i *InteractiveRedisCache) DecrCollectCntIfPresent(ctx context.Context, biz string, id int64) error {
+	key := i.key(biz, id)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldCollectCnt, -1).Err()
 }
 
 // IncrLikeCntIfPresent implements [InteractiveCache].
 func (i *InteractiveRedisCache) IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
-	panic("unimplemented")
+	key := i.key(biz, id)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldLikeCnt, 1).Err()
 }
 
 // IncrReadCntIfPresent implements [InteractiveCache].
 func (i *InteractiveRedisCache) IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error {
 	key := i.key(biz, bizId)
-	return i.client.Eval(ctx, luaIncrCnt,[]string{key},fieldReadCnt,1).Err()
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldReadCnt, 1).Err()
+}
+
+// BatchIncrReadCntIfPresent 在一个 pipeline 里面执行所有的 lua 脚本
+func (i *InteractiveRedisCache) BatchIncrReadCntIfPresent(ctx context.Context, bizs []string, bizIds []int64, deltas []int64) error {
+	if len(bizs) == 0 {
+		return nil
+	}
+	pipe := i.client.Pipeline()
+	for idx := range bizs {
+		key := i.key(bizs[idx], bizIds[idx])
+		pipe.Eval(ctx, luaIncrCnt, []string{key}, fieldReadCnt, deltas[idx])
+	}
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 // Set implements [InteractiveCache].
 func (i *InteractiveRedisCache) Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error {
-	panic("unimplemented")
+	key := i.key(biz, bizId)
+	err := i.client.HSet(ctx, key,
+		fieldReadCnt, res.ReadCnt,
+		fieldLikeCnt, res.LikeCnt,
+		fieldCollectCnt, res.CollectCnt).Err()
+	if err != nil {
+		return err
+	}
+	return i.client.Expire(ctx, key, i.expiration).Err()
 }
 
 func NewInteractiveRedisCache(client redis.Cmdable) InteractiveCache {
 	return &InteractiveRedisCache{
-		client: client,
+		client:     client,
+		expiration: time.Minute * 15,
+	}
+}
+
+// Del implements [InteractiveCache].
+func (i *InteractiveRedisCache) Del(ctx context.Context, biz string, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
 	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, i.key(biz, id))
+	}
+	return i.client.Del(ctx, keys...).Err()
+}
+
+// toDomain 字段不存在或者格式不对，当成 0 来处理
+func (i *InteractiveRedisCache) toDomain(res map[string]string) domain.Interactive {
+	var intr domain.Interactive
+	intr.ReadCnt, _ = strconv.ParseInt(res[fieldReadCnt], 10, 64)
+	intr.LikeCnt, _ = strconv.ParseInt(res[fieldLikeCnt], 10, 64)
+	intr.CollectCnt, _ = strconv.ParseInt(res[fieldCollectCnt], 10, 64)
+	return intr
 }
 
 func (i *InteractiveRedisCache) key(biz string, bizId int64) string {
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/incr_like_rank.lua
+	luaIncrLikeRank string
+	//go:embed lua/top_like_rank.lua
+	luaTopLikeRank string
+)
+
+const (
+	likeRankHoursPerDay = 24
+	likeRankDaysPerWeek = 7
+	// 重建的时候每一批写多少个
+	likeRankRebuildBatch = 1000
+)
+
+// LikeRankCache 点赞榜
+// 总榜是一个 ZSET，24 小时榜和 7 天榜分别按小时、按天分桶，查询的时候合并窗口内的桶。
+// 总榜同时也是整个点赞榜是否可用的标记：总榜不存在的时候不再更新，查询返回 ErrKeyNotExist，
+// 由上层从数据库重建。
+type LikeRankCache interface {
+	// IncrLikeCntIfPresent delta 是 1 或者 -1
+	IncrLikeCntIfPresent(ctx context.Context, biz string, bizId int64, delta int64) error
+	// Top 按照点赞数从高到低返回前 n 个
+	Top(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error)
+	// Rebuild 用数据库里面的数据重建点赞榜
+	// all 是总点赞数，hourly 是最近 7 天每个小时的点赞数，key 是 Unix 时间戳按小时取整
+	Rebuild(ctx context.Context, biz string, all []domain.LikeRankItem,
+		hourly map[int64][]domain.LikeRankItem) error
+}
+
+type LikeRankRedisCache struct {
+	client redis.Cmdable
+	// 窗口合并的结果缓存多久，这段时间内 24 小时榜和 7 天榜是不变的
+	unionExpiration time.Duration
+}
+
+func NewLikeRankRedisCache(client redis.Cmdable) LikeRankCache {
+	return &LikeRankRedisCache{
+		client:          client,
+		unionExpiration: time.Second * 10,
+	}
+}
+
+func (c *LikeRankRedisCache) IncrLikeCntIfPresent(ctx context.Context, biz string, bizId int64, delta int64) error {
+	hour := time.Now().UnixMilli() / time.Hour.Milliseconds()
+	day := hour / likeRankHoursPerDay
+	return c.client.Eval(ctx, luaIncrLikeRank,
+		[]string{c.allKey(biz), c.hourKey(biz, hour), c.dayKey(biz, day)},
+		bizId, delta,
+		int64(c.hourTTL().Seconds()), int64(c.dayTTL().Seconds())).Err()
+}
+
+// Top 总榜不存在的时候返回 ErrKeyNotExist
+func (c *LikeRankRedisCache) Top(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error) {
+	keys := []string{c.allKey(biz)}
+	hour := time.Now().UnixMilli() / time.Hour.Milliseconds()
+	switch window {
+	case domain.LikeRankWindowDay:
+		keys = append(keys, c.unionKey(biz, window))
+		for i := int64(0); i < likeRankHoursPerDay; i++ {
+			keys = append(keys, c.hourKey(biz, hour-i))
+		}
+	case domain.LikeRankWindowWeek:
+		keys = append(keys, c.unionKey(biz, window))
+		day := hour / likeRankHoursPerDay
+		for i := int64(0); i < likeRankDaysPerWeek; i++ {
+			keys = append(keys, c.dayKey(biz, day-i))
+		}
+	default:
+		keys = append(keys, c.allKey(biz))
+	}
+	vals, err := c.client.Eval(ctx, luaTopLikeRank, keys,
+		n, int64(c.unionExpiration.Seconds())).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	// 返回的是 member, score, member, score...
+	res := make([]domain.LikeRankItem, 0, len(vals)/2)
+	for i := 0; i+1 < len(vals); i += 2 {
+		bizId, err := strconv.ParseInt(vals[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		score, err := strconv.ParseFloat(vals[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		if score <= 0 {
+			// 在窗口内取消点赞比点赞还多，后面的都不用看了
+			break
+		}
+		res = append(res, domain.LikeRankItem{BizId: bizId, LikeCnt: int64(score)})
+	}
+	return res, nil
+}
+
+// Rebuild 先重建各个桶，最后再把总榜换上去。
+// 总榜先写到临时的 key 上再 RENAME，保证别人看到总榜的时候，榜单已经是完整的。
+func (c *LikeRankRedisCache) Rebuild(ctx context.Context, biz string,
+	all []domain.LikeRankItem, hourly map[int64][]domain.LikeRankItem) error {
+	now := time.Now()
+	hour := now.UnixMilli() / time.Hour.Milliseconds()
+	day := hour / likeRankHoursPerDay
+	daily := make(map[int64]map[int64]int64, likeRankDaysPerWeek)
+	pipe := c.client.Pipeline()
+	// 窗口内的桶和合并结果全部清掉再写
+	for i := int64(0); i < likeRankHoursPerDay; i++ {
+		pipe.Del(ctx, c.hourKey(biz, hour-i))
+	}
+	for i := int64(0); i < likeRankDaysPerWeek; i++ {
+		pipe.Del(ctx, c.dayKey(biz, day-i))
+	}
+	pipe.Del(ctx, c.unionKey(biz, domain.LikeRankWindowDay), c.unionKey(biz, domain.LikeRankWindowWeek))
+	for h, items := range hourly {
+		d := h / likeRankHoursPerDay
+		if daily[d] == nil {
+			daily[d] = make(map[int64]int64, len(items))
+		}
+		for _, item := range items {
+			daily[d][item.BizId] += item.LikeCnt
+		}
+		if hour-h >= likeRankHoursPerDay {
+			// 只有 24 小时榜要用到小时桶
+			continue
+		}
+		key := c.hourKey(biz, h)
+		pipe.ZAdd(ctx, key, c.members(items)...)
+		pipe.Expire(ctx, key, c.hourTTL())
+	}
+	for d, cnts := range daily {
+		if day-d >= likeRankDaysPerWeek {
+			continue
+		}
+		items := make([]domain.LikeRankItem, 0, len(cnts))
+		for bizId, cnt := range cnts {
+			items = append(items, domain.LikeRankItem{BizId: bizId, LikeCnt: cnt})
+		}
+		key := c.dayKey(biz, d)
+		pipe.ZAdd(ctx, key, c.members(items)...)
+		pipe.Expire(ctx, key, c.dayTTL())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	tmpKey := fmt.Sprintf("%s:rebuild:%d", c.allKey(biz), now.UnixNano())
+	for start := 0; start < len(all); start += likeRankRebuildBatch {
+		end := min(start+likeRankRebuildBatch, len(all))
+		err := c.client.ZAdd(ctx, tmpKey, c.members(all[start:end])...).Err()
+		if err != nil {
+			return err
+		}
+	}
+	if len(all) == 0 {
+		// 一个赞都没有，也要有总榜，不然每次查询都会重建。
+		// 放一个分数为 0 的占位成员，查询的时候会被过滤掉
+		err := c.client.ZAdd(ctx, tmpKey, redis.Z{Member: 0, Score: 0}).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return c.client.Rename(ctx, tmpKey, c.allKey(biz)).Err()
+}
+
+func (c *LikeRankRedisCache) members(items []domain.LikeRankItem) []redis.Z {
+	res := make([]redis.Z, 0, len(items))
+	for _, item := range items {
+		res = append(res, redis.Z{Member: item.BizId, Score: float64(item.LikeCnt)})
+	}
+	return res
+}
+
+// hourTTL 小时桶只有 24 小时榜会用，多留一个小时
+func (c *LikeRankRedisCache) hourTTL() time.Duration {
+	return time.Hour * (likeRankHoursPerDay + 1)
+}
+
+// dayTTL 天桶只有 7 天榜会用，多留一天
+func (c *LikeRankRedisCache) dayTTL() time.Duration {
+	return time.Hour * likeRankHoursPerDay * (likeRankDaysPerWeek + 1)
+}
+
+func (c *LikeRankRedisCache) allKey(biz string) string {
+	return fmt.Sprintf("like_rank:%s:all", biz)
+}
+
+func (c *LikeRankRedisCache) hourKey(biz string, hour int64) string {
+	return fmt.Sprintf("like_rank:%s:h:%d", biz, hour)
+}
+
+func (c *LikeRankRedisCache) dayKey(biz string, day int64) string {
+	return fmt.Sprintf("like_rank:%s:d:%d", biz, day)
+}
+
+func (c *LikeRankRedisCache) unionKey(biz string, window domain.LikeRankWindow) string {
+	return fmt.Sprintf("like_rank:%s:%s", biz, window)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/incr_login_failure.lua
+	luaIncrLoginFailure string
+	//go:embed lua/get_login_attempt.lua
+	luaGetLoginAttempt string
+)
+
+// LoginAttemptCache 登录失败计数，subject 是账号或者 IP
+type LoginAttemptCache interface {
+	Get(ctx context.Context, subject string) (domain.LoginAttempt, error)
+	// IncrFailure 记一次失败，达到 policy 的阈值就锁定
+	IncrFailure(ctx context.Context, subject string, policy domain.LoginLockPolicy) (domain.LoginAttempt, error)
+	// Reset 清掉失败次数和锁定
+	Reset(ctx context.Context, subject string) error
+}
+
+type LoginAttemptRedisCache struct {
+	client redis.Cmdable
+}
+
+func NewLoginAttemptRedisCache(client redis.Cmdable) LoginAttemptCache {
+	return &LoginAttemptRedisCache{client: client}
+}
+
+func (c *LoginAttemptRedisCache) Get(ctx context.Context, subject string) (domain.LoginAttempt, error) {
+	res, err := c.client.Eval(ctx, luaGetLoginAttempt, c.keys(subject)[:2]).Int64Slice()
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	return c.toDomain(res)
+}
+
+func (c *LoginAttemptRedisCache) IncrFailure(ctx context.Context, subject string,
+	policy domain.LoginLockPolicy) (domain.LoginAttempt, error) {
+	res, err := c.client.Eval(ctx, luaIncrLoginFailure, c.keys(subject),
+		policy.Window.Milliseconds(), policy.Threshold,
+		policy.LockDuration.Milliseconds(), policy.MaxLockDuration.Milliseconds()).Int64Slice()
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	return c.toDomain(res)
+}
+
+func (c *LoginAttemptRedisCache) Reset(ctx context.Context, subject string) error {
+	return c.client.Del(ctx, c.keys(subject)...).Err()
+}
+
+func (c *LoginAttemptRedisCache) toDomain(res []int64) (domain.LoginAttempt, error) {
+	if len(res) != 2 {
+		return domain.LoginAttempt{}, fmt.Errorf("登录失败计数的返回值不对 %v", res)
+	}
+	return domain.LoginAttempt{
+		Failures:  res[0],
+		LockedFor: time.Duration(res[1]) * time.Millisecond,
+	}, nil
+}
+
+// keys 失败次数、锁、连续被锁的次数
+func (c *LoginAttemptRedisCache) keys(subject string) []string {
+	return []string{
+		fmt.Sprintf("login_attempt:%s:fail", subject),
+		fmt.Sprintf("login_attempt:%s:lock", subject),
+		fmt.Sprintf("login_attempt:%s:level", subject),
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache/redismocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoginAttemptRedisCache_IncrFailure(t *testing.T) {
+	policy := domain.LoginLockPolicy{
+		Threshold:       5,
+		Window:          time.Minute * 15,
+		LockDuration:    time.Minute * 15,
+		MaxLockDuration: time.Hour * 24,
+	}
+	keys := []string{
+		"login_attempt:account:a@qq.com:fail",
+		"login_attempt:account:a@qq.com:lock",
+		"login_attempt:account:a@qq.com:level",
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantRes domain.LoginAttempt
+		wantErr error
+	}{
+		{
+			name: "还没到阈值",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				client := redismocks.NewMockCmdable(ctrl)
+				cmd := redis.NewCmd(context.Background())
+				cmd.SetVal([]any{int64(3), int64(0)})
+				client.EXPECT().Eval(gomock.Any(), luaIncrLoginFailure, keys,
+					[]any{int64(900000), int64(5), int64(900000), int64(86400000)}).Return(cmd)
+				return client
+			},
+			wantRes: domain.LoginAttempt{Failures: 3},
+		},
+		{
+			name: "这次失败触发锁定",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				client := redismocks.NewMockCmdable(ctrl)
+				cmd := redis.NewCmd(context.Background())
+				cmd.SetVal([]any{int64(5), int64(900000)})
+				client.EXPECT().Eval(gomock.Any(), luaIncrLoginFailure, keys,
+					[]any{int64(900000), int64(5), int64(900000), int64(86400000)}).Return(cmd)
+				return client
+			},
+			wantRes: domain.LoginAttempt{Failures: 5, LockedFor: time.Minute * 15},
+		},
+		{
+			name: "redis 出错",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				client := redismocks.NewMockCmdable(ctrl)
+				cmd := redis.NewCmd(context.Background())
+				cmd.SetErr(errors.New("redis 错误"))
+				client.EXPECT().Eval(gomock.Any(), luaIncrLoginFailure, keys,
+					[]any{int64(900000), int64(5), int64(900000), int64(86400000)}).Return(cmd)
+				return client
+			},
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewLoginAttemptRedisCache(tc.mock(ctrl))
+			res, err := c.IncrFailure(context.Background(), "account:a@qq.com", policy)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,7 @@
+-- KEYS[1] 失败次数，KEYS[2] 锁
+local cnt = tonumber(redis.call("get", KEYS[1])) or 0
+local ttl = redis.call("pttl", KEYS[2])
+if ttl < 0 then
+    ttl = 0
+end
+return {cnt, ttl}
@@ -0,0 +1,20 @@
+-- 总榜，总榜不存在说明要重建，这时候什么都不做
+local allKey = KEYS[1]
+-- 当前这个小时和当天的桶
+local hourKey = KEYS[2]
+local dayKey = KEYS[3]
+
+local member = ARGV[1]
+local delta = tonumber(ARGV[2])
+local hourTTL = tonumber(ARGV[3])
+local dayTTL = tonumber(ARGV[4])
+
+if redis.call("EXISTS", allKey) == 0 then
+    return 0
+end
+redis.call("ZINCRBY", allKey, delta, member)
+redis.call("ZINCRBY", hourKey, delta, member)
+redis.call("EXPIRE", hourKey, hourTTL)
+redis.call("ZINCRBY", dayKey, delta, member)
+redis.call("EXPIRE", dayKey, dayTTL)
+return 1
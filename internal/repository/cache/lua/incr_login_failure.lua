@@ -0,0 +1,37 @@
+-- 记一次登录失败，达到阈值就锁定
+-- KEYS[1] 失败次数，KEYS[2] 锁，KEYS[3] 连续被锁了几次
+local window = tonumber(ARGV[1])
+local threshold = tonumber(ARGV[2])
+local lockMs = tonumber(ARGV[3])
+local maxLockMs = tonumber(ARGV[4])
+
+-- 已经锁定了，不再累加
+local ttl = redis.call("pttl", KEYS[2])
+if ttl > 0 then
+    return {threshold, ttl}
+end
+
+local cnt = redis.call("incr", KEYS[1])
+if cnt == 1 then
+    redis.call("pexpire", KEYS[1], window)
+end
+if cnt < threshold then
+    return {cnt, 0}
+end
+
+-- 每多被锁一次，时间翻倍
+local level = redis.call("incr", KEYS[3])
+for i = 2, level do
+    lockMs = lockMs * 2
+    if lockMs >= maxLockMs then
+        break
+    end
+end
+if lockMs > maxLockMs then
+    lockMs = maxLockMs
+end
+redis.call("set", KEYS[2], level, "px", lockMs)
+-- 解锁之后一个窗口内又被锁，接着翻倍；之后就从头开始
+redis.call("pexpire", KEYS[3], lockMs + window)
+redis.call("del", KEYS[1])
+return {cnt, lockMs}
@@ -0,0 +1,17 @@
+-- 榜单
+local key = KEYS[1]
+-- 写入榜单的任务拿到的最大 fencing token
+local tokenKey = KEYS[2]
+
+local val = ARGV[1]
+local expiration = ARGV[2]
+local token = tonumber(ARGV[3])
+
+local latest = tonumber(redis.call("GET", tokenKey) or "0")
+if token < latest then
+    -- 锁已经被更新的任务拿走了，这是一次过期的写入
+    return 0
+end
+redis.call("SET", key, val, "EX", expiration)
+redis.call("SET", tokenKey, token)
+return 1
@@ -0,0 +1,21 @@
+-- 总榜，总榜不存在说明要重建
+local allKey = KEYS[1]
+-- 查询的目标，查总榜的时候就是总榜本身，否则是窗口内的桶合并之后的结果
+local destKey = KEYS[2]
+
+local n = tonumber(ARGV[1])
+local destTTL = tonumber(ARGV[2])
+
+if redis.call("EXISTS", allKey) == 0 then
+    return false
+end
+if #KEYS > 2 and redis.call("EXISTS", destKey) == 0 then
+    -- KEYS[3] 之后都是窗口内的桶
+    local buckets = {}
+    for i = 3, #KEYS do
+        buckets[#buckets + 1] = KEYS[i]
+    end
+    redis.call("ZUNIONSTORE", destKey, #buckets, unpack(buckets))
+    redis.call("EXPIRE", destKey, destTTL)
+end
+return redis.call("ZREVRANGE", destKey, 0, n - 1, "WITHSCORES")
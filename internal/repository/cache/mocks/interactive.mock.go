@@ -0,0 +1,201 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/cache/interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/cache/interactive.go -package=cachemocks -destination=./webook/internal/repository/cache/mocks/interactive.mock.go
+//
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveCache is a mock of InteractiveCache interface.
+type MockInteractiveCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveCacheMockRecorder
+}
+
+// MockInteractiveCacheMockRecorder is the mock recorder for MockInteractiveCache.
+type MockInteractiveCacheMockRecorder struct {
+	mock *MockInteractiveCache
+}
+
+// NewMockInteractiveCache creates a new mock instance.
+func NewMockInteractiveCache(ctrl *gomock.Controller) *MockInteractiveCache {
+	mock := &MockInteractiveCache{ctrl: ctrl}
+	mock.recorder = &MockInteractiveCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveCache) EXPECT() *MockInteractiveCacheMockRecorder {
+	return m.recorder
+}
+
+// BatchIncrReadCntIfPresent mocks base method.
+func (m *MockInteractiveCache) BatchIncrReadCntIfPresent(ctx context.Context, bizs []string, bizIds, deltas []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncrReadCntIfPresent", ctx, bizs, bizIds, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncrReadCntIfPresent indicates an expected call of BatchIncrReadCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) BatchIncrReadCntIfPresent(ctx, bizs, bizIds, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncrReadCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).BatchIncrReadCntIfPresent), ctx, bizs, bizIds, deltas)
+}
+
+// BatchSet mocks base method.
+func (m *MockInteractiveCache) BatchSet(ctx context.Context, biz string, intrs map[int64]domain.Interactive) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchSet", ctx, biz, intrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchSet indicates an expected call of BatchSet.
+func (mr *MockInteractiveCacheMockRecorder) BatchSet(ctx, biz, intrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchSet", reflect.TypeOf((*MockInteractiveCache)(nil).BatchSet), ctx, biz, intrs)
+}
+
+// DecrCollectCntIfPresent mocks base method.
+func (m *MockInteractiveCache) DecrCollectCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrCollectCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrCollectCntIfPresent indicates an expected call of DecrCollectCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) DecrCollectCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrCollectCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).DecrCollectCntIfPresent), ctx, biz, id)
+}
+
+// DecrLikeCntIfPresent mocks base method.
+func (m *MockInteractiveCache) DecrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrLikeCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrLikeCntIfPresent indicates an expected call of DecrLikeCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) DecrLikeCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrLikeCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).DecrLikeCntIfPresent), ctx, biz, id)
+}
+
+// Del mocks base method.
+func (m *MockInteractiveCache) Del(ctx context.Context, biz string, ids ...int64) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, biz}
+	for _, a := range ids {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Del", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Del indicates an expected call of Del.
+func (mr *MockInteractiveCacheMockRecorder) Del(ctx, biz any, ids ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, biz}, ids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockInteractiveCache)(nil).Del), varargs...)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveCache) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id)
+	ret0, _ := ret[0].(domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveCacheMockRecorder) Get(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveCache)(nil).Get), ctx, biz, id)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveCache) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids)
+	ret0, _ := ret[0].(map[int64]domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveCacheMockRecorder) GetByIds(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveCache)(nil).GetByIds), ctx, biz, ids)
+}
+
+// IncrCollectCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrCollectCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrCollectCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrCollectCntIfPresent indicates an expected call of IncrCollectCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrCollectCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrCollectCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrCollectCntIfPresent), ctx, biz, id)
+}
+
+// IncrLikeCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrLikeCntIfPresent", ctx, biz, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrLikeCntIfPresent indicates an expected call of IncrLikeCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrLikeCntIfPresent(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrLikeCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrLikeCntIfPresent), ctx, biz, id)
+}
+
+// IncrReadCntIfPresent mocks base method.
+func (m *MockInteractiveCache) IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCntIfPresent", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCntIfPresent indicates an expected call of IncrReadCntIfPresent.
+func (mr *MockInteractiveCacheMockRecorder) IncrReadCntIfPresent(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCntIfPresent", reflect.TypeOf((*MockInteractiveCache)(nil).IncrReadCntIfPresent), ctx, biz, bizId)
+}
+
+// Set mocks base method.
+func (m *MockInteractiveCache) Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, biz, bizId, res)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockInteractiveCacheMockRecorder) Set(ctx, biz, bizId, res any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockInteractiveCache)(nil).Set), ctx, biz, bizId, res)
+}
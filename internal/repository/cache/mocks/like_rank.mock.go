@@ -0,0 +1,83 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/cache/like_rank.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/cache/like_rank.go -package=cachemocks -destination=./webook/internal/repository/cache/mocks/like_rank.mock.go
+//
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLikeRankCache is a mock of LikeRankCache interface.
+type MockLikeRankCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockLikeRankCacheMockRecorder
+}
+
+// MockLikeRankCacheMockRecorder is the mock recorder for MockLikeRankCache.
+type MockLikeRankCacheMockRecorder struct {
+	mock *MockLikeRankCache
+}
+
+// NewMockLikeRankCache creates a new mock instance.
+func NewMockLikeRankCache(ctrl *gomock.Controller) *MockLikeRankCache {
+	mock := &MockLikeRankCache{ctrl: ctrl}
+	mock.recorder = &MockLikeRankCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLikeRankCache) EXPECT() *MockLikeRankCacheMockRecorder {
+	return m.recorder
+}
+
+// IncrLikeCntIfPresent mocks base method.
+func (m *MockLikeRankCache) IncrLikeCntIfPresent(ctx context.Context, biz string, bizId, delta int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrLikeCntIfPresent", ctx, biz, bizId, delta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrLikeCntIfPresent indicates an expected call of IncrLikeCntIfPresent.
+func (mr *MockLikeRankCacheMockRecorder) IncrLikeCntIfPresent(ctx, biz, bizId, delta any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrLikeCntIfPresent", reflect.TypeOf((*MockLikeRankCache)(nil).IncrLikeCntIfPresent), ctx, biz, bizId, delta)
+}
+
+// Rebuild mocks base method.
+func (m *MockLikeRankCache) Rebuild(ctx context.Context, biz string, all []domain.LikeRankItem, hourly map[int64][]domain.LikeRankItem) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebuild", ctx, biz, all, hourly)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rebuild indicates an expected call of Rebuild.
+func (mr *MockLikeRankCacheMockRecorder) Rebuild(ctx, biz, all, hourly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebuild", reflect.TypeOf((*MockLikeRankCache)(nil).Rebuild), ctx, biz, all, hourly)
+}
+
+// Top mocks base method.
+func (m *MockLikeRankCache) Top(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Top", ctx, biz, window, n)
+	ret0, _ := ret[0].([]domain.LikeRankItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Top indicates an expected call of Top.
+func (mr *MockLikeRankCacheMockRecorder) Top(ctx, biz, window, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Top", reflect.TypeOf((*MockLikeRankCache)(nil).Top), ctx, biz, window, n)
+}
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/cache/login_attempt.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/cache/login_attempt.go -package=cachemocks -destination=./webook/internal/repository/cache/mocks/login_attempt.mock.go
+//
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginAttemptCache is a mock of LoginAttemptCache interface.
+type MockLoginAttemptCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptCacheMockRecorder
+}
+
+// MockLoginAttemptCacheMockRecorder is the mock recorder for MockLoginAttemptCache.
+type MockLoginAttemptCacheMockRecorder struct {
+	mock *MockLoginAttemptCache
+}
+
+// NewMockLoginAttemptCache creates a new mock instance.
+func NewMockLoginAttemptCache(ctrl *gomock.Controller) *MockLoginAttemptCache {
+	mock := &MockLoginAttemptCache{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptCache) EXPECT() *MockLoginAttemptCacheMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockLoginAttemptCache) Get(ctx context.Context, subject string) (domain.LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, subject)
+	ret0, _ := ret[0].(domain.LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockLoginAttemptCacheMockRecorder) Get(ctx, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockLoginAttemptCache)(nil).Get), ctx, subject)
+}
+
+// IncrFailure mocks base method.
+func (m *MockLoginAttemptCache) IncrFailure(ctx context.Context, subject string, policy domain.LoginLockPolicy) (domain.LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrFailure", ctx, subject, policy)
+	ret0, _ := ret[0].(domain.LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrFailure indicates an expected call of IncrFailure.
+func (mr *MockLoginAttemptCacheMockRecorder) IncrFailure(ctx, subject, policy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrFailure", reflect.TypeOf((*MockLoginAttemptCache)(nil).IncrFailure), ctx, subject, policy)
+}
+
+// Reset mocks base method.
+func (m *MockLoginAttemptCache) Reset(ctx context.Context, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLoginAttemptCacheMockRecorder) Reset(ctx, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLoginAttemptCache)(nil).Reset), ctx, subject)
+}
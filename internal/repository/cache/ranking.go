@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/set_ranking.lua
+	luaSetRanking string
+)
+
+var (
+	// ErrStaleFencingToken 计算榜单的任务已经不再持有分布式锁了
+	ErrStaleFencingToken = errors.New("fencing token 已经过期")
+	// ErrLocalCacheExpired 本地缓存没有数据或者已经过期
+	ErrLocalCacheExpired = errors.New("本地缓存已经过期")
+)
+
+// RankingCache 热榜，整个榜单作为一个 JSON 存
+type RankingCache interface {
+	// Set token 是计算榜单的任务拿到的分布式锁的 fencing token，
+	// 比已经写入过的 token 小的时候返回 ErrStaleFencingToken
+	Set(ctx context.Context, arts []domain.Article, token int64) error
+	Get(ctx context.Context) ([]domain.Article, error)
+}
+
+type RankingRedisCache struct {
+	client     redis.Cmdable
+	key        string
+	expiration time.Duration
+}
+
+func NewRankingRedisCache(client redis.Cmdable) RankingCache {
+	return &RankingRedisCache{
+		client: client,
+		key:    "ranking:hot_article",
+		// 任务挂了一段时间，也还能用旧的榜单
+		expiration: time.Minute * 30,
+	}
+}
+
+func (r *RankingRedisCache) Set(ctx context.Context, arts []domain.Article, token int64) error {
+	val, err := json.Marshal(arts)
+	if err != nil {
+		return err
+	}
+	res, err := r.client.Eval(ctx, luaSetRanking, []string{r.key, r.key + ":fencing_token"},
+		val, int64(r.expiration.Seconds()), token).Int64()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrStaleFencingToken
+	}
+	return nil
+}
+
+func (r *RankingRedisCache) Get(ctx context.Context) ([]domain.Article, error) {
+	val, err := r.client.Get(ctx, r.key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Article
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+// RankingLocalCache 热榜的本地缓存，热榜是所有人看同一份，放在本地最合适
+type RankingLocalCache struct {
+	mu         sync.RWMutex
+	arts       []domain.Article
+	ddl        time.Time
+	expiration time.Duration
+}
+
+func NewRankingLocalCache() *RankingLocalCache {
+	return &RankingLocalCache{
+		// 其它实例要靠本地缓存过期才能看到新的榜单，所以不能太长
+		expiration: time.Minute,
+	}
+}
+
+func (r *RankingLocalCache) Set(arts []domain.Article) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.arts = arts
+	r.ddl = time.Now().Add(r.expiration)
+}
+
+// Get 没有数据或者已经过期，返回 ErrLocalCacheExpired
+func (r *RankingLocalCache) Get() ([]domain.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.arts) == 0 || time.Now().After(r.ddl) {
+		return nil, ErrLocalCacheExpired
+	}
+	return r.arts, nil
+}
+
+// ForceGet 不管有没有过期都返回，Redis 出问题的时候兜底用
+func (r *RankingLocalCache) ForceGet() ([]domain.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.arts) == 0 {
+		return nil, ErrLocalCacheExpired
+	}
+	return r.arts, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+var (
+	ErrCollectionNotFound  = dao.ErrCollectionNotFound
+	ErrDuplicateCollection = dao.ErrDuplicateCollectionBiz
+	ErrCollectItemNotFound = dao.ErrRecordNotFound
+)
+
+type CollectionRepository interface {
+	Create(ctx context.Context, c domain.Collection) (int64, error)
+	Rename(ctx context.Context, uid int64, id int64, name string) error
+	Delete(ctx context.Context, uid int64, id int64) error
+	List(ctx context.Context, uid int64) ([]domain.Collection, error)
+	MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error
+	ListItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]domain.CollectionItem, error)
+}
+
+type CachedCollectionRepository struct {
+	dao dao.CollectionDAO
+	// 删除收藏夹的时候，要同步减少缓存里面的收藏数
+	intrCache cache.InteractiveCache
+	l         logger.LoggerV1
+}
+
+func NewCachedCollectionRepository(dao dao.CollectionDAO,
+	intrCache cache.InteractiveCache,
+	l logger.LoggerV1) CollectionRepository {
+	return &CachedCollectionRepository{
+		dao:       dao,
+		intrCache: intrCache,
+		l:         l,
+	}
+}
+
+func (c *CachedCollectionRepository) Create(ctx context.Context, coll domain.Collection) (int64, error) {
+	return c.dao.Insert(ctx, dao.Collection{
+		Uid:  coll.Uid,
+		Name: coll.Name,
+	})
+}
+
+func (c *CachedCollectionRepository) Rename(ctx context.Context, uid int64, id int64, name string) error {
+	return c.dao.UpdateName(ctx, uid, id, name)
+}
+
+func (c *CachedCollectionRepository) Delete(ctx context.Context, uid int64, id int64) error {
+	items, err := c.dao.Delete(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		er := c.intrCache.DecrCollectCntIfPresent(ctx, item.Biz, item.BizId)
+		if er != nil {
+			c.l.Error("缓存减少收藏数失败",
+				logger.Field{Key: "biz", Val: item.Biz},
+				logger.Field{Key: "biz_id", Val: item.BizId},
+				logger.Field{Key: "error", Val: er})
+		}
+	}
+	return nil
+}
+
+func (c *CachedCollectionRepository) List(ctx context.Context, uid int64) ([]domain.Collection, error) {
+	colls, err := c.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Collection, 0, len(colls))
+	for _, coll := range colls {
+		res = append(res, domain.Collection{
+			Id:    coll.Id,
+			Uid:   coll.Uid,
+			Name:  coll.Name,
+			Ctime: time.UnixMilli(coll.Ctime),
+			Utime: time.UnixMilli(coll.Utime),
+		})
+	}
+	return res, nil
+}
+
+func (c *CachedCollectionRepository) MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error {
+	return c.dao.MoveItem(ctx, uid, biz, bizId, cid)
+}
+
+func (c *CachedCollectionRepository) ListItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]domain.CollectionItem, error) {
+	items, err := c.dao.FindItems(ctx, uid, cid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.CollectionItem, 0, len(items))
+	for _, item := range items {
+		res = append(res, domain.CollectionItem{
+			Biz:   item.Biz,
+			BizId: item.BizId,
+			Cid:   item.Cid,
+			Ctime: time.UnixMilli(item.Ctime),
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	cachemocks "gitee.com/geekbang/basic-go/webook/internal/repository/cache/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedCollectionRepository_Delete(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.CollectionDAO, cache.InteractiveCache)
+
+		wantErr error
+	}{
+		{
+			name: "删除成功，减少缓存收藏数",
+			mock: func(ctrl *gomock.Controller) (dao.CollectionDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockCollectionDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().Delete(gomock.Any(), int64(123), int64(1)).
+					Return([]dao.UserCollectionBiz{
+						{Biz: "article", BizId: 2},
+						{Biz: "article", BizId: 3},
+					}, nil)
+				c.EXPECT().DecrCollectCntIfPresent(gomock.Any(), "article", int64(2)).
+					Return(nil)
+				c.EXPECT().DecrCollectCntIfPresent(gomock.Any(), "article", int64(3)).
+					Return(errors.New("redis error"))
+				return d, c
+			},
+		},
+		{
+			name: "收藏夹不存在",
+			mock: func(ctrl *gomock.Controller) (dao.CollectionDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockCollectionDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().Delete(gomock.Any(), int64(123), int64(1)).
+					Return(nil, dao.ErrCollectionNotFound)
+				return d, c
+			},
+			wantErr: ErrCollectionNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedCollectionRepository(d, c, logger.NewNopLogger())
+			err := repo.Delete(context.Background(), 123, 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
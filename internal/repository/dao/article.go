@@ -3,11 +3,14 @@ package dao
 import (
 	"context"
 	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"time"
 )
 
+var ErrArticleIdOrAuthor = errors.New("ID 不对或者创作者不对")
+
 type ArticleDAO interface {
 	Insert(ctx context.Context, art Article) (int64, error)
 	UpdateById(ctx context.Context, entity Article) error
@@ -16,6 +19,10 @@ type ArticleDAO interface {
 	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error)
 	GetById(ctx context.Context, id int64) (Article, error)
 	GetPubById(ctx context.Context, id int64) (PublishedArticle, error)
+	// GetPubByIds 查不到的 id 不会出现在结果里面，结果的顺序也不保证和 ids 一致
+	GetPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error)
+	// ListPub 分页查询 start 之后更新过的已发表文章，按照更新时间倒序
+	ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error)
 }
 
 type ArticleGORMDAO struct {
@@ -30,6 +37,24 @@ func (a *ArticleGORMDAO) GetPubById(ctx context.Context, id int64) (PublishedArt
 	return res, err
 }
 
+func (a *ArticleGORMDAO) GetPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := a.db.WithContext(ctx).
+		Where("id IN ?", ids).
+		Find(&res).Error
+	return res, err
+}
+
+func (a *ArticleGORMDAO) ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := a.db.WithContext(ctx).
+		Where("utime > ? AND status = ?", start, domain.ArticleStatusPublished.ToUint8()).
+		Order("utime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
 func (a *ArticleGORMDAO) GetById(ctx context.Context, id int64) (Article, error) {
 	var art Article
 	err := a.db.WithContext(ctx).
@@ -51,8 +76,9 @@ func (a *ArticleGORMDAO) GetByAuthor(ctx context.Context, uid int64, offset int,
 func (a *ArticleGORMDAO) SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error {
 	now := time.Now().UnixMilli()
 	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 只有状态机允许的状态才能迁移过来
 		res := tx.Model(&Article{}).
-			Where("id = ? and author_id = ?", uid, id).
+			Where("id = ? AND author_id = ? AND status IN ?", id, uid, statusSources(status)).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -61,10 +87,10 @@ func (a *ArticleGORMDAO) SyncStatus(ctx context.Context, uid int64, id int64, st
 			return res.Error
 		}
 		if res.RowsAffected != 1 {
-			return errors.New("ID 不对或者创作者不对")
+			return (&ArticleGORMDAO{db: tx}).transitErr(ctx, id, uid, status)
 		}
 		return tx.Model(&PublishedArticle{}).
-			Where("id = ?", uid).
+			Where("id = ?", id).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -158,7 +184,8 @@ func (a *ArticleGORMDAO) SyncV1(ctx context.Context, art Article) (int64, error)
 func (a *ArticleGORMDAO) UpdateById(ctx context.Context, art Article) error {
 	now := time.Now().UnixMilli()
 	res := a.db.WithContext(ctx).Model(&art).
-		Where("id = ? AND author_id = ?", art.Id, art.AuthorId).Updates(map[string]any{
+		Where("id = ? AND author_id = ? AND status IN ?",
+			art.Id, art.AuthorId, statusSources(art.Status)).Updates(map[string]any{
 		"title":   art.Title,
 		"content": art.Content,
 		"status":  art.Status,
@@ -169,13 +196,34 @@ func (a *ArticleGORMDAO) UpdateById(ctx context.Context, art Article) error {
 	}
 	// 我怎么知道有没有更新数据？
 	if res.RowsAffected == 0 {
-		// 创作者不对，说明有人在瞎搞
-		return errors.New("ID 不对或者创作者不对")
+		// 要么创作者不对，说明有人在瞎搞；要么状态不允许
+		return a.transitErr(ctx, art.Id, art.AuthorId, art.Status)
 	}
 	return nil
 }
 
+// transitErr 条件更新没有命中的时候，查一下到底是为什么
+func (a *ArticleGORMDAO) transitErr(ctx context.Context, id int64, uid int64, to uint8) error {
+	var art Article
+	err := a.db.WithContext(ctx).Select("status").
+		Where("id = ? AND author_id = ?", id, uid).First(&art).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrArticleIdOrAuthor
+	}
+	if err != nil {
+		return err
+	}
+	if err = checkStatusTransit(art.Status, to); err != nil {
+		return err
+	}
+	return ErrArticleIdOrAuthor
+}
+
 func (a *ArticleGORMDAO) Insert(ctx context.Context, art Article) (int64, error) {
+	// 新建的文章是从未知状态迁移过来的
+	if err := checkStatusTransit(domain.ArticleStatusUnknown.ToUint8(), art.Status); err != nil {
+		return 0, err
+	}
 	now := time.Now().UnixMilli()
 	art.Ctime = now
 	art.Utime = now
@@ -189,6 +237,24 @@ func NewArticleGORMDAO(db *gorm.DB) ArticleDAO {
 	}
 }
 
+// statusSources 所有可以迁移到 to 的状态，用来拼 status IN (...)
+func statusSources(to uint8) []uint8 {
+	srcs := domain.ArticleStatus(to).Sources()
+	res := make([]uint8, 0, len(srcs))
+	for _, src := range srcs {
+		res = append(res, src.ToUint8())
+	}
+	return res
+}
+
+func checkStatusTransit(from uint8, to uint8) error {
+	f, t := domain.ArticleStatus(from), domain.ArticleStatus(to)
+	if !f.CanTransitTo(t) {
+		return &domain.ArticleStatusTransitionError{From: f, To: t}
+	}
+	return nil
+}
+
 type Article struct {
 	Id      int64  `gorm:"primaryKey,autoIncrement" bson:"id,omitempty"`
 	Title   string `gorm:"type=varchar(4096)" bson:"title,omitempty"`
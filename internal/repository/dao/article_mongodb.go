@@ -2,9 +2,9 @@ package dao
 
 import (
 	"context"
-	"errors"
 	"time"
 
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"github.com/bwmarrin/snowflake"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -34,6 +34,9 @@ var _ ArticleDAO = (*MongoDBArticleDAO)(nil)
 
 // 制作库插入一篇文章【雪花算法生成id】
 func (m *MongoDBArticleDAO) Insert(ctx context.Context, art Article) (int64, error) {
+	if err := checkStatusTransit(domain.ArticleStatusUnknown.ToUint8(), art.Status); err != nil {
+		return 0, err
+	}
 	now := time.Now().UnixMilli()
 	art.Ctime = now
 	art.Utime = now
@@ -46,7 +49,10 @@ func (m *MongoDBArticleDAO) Insert(ctx context.Context, art Article) (int64, err
 func (m *MongoDBArticleDAO) UpdateById(ctx context.Context, art Article) error {
 	now := time.Now().UnixMilli()
 
-	filter := bson.D{bson.E{Key: "id", Value: art.Id}}
+	// 状态机允许的才能更新
+	filter := bson.D{bson.E{Key: "id", Value: art.Id},
+		bson.E{Key: "author_id", Value: art.AuthorId},
+		bson.E{Key: "status", Value: bson.M{"$in": statusSources(art.Status)}}}
 	set := bson.D{bson.E{Key: "$set", Value: bson.M{
 		"title":   art.Title,
 		"content": art.Content,
@@ -57,12 +63,29 @@ func (m *MongoDBArticleDAO) UpdateById(ctx context.Context, art Article) error {
 	if err != nil {
 		return err
 	}
-	if res.ModifiedCount == 0 {
-		return errors.New("ID 不对或者创作者不对")
+	if res.MatchedCount == 0 {
+		return m.transitErr(ctx, art.Id, art.AuthorId, art.Status)
 	}
 	return nil
 }
 
+// transitErr 条件更新没有命中的时候，查一下到底是为什么
+func (m *MongoDBArticleDAO) transitErr(ctx context.Context, id int64, uid int64, to uint8) error {
+	var art Article
+	filter := bson.D{bson.E{Key: "id", Value: id}, bson.E{Key: "author_id", Value: uid}}
+	err := m.col.FindOne(ctx, filter).Decode(&art)
+	if err == mongo.ErrNoDocuments {
+		return ErrArticleIdOrAuthor
+	}
+	if err != nil {
+		return err
+	}
+	if err = checkStatusTransit(art.Status, to); err != nil {
+		return err
+	}
+	return ErrArticleIdOrAuthor
+}
+
 // 查询制作库文章列表（根据作者id分页）
 func (m *MongoDBArticleDAO) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
 	var arts []Article
@@ -99,6 +122,37 @@ func (m *MongoDBArticleDAO) GetPubById(ctx context.Context, id int64) (Published
 	return pubArt, err
 }
 
+// 批量查询线上库的文章（根据文章id）
+func (m *MongoDBArticleDAO) GetPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error) {
+	var pubArts []PublishedArticle
+	filter := bson.M{"id": bson.M{"$in": ids}}
+	res, err := m.liveCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	err = res.All(ctx, &pubArts)
+	return pubArts, err
+}
+
+// 分页查询线上库最近更新过的已发表文章
+func (m *MongoDBArticleDAO) ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error) {
+	var pubArts []PublishedArticle
+	filter := bson.M{
+		"utime":  bson.M{"$gt": start},
+		"status": domain.ArticleStatusPublished.ToUint8(),
+	}
+	opts := options.Find().
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{bson.E{Key: "utime", Value: -1}})
+	res, err := m.liveCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = res.All(ctx, &pubArts)
+	return pubArts, err
+}
+
 // 同步文章到线上库
 // 【可能是制作库也没有的，都是insert|
 // 可能是制作库已有制作库更新update，线上库insert|
@@ -137,13 +191,14 @@ func (m *MongoDBArticleDAO) SyncStatus(ctx context.Context, uid int64, id int64,
 		"status": status,
 	}}}
 
-	// 制作库更新
-	res, err := m.col.UpdateOne(ctx, filter, set)
+	// 制作库更新，只有状态机允许的状态才能迁移过来
+	colFilter := append(bson.D{bson.E{Key: "status", Value: bson.M{"$in": statusSources(status)}}}, filter...)
+	res, err := m.col.UpdateOne(ctx, colFilter, set)
 	if err != nil {
 		return err
 	}
-	if res.ModifiedCount == 0 {
-		return errors.New("ID 不对或者创作者不对")
+	if res.MatchedCount == 0 {
+		return m.transitErr(ctx, id, uid, status)
 	}
 	// 线上库更新，并且不用check
 	_, err = m.liveCol.UpdateOne(ctx, filter, set)
@@ -3,10 +3,10 @@ package dao
 import (
 	"bytes"
 	"context"
-	"errors"
 	"strconv"
 	"time"
 
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/ecodeclub/ekit"
 	"gorm.io/gorm"
@@ -89,7 +89,7 @@ func (a *ArticleS3DAO) SyncStatus(ctx context.Context, uid int64, id int64, stat
 	now := time.Now().UnixMilli()
 	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		res := tx.Model(&Article{}).
-			Where("id = ? and author_id = ?", uid, id).
+			Where("id = ? AND author_id = ? AND status IN ?", id, uid, statusSources(status)).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -98,10 +98,10 @@ func (a *ArticleS3DAO) SyncStatus(ctx context.Context, uid int64, id int64, stat
 			return res.Error
 		}
 		if res.RowsAffected != 1 {
-			return errors.New("ID 不对或者创作者不对")
+			return (&ArticleGORMDAO{db: tx}).transitErr(ctx, id, uid, status)
 		}
 		return tx.Model(&PulishedArticleV2{}).
-			Where("id = ?", uid).
+			Where("id = ?", id).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -111,9 +111,9 @@ func (a *ArticleS3DAO) SyncStatus(ctx context.Context, uid int64, id int64, stat
 		return err
 	}
 
-	// 只有设置的状态是私密的时候，删除oss上的内容
-	const statusPrivate = 3
-	if status == statusPrivate {
+	// 读者看不到的状态，删除oss上的内容
+	switch domain.ArticleStatus(status) {
+	case domain.ArticleStatusPrivate, domain.ArticleStatusArchived, domain.ArticleStatusDeleted:
 		_, err = a.oss.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 			Bucket: ekit.ToPtr[string]("webook-1314583317"),
 			Key:    ekit.ToPtr[string](strconv.FormatInt(id, 10)),
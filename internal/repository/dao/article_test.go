@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestArticleGORMDAO_UpdateById(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+		art  Article
+
+		wantErr error
+	}{
+		{
+			name: "更新成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+			art: Article{
+				Id:       1,
+				AuthorId: 123,
+				Status:   domain.ArticleStatusUnpublished.ToUint8(),
+			},
+		},
+		{
+			name: "已删除的文章不能再编辑",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				rows := sqlmock.NewRows([]string{"status"}).
+					AddRow(domain.ArticleStatusDeleted.ToUint8())
+				mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+				return db
+			},
+			art: Article{
+				Id:       1,
+				AuthorId: 123,
+				Status:   domain.ArticleStatusUnpublished.ToUint8(),
+			},
+			wantErr: &domain.ArticleStatusTransitionError{
+				From: domain.ArticleStatusDeleted,
+				To:   domain.ArticleStatusUnpublished,
+			},
+		},
+		{
+			name: "创作者不对",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT .*").WillReturnError(gorm.ErrRecordNotFound)
+				return db
+			},
+			art: Article{
+				Id:       1,
+				AuthorId: 456,
+				Status:   domain.ArticleStatusUnpublished.ToUint8(),
+			},
+			wantErr: ErrArticleIdOrAuthor,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			dao := NewArticleGORMDAO(db)
+			err = dao.UpdateById(context.Background(), tc.art)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestArticleGORMDAO_Insert_IllegalStatus(t *testing.T) {
+	// 不需要真的访问数据库，状态检查在前面就拦下来了
+	dao := NewArticleGORMDAO(nil)
+	_, err := dao.Insert(context.Background(), Article{
+		Status: domain.ArticleStatusDeleted.ToUint8(),
+	})
+	assert.Equal(t, &domain.ArticleStatusTransitionError{
+		From: domain.ArticleStatusUnknown,
+		To:   domain.ArticleStatusDeleted,
+	}, err)
+}
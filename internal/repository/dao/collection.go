@@ -0,0 +1,164 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrCollectionNotFound 收藏夹不存在，或者不是这个用户的
+var ErrCollectionNotFound = errors.New("收藏夹不存在")
+
+// CollectionDAO 收藏夹本身的操作
+// 会影响收藏数的收藏和取消收藏在 InteractiveDAO 里面
+type CollectionDAO interface {
+	Insert(ctx context.Context, c Collection) (int64, error)
+	UpdateName(ctx context.Context, uid int64, id int64, name string) error
+	// Delete 删除收藏夹，连带删除里面的收藏并减少收藏数
+	// 返回被删除的收藏，方便上层更新缓存
+	Delete(ctx context.Context, uid int64, id int64) ([]UserCollectionBiz, error)
+	FindByUid(ctx context.Context, uid int64) ([]Collection, error)
+	// MoveItem 把收藏挪到另外一个收藏夹
+	MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error
+	FindItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]UserCollectionBiz, error)
+}
+
+type GORMCollectionDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMCollectionDAO(db *gorm.DB) CollectionDAO {
+	return &GORMCollectionDAO{db: db}
+}
+
+func (g *GORMCollectionDAO) Insert(ctx context.Context, c Collection) (int64, error) {
+	now := time.Now().UnixMilli()
+	c.Ctime = now
+	c.Utime = now
+	err := g.db.WithContext(ctx).Create(&c).Error
+	return c.Id, err
+}
+
+func (g *GORMCollectionDAO) UpdateName(ctx context.Context, uid int64, id int64, name string) error {
+	res := g.db.WithContext(ctx).Model(&Collection{}).
+		Where("id = ? AND uid = ?", id, uid).
+		Updates(map[string]any{
+			"name":  name,
+			"utime": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+func (g *GORMCollectionDAO) Delete(ctx context.Context, uid int64, id int64) ([]UserCollectionBiz, error) {
+	var items []UserCollectionBiz
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND uid = ?", id, uid).Delete(&Collection{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCollectionNotFound
+		}
+		err := tx.Where("uid = ? AND cid = ?", uid, id).Find(&items).Error
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		err = tx.Where("uid = ? AND cid = ?", uid, id).Delete(&UserCollectionBiz{}).Error
+		if err != nil {
+			return err
+		}
+		// 同一个用户对同一个资源只能收藏一次，所以每一条都是减一
+		now := time.Now().UnixMilli()
+		for _, item := range items {
+			err = tx.Model(&Interactive{}).
+				Where("biz = ? AND biz_id = ?", item.Biz, item.BizId).
+				Updates(map[string]any{
+					"collect_cnt": gorm.Expr("`collect_cnt` - 1"),
+					"utime":       now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+func (g *GORMCollectionDAO) FindByUid(ctx context.Context, uid int64) ([]Collection, error) {
+	var res []Collection
+	err := g.db.WithContext(ctx).
+		Where("uid = ?", uid).
+		Order("ctime ASC").
+		Find(&res).Error
+	return res, err
+}
+
+func (g *GORMCollectionDAO) MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCollectionOwner(tx, uid, cid); err != nil {
+			return err
+		}
+		res := tx.Model(&UserCollectionBiz{}).
+			Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, bizId).
+			Updates(map[string]any{
+				"cid":   cid,
+				"utime": time.Now().UnixMilli(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (g *GORMCollectionDAO) FindItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	err := g.db.WithContext(ctx).
+		Where("uid = ? AND cid = ?", uid, cid).
+		Order("ctime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+// checkCollectionOwner 确认收藏夹是这个用户的，cid 为 0 的是默认收藏夹，不需要检查
+func checkCollectionOwner(tx *gorm.DB, uid int64, cid int64) error {
+	if cid == 0 {
+		return nil
+	}
+	var cnt int64
+	err := tx.Model(&Collection{}).
+		Where("id = ? AND uid = ?", cid, uid).
+		Count(&cnt).Error
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// Collection 收藏夹
+type Collection struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Uid   int64  `gorm:"index"`
+	Name  string `gorm:"type:varchar(128)"`
+	Ctime int64
+	Utime int64
+}
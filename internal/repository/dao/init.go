@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -12,7 +13,25 @@ import (
 
 func InitTables(db *gorm.DB) error {
 	// 严格来说，这个不是优秀实践
-	return db.AutoMigrate(&User{})
+	err := db.AutoMigrate(&User{},
+		&Article{},
+		&PublishedArticle{},
+		&Interactive{},
+		&UserLikeBiz{},
+		&UserCollectionBiz{},
+		&Collection{},
+		&Role{},
+		&RolePermission{},
+		&UserRole{},
+		&UserTOTP{},
+		&UserRecoveryCode{},
+		&SecurityEvent{},
+		&UserIdentity{},
+	)
+	if err != nil {
+		return err
+	}
+	return initRoles(db, domain.BuiltinRoles)
 }
 
 // 以前这里只用 gorm 初始化了 mysql 的表
@@ -27,10 +46,10 @@ func InitCollection(mdb *mongo.Database) error {
 	// 创建索引
 	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"id", 1}},
+			Keys:    bson.D{{Key: "id", Value: 1}},
 			Options: options.Index().SetUnique(true).SetName("idx_id")},
 		{
-			Keys: bson.D{{"author_id", 1}},
+			Keys: bson.D{{Key: "author_id", Value: 1}},
 		},
 	})
 	if err != nil {
@@ -41,11 +60,11 @@ func InitCollection(mdb *mongo.Database) error {
 	liveCol := mdb.Collection("published_articles")
 	_, err = liveCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"id", 1}},
+			Keys:    bson.D{{Key: "id", Value: 1}},
 			Options: options.Index().SetUnique(true).SetName("idx_id"),
 		},
 		{
-			Keys: bson.D{{"author_id", 1}},
+			Keys: bson.D{{Key: "author_id", Value: 1}},
 		},
 	})
 	return err
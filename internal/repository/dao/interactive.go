@@ -2,46 +2,269 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+var (
+	// ErrLikeInfoUnchanged 重复点赞或者重复取消点赞，点赞状态没有变化
+	ErrLikeInfoUnchanged = errors.New("点赞状态没有变化")
+	// ErrDuplicateCollectionBiz 同一个资源，一个用户只能收藏一次
+	ErrDuplicateCollectionBiz = errors.New("重复收藏")
+)
+
+const (
+	// likeStatusDeleted 取消了点赞，点赞记录是软删除的
+	likeStatusDeleted = 0
+	likeStatusValid   = 1
+)
+
 type InteractiveDAO interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
+	// BatchIncrReadCnt 一次性增加多个资源的阅读数，三个切片一一对应。
+	// 调用方要按照 (biz, bizId) 排好序，不然并发的批量写可能死锁
+	BatchIncrReadCnt(ctx context.Context, bizs []string, bizIds []int64, deltas []int64) error
 	InsertLikeInfo(ctx context.Context, biz string, id int64, uid int64) error
 	DeleteLikeInfo(ctx context.Context, biz string, id int64, uid int64) error
 	InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) error
+	DeleteCollectionBiz(ctx context.Context, biz string, id int64, uid int64) error
 	GetLikeInfo(ctx context.Context,
 		biz string, id int64, uid int64) (UserLikeBiz, error)
 	GetCollectInfo(ctx context.Context,
 		biz string, id int64, uid int64) (UserCollectionBiz, error)
 	Get(ctx context.Context, biz string, id int64) (Interactive, error)
+	// GetByIds 没有数据的 id 不会出现在结果里面
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
+	// GetLikeInfos 这个用户在 ids 里面点过赞的记录
+	GetLikeInfos(ctx context.Context, biz string, ids []int64, uid int64) ([]UserLikeBiz, error)
+	// GetCollectInfos 这个用户在 ids 里面收藏过的记录
+	GetCollectInfos(ctx context.Context, biz string, ids []int64, uid int64) ([]UserCollectionBiz, error)
+	// ListLikeCnt 按照 id 遍历有点赞的数据，返回 id 大于 startId 的最多 limit 条
+	ListLikeCnt(ctx context.Context, biz string, startId int64, limit int) ([]Interactive, error)
+	// CountLikesByHour 统计 since 之后的有效点赞，按照资源和小时分组
+	CountLikesByHour(ctx context.Context, biz string, since int64) ([]LikeHourCnt, error)
+	// ListLikeInfos 这个用户的有效点赞记录，按照 id 倒序，也就是第一次点赞的时间倒序，
+	// cursor 大于 0 的时候只返回 id 小于 cursor 的
+	ListLikeInfos(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]UserLikeBiz, error)
+	// ListCollectInfos 这个用户的收藏记录，分页方式和 ListLikeInfos 一样
+	ListCollectInfos(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]UserCollectionBiz, error)
+
+	// FindBatch 按照 id 遍历所有的计数，返回 id 大于 startId 的最多 limit 条
+	FindBatch(ctx context.Context, startId int64, limit int) ([]Interactive, error)
+	// CountLikes 按照明细表统计有效点赞数，没有点赞的 id 不在结果里面
+	CountLikes(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
+	// CountCollects 按照明细表统计收藏数，没有收藏的 id 不在结果里面
+	CountCollects(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
+	// FixCnts 用明细表重新统计这些资源的点赞数和收藏数
+	FixCnts(ctx context.Context, biz string, bizIds []int64) error
 }
 
 type GORMInteractiveDAO struct {
 	db *gorm.DB
 }
 
-// DeleteLikeInfo implements [InteractiveDAO].
+// DeleteLikeInfo 取消点赞，软删除点赞记录并且减少点赞数
 func (g *GORMInteractiveDAO) DeleteLikeInfo(ctx context.Context, biz string, id int64, uid int64) error {
-	panic("unimplemented")
+	now := time.Now().UnixMilli()
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&UserLikeBiz{}).
+			Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?",
+				uid, id, biz, likeStatusValid).
+			Updates(map[string]any{
+				"status": likeStatusDeleted,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 本来就没有点赞，不能再减一次
+			return ErrLikeInfoUnchanged
+		}
+		return tx.Model(&Interactive{}).
+			Where("biz = ? AND biz_id = ?", biz, id).
+			Updates(map[string]any{
+				"like_cnt": gorm.Expr("`like_cnt` - 1"),
+				"utime":    now,
+			}).Error
+	})
 }
 
 // Get implements [InteractiveDAO].
 func (g *GORMInteractiveDAO) Get(ctx context.Context, biz string, id int64) (Interactive, error) {
-	panic("unimplemented")
+	var res Interactive
+	err := g.db.WithContext(ctx).
+		Where("biz = ? AND biz_id = ?", biz, id).
+		First(&res).Error
+	return res, err
+}
+
+// GetByIds implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error) {
+	var res []Interactive
+	err := g.db.WithContext(ctx).
+		Where("biz = ? AND biz_id IN ?", biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
+// GetLikeInfos 只会返回有效的点赞记录
+func (g *GORMInteractiveDAO) GetLikeInfos(ctx context.Context, biz string, ids []int64, uid int64) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	err := g.db.WithContext(ctx).
+		Where("uid = ? AND biz = ? AND biz_id IN ? AND status = ?",
+			uid, biz, ids, likeStatusValid).
+		Find(&res).Error
+	return res, err
+}
+
+// GetCollectInfos implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) GetCollectInfos(ctx context.Context, biz string, ids []int64, uid int64) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	err := g.db.WithContext(ctx).
+		Where("uid = ? AND biz = ? AND biz_id IN ?", uid, biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
+// ListLikeCnt implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) ListLikeCnt(ctx context.Context, biz string, startId int64, limit int) ([]Interactive, error) {
+	var res []Interactive
+	err := g.db.WithContext(ctx).
+		Where("biz = ? AND id > ? AND like_cnt > 0", biz, startId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+// CountLikesByHour 取消之后再点赞的，按照最后一次点赞的时间算
+func (g *GORMInteractiveDAO) CountLikesByHour(ctx context.Context, biz string, since int64) ([]LikeHourCnt, error) {
+	var res []LikeHourCnt
+	hour := time.Hour.Milliseconds()
+	err := g.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Select("biz_id, utime DIV ? AS hour, COUNT(*) AS cnt", hour).
+		Where("biz = ? AND status = ? AND utime >= ?", biz, likeStatusValid, since).
+		Group("biz_id, hour").
+		Scan(&res).Error
+	return res, err
+}
+
+// ListLikeInfos implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) ListLikeInfos(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	query := g.db.WithContext(ctx).
+		Where("uid = ? AND biz = ? AND status = ?", uid, biz, likeStatusValid)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// ListCollectInfos implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) ListCollectInfos(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	query := g.db.WithContext(ctx).
+		Where("uid = ? AND biz = ?", uid, biz)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// FindBatch implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) FindBatch(ctx context.Context, startId int64, limit int) ([]Interactive, error) {
+	var res []Interactive
+	err := g.db.WithContext(ctx).
+		Where("id > ?", startId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+// CountLikes implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) CountLikes(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	var rows []bizCnt
+	err := g.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Select("biz_id, COUNT(*) AS cnt").
+		Where("biz = ? AND biz_id IN ? AND status = ?", biz, bizIds, likeStatusValid).
+		Group("biz_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toCntMap(rows), nil
+}
+
+// CountCollects implements [InteractiveDAO].
+func (g *GORMInteractiveDAO) CountCollects(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	var rows []bizCnt
+	err := g.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Select("biz_id, COUNT(*) AS cnt").
+		Where("biz = ? AND biz_id IN ?", biz, bizIds).
+		Group("biz_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return toCntMap(rows), nil
+}
+
+// FixCnts 统计和更新在同一条语句里面完成，
+// 不会覆盖掉统计之后、更新之前发生的点赞和收藏
+func (g *GORMInteractiveDAO) FixCnts(ctx context.Context, biz string, bizIds []int64) error {
+	likeCnt := g.db.Model(&UserLikeBiz{}).
+		Select("COUNT(*)").
+		Where("user_like_bizs.biz = interactives.biz AND user_like_bizs.biz_id = interactives.biz_id AND user_like_bizs.status = ?",
+			likeStatusValid)
+	collectCnt := g.db.Model(&UserCollectionBiz{}).
+		Select("COUNT(*)").
+		Where("user_collection_bizs.biz = interactives.biz AND user_collection_bizs.biz_id = interactives.biz_id")
+	return g.db.WithContext(ctx).Model(&Interactive{}).
+		Where("biz = ? AND biz_id IN ?", biz, bizIds).
+		Updates(map[string]any{
+			"like_cnt":    likeCnt,
+			"collect_cnt": collectCnt,
+			"utime":       time.Now().UnixMilli(),
+		}).Error
+}
+
+type bizCnt struct {
+	BizId int64
+	Cnt   int64
+}
+
+func toCntMap(rows []bizCnt) map[int64]int64 {
+	res := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		res[row.BizId] = row.Cnt
+	}
+	return res
 }
 
 // GetCollectInfo implements [InteractiveDAO].
 func (g *GORMInteractiveDAO) GetCollectInfo(ctx context.Context, biz string, id int64, uid int64) (UserCollectionBiz, error) {
-	panic("unimplemented")
+	var res UserCollectionBiz
+	err := g.db.WithContext(ctx).
+		Where("uid = ? AND biz_id = ? AND biz = ?", uid, id, biz).
+		First(&res).Error
+	return res, err
 }
 
-// GetLikeInfo implements [InteractiveDAO].
+// GetLikeInfo 只会返回有效的点赞记录
 func (g *GORMInteractiveDAO) GetLikeInfo(ctx context.Context, biz string, id int64, uid int64) (UserLikeBiz, error) {
-	panic("unimplemented")
+	var res UserLikeBiz
+	err := g.db.WithContext(ctx).
+		Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?",
+			uid, id, biz, likeStatusValid).
+		First(&res).Error
+	return res, err
 }
 
 // IncrReadCnt implements [InteractiveDAO].
@@ -54,22 +277,144 @@ func (g *GORMInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizId
 			"utime":    now,
 		}),
 	}).Create(&Interactive{
-		BizId:  bizId,
-		Biz:    biz,
+		BizId:   bizId,
+		Biz:     biz,
 		ReadCnt: 1,
-		Ctime:  now,
-		Utime: now,
+		Ctime:   now,
+		Utime:   now,
 	}).Error
 }
 
-// InsertCollectionBiz implements [InteractiveDAO].
+// BatchIncrReadCnt 用一条 INSERT ... ON DUPLICATE KEY UPDATE 语句批量增加阅读数
+func (g *GORMInteractiveDAO) BatchIncrReadCnt(ctx context.Context, bizs []string, bizIds []int64, deltas []int64) error {
+	if len(bizs) == 0 {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	intrs := make([]Interactive, 0, len(bizs))
+	for i := range bizs {
+		intrs = append(intrs, Interactive{
+			BizId:   bizIds[i],
+			Biz:     bizs[i],
+			ReadCnt: deltas[i],
+			Ctime:   now,
+			Utime:   now,
+		})
+	}
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "biz_id"}, {Name: "biz"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			// 新插入的行的 read_cnt 就是增量。
+			// VALUES() 在 MySQL 8.0.20 之后标记为废弃，但是 8.x 都还能用；
+			// GORM 生成不了行别名（AS new ... new.read_cnt）的写法，升级到不支持 VALUES() 的版本之前要改成原生 SQL
+			"read_cnt": gorm.Expr("`read_cnt` + VALUES(`read_cnt`)"),
+			"utime":    now,
+		}),
+	}).Create(&intrs).Error
+}
+
+// InsertCollectionBiz 收藏，在同一个事务里面插入收藏记录并且增加收藏数
 func (g *GORMInteractiveDAO) InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) error {
-	panic("unimplemented")
+	now := time.Now().UnixMilli()
+	cb.Ctime = now
+	cb.Utime = now
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 只能收藏到自己的收藏夹里面
+		if err := checkCollectionOwner(tx, cb.Uid, cb.Cid); err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cb)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrDuplicateCollectionBiz
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "biz_id"}, {Name: "biz"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"collect_cnt": gorm.Expr("`collect_cnt` + 1"),
+				"utime":       now,
+			}),
+		}).Create(&Interactive{
+			BizId:      cb.BizId,
+			Biz:        cb.Biz,
+			CollectCnt: 1,
+			Ctime:      now,
+			Utime:      now,
+		}).Error
+	})
+}
+
+// DeleteCollectionBiz 取消收藏，没有收藏过的返回 ErrRecordNotFound
+func (g *GORMInteractiveDAO) DeleteCollectionBiz(ctx context.Context, biz string, id int64, uid int64) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("uid = ? AND biz_id = ? AND biz = ?", uid, id, biz).
+			Delete(&UserCollectionBiz{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return tx.Model(&Interactive{}).
+			Where("biz = ? AND biz_id = ?", biz, id).
+			Updates(map[string]any{
+				"collect_cnt": gorm.Expr("`collect_cnt` - 1"),
+				"utime":       time.Now().UnixMilli(),
+			}).Error
+	})
 }
 
-// InsertLikeInfo implements [InteractiveDAO].
+// InsertLikeInfo 点赞，在同一个事务里面插入点赞记录并且增加点赞数
+// 已经点过赞的，返回 ErrLikeInfoUnchanged，不会重复计数
 func (g *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context, biz string, id int64, uid int64) error {
-	panic("unimplemented")
+	now := time.Now().UnixMilli()
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先试着恢复以前取消过的点赞
+		res := tx.Model(&UserLikeBiz{}).
+			Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?",
+				uid, id, biz, likeStatusDeleted).
+			Updates(map[string]any{
+				"status": likeStatusValid,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 要么从来没有点过赞，要么已经点过赞了
+			// 唯一索引冲突说明已经点过赞了，并发点赞也是靠唯一索引兜底
+			res = tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&UserLikeBiz{
+					Uid:    uid,
+					BizId:  id,
+					Biz:    biz,
+					Status: likeStatusValid,
+					Utime:  now,
+					Ctime:  now,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrLikeInfoUnchanged
+			}
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "biz_id"}, {Name: "biz"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"like_cnt": gorm.Expr("`like_cnt` + 1"),
+				"utime":    now,
+			}),
+		}).Create(&Interactive{
+			BizId:   id,
+			Biz:     biz,
+			LikeCnt: 1,
+			Ctime:   now,
+			Utime:   now,
+		}).Error
+	})
 }
 
 func NewGORMInteractiveDAO(db *gorm.DB) InteractiveDAO {
@@ -79,11 +424,19 @@ func NewGORMInteractiveDAO(db *gorm.DB) InteractiveDAO {
 // dao 里面几个结构体，其实就是数据库里几张表了
 // domain 里面只有 interactive 说明， UserLikeBiz， UserCollectionBiz 这两个表是对上层屏蔽的
 
+// LikeHourCnt 某个资源在某个小时内的点赞数，Hour 是 Unix 毫秒时间戳按小时取整
+type LikeHourCnt struct {
+	BizId int64
+	Hour  int64
+	Cnt   int64
+}
+
 type UserLikeBiz struct {
-	Id     int64  `gorm:"primaryKey,autoIncrement"`
-	Uid    int64  `gorm:"uniqueIndex:uid_biz_type_id"`
-	BizId  int64  `gorm:"uniqueIndex:uid_biz_type_id"`
-	Biz    string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Uid   int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	BizId int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	// 1 是点赞，0 是取消点赞
 	Status int
 	Utime  int64
 	Ctime  int64
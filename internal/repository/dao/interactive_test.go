@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestGORMInteractiveDAO_FixCnts(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	// 统计和更新要在同一条语句里面
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `interactives` SET "+
+		"`collect_cnt`=(SELECT COUNT(*) FROM `user_collection_bizs` WHERE user_collection_bizs.biz = interactives.biz AND user_collection_bizs.biz_id = interactives.biz_id),"+
+		"`like_cnt`=(SELECT COUNT(*) FROM `user_like_bizs` WHERE user_like_bizs.biz = interactives.biz AND user_like_bizs.biz_id = interactives.biz_id AND user_like_bizs.status = ?),"+
+		"`utime`=? WHERE biz = ? AND biz_id IN (?,?)")).
+		WithArgs(likeStatusValid, sqlmock.AnyArg(), "article", int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	dao := NewGORMInteractiveDAO(db)
+	err = dao.FixCnts(context.Background(), "article", []int64{1, 2})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/dao/collection.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/dao/collection.go -package=daomocks -destination=./webook/internal/repository/dao/mocks/collection.mock.go
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCollectionDAO is a mock of CollectionDAO interface.
+type MockCollectionDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockCollectionDAOMockRecorder
+}
+
+// MockCollectionDAOMockRecorder is the mock recorder for MockCollectionDAO.
+type MockCollectionDAOMockRecorder struct {
+	mock *MockCollectionDAO
+}
+
+// NewMockCollectionDAO creates a new mock instance.
+func NewMockCollectionDAO(ctrl *gomock.Controller) *MockCollectionDAO {
+	mock := &MockCollectionDAO{ctrl: ctrl}
+	mock.recorder = &MockCollectionDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCollectionDAO) EXPECT() *MockCollectionDAOMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockCollectionDAO) Delete(ctx context.Context, uid, id int64) ([]dao.UserCollectionBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, id)
+	ret0, _ := ret[0].([]dao.UserCollectionBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCollectionDAOMockRecorder) Delete(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCollectionDAO)(nil).Delete), ctx, uid, id)
+}
+
+// FindByUid mocks base method.
+func (m *MockCollectionDAO) FindByUid(ctx context.Context, uid int64) ([]dao.Collection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.Collection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockCollectionDAOMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockCollectionDAO)(nil).FindByUid), ctx, uid)
+}
+
+// FindItems mocks base method.
+func (m *MockCollectionDAO) FindItems(ctx context.Context, uid, cid int64, offset, limit int) ([]dao.UserCollectionBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindItems", ctx, uid, cid, offset, limit)
+	ret0, _ := ret[0].([]dao.UserCollectionBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindItems indicates an expected call of FindItems.
+func (mr *MockCollectionDAOMockRecorder) FindItems(ctx, uid, cid, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindItems", reflect.TypeOf((*MockCollectionDAO)(nil).FindItems), ctx, uid, cid, offset, limit)
+}
+
+// Insert mocks base method.
+func (m *MockCollectionDAO) Insert(ctx context.Context, c dao.Collection) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, c)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockCollectionDAOMockRecorder) Insert(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockCollectionDAO)(nil).Insert), ctx, c)
+}
+
+// MoveItem mocks base method.
+func (m *MockCollectionDAO) MoveItem(ctx context.Context, uid int64, biz string, bizId, cid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveItem", ctx, uid, biz, bizId, cid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveItem indicates an expected call of MoveItem.
+func (mr *MockCollectionDAOMockRecorder) MoveItem(ctx, uid, biz, bizId, cid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveItem", reflect.TypeOf((*MockCollectionDAO)(nil).MoveItem), ctx, uid, biz, bizId, cid)
+}
+
+// UpdateName mocks base method.
+func (m *MockCollectionDAO) UpdateName(ctx context.Context, uid, id int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateName", ctx, uid, id, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateName indicates an expected call of UpdateName.
+func (mr *MockCollectionDAOMockRecorder) UpdateName(ctx, uid, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateName", reflect.TypeOf((*MockCollectionDAO)(nil).UpdateName), ctx, uid, id, name)
+}
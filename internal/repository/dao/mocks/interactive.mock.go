@@ -0,0 +1,333 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/dao/interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/dao/interactive.go -package=daomocks -destination=./webook/internal/repository/dao/mocks/interactive.mock.go
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveDAO is a mock of InteractiveDAO interface.
+type MockInteractiveDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveDAOMockRecorder
+}
+
+// MockInteractiveDAOMockRecorder is the mock recorder for MockInteractiveDAO.
+type MockInteractiveDAOMockRecorder struct {
+	mock *MockInteractiveDAO
+}
+
+// NewMockInteractiveDAO creates a new mock instance.
+func NewMockInteractiveDAO(ctrl *gomock.Controller) *MockInteractiveDAO {
+	mock := &MockInteractiveDAO{ctrl: ctrl}
+	mock.recorder = &MockInteractiveDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveDAO) EXPECT() *MockInteractiveDAOMockRecorder {
+	return m.recorder
+}
+
+// BatchIncrReadCnt mocks base method.
+func (m *MockInteractiveDAO) BatchIncrReadCnt(ctx context.Context, bizs []string, bizIds, deltas []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchIncrReadCnt", ctx, bizs, bizIds, deltas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchIncrReadCnt indicates an expected call of BatchIncrReadCnt.
+func (mr *MockInteractiveDAOMockRecorder) BatchIncrReadCnt(ctx, bizs, bizIds, deltas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchIncrReadCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).BatchIncrReadCnt), ctx, bizs, bizIds, deltas)
+}
+
+// CountCollects mocks base method.
+func (m *MockInteractiveDAO) CountCollects(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountCollects", ctx, biz, bizIds)
+	ret0, _ := ret[0].(map[int64]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountCollects indicates an expected call of CountCollects.
+func (mr *MockInteractiveDAOMockRecorder) CountCollects(ctx, biz, bizIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCollects", reflect.TypeOf((*MockInteractiveDAO)(nil).CountCollects), ctx, biz, bizIds)
+}
+
+// CountLikes mocks base method.
+func (m *MockInteractiveDAO) CountLikes(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountLikes", ctx, biz, bizIds)
+	ret0, _ := ret[0].(map[int64]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountLikes indicates an expected call of CountLikes.
+func (mr *MockInteractiveDAOMockRecorder) CountLikes(ctx, biz, bizIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountLikes", reflect.TypeOf((*MockInteractiveDAO)(nil).CountLikes), ctx, biz, bizIds)
+}
+
+// CountLikesByHour mocks base method.
+func (m *MockInteractiveDAO) CountLikesByHour(ctx context.Context, biz string, since int64) ([]dao.LikeHourCnt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountLikesByHour", ctx, biz, since)
+	ret0, _ := ret[0].([]dao.LikeHourCnt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountLikesByHour indicates an expected call of CountLikesByHour.
+func (mr *MockInteractiveDAOMockRecorder) CountLikesByHour(ctx, biz, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountLikesByHour", reflect.TypeOf((*MockInteractiveDAO)(nil).CountLikesByHour), ctx, biz, since)
+}
+
+// DeleteCollectionBiz mocks base method.
+func (m *MockInteractiveDAO) DeleteCollectionBiz(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCollectionBiz", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCollectionBiz indicates an expected call of DeleteCollectionBiz.
+func (mr *MockInteractiveDAOMockRecorder) DeleteCollectionBiz(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCollectionBiz", reflect.TypeOf((*MockInteractiveDAO)(nil).DeleteCollectionBiz), ctx, biz, id, uid)
+}
+
+// DeleteLikeInfo mocks base method.
+func (m *MockInteractiveDAO) DeleteLikeInfo(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLikeInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLikeInfo indicates an expected call of DeleteLikeInfo.
+func (mr *MockInteractiveDAOMockRecorder) DeleteLikeInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLikeInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).DeleteLikeInfo), ctx, biz, id, uid)
+}
+
+// FindBatch mocks base method.
+func (m *MockInteractiveDAO) FindBatch(ctx context.Context, startId int64, limit int) ([]dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindBatch", ctx, startId, limit)
+	ret0, _ := ret[0].([]dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindBatch indicates an expected call of FindBatch.
+func (mr *MockInteractiveDAOMockRecorder) FindBatch(ctx, startId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBatch", reflect.TypeOf((*MockInteractiveDAO)(nil).FindBatch), ctx, startId, limit)
+}
+
+// FixCnts mocks base method.
+func (m *MockInteractiveDAO) FixCnts(ctx context.Context, biz string, bizIds []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FixCnts", ctx, biz, bizIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FixCnts indicates an expected call of FixCnts.
+func (mr *MockInteractiveDAOMockRecorder) FixCnts(ctx, biz, bizIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FixCnts", reflect.TypeOf((*MockInteractiveDAO)(nil).FixCnts), ctx, biz, bizIds)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveDAO) Get(ctx context.Context, biz string, id int64) (dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id)
+	ret0, _ := ret[0].(dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveDAOMockRecorder) Get(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveDAO)(nil).Get), ctx, biz, id)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids)
+	ret0, _ := ret[0].([]dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveDAOMockRecorder) GetByIds(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveDAO)(nil).GetByIds), ctx, biz, ids)
+}
+
+// GetCollectInfo mocks base method.
+func (m *MockInteractiveDAO) GetCollectInfo(ctx context.Context, biz string, id, uid int64) (dao.UserCollectionBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCollectInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(dao.UserCollectionBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCollectInfo indicates an expected call of GetCollectInfo.
+func (mr *MockInteractiveDAOMockRecorder) GetCollectInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollectInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).GetCollectInfo), ctx, biz, id, uid)
+}
+
+// GetCollectInfos mocks base method.
+func (m *MockInteractiveDAO) GetCollectInfos(ctx context.Context, biz string, ids []int64, uid int64) ([]dao.UserCollectionBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCollectInfos", ctx, biz, ids, uid)
+	ret0, _ := ret[0].([]dao.UserCollectionBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCollectInfos indicates an expected call of GetCollectInfos.
+func (mr *MockInteractiveDAOMockRecorder) GetCollectInfos(ctx, biz, ids, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollectInfos", reflect.TypeOf((*MockInteractiveDAO)(nil).GetCollectInfos), ctx, biz, ids, uid)
+}
+
+// GetLikeInfo mocks base method.
+func (m *MockInteractiveDAO) GetLikeInfo(ctx context.Context, biz string, id, uid int64) (dao.UserLikeBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLikeInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(dao.UserLikeBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLikeInfo indicates an expected call of GetLikeInfo.
+func (mr *MockInteractiveDAOMockRecorder) GetLikeInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLikeInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).GetLikeInfo), ctx, biz, id, uid)
+}
+
+// GetLikeInfos mocks base method.
+func (m *MockInteractiveDAO) GetLikeInfos(ctx context.Context, biz string, ids []int64, uid int64) ([]dao.UserLikeBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLikeInfos", ctx, biz, ids, uid)
+	ret0, _ := ret[0].([]dao.UserLikeBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLikeInfos indicates an expected call of GetLikeInfos.
+func (mr *MockInteractiveDAOMockRecorder) GetLikeInfos(ctx, biz, ids, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLikeInfos", reflect.TypeOf((*MockInteractiveDAO)(nil).GetLikeInfos), ctx, biz, ids, uid)
+}
+
+// IncrReadCnt mocks base method.
+func (m *MockInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCnt", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCnt indicates an expected call of IncrReadCnt.
+func (mr *MockInteractiveDAOMockRecorder) IncrReadCnt(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).IncrReadCnt), ctx, biz, bizId)
+}
+
+// InsertCollectionBiz mocks base method.
+func (m *MockInteractiveDAO) InsertCollectionBiz(ctx context.Context, cb dao.UserCollectionBiz) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertCollectionBiz", ctx, cb)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertCollectionBiz indicates an expected call of InsertCollectionBiz.
+func (mr *MockInteractiveDAOMockRecorder) InsertCollectionBiz(ctx, cb any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCollectionBiz", reflect.TypeOf((*MockInteractiveDAO)(nil).InsertCollectionBiz), ctx, cb)
+}
+
+// InsertLikeInfo mocks base method.
+func (m *MockInteractiveDAO) InsertLikeInfo(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertLikeInfo", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertLikeInfo indicates an expected call of InsertLikeInfo.
+func (mr *MockInteractiveDAOMockRecorder) InsertLikeInfo(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertLikeInfo", reflect.TypeOf((*MockInteractiveDAO)(nil).InsertLikeInfo), ctx, biz, id, uid)
+}
+
+// ListCollectInfos mocks base method.
+func (m *MockInteractiveDAO) ListCollectInfos(ctx context.Context, biz string, uid, cursor int64, limit int) ([]dao.UserCollectionBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCollectInfos", ctx, biz, uid, cursor, limit)
+	ret0, _ := ret[0].([]dao.UserCollectionBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCollectInfos indicates an expected call of ListCollectInfos.
+func (mr *MockInteractiveDAOMockRecorder) ListCollectInfos(ctx, biz, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCollectInfos", reflect.TypeOf((*MockInteractiveDAO)(nil).ListCollectInfos), ctx, biz, uid, cursor, limit)
+}
+
+// ListLikeCnt mocks base method.
+func (m *MockInteractiveDAO) ListLikeCnt(ctx context.Context, biz string, startId int64, limit int) ([]dao.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLikeCnt", ctx, biz, startId, limit)
+	ret0, _ := ret[0].([]dao.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLikeCnt indicates an expected call of ListLikeCnt.
+func (mr *MockInteractiveDAOMockRecorder) ListLikeCnt(ctx, biz, startId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLikeCnt", reflect.TypeOf((*MockInteractiveDAO)(nil).ListLikeCnt), ctx, biz, startId, limit)
+}
+
+// ListLikeInfos mocks base method.
+func (m *MockInteractiveDAO) ListLikeInfos(ctx context.Context, biz string, uid, cursor int64, limit int) ([]dao.UserLikeBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLikeInfos", ctx, biz, uid, cursor, limit)
+	ret0, _ := ret[0].([]dao.UserLikeBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLikeInfos indicates an expected call of ListLikeInfos.
+func (mr *MockInteractiveDAOMockRecorder) ListLikeInfos(ctx, biz, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLikeInfos", reflect.TypeOf((*MockInteractiveDAO)(nil).ListLikeInfos), ctx, biz, uid, cursor, limit)
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/dao/rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/dao/rbac.go -package=daomocks -destination=./webook/internal/repository/dao/mocks/rbac.mock.go
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACDAO is a mock of RBACDAO interface.
+type MockRBACDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACDAOMockRecorder
+}
+
+// MockRBACDAOMockRecorder is the mock recorder for MockRBACDAO.
+type MockRBACDAOMockRecorder struct {
+	mock *MockRBACDAO
+}
+
+// NewMockRBACDAO creates a new mock instance.
+func NewMockRBACDAO(ctrl *gomock.Controller) *MockRBACDAO {
+	mock := &MockRBACDAO{ctrl: ctrl}
+	mock.recorder = &MockRBACDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACDAO) EXPECT() *MockRBACDAOMockRecorder {
+	return m.recorder
+}
+
+// FindPermissions mocks base method.
+func (m *MockRBACDAO) FindPermissions(ctx context.Context, roleIds []int64) ([]dao.RolePermission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissions", ctx, roleIds)
+	ret0, _ := ret[0].([]dao.RolePermission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissions indicates an expected call of FindPermissions.
+func (mr *MockRBACDAOMockRecorder) FindPermissions(ctx, roleIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissions", reflect.TypeOf((*MockRBACDAO)(nil).FindPermissions), ctx, roleIds)
+}
+
+// FindRolesByNames mocks base method.
+func (m *MockRBACDAO) FindRolesByNames(ctx context.Context, names []string) ([]dao.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRolesByNames", ctx, names)
+	ret0, _ := ret[0].([]dao.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRolesByNames indicates an expected call of FindRolesByNames.
+func (mr *MockRBACDAOMockRecorder) FindRolesByNames(ctx, names any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRolesByNames", reflect.TypeOf((*MockRBACDAO)(nil).FindRolesByNames), ctx, names)
+}
+
+// FindRolesByUid mocks base method.
+func (m *MockRBACDAO) FindRolesByUid(ctx context.Context, uid int64) ([]dao.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRolesByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRolesByUid indicates an expected call of FindRolesByUid.
+func (mr *MockRBACDAOMockRecorder) FindRolesByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRolesByUid", reflect.TypeOf((*MockRBACDAO)(nil).FindRolesByUid), ctx, uid)
+}
+
+// SetUserRoles mocks base method.
+func (m *MockRBACDAO) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, uid, roleIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRoles indicates an expected call of SetUserRoles.
+func (mr *MockRBACDAOMockRecorder) SetUserRoles(ctx, uid, roleIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockRBACDAO)(nil).SetUserRoles), ctx, uid, roleIds)
+}
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/dao/security_event.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/dao/security_event.go -package=daomocks -destination=./webook/internal/repository/dao/mocks/security_event.mock.go
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSecurityEventDAO is a mock of SecurityEventDAO interface.
+type MockSecurityEventDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecurityEventDAOMockRecorder
+}
+
+// MockSecurityEventDAOMockRecorder is the mock recorder for MockSecurityEventDAO.
+type MockSecurityEventDAOMockRecorder struct {
+	mock *MockSecurityEventDAO
+}
+
+// NewMockSecurityEventDAO creates a new mock instance.
+func NewMockSecurityEventDAO(ctrl *gomock.Controller) *MockSecurityEventDAO {
+	mock := &MockSecurityEventDAO{ctrl: ctrl}
+	mock.recorder = &MockSecurityEventDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecurityEventDAO) EXPECT() *MockSecurityEventDAOMockRecorder {
+	return m.recorder
+}
+
+// FindByUid mocks base method.
+func (m *MockSecurityEventDAO) FindByUid(ctx context.Context, uid, cursor int64, limit int) ([]dao.SecurityEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid, cursor, limit)
+	ret0, _ := ret[0].([]dao.SecurityEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockSecurityEventDAOMockRecorder) FindByUid(ctx, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockSecurityEventDAO)(nil).FindByUid), ctx, uid, cursor, limit)
+}
+
+// HasLogin mocks base method.
+func (m *MockSecurityEventDAO) HasLogin(ctx context.Context, uid int64, device string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasLogin", ctx, uid, device)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasLogin indicates an expected call of HasLogin.
+func (mr *MockSecurityEventDAOMockRecorder) HasLogin(ctx, uid, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasLogin", reflect.TypeOf((*MockSecurityEventDAO)(nil).HasLogin), ctx, uid, device)
+}
+
+// Insert mocks base method.
+func (m *MockSecurityEventDAO) Insert(ctx context.Context, evt dao.SecurityEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockSecurityEventDAOMockRecorder) Insert(ctx, evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockSecurityEventDAO)(nil).Insert), ctx, evt)
+}
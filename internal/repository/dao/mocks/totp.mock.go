@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/dao/totp.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/dao/totp.go -package=daomocks -destination=./webook/internal/repository/dao/mocks/totp.mock.go
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTOTPDAO is a mock of TOTPDAO interface.
+type MockTOTPDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockTOTPDAOMockRecorder
+}
+
+// MockTOTPDAOMockRecorder is the mock recorder for MockTOTPDAO.
+type MockTOTPDAOMockRecorder struct {
+	mock *MockTOTPDAO
+}
+
+// NewMockTOTPDAO creates a new mock instance.
+func NewMockTOTPDAO(ctrl *gomock.Controller) *MockTOTPDAO {
+	mock := &MockTOTPDAO{ctrl: ctrl}
+	mock.recorder = &MockTOTPDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTOTPDAO) EXPECT() *MockTOTPDAOMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockTOTPDAO) Delete(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTOTPDAOMockRecorder) Delete(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTOTPDAO)(nil).Delete), ctx, uid)
+}
+
+// Enable mocks base method.
+func (m *MockTOTPDAO) Enable(ctx context.Context, uid, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockTOTPDAOMockRecorder) Enable(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockTOTPDAO)(nil).Enable), ctx, uid, step)
+}
+
+// FindByUid mocks base method.
+func (m *MockTOTPDAO) FindByUid(ctx context.Context, uid int64) (dao.UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].(dao.UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockTOTPDAOMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockTOTPDAO)(nil).FindByUid), ctx, uid)
+}
+
+// UpdateLastStep mocks base method.
+func (m *MockTOTPDAO) UpdateLastStep(ctx context.Context, uid, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastStep", ctx, uid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastStep indicates an expected call of UpdateLastStep.
+func (mr *MockTOTPDAOMockRecorder) UpdateLastStep(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastStep", reflect.TypeOf((*MockTOTPDAO)(nil).UpdateLastStep), ctx, uid, step)
+}
+
+// UpsertPending mocks base method.
+func (m *MockTOTPDAO) UpsertPending(ctx context.Context, t dao.UserTOTP, codes []dao.UserRecoveryCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertPending", ctx, t, codes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertPending indicates an expected call of UpsertPending.
+func (mr *MockTOTPDAOMockRecorder) UpsertPending(ctx, t, codes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertPending", reflect.TypeOf((*MockTOTPDAO)(nil).UpsertPending), ctx, t, codes)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTOTPDAO) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, uid, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTOTPDAOMockRecorder) UseRecoveryCode(ctx, uid, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTOTPDAO)(nil).UseRecoveryCode), ctx, uid, codeHash)
+}
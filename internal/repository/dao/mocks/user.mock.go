@@ -84,6 +84,21 @@ func (mr *MockUserDAOMockRecorder) FindByPhone(ctx, phone any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPhone", reflect.TypeOf((*MockUserDAO)(nil).FindByPhone), ctx, phone)
 }
 
+// FindByWechat mocks base method.
+func (m *MockUserDAO) FindByWechat(ctx context.Context, openId string) (dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByWechat", ctx, openId)
+	ret0, _ := ret[0].(dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByWechat indicates an expected call of FindByWechat.
+func (mr *MockUserDAOMockRecorder) FindByWechat(ctx, openId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechat", reflect.TypeOf((*MockUserDAO)(nil).FindByWechat), ctx, openId)
+}
+
 // Insert mocks base method.
 func (m *MockUserDAO) Insert(ctx context.Context, u dao.User) error {
 	m.ctrl.T.Helper()
@@ -98,6 +113,20 @@ func (mr *MockUserDAOMockRecorder) Insert(ctx, u any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockUserDAO)(nil).Insert), ctx, u)
 }
 
+// UpdateBanned mocks base method.
+func (m *MockUserDAO) UpdateBanned(ctx context.Context, uid int64, banned bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBanned", ctx, uid, banned)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateBanned indicates an expected call of UpdateBanned.
+func (mr *MockUserDAOMockRecorder) UpdateBanned(ctx, uid, banned any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBanned", reflect.TypeOf((*MockUserDAO)(nil).UpdateBanned), ctx, uid, banned)
+}
+
 // UpdateById mocks base method.
 func (m *MockUserDAO) UpdateById(ctx context.Context, entity dao.User) error {
 	m.ctrl.T.Helper()
@@ -111,3 +140,31 @@ func (mr *MockUserDAOMockRecorder) UpdateById(ctx, entity any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateById", reflect.TypeOf((*MockUserDAO)(nil).UpdateById), ctx, entity)
 }
+
+// UpdateEmailVerified mocks base method.
+func (m *MockUserDAO) UpdateEmailVerified(ctx context.Context, uid int64, clearPassword bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmailVerified", ctx, uid, clearPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmailVerified indicates an expected call of UpdateEmailVerified.
+func (mr *MockUserDAOMockRecorder) UpdateEmailVerified(ctx, uid, clearPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmailVerified", reflect.TypeOf((*MockUserDAO)(nil).UpdateEmailVerified), ctx, uid, clearPassword)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserDAO) UpdatePassword(ctx context.Context, uid int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, uid, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserDAOMockRecorder) UpdatePassword(ctx, uid, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserDAO)(nil).UpdatePassword), ctx, uid, password)
+}
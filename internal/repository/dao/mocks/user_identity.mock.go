@@ -0,0 +1,156 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/dao/user_identity.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/dao/user_identity.go -package=daomocks -destination=./webook/internal/repository/dao/mocks/user_identity.mock.go
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserIdentityDAO is a mock of UserIdentityDAO interface.
+type MockUserIdentityDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserIdentityDAOMockRecorder
+}
+
+// MockUserIdentityDAOMockRecorder is the mock recorder for MockUserIdentityDAO.
+type MockUserIdentityDAOMockRecorder struct {
+	mock *MockUserIdentityDAO
+}
+
+// NewMockUserIdentityDAO creates a new mock instance.
+func NewMockUserIdentityDAO(ctrl *gomock.Controller) *MockUserIdentityDAO {
+	mock := &MockUserIdentityDAO{ctrl: ctrl}
+	mock.recorder = &MockUserIdentityDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserIdentityDAO) EXPECT() *MockUserIdentityDAOMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockUserIdentityDAO) Delete(ctx context.Context, uid int64, provider string, allowLast bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, provider, allowLast)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserIdentityDAOMockRecorder) Delete(ctx, uid, provider, allowLast any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserIdentityDAO)(nil).Delete), ctx, uid, provider, allowLast)
+}
+
+// FindByProviderSubject mocks base method.
+func (m *MockUserIdentityDAO) FindByProviderSubject(ctx context.Context, provider, subject string) (dao.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByProviderSubject", ctx, provider, subject)
+	ret0, _ := ret[0].(dao.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByProviderSubject indicates an expected call of FindByProviderSubject.
+func (mr *MockUserIdentityDAOMockRecorder) FindByProviderSubject(ctx, provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByProviderSubject", reflect.TypeOf((*MockUserIdentityDAO)(nil).FindByProviderSubject), ctx, provider, subject)
+}
+
+// FindByProviderUnionId mocks base method.
+func (m *MockUserIdentityDAO) FindByProviderUnionId(ctx context.Context, provider, unionId string) (dao.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByProviderUnionId", ctx, provider, unionId)
+	ret0, _ := ret[0].(dao.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByProviderUnionId indicates an expected call of FindByProviderUnionId.
+func (mr *MockUserIdentityDAOMockRecorder) FindByProviderUnionId(ctx, provider, unionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByProviderUnionId", reflect.TypeOf((*MockUserIdentityDAO)(nil).FindByProviderUnionId), ctx, provider, unionId)
+}
+
+// FindByUid mocks base method.
+func (m *MockUserIdentityDAO) FindByUid(ctx context.Context, uid int64) ([]dao.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockUserIdentityDAOMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockUserIdentityDAO)(nil).FindByUid), ctx, uid)
+}
+
+// Insert mocks base method.
+func (m *MockUserIdentityDAO) Insert(ctx context.Context, identity dao.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockUserIdentityDAOMockRecorder) Insert(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockUserIdentityDAO)(nil).Insert), ctx, identity)
+}
+
+// InsertWithUser mocks base method.
+func (m *MockUserIdentityDAO) InsertWithUser(ctx context.Context, u dao.User, identity dao.UserIdentity) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWithUser", ctx, u, identity)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertWithUser indicates an expected call of InsertWithUser.
+func (mr *MockUserIdentityDAOMockRecorder) InsertWithUser(ctx, u, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWithUser", reflect.TypeOf((*MockUserIdentityDAO)(nil).InsertWithUser), ctx, u, identity)
+}
+
+// MigrateWechat mocks base method.
+func (m *MockUserIdentityDAO) MigrateWechat(ctx context.Context, identity dao.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateWechat", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateWechat indicates an expected call of MigrateWechat.
+func (mr *MockUserIdentityDAOMockRecorder) MigrateWechat(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateWechat", reflect.TypeOf((*MockUserIdentityDAO)(nil).MigrateWechat), ctx, identity)
+}
+
+// UpdateToken mocks base method.
+func (m *MockUserIdentityDAO) UpdateToken(ctx context.Context, identity dao.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateToken", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateToken indicates an expected call of UpdateToken.
+func (mr *MockUserIdentityDAOMockRecorder) UpdateToken(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateToken", reflect.TypeOf((*MockUserIdentityDAO)(nil).UpdateToken), ctx, identity)
+}
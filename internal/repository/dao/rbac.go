@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RBACDAO interface {
+	// FindRolesByUid 用户的所有角色
+	FindRolesByUid(ctx context.Context, uid int64) ([]Role, error)
+	FindRolesByNames(ctx context.Context, names []string) ([]Role, error)
+	FindPermissions(ctx context.Context, roleIds []int64) ([]RolePermission, error)
+	// SetUserRoles 把用户的角色整个替换成 roleIds
+	SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error
+}
+
+type GORMRBACDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMRBACDAO(db *gorm.DB) RBACDAO {
+	return &GORMRBACDAO{db: db}
+}
+
+func (g *GORMRBACDAO) FindRolesByUid(ctx context.Context, uid int64) ([]Role, error) {
+	var res []Role
+	err := g.db.WithContext(ctx).
+		Where("id IN (?)", g.db.Model(&UserRole{}).Select("role_id").Where("uid = ?", uid)).
+		Order("id").
+		Find(&res).Error
+	return res, err
+}
+
+func (g *GORMRBACDAO) FindRolesByNames(ctx context.Context, names []string) ([]Role, error) {
+	var res []Role
+	err := g.db.WithContext(ctx).Where("name IN ?", names).Find(&res).Error
+	return res, err
+}
+
+func (g *GORMRBACDAO) FindPermissions(ctx context.Context, roleIds []int64) ([]RolePermission, error) {
+	var res []RolePermission
+	err := g.db.WithContext(ctx).Where("role_id IN ?", roleIds).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (g *GORMRBACDAO) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	now := time.Now().UnixMilli()
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("uid = ?", uid).Delete(&UserRole{}).Error
+		if err != nil {
+			return err
+		}
+		if len(roleIds) == 0 {
+			return nil
+		}
+		urs := make([]UserRole, 0, len(roleIds))
+		for _, rid := range roleIds {
+			urs = append(urs, UserRole{Uid: uid, RoleId: rid, Ctime: now})
+		}
+		return tx.Create(&urs).Error
+	})
+}
+
+// initRoles 初始化内置角色，已经存在的角色和权限不会重复插入，
+// 也不会删除在线上手动加的权限
+func initRoles(db *gorm.DB, roles map[string][]string) error {
+	now := time.Now().UnixMilli()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, perms := range roles {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&Role{Name: name, Ctime: now, Utime: now}).Error
+			if err != nil {
+				return err
+			}
+			var role Role
+			err = tx.Where("name = ?", name).First(&role).Error
+			if err != nil {
+				return err
+			}
+			rps := make([]RolePermission, 0, len(perms))
+			for _, p := range perms {
+				rps = append(rps, RolePermission{RoleId: role.Id, Permission: p, Ctime: now})
+			}
+			err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rps).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type Role struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Name  string `gorm:"type:varchar(64);unique"`
+	Ctime int64
+	Utime int64
+}
+
+type RolePermission struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	RoleId     int64  `gorm:"uniqueIndex:role_permission"`
+	Permission string `gorm:"type:varchar(64);uniqueIndex:role_permission"`
+	Ctime      int64
+}
+
+type UserRole struct {
+	Id     int64 `gorm:"primaryKey,autoIncrement"`
+	Uid    int64 `gorm:"uniqueIndex:uid_role"`
+	RoleId int64 `gorm:"uniqueIndex:uid_role"`
+	Ctime  int64
+}
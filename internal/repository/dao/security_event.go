@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gorm.io/gorm"
+)
+
+type SecurityEventDAO interface {
+	Insert(ctx context.Context, evt SecurityEvent) error
+	// FindByUid 按照 id 倒序，cursor 是上一页最后一条的 id，第一页传 0
+	FindByUid(ctx context.Context, uid int64, cursor int64, limit int) ([]SecurityEvent, error)
+	// HasLogin 用户有没有在 device 上登录成功过，device 为空就是任意设备
+	HasLogin(ctx context.Context, uid int64, device string) (bool, error)
+}
+
+type GORMSecurityEventDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSecurityEventDAO(db *gorm.DB) SecurityEventDAO {
+	return &GORMSecurityEventDAO{db: db}
+}
+
+func (g *GORMSecurityEventDAO) Insert(ctx context.Context, evt SecurityEvent) error {
+	evt.Ctime = time.Now().UnixMilli()
+	return g.db.WithContext(ctx).Create(&evt).Error
+}
+
+func (g *GORMSecurityEventDAO) FindByUid(ctx context.Context, uid int64, cursor int64, limit int) ([]SecurityEvent, error) {
+	var res []SecurityEvent
+	query := g.db.WithContext(ctx).Where("uid = ?", uid)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (g *GORMSecurityEventDAO) HasLogin(ctx context.Context, uid int64, device string) (bool, error) {
+	query := g.db.WithContext(ctx).Model(&SecurityEvent{}).
+		Where("uid = ? AND type = ? AND result = ?", uid,
+			domain.SecurityEventLogin, domain.SecurityResultSuccess)
+	if device != "" {
+		query = query.Where("device = ?", device)
+	}
+	var res []int64
+	err := query.Limit(1).Pluck("id", &res).Error
+	return len(res) > 0, err
+}
+
+// SecurityEvent 只增不改，按照 uid 查最近的记录
+type SecurityEvent struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"index:uid_device"`
+	// Account 登录失败的时候用户填的邮箱或者手机号
+	Account string `gorm:"type:varchar(128)"`
+	Type    string `gorm:"type:varchar(32)"`
+	Method  string `gorm:"type:varchar(32)"`
+	Result  string `gorm:"type:varchar(16)"`
+	Detail  string `gorm:"type:varchar(64)"`
+	IP      string `gorm:"type:varchar(64)"`
+	// UserAgent 可能很长，截断之后再存
+	UserAgent string `gorm:"type:varchar(512)"`
+	// Device 设备指纹或者 UserAgent 的哈希
+	Device    string `gorm:"type:char(64);index:uid_device"`
+	NewDevice bool
+	Ctime     int64
+}
@@ -0,0 +1,162 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTOTPAlreadyEnabled = errors.New("已经开启了两步验证")
+
+type TOTPDAO interface {
+	// UpsertPending 重新开始绑定，覆盖掉还没确认的密钥和恢复码。
+	// 已经开启了就返回 ErrTOTPAlreadyEnabled，要先关掉
+	UpsertPending(ctx context.Context, t UserTOTP, codes []UserRecoveryCode) error
+	FindByUid(ctx context.Context, uid int64) (UserTOTP, error)
+	// Enable 确认绑定，step 是确认时用掉的时间步
+	Enable(ctx context.Context, uid int64, step int64) error
+	// UpdateLastStep 只能往前走，step 不比上一次大就返回 ErrRecordNotFound
+	UpdateLastStep(ctx context.Context, uid int64, step int64) error
+	// UseRecoveryCode 恢复码只能用一次，不对或者用过了返回 ErrRecordNotFound
+	UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error
+	// Delete 关闭两步验证，恢复码一起删掉
+	Delete(ctx context.Context, uid int64) error
+}
+
+type GORMTOTPDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMTOTPDAO(db *gorm.DB) TOTPDAO {
+	return &GORMTOTPDAO{db: db}
+}
+
+func (g *GORMTOTPDAO) UpsertPending(ctx context.Context, t UserTOTP, codes []UserRecoveryCode) error {
+	now := time.Now().UnixMilli()
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old UserTOTP
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid = ?", t.Uid).First(&old).Error
+		switch err {
+		case nil:
+			if old.Enabled {
+				return ErrTOTPAlreadyEnabled
+			}
+		case gorm.ErrRecordNotFound:
+		default:
+			return err
+		}
+		err = g.delete(tx, t.Uid)
+		if err != nil {
+			return err
+		}
+		t.Enabled = false
+		t.Ctime = now
+		t.Utime = now
+		err = tx.Create(&t).Error
+		if err != nil {
+			return err
+		}
+		for i := range codes {
+			codes[i].Uid = t.Uid
+			codes[i].Ctime = now
+			codes[i].Utime = now
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (g *GORMTOTPDAO) FindByUid(ctx context.Context, uid int64) (UserTOTP, error) {
+	var res UserTOTP
+	err := g.db.WithContext(ctx).Where("uid = ?", uid).First(&res).Error
+	return res, err
+}
+
+func (g *GORMTOTPDAO) Enable(ctx context.Context, uid int64, step int64) error {
+	res := g.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ? AND enabled = ?", uid, false).
+		Updates(map[string]any{
+			"enabled":   true,
+			"last_step": step,
+			"utime":     time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (g *GORMTOTPDAO) UpdateLastStep(ctx context.Context, uid int64, step int64) error {
+	// 条件更新，两个请求同时用一个码只有一个能成功
+	res := g.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ? AND enabled = ? AND last_step < ?", uid, true, step).
+		Updates(map[string]any{
+			"last_step": step,
+			"utime":     time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (g *GORMTOTPDAO) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error {
+	res := g.db.WithContext(ctx).Model(&UserRecoveryCode{}).
+		Where("uid = ? AND code_hash = ? AND used = ?", uid, codeHash, false).
+		Updates(map[string]any{
+			"used":  true,
+			"utime": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (g *GORMTOTPDAO) Delete(ctx context.Context, uid int64) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return g.delete(tx, uid)
+	})
+}
+
+func (g *GORMTOTPDAO) delete(tx *gorm.DB, uid int64) error {
+	err := tx.Where("uid = ?", uid).Delete(&UserRecoveryCode{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("uid = ?", uid).Delete(&UserTOTP{}).Error
+}
+
+// UserTOTP 两步验证的密钥，一个用户只有一个
+type UserTOTP struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"unique"`
+	// Secret 加密之后的密钥，不能明文存
+	Secret   []byte `gorm:"type:varbinary(256)"`
+	Enabled  bool
+	LastStep int64
+	Ctime    int64
+	Utime    int64
+}
+
+// UserRecoveryCode 恢复码，手机丢了的时候用，只存哈希
+type UserRecoveryCode struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Uid      int64  `gorm:"uniqueIndex:uid_code"`
+	CodeHash string `gorm:"type:char(64);uniqueIndex:uid_code"`
+	Used     bool
+	Ctime    int64
+	Utime    int64
+}
@@ -21,6 +21,13 @@ type UserDAO interface {
 	FindById(ctx context.Context, uid int64) (User, error)
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindByWechat(ctx context.Context, openId string) (User, error)
+	// UpdateBanned 封号和解封，用户不存在返回 ErrRecordNotFound
+	UpdateBanned(ctx context.Context, uid int64, banned bool) error
+	// UpdateEmailVerified clearPassword 为 true 的时候顺便清掉密码，
+	// 用户不存在返回 ErrRecordNotFound
+	UpdateEmailVerified(ctx context.Context, uid int64, clearPassword bool) error
+	// UpdatePassword password 是加密之后的，用户不存在返回 ErrRecordNotFound
+	UpdatePassword(ctx context.Context, uid int64, password string) error
 }
 
 type GORMUserDAO struct {
@@ -74,6 +81,55 @@ func (dao *GORMUserDAO) UpdateById(ctx context.Context, entity User) error {
 		}).Error
 }
 
+func (dao *GORMUserDAO) UpdateBanned(ctx context.Context, uid int64, banned bool) error {
+	// utime 一定会变，所以只要用户存在，影响的行数就是 1
+	res := dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{
+			"utime":  time.Now().UnixMilli(),
+			"banned": banned,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) UpdateEmailVerified(ctx context.Context, uid int64, clearPassword bool) error {
+	updates := map[string]any{
+		"utime":          time.Now().UnixMilli(),
+		"email_verified": true,
+	}
+	if clearPassword {
+		updates["password"] = ""
+	}
+	res := dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) UpdatePassword(ctx context.Context, uid int64, password string) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{
+			"utime":    time.Now().UnixMilli(),
+			"password": password,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
 func (dao *GORMUserDAO) FindById(ctx context.Context, uid int64) (User, error) {
 	var res User
 	err := dao.db.WithContext(ctx).Where("id = ?", uid).First(&res).Error
@@ -90,13 +146,17 @@ type User struct {
 	Id int64 `gorm:"primaryKey,autoIncrement"`
 	// 代表这是一个可以为 NULL 的列
 	//Email    *string
-	Email    sql.NullString `gorm:"unique"`
-	Password string
+	Email sql.NullString `gorm:"unique"`
+	// EmailVerified 用户收到过发到这个邮箱的验证码
+	EmailVerified bool
+	Password      string
 
 	Nickname string `gorm:"type=varchar(128)"`
 	// YYYY-MM-DD
 	Birthday int64
 	AboutMe  string `gorm:"type=varchar(4096)"`
+	// Avatar 头像的地址，目前只有第三方登录的时候会填
+	Avatar string `gorm:"type:varchar(1024)"`
 
 	// 代表这是一个可以为 NULL 的列
 	Phone sql.NullString `gorm:"unique"`
@@ -107,6 +167,9 @@ type User struct {
 	WechatOpenId  sql.NullString `gorm:"unique"`
 	WechatUnionId sql.NullString
 
+	// Banned 被封号的用户不能登录
+	Banned bool
+
 	// 时区，UTC 0 的毫秒数
 	// 创建时间
 	Ctime int64
@@ -0,0 +1,179 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrDuplicateIdentity 第三方账号已经被绑定了，或者用户已经绑定过这个供应商了
+	ErrDuplicateIdentity = errors.New("第三方账号冲突")
+	// ErrLastIdentity 这是用户唯一的登录方式，不能解绑
+	ErrLastIdentity = errors.New("这是最后一个第三方账号")
+)
+
+type UserIdentityDAO interface {
+	// Insert 违反唯一索引返回 ErrDuplicateIdentity
+	Insert(ctx context.Context, identity UserIdentity) error
+	// InsertWithUser 第三方登录的新用户，用户和第三方账号一起创建，返回用户 id
+	InsertWithUser(ctx context.Context, u User, identity UserIdentity) (int64, error)
+	// MigrateWechat 把老用户 users 表上的微信字段挪到 user_identities 里面
+	MigrateWechat(ctx context.Context, identity UserIdentity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (UserIdentity, error)
+	// FindByProviderUnionId 同一个 unionid 理论上只会绑定一个用户，万一有多条就拿最早绑定的
+	FindByProviderUnionId(ctx context.Context, provider, unionId string) (UserIdentity, error)
+	// UpdateToken 每次登录都会拿到新的令牌和用户信息，按照 uid 和 provider 更新
+	UpdateToken(ctx context.Context, identity UserIdentity) error
+	FindByUid(ctx context.Context, uid int64) ([]UserIdentity, error)
+	// Delete 解绑。allowLast 为 false 的时候，这是用户最后一个第三方账号就返回 ErrLastIdentity，
+	// 没有绑定过返回 ErrRecordNotFound
+	Delete(ctx context.Context, uid int64, provider string, allowLast bool) error
+}
+
+type GORMUserIdentityDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMUserIdentityDAO(db *gorm.DB) UserIdentityDAO {
+	return &GORMUserIdentityDAO{db: db}
+}
+
+func (g *GORMUserIdentityDAO) Insert(ctx context.Context, identity UserIdentity) error {
+	return g.insert(g.db.WithContext(ctx), identity)
+}
+
+func (g *GORMUserIdentityDAO) insert(tx *gorm.DB, identity UserIdentity) error {
+	now := time.Now().UnixMilli()
+	identity.Ctime = now
+	identity.Utime = now
+	err := tx.Create(&identity).Error
+	if isDuplicateKey(err) {
+		return ErrDuplicateIdentity
+	}
+	return err
+}
+
+func isDuplicateKey(err error) bool {
+	const duplicateErr uint16 = 1062
+	me, ok := err.(*mysql.MySQLError)
+	return ok && me.Number == duplicateErr
+}
+
+func (g *GORMUserIdentityDAO) InsertWithUser(ctx context.Context, u User, identity UserIdentity) (int64, error) {
+	now := time.Now().UnixMilli()
+	u.Ctime = now
+	u.Utime = now
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&u).Error
+		if isDuplicateKey(err) {
+			// 邮箱被别人注册了
+			return ErrDuplicateEmail
+		}
+		if err != nil {
+			return err
+		}
+		identity.Uid = u.Id
+		return g.insert(tx, identity)
+	})
+	return u.Id, err
+}
+
+func (g *GORMUserIdentityDAO) MigrateWechat(ctx context.Context, identity UserIdentity) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := g.insert(tx, identity)
+		if err != nil {
+			return err
+		}
+		// 不清掉的话，解绑之后按照老字段还是能找到这个用户
+		return tx.Model(&User{}).Where("id = ?", identity.Uid).
+			Updates(map[string]any{
+				"utime":           time.Now().UnixMilli(),
+				"wechat_open_id":  sql.NullString{},
+				"wechat_union_id": sql.NullString{},
+			}).Error
+	})
+}
+
+func (g *GORMUserIdentityDAO) FindByProviderSubject(ctx context.Context, provider, subject string) (UserIdentity, error) {
+	var res UserIdentity
+	err := g.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).First(&res).Error
+	return res, err
+}
+
+func (g *GORMUserIdentityDAO) FindByProviderUnionId(ctx context.Context, provider, unionId string) (UserIdentity, error) {
+	var res UserIdentity
+	err := g.db.WithContext(ctx).
+		Where("provider = ? AND union_id = ?", provider, unionId).Order("id").First(&res).Error
+	return res, err
+}
+
+func (g *GORMUserIdentityDAO) UpdateToken(ctx context.Context, identity UserIdentity) error {
+	return g.db.WithContext(ctx).Model(&UserIdentity{}).
+		Where("uid = ? AND provider = ?", identity.Uid, identity.Provider).
+		Updates(map[string]any{
+			"utime":         time.Now().UnixMilli(),
+			"access_token":  identity.AccessToken,
+			"refresh_token": identity.RefreshToken,
+			"token_expiry":  identity.TokenExpiry,
+			"profile":       identity.Profile,
+		}).Error
+}
+
+func (g *GORMUserIdentityDAO) FindByUid(ctx context.Context, uid int64) ([]UserIdentity, error) {
+	var res []UserIdentity
+	err := g.db.WithContext(ctx).Where("uid = ?", uid).Order("id").Find(&res).Error
+	return res, err
+}
+
+func (g *GORMUserIdentityDAO) Delete(ctx context.Context, uid int64, provider string, allowLast bool) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁住这个用户所有的第三方账号，两个解绑请求并发的时候不会把账号解绑光
+		var identities []UserIdentity
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid = ?", uid).Find(&identities).Error
+		if err != nil {
+			return err
+		}
+		var target *UserIdentity
+		for i := range identities {
+			if identities[i].Provider == provider {
+				target = &identities[i]
+			}
+		}
+		if target == nil {
+			return ErrRecordNotFound
+		}
+		if !allowLast && len(identities) == 1 {
+			return ErrLastIdentity
+		}
+		return tx.Delete(&UserIdentity{}, target.Id).Error
+	})
+}
+
+// UserIdentity 表名是 user_identities，新增供应商不需要改表结构
+type UserIdentity struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 一个用户在一个供应商下面只能绑定一个账号
+	Uid      int64  `gorm:"uniqueIndex:uid_provider"`
+	Provider string `gorm:"type:varchar(32);uniqueIndex:uid_provider;uniqueIndex:provider_subject;index:provider_union"`
+	Subject  string `gorm:"type:varchar(255);uniqueIndex:provider_subject"`
+	// UnionId 微信同一个开放平台下面多个应用通用
+	UnionId sql.NullString `gorm:"type:varchar(255);index:provider_union"`
+	// Profile 供应商返回的原始用户信息，JSON
+	Profile string `gorm:"type:text"`
+	// AccessToken 和 RefreshToken 是供应商发的令牌，加密之后存，
+	// TokenExpiry 是 access token 过期的毫秒数
+	AccessToken  []byte `gorm:"type:varbinary(1024)"`
+	RefreshToken []byte `gorm:"type:varbinary(1024)"`
+	TokenExpiry  int64
+	// Ctime 绑定的时间
+	Ctime int64
+	Utime int64
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
@@ -14,63 +16,365 @@ type InteractiveRepository interface {
 	IncrLike(ctx context.Context, biz string, id int64, uid int64) error
 	DecrLike(ctx context.Context, biz string, id int64, uid int64) error
 	AddCollectionItem(ctx context.Context, biz string, id int64, cid int64, uid int64) error
+	DeleteCollectionItem(ctx context.Context, biz string, id int64, uid int64) error
 	Get(ctx context.Context, biz string, id int64) (domain.Interactive, error)
 	Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	Collected(ctx context.Context, biz string, id int64, uid int64) (bool, error)
+	// GetByIds 每一个 id 都会出现在结果里面，没有数据的就都是 0
+	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	// LikedIds 返回 ids 里面这个用户点过赞的
+	LikedIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]bool, error)
+	// CollectedIds 返回 ids 里面这个用户收藏过的
+	CollectedIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]bool, error)
+	// TopLiked 点赞榜，点赞数从高到低的前 n 个
+	TopLiked(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error)
+	// ListLiked 这个用户点过赞的资源，按照第一次点赞的时间倒序，取消之后再点赞不会排到前面
+	ListLiked(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error)
+	// ListCollected 这个用户收藏过的资源，最近收藏的在前面
+	ListCollected(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error)
 }
 
 type CachedInteractiveRepository struct {
 	dao   dao.InteractiveDAO
 	cache cache.InteractiveCache
-	l     logger.LoggerV1
+	// 阅读数先在内存里面聚合，再批量写回去
+	readCntAgg *ReadCntAggregator
+	// 点赞榜，和点赞数一起维护
+	rankCache cache.LikeRankCache
+	// 同一个实例里面只需要一个 goroutine 重建点赞榜
+	rebuildMu sync.Mutex
+	l         logger.LoggerV1
 }
 
 // AddCollectionItem implements [InteractiveRepository].
 func (c *CachedInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, id int64, cid int64, uid int64) error {
-	panic("unimplemented")
+	err := c.dao.InsertCollectionBiz(ctx, dao.UserCollectionBiz{
+		Uid:   uid,
+		Biz:   biz,
+		BizId: id,
+		Cid:   cid,
+	})
+	if err != nil {
+		return err
+	}
+	err = c.cache.IncrCollectCntIfPresent(ctx, biz, id)
+	if err != nil {
+		c.l.Error("缓存增加收藏数失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	return nil
+}
+
+// DeleteCollectionItem implements [InteractiveRepository].
+func (c *CachedInteractiveRepository) DeleteCollectionItem(ctx context.Context, biz string, id int64, uid int64) error {
+	err := c.dao.DeleteCollectionBiz(ctx, biz, id, uid)
+	if err == dao.ErrRecordNotFound {
+		// 本来就没有收藏
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = c.cache.DecrCollectCntIfPresent(ctx, biz, id)
+	if err != nil {
+		c.l.Error("缓存减少收藏数失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	return nil
 }
 
 // Collected implements [InteractiveRepository].
 func (c *CachedInteractiveRepository) Collected(ctx context.Context, biz string, id int64, uid int64) (bool, error) {
-	panic("unimplemented")
+	_, err := c.dao.GetCollectInfo(ctx, biz, id, uid)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
 }
 
 // DecrLike implements [InteractiveRepository].
 func (c *CachedInteractiveRepository) DecrLike(ctx context.Context, biz string, id int64, uid int64) error {
-	panic("unimplemented")
+	err := c.dao.DeleteLikeInfo(ctx, biz, id, uid)
+	if err == dao.ErrLikeInfoUnchanged {
+		// 本来就没有点赞，缓存也不能动
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = c.cache.DecrLikeCntIfPresent(ctx, biz, id)
+	if err != nil {
+		c.l.Error("缓存减少点赞数失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	// 不知道当初是什么时候点的赞，只能算在当前的桶里面。
+	// 所以窗口之外点的赞在窗口内取消，窗口榜会少算一个
+	err = c.rankCache.IncrLikeCntIfPresent(ctx, biz, id, -1)
+	if err != nil {
+		c.l.Error("点赞榜减少点赞数失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	return nil
 }
 
 // Get implements [InteractiveRepository].
 func (c *CachedInteractiveRepository) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
-	panic("unimplemented")
+	intr, err := c.cache.Get(ctx, biz, id)
+	if err == nil {
+		return intr, nil
+	}
+	ie, err := c.dao.Get(ctx, biz, id)
+	switch err {
+	case nil:
+		intr = c.toDomain(ie)
+	case dao.ErrRecordNotFound:
+		// 还没有人看过、点过赞，就都是 0
+	default:
+		return domain.Interactive{}, err
+	}
+	err = c.cache.Set(ctx, biz, id, intr)
+	if err != nil {
+		c.l.Error("回写缓存失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	return intr, nil
 }
 
-// IncrLike implements [InteractiveRepository].
-func (c *CachedInteractiveRepository) IncrLike(ctx context.Context, biz string, id int64, uid int64) error {
-	panic("unimplemented")
+// GetByIds 先批量查缓存，没命中的用一个 IN 查询查数据库，再批量回写缓存
+func (c *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	res, err := c.cache.GetByIds(ctx, biz, ids)
+	if err != nil {
+		// 缓存出问题了，全部查数据库
+		c.l.Error("批量查询缓存失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "error", Val: err})
+		res = make(map[int64]domain.Interactive, len(ids))
+	}
+	missIds := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := res[id]; !ok {
+			missIds = append(missIds, id)
+		}
+	}
+	if len(missIds) == 0 {
+		return res, nil
+	}
+	ies, err := c.dao.GetByIds(ctx, biz, missIds)
+	if err != nil {
+		return nil, err
+	}
+	misses := make(map[int64]domain.Interactive, len(missIds))
+	for _, id := range missIds {
+		// 还没有人看过、点过赞，就都是 0
+		misses[id] = domain.Interactive{}
+	}
+	for _, ie := range ies {
+		misses[ie.BizId] = c.toDomain(ie)
+	}
+	err = c.cache.BatchSet(ctx, biz, misses)
+	if err != nil {
+		c.l.Error("批量回写缓存失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "error", Val: err})
+	}
+	for id, intr := range misses {
+		res[id] = intr
+	}
+	return res, nil
 }
 
-// IncrReadCnt implements [InteractiveRepository].
-func (c *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
-	err := c.dao.IncrReadCnt(ctx, biz, bizId)
+// LikedIds implements [InteractiveRepository].
+func (c *CachedInteractiveRepository) LikedIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]bool, error) {
+	likes, err := c.dao.GetLikeInfos(ctx, biz, ids, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]bool, len(likes))
+	for _, like := range likes {
+		res[like.BizId] = true
+	}
+	return res, nil
+}
+
+// CollectedIds implements [InteractiveRepository].
+func (c *CachedInteractiveRepository) CollectedIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]bool, error) {
+	cbs, err := c.dao.GetCollectInfos(ctx, biz, ids, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]bool, len(cbs))
+	for _, cb := range cbs {
+		res[cb.BizId] = true
+	}
+	return res, nil
+}
+
+// ListLiked implements [InteractiveRepository].
+func (c *CachedInteractiveRepository) ListLiked(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error) {
+	likes, err := c.dao.ListLikeInfos(ctx, biz, uid, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.UserBiz, 0, len(likes))
+	for _, like := range likes {
+		res = append(res, domain.UserBiz{
+			Id:    like.Id,
+			Biz:   like.Biz,
+			BizId: like.BizId,
+			Utime: time.UnixMilli(like.Utime),
+		})
+	}
+	return res, nil
+}
+
+// ListCollected implements [InteractiveRepository].
+func (c *CachedInteractiveRepository) ListCollected(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error) {
+	cbs, err := c.dao.ListCollectInfos(ctx, biz, uid, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.UserBiz, 0, len(cbs))
+	for _, cb := range cbs {
+		res = append(res, domain.UserBiz{
+			Id:    cb.Id,
+			Biz:   cb.Biz,
+			BizId: cb.BizId,
+			Utime: time.UnixMilli(cb.Utime),
+		})
+	}
+	return res, nil
+}
+
+// IncrLike implements [InteractiveRepository].
+func (c *CachedInteractiveRepository) IncrLike(ctx context.Context, biz string, id int64, uid int64) error {
+	err := c.dao.InsertLikeInfo(ctx, biz, id, uid)
+	if err == dao.ErrLikeInfoUnchanged {
+		// 重复点赞，幂等处理，缓存也不能再加一次
+		return nil
+	}
 	if err != nil {
-		c.l.Error("增加阅读数失败", logger.Field{Key: "error", Val: err})
 		return err
 	}
-	err = c.cache.IncrReadCntIfPresent(ctx, biz, bizId)
+	err = c.cache.IncrLikeCntIfPresent(ctx, biz, id)
+	if err != nil {
+		c.l.Error("缓存增加点赞数失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	err = c.rankCache.IncrLikeCntIfPresent(ctx, biz, id, 1)
 	if err != nil {
-		c.l.Error("缓存增加阅读数失败", logger.Field{Key: "error", Val: err})
+		c.l.Error("点赞榜增加点赞数失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_id", Val: id},
+			logger.Field{Key: "error", Val: err})
 	}
 	return nil
 }
 
+// TopLiked 点赞榜在 Redis 里面丢了，就从数据库重建
+func (c *CachedInteractiveRepository) TopLiked(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error) {
+	res, err := c.rankCache.Top(ctx, biz, window, n)
+	if err != cache.ErrKeyNotExist {
+		return res, err
+	}
+	c.rebuildMu.Lock()
+	defer c.rebuildMu.Unlock()
+	// 可能在等锁的时候别人已经重建好了
+	res, err = c.rankCache.Top(ctx, biz, window, n)
+	if err != cache.ErrKeyNotExist {
+		return res, err
+	}
+	c.l.Warn("点赞榜不存在，从数据库重建", logger.Field{Key: "biz", Val: biz})
+	err = c.rebuildLikeRank(ctx, biz)
+	if err != nil {
+		return nil, err
+	}
+	return c.rankCache.Top(ctx, biz, window, n)
+}
+
+// rebuildLikeRank 总榜来自 interactives 表，窗口榜来自最近 7 天的点赞记录。
+// 重建期间发生的点赞不会写进点赞榜，所以重建出来的榜单可能会差几个赞
+func (c *CachedInteractiveRepository) rebuildLikeRank(ctx context.Context, biz string) error {
+	const batchSize = 1000
+	var all []domain.LikeRankItem
+	var startId int64
+	for {
+		ies, err := c.dao.ListLikeCnt(ctx, biz, startId, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, ie := range ies {
+			all = append(all, domain.LikeRankItem{BizId: ie.BizId, LikeCnt: ie.LikeCnt})
+		}
+		if len(ies) < batchSize {
+			break
+		}
+		startId = ies[len(ies)-1].Id
+	}
+	// 多查一天，保证 7 天榜最早的那一天是完整的
+	since := time.Now().Add(-time.Hour * 24 * 8).UnixMilli()
+	cnts, err := c.dao.CountLikesByHour(ctx, biz, since)
+	if err != nil {
+		return err
+	}
+	hourly := make(map[int64][]domain.LikeRankItem)
+	for _, cnt := range cnts {
+		hourly[cnt.Hour] = append(hourly[cnt.Hour], domain.LikeRankItem{BizId: cnt.BizId, LikeCnt: cnt.Cnt})
+	}
+	return c.rankCache.Rebuild(ctx, biz, all, hourly)
+}
+
+// IncrReadCnt 只是交给聚合器，数据库和缓存是异步批量更新的
+func (c *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	return c.readCntAgg.Add(biz, bizId)
+}
+
 // Liked implements [InteractiveRepository].
 func (c *CachedInteractiveRepository) Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error) {
-	panic("unimplemented")
+	_, err := c.dao.GetLikeInfo(ctx, biz, id, uid)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (c *CachedInteractiveRepository) toDomain(ie dao.Interactive) domain.Interactive {
+	return domain.Interactive{
+		ReadCnt:    ie.ReadCnt,
+		LikeCnt:    ie.LikeCnt,
+		CollectCnt: ie.CollectCnt,
+	}
 }
 
 func NewCachedInteractiveRepository(dao dao.InteractiveDAO,
 	l logger.LoggerV1,
-	cache cache.InteractiveCache) InteractiveRepository {
-	return &CachedInteractiveRepository{dao: dao, cache: cache, l: l}
+	cache cache.InteractiveCache,
+	readCntAgg *ReadCntAggregator,
+	rankCache cache.LikeRankCache) InteractiveRepository {
+	return &CachedInteractiveRepository{
+		dao:        dao,
+		cache:      cache,
+		readCntAgg: readCntAgg,
+		rankCache:  rankCache,
+		l:          l,
+	}
 }
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+// InteractiveReconcileRepository 核对 interactives 表里面的点赞数、收藏数和明细表
+type InteractiveReconcileRepository interface {
+	// FindDiffs 核对 id 大于 startId 的最多 limit 条计数，
+	// 返回对不上的计数，以及这一批最后一条的 id，没有更多数据的时候返回 0
+	FindDiffs(ctx context.Context, startId int64, limit int) ([]domain.InteractiveCntDiff, int64, error)
+	// Fix 重新统计这些资源的计数，并且删掉它们的缓存
+	Fix(ctx context.Context, biz string, bizIds []int64) error
+}
+
+type CachedInteractiveReconcileRepository struct {
+	dao   dao.InteractiveDAO
+	cache cache.InteractiveCache
+	l     logger.LoggerV1
+}
+
+func NewCachedInteractiveReconcileRepository(dao dao.InteractiveDAO,
+	cache cache.InteractiveCache,
+	l logger.LoggerV1) InteractiveReconcileRepository {
+	return &CachedInteractiveReconcileRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+func (c *CachedInteractiveReconcileRepository) FindDiffs(ctx context.Context, startId int64, limit int) ([]domain.InteractiveCntDiff, int64, error) {
+	ies, err := c.dao.FindBatch(ctx, startId, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ies) == 0 {
+		return nil, 0, nil
+	}
+	// 同一批里面可能有不同的 biz，按照 biz 分组统计
+	bizIds := make(map[string][]int64)
+	for _, ie := range ies {
+		bizIds[ie.Biz] = append(bizIds[ie.Biz], ie.BizId)
+	}
+	likeCnts := make(map[string]map[int64]int64, len(bizIds))
+	collectCnts := make(map[string]map[int64]int64, len(bizIds))
+	for biz, ids := range bizIds {
+		likeCnts[biz], err = c.dao.CountLikes(ctx, biz, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		collectCnts[biz], err = c.dao.CountCollects(ctx, biz, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	var diffs []domain.InteractiveCntDiff
+	for _, ie := range ies {
+		likeCnt := likeCnts[ie.Biz][ie.BizId]
+		collectCnt := collectCnts[ie.Biz][ie.BizId]
+		if likeCnt == ie.LikeCnt && collectCnt == ie.CollectCnt {
+			continue
+		}
+		diffs = append(diffs, domain.InteractiveCntDiff{
+			Biz:              ie.Biz,
+			BizId:            ie.BizId,
+			LikeCnt:          ie.LikeCnt,
+			CollectCnt:       ie.CollectCnt,
+			ActualLikeCnt:    likeCnt,
+			ActualCollectCnt: collectCnt,
+		})
+	}
+	return diffs, ies[len(ies)-1].Id, nil
+}
+
+func (c *CachedInteractiveReconcileRepository) Fix(ctx context.Context, biz string, bizIds []int64) error {
+	err := c.dao.FixCnts(ctx, biz, bizIds)
+	if err != nil {
+		return err
+	}
+	// 缓存里面是错的计数，而且 HINCRBY 会一直在错的基础上加减，所以要删掉
+	err = c.cache.Del(ctx, biz, bizIds...)
+	if err != nil {
+		c.l.Error("删除互动缓存失败",
+			logger.Field{Key: "biz", Val: biz},
+			logger.Field{Key: "biz_ids", Val: bizIds},
+			logger.Field{Key: "error", Val: err})
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	cachemocks "gitee.com/geekbang/basic-go/webook/internal/repository/cache/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedInteractiveReconcileRepository_FindDiffs(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) dao.InteractiveDAO
+
+		wantDiffs  []domain.InteractiveCntDiff
+		wantLastId int64
+		wantErr    error
+	}{
+		{
+			name: "找到对不上的计数",
+			mock: func(ctrl *gomock.Controller) dao.InteractiveDAO {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				d.EXPECT().FindBatch(gomock.Any(), int64(0), 10).
+					Return([]dao.Interactive{
+						{Id: 1, Biz: "article", BizId: 11, LikeCnt: 2, CollectCnt: 1},
+						{Id: 2, Biz: "article", BizId: 12, LikeCnt: 3},
+						{Id: 3, Biz: "article", BizId: 13},
+					}, nil)
+				d.EXPECT().CountLikes(gomock.Any(), "article", []int64{11, 12, 13}).
+					Return(map[int64]int64{11: 2, 12: 2}, nil)
+				d.EXPECT().CountCollects(gomock.Any(), "article", []int64{11, 12, 13}).
+					Return(map[int64]int64{11: 1, 13: 1}, nil)
+				return d
+			},
+			wantDiffs: []domain.InteractiveCntDiff{
+				{Biz: "article", BizId: 12, LikeCnt: 3, ActualLikeCnt: 2},
+				{Biz: "article", BizId: 13, ActualCollectCnt: 1},
+			},
+			wantLastId: 3,
+		},
+		{
+			name: "没有更多数据",
+			mock: func(ctrl *gomock.Controller) dao.InteractiveDAO {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				d.EXPECT().FindBatch(gomock.Any(), int64(0), 10).
+					Return([]dao.Interactive{}, nil)
+				return d
+			},
+		},
+		{
+			name: "统计失败",
+			mock: func(ctrl *gomock.Controller) dao.InteractiveDAO {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				d.EXPECT().FindBatch(gomock.Any(), int64(0), 10).
+					Return([]dao.Interactive{{Id: 1, Biz: "article", BizId: 11}}, nil)
+				d.EXPECT().CountLikes(gomock.Any(), "article", []int64{11}).
+					Return(nil, errors.New("db error"))
+				return d
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := NewCachedInteractiveReconcileRepository(tc.mock(ctrl),
+				cachemocks.NewMockInteractiveCache(ctrl), logger.NewNopLogger())
+			diffs, lastId, err := repo.FindDiffs(context.Background(), 0, 10)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDiffs, diffs)
+			assert.Equal(t, tc.wantLastId, lastId)
+		})
+	}
+}
+
+func TestCachedInteractiveReconcileRepository_Fix(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+
+		wantErr error
+	}{
+		{
+			name: "修复并删除缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().FixCnts(gomock.Any(), "article", []int64{1, 2}).Return(nil)
+				c.EXPECT().Del(gomock.Any(), "article", int64(1), int64(2)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "修复失败，不动缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().FixCnts(gomock.Any(), "article", []int64{1, 2}).
+					Return(errors.New("db error"))
+				return d, c
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveReconcileRepository(d, c, logger.NewNopLogger())
+			err := repo.Fix(context.Background(), "article", []int64{1, 2})
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
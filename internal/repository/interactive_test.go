@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	cachemocks "gitee.com/geekbang/basic-go/webook/internal/repository/cache/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedInteractiveRepository_IncrLike(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache, cache.LikeRankCache)
+
+		wantErr error
+	}{
+		{
+			name: "点赞成功",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).
+					Return(nil)
+				c.EXPECT().IncrLikeCntIfPresent(gomock.Any(), "article", int64(1)).
+					Return(nil)
+				rc.EXPECT().IncrLikeCntIfPresent(gomock.Any(), "article", int64(1), int64(1)).
+					Return(nil)
+				return d, c, rc
+			},
+		},
+		{
+			name: "重复点赞，不动缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).
+					Return(dao.ErrLikeInfoUnchanged)
+				return d, c, rc
+			},
+		},
+		{
+			name: "缓存失败也算成功",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).
+					Return(nil)
+				c.EXPECT().IncrLikeCntIfPresent(gomock.Any(), "article", int64(1)).
+					Return(errors.New("redis error"))
+				rc.EXPECT().IncrLikeCntIfPresent(gomock.Any(), "article", int64(1), int64(1)).
+					Return(errors.New("redis error"))
+				return d, c, rc
+			},
+		},
+		{
+			name: "数据库失败",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).
+					Return(errors.New("db error"))
+				return d, c, rc
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c, rc := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, logger.NewNopLogger(), c, nil, rc)
+			err := repo.IncrLike(context.Background(), "article", 1, 123)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_Get(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+
+		wantIntr domain.Interactive
+		wantErr  error
+	}{
+		{
+			name: "缓存命中",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).
+					Return(domain.Interactive{ReadCnt: 1, LikeCnt: 2}, nil)
+				return d, c
+			},
+			wantIntr: domain.Interactive{ReadCnt: 1, LikeCnt: 2},
+		},
+		{
+			name: "缓存未命中，回写缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).
+					Return(domain.Interactive{}, cache.ErrKeyNotExist)
+				d.EXPECT().Get(gomock.Any(), "article", int64(1)).
+					Return(dao.Interactive{ReadCnt: 1, LikeCnt: 2, CollectCnt: 3}, nil)
+				c.EXPECT().Set(gomock.Any(), "article", int64(1),
+					domain.Interactive{ReadCnt: 1, LikeCnt: 2, CollectCnt: 3}).Return(nil)
+				return d, c
+			},
+			wantIntr: domain.Interactive{ReadCnt: 1, LikeCnt: 2, CollectCnt: 3},
+		},
+		{
+			name: "没有数据，都是 0",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).
+					Return(domain.Interactive{}, cache.ErrKeyNotExist)
+				d.EXPECT().Get(gomock.Any(), "article", int64(1)).
+					Return(dao.Interactive{}, dao.ErrRecordNotFound)
+				c.EXPECT().Set(gomock.Any(), "article", int64(1),
+					domain.Interactive{}).Return(nil)
+				return d, c
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, logger.NewNopLogger(), c, nil, nil)
+			intr, err := repo.Get(context.Background(), "article", 1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantIntr, intr)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_GetByIds(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		ids  []int64
+
+		wantIntrs map[int64]domain.Interactive
+		wantErr   error
+	}{
+		{
+			name: "全部命中缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).
+					Return(map[int64]domain.Interactive{
+						1: {ReadCnt: 1},
+						2: {ReadCnt: 2},
+					}, nil)
+				return d, c
+			},
+			ids: []int64{1, 2},
+			wantIntrs: map[int64]domain.Interactive{
+				1: {ReadCnt: 1},
+				2: {ReadCnt: 2},
+			},
+		},
+		{
+			name: "部分命中，没命中的查数据库并回写",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2, 3}).
+					Return(map[int64]domain.Interactive{
+						1: {ReadCnt: 1},
+					}, nil)
+				d.EXPECT().GetByIds(gomock.Any(), "article", []int64{2, 3}).
+					Return([]dao.Interactive{
+						{BizId: 2, Biz: "article", ReadCnt: 2, LikeCnt: 3},
+					}, nil)
+				// 3 在数据库里面也没有，缓存 0
+				c.EXPECT().BatchSet(gomock.Any(), "article", map[int64]domain.Interactive{
+					2: {ReadCnt: 2, LikeCnt: 3},
+					3: {},
+				}).Return(nil)
+				return d, c
+			},
+			ids: []int64{1, 2, 3},
+			wantIntrs: map[int64]domain.Interactive{
+				1: {ReadCnt: 1},
+				2: {ReadCnt: 2, LikeCnt: 3},
+				3: {},
+			},
+		},
+		{
+			name: "缓存失败，全部查数据库",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).
+					Return(nil, errors.New("redis error"))
+				d.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).
+					Return([]dao.Interactive{
+						{BizId: 1, Biz: "article", ReadCnt: 1},
+						{BizId: 2, Biz: "article", ReadCnt: 2},
+					}, nil)
+				c.EXPECT().BatchSet(gomock.Any(), "article", gomock.Any()).
+					Return(errors.New("redis error"))
+				return d, c
+			},
+			ids: []int64{1, 2},
+			wantIntrs: map[int64]domain.Interactive{
+				1: {ReadCnt: 1},
+				2: {ReadCnt: 2},
+			},
+		},
+		{
+			name: "数据库失败",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).
+					Return(map[int64]domain.Interactive{}, nil)
+				d.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).
+					Return(nil, errors.New("db error"))
+				return d, c
+			},
+			ids:     []int64{1, 2},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, logger.NewNopLogger(), c, nil, nil)
+			intrs, err := repo.GetByIds(context.Background(), "article", tc.ids)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantIntrs, intrs)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_TopLiked(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.LikeRankCache)
+
+		wantItems []domain.LikeRankItem
+		wantErr   error
+	}{
+		{
+			name: "点赞榜存在",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				rc.EXPECT().Top(gomock.Any(), "article", domain.LikeRankWindowDay, 10).
+					Return([]domain.LikeRankItem{{BizId: 1, LikeCnt: 10}}, nil)
+				return d, rc
+			},
+			wantItems: []domain.LikeRankItem{{BizId: 1, LikeCnt: 10}},
+		},
+		{
+			name: "点赞榜不存在，从数据库重建",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				rc.EXPECT().Top(gomock.Any(), "article", domain.LikeRankWindowDay, 10).
+					Times(2).Return(nil, cache.ErrKeyNotExist)
+				d.EXPECT().ListLikeCnt(gomock.Any(), "article", int64(0), 1000).
+					Return([]dao.Interactive{
+						{Id: 3, BizId: 1, LikeCnt: 10},
+						{Id: 4, BizId: 2, LikeCnt: 5},
+					}, nil)
+				d.EXPECT().CountLikesByHour(gomock.Any(), "article", gomock.Any()).
+					Return([]dao.LikeHourCnt{
+						{BizId: 1, Hour: 100, Cnt: 2},
+						{BizId: 2, Hour: 100, Cnt: 3},
+					}, nil)
+				rc.EXPECT().Rebuild(gomock.Any(), "article",
+					[]domain.LikeRankItem{{BizId: 1, LikeCnt: 10}, {BizId: 2, LikeCnt: 5}},
+					map[int64][]domain.LikeRankItem{
+						100: {{BizId: 1, LikeCnt: 2}, {BizId: 2, LikeCnt: 3}},
+					}).Return(nil)
+				rc.EXPECT().Top(gomock.Any(), "article", domain.LikeRankWindowDay, 10).
+					Return([]domain.LikeRankItem{{BizId: 2, LikeCnt: 3}, {BizId: 1, LikeCnt: 2}}, nil)
+				return d, rc
+			},
+			wantItems: []domain.LikeRankItem{{BizId: 2, LikeCnt: 3}, {BizId: 1, LikeCnt: 2}},
+		},
+		{
+			name: "重建失败",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.LikeRankCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				rc := cachemocks.NewMockLikeRankCache(ctrl)
+				rc.EXPECT().Top(gomock.Any(), "article", domain.LikeRankWindowDay, 10).
+					Times(2).Return(nil, cache.ErrKeyNotExist)
+				d.EXPECT().ListLikeCnt(gomock.Any(), "article", int64(0), 1000).
+					Return(nil, errors.New("db error"))
+				return d, rc
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, rc := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, logger.NewNopLogger(), nil, nil, rc)
+			items, err := repo.TopLiked(context.Background(), "article", domain.LikeRankWindowDay, 10)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantItems, items)
+		})
+	}
+}
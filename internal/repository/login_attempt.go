@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+)
+
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, subject string) (domain.LoginAttempt, error)
+	IncrFailure(ctx context.Context, subject string, policy domain.LoginLockPolicy) (domain.LoginAttempt, error)
+	Reset(ctx context.Context, subject string) error
+}
+
+type CachedLoginAttemptRepository struct {
+	cache cache.LoginAttemptCache
+}
+
+func NewCachedLoginAttemptRepository(c cache.LoginAttemptCache) LoginAttemptRepository {
+	return &CachedLoginAttemptRepository{cache: c}
+}
+
+func (c *CachedLoginAttemptRepository) Get(ctx context.Context, subject string) (domain.LoginAttempt, error) {
+	return c.cache.Get(ctx, subject)
+}
+
+func (c *CachedLoginAttemptRepository) IncrFailure(ctx context.Context, subject string,
+	policy domain.LoginLockPolicy) (domain.LoginAttempt, error) {
+	return c.cache.IncrFailure(ctx, subject, policy)
+}
+
+func (c *CachedLoginAttemptRepository) Reset(ctx context.Context, subject string) error {
+	return c.cache.Reset(ctx, subject)
+}
@@ -0,0 +1,127 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/collection.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/collection.go -package=repomocks -destination=./webook/internal/repository/mocks/collection.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCollectionRepository is a mock of CollectionRepository interface.
+type MockCollectionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCollectionRepositoryMockRecorder
+}
+
+// MockCollectionRepositoryMockRecorder is the mock recorder for MockCollectionRepository.
+type MockCollectionRepositoryMockRecorder struct {
+	mock *MockCollectionRepository
+}
+
+// NewMockCollectionRepository creates a new mock instance.
+func NewMockCollectionRepository(ctrl *gomock.Controller) *MockCollectionRepository {
+	mock := &MockCollectionRepository{ctrl: ctrl}
+	mock.recorder = &MockCollectionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCollectionRepository) EXPECT() *MockCollectionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockCollectionRepository) Create(ctx context.Context, c domain.Collection) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, c)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockCollectionRepositoryMockRecorder) Create(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCollectionRepository)(nil).Create), ctx, c)
+}
+
+// Delete mocks base method.
+func (m *MockCollectionRepository) Delete(ctx context.Context, uid, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCollectionRepositoryMockRecorder) Delete(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCollectionRepository)(nil).Delete), ctx, uid, id)
+}
+
+// List mocks base method.
+func (m *MockCollectionRepository) List(ctx context.Context, uid int64) ([]domain.Collection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, uid)
+	ret0, _ := ret[0].([]domain.Collection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockCollectionRepositoryMockRecorder) List(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockCollectionRepository)(nil).List), ctx, uid)
+}
+
+// ListItems mocks base method.
+func (m *MockCollectionRepository) ListItems(ctx context.Context, uid, cid int64, offset, limit int) ([]domain.CollectionItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListItems", ctx, uid, cid, offset, limit)
+	ret0, _ := ret[0].([]domain.CollectionItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListItems indicates an expected call of ListItems.
+func (mr *MockCollectionRepositoryMockRecorder) ListItems(ctx, uid, cid, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockCollectionRepository)(nil).ListItems), ctx, uid, cid, offset, limit)
+}
+
+// MoveItem mocks base method.
+func (m *MockCollectionRepository) MoveItem(ctx context.Context, uid int64, biz string, bizId, cid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveItem", ctx, uid, biz, bizId, cid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveItem indicates an expected call of MoveItem.
+func (mr *MockCollectionRepositoryMockRecorder) MoveItem(ctx, uid, biz, bizId, cid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveItem", reflect.TypeOf((*MockCollectionRepository)(nil).MoveItem), ctx, uid, biz, bizId, cid)
+}
+
+// Rename mocks base method.
+func (m *MockCollectionRepository) Rename(ctx context.Context, uid, id int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rename", ctx, uid, id, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rename indicates an expected call of Rename.
+func (mr *MockCollectionRepositoryMockRecorder) Rename(ctx, uid, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rename", reflect.TypeOf((*MockCollectionRepository)(nil).Rename), ctx, uid, id, name)
+}
@@ -0,0 +1,245 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/interactive.go -package=repomocks -destination=./webook/internal/repository/mocks/interactive.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveRepository is a mock of InteractiveRepository interface.
+type MockInteractiveRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveRepositoryMockRecorder
+}
+
+// MockInteractiveRepositoryMockRecorder is the mock recorder for MockInteractiveRepository.
+type MockInteractiveRepositoryMockRecorder struct {
+	mock *MockInteractiveRepository
+}
+
+// NewMockInteractiveRepository creates a new mock instance.
+func NewMockInteractiveRepository(ctrl *gomock.Controller) *MockInteractiveRepository {
+	mock := &MockInteractiveRepository{ctrl: ctrl}
+	mock.recorder = &MockInteractiveRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveRepository) EXPECT() *MockInteractiveRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddCollectionItem mocks base method.
+func (m *MockInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, id, cid, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCollectionItem", ctx, biz, id, cid, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCollectionItem indicates an expected call of AddCollectionItem.
+func (mr *MockInteractiveRepositoryMockRecorder) AddCollectionItem(ctx, biz, id, cid, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCollectionItem", reflect.TypeOf((*MockInteractiveRepository)(nil).AddCollectionItem), ctx, biz, id, cid, uid)
+}
+
+// Collected mocks base method.
+func (m *MockInteractiveRepository) Collected(ctx context.Context, biz string, id, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Collected", ctx, biz, id, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Collected indicates an expected call of Collected.
+func (mr *MockInteractiveRepositoryMockRecorder) Collected(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collected", reflect.TypeOf((*MockInteractiveRepository)(nil).Collected), ctx, biz, id, uid)
+}
+
+// CollectedIds mocks base method.
+func (m *MockInteractiveRepository) CollectedIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectedIds", ctx, biz, ids, uid)
+	ret0, _ := ret[0].(map[int64]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectedIds indicates an expected call of CollectedIds.
+func (mr *MockInteractiveRepositoryMockRecorder) CollectedIds(ctx, biz, ids, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectedIds", reflect.TypeOf((*MockInteractiveRepository)(nil).CollectedIds), ctx, biz, ids, uid)
+}
+
+// DecrLike mocks base method.
+func (m *MockInteractiveRepository) DecrLike(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrLike", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecrLike indicates an expected call of DecrLike.
+func (mr *MockInteractiveRepositoryMockRecorder) DecrLike(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrLike", reflect.TypeOf((*MockInteractiveRepository)(nil).DecrLike), ctx, biz, id, uid)
+}
+
+// DeleteCollectionItem mocks base method.
+func (m *MockInteractiveRepository) DeleteCollectionItem(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCollectionItem", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCollectionItem indicates an expected call of DeleteCollectionItem.
+func (mr *MockInteractiveRepositoryMockRecorder) DeleteCollectionItem(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCollectionItem", reflect.TypeOf((*MockInteractiveRepository)(nil).DeleteCollectionItem), ctx, biz, id, uid)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveRepository) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id)
+	ret0, _ := ret[0].(domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveRepositoryMockRecorder) Get(ctx, biz, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveRepository)(nil).Get), ctx, biz, id)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids)
+	ret0, _ := ret[0].(map[int64]domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveRepositoryMockRecorder) GetByIds(ctx, biz, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveRepository)(nil).GetByIds), ctx, biz, ids)
+}
+
+// IncrLike mocks base method.
+func (m *MockInteractiveRepository) IncrLike(ctx context.Context, biz string, id, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrLike", ctx, biz, id, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrLike indicates an expected call of IncrLike.
+func (mr *MockInteractiveRepositoryMockRecorder) IncrLike(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrLike", reflect.TypeOf((*MockInteractiveRepository)(nil).IncrLike), ctx, biz, id, uid)
+}
+
+// IncrReadCnt mocks base method.
+func (m *MockInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCnt", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCnt indicates an expected call of IncrReadCnt.
+func (mr *MockInteractiveRepositoryMockRecorder) IncrReadCnt(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCnt", reflect.TypeOf((*MockInteractiveRepository)(nil).IncrReadCnt), ctx, biz, bizId)
+}
+
+// Liked mocks base method.
+func (m *MockInteractiveRepository) Liked(ctx context.Context, biz string, id, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Liked", ctx, biz, id, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Liked indicates an expected call of Liked.
+func (mr *MockInteractiveRepositoryMockRecorder) Liked(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Liked", reflect.TypeOf((*MockInteractiveRepository)(nil).Liked), ctx, biz, id, uid)
+}
+
+// LikedIds mocks base method.
+func (m *MockInteractiveRepository) LikedIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LikedIds", ctx, biz, ids, uid)
+	ret0, _ := ret[0].(map[int64]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LikedIds indicates an expected call of LikedIds.
+func (mr *MockInteractiveRepositoryMockRecorder) LikedIds(ctx, biz, ids, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LikedIds", reflect.TypeOf((*MockInteractiveRepository)(nil).LikedIds), ctx, biz, ids, uid)
+}
+
+// ListCollected mocks base method.
+func (m *MockInteractiveRepository) ListCollected(ctx context.Context, biz string, uid, cursor int64, limit int) ([]domain.UserBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCollected", ctx, biz, uid, cursor, limit)
+	ret0, _ := ret[0].([]domain.UserBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCollected indicates an expected call of ListCollected.
+func (mr *MockInteractiveRepositoryMockRecorder) ListCollected(ctx, biz, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCollected", reflect.TypeOf((*MockInteractiveRepository)(nil).ListCollected), ctx, biz, uid, cursor, limit)
+}
+
+// ListLiked mocks base method.
+func (m *MockInteractiveRepository) ListLiked(ctx context.Context, biz string, uid, cursor int64, limit int) ([]domain.UserBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLiked", ctx, biz, uid, cursor, limit)
+	ret0, _ := ret[0].([]domain.UserBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLiked indicates an expected call of ListLiked.
+func (mr *MockInteractiveRepositoryMockRecorder) ListLiked(ctx, biz, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLiked", reflect.TypeOf((*MockInteractiveRepository)(nil).ListLiked), ctx, biz, uid, cursor, limit)
+}
+
+// TopLiked mocks base method.
+func (m *MockInteractiveRepository) TopLiked(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TopLiked", ctx, biz, window, n)
+	ret0, _ := ret[0].([]domain.LikeRankItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopLiked indicates an expected call of TopLiked.
+func (mr *MockInteractiveRepositoryMockRecorder) TopLiked(ctx, biz, window, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopLiked", reflect.TypeOf((*MockInteractiveRepository)(nil).TopLiked), ctx, biz, window, n)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/interactive_reconcile.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/interactive_reconcile.go -package=repomocks -destination=./webook/internal/repository/mocks/interactive_reconcile.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveReconcileRepository is a mock of InteractiveReconcileRepository interface.
+type MockInteractiveReconcileRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveReconcileRepositoryMockRecorder
+}
+
+// MockInteractiveReconcileRepositoryMockRecorder is the mock recorder for MockInteractiveReconcileRepository.
+type MockInteractiveReconcileRepositoryMockRecorder struct {
+	mock *MockInteractiveReconcileRepository
+}
+
+// NewMockInteractiveReconcileRepository creates a new mock instance.
+func NewMockInteractiveReconcileRepository(ctrl *gomock.Controller) *MockInteractiveReconcileRepository {
+	mock := &MockInteractiveReconcileRepository{ctrl: ctrl}
+	mock.recorder = &MockInteractiveReconcileRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveReconcileRepository) EXPECT() *MockInteractiveReconcileRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindDiffs mocks base method.
+func (m *MockInteractiveReconcileRepository) FindDiffs(ctx context.Context, startId int64, limit int) ([]domain.InteractiveCntDiff, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDiffs", ctx, startId, limit)
+	ret0, _ := ret[0].([]domain.InteractiveCntDiff)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindDiffs indicates an expected call of FindDiffs.
+func (mr *MockInteractiveReconcileRepositoryMockRecorder) FindDiffs(ctx, startId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDiffs", reflect.TypeOf((*MockInteractiveReconcileRepository)(nil).FindDiffs), ctx, startId, limit)
+}
+
+// Fix mocks base method.
+func (m *MockInteractiveReconcileRepository) Fix(ctx context.Context, biz string, bizIds []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fix", ctx, biz, bizIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fix indicates an expected call of Fix.
+func (mr *MockInteractiveReconcileRepositoryMockRecorder) Fix(ctx, biz, bizIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fix", reflect.TypeOf((*MockInteractiveReconcileRepository)(nil).Fix), ctx, biz, bizIds)
+}
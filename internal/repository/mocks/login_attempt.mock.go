@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/login_attempt.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/login_attempt.go -package=repomocks -destination=./webook/internal/repository/mocks/login_attempt.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginAttemptRepository is a mock of LoginAttemptRepository interface.
+type MockLoginAttemptRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptRepositoryMockRecorder
+}
+
+// MockLoginAttemptRepositoryMockRecorder is the mock recorder for MockLoginAttemptRepository.
+type MockLoginAttemptRepositoryMockRecorder struct {
+	mock *MockLoginAttemptRepository
+}
+
+// NewMockLoginAttemptRepository creates a new mock instance.
+func NewMockLoginAttemptRepository(ctrl *gomock.Controller) *MockLoginAttemptRepository {
+	mock := &MockLoginAttemptRepository{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptRepository) EXPECT() *MockLoginAttemptRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockLoginAttemptRepository) Get(ctx context.Context, subject string) (domain.LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, subject)
+	ret0, _ := ret[0].(domain.LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockLoginAttemptRepositoryMockRecorder) Get(ctx, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockLoginAttemptRepository)(nil).Get), ctx, subject)
+}
+
+// IncrFailure mocks base method.
+func (m *MockLoginAttemptRepository) IncrFailure(ctx context.Context, subject string, policy domain.LoginLockPolicy) (domain.LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrFailure", ctx, subject, policy)
+	ret0, _ := ret[0].(domain.LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrFailure indicates an expected call of IncrFailure.
+func (mr *MockLoginAttemptRepositoryMockRecorder) IncrFailure(ctx, subject, policy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrFailure", reflect.TypeOf((*MockLoginAttemptRepository)(nil).IncrFailure), ctx, subject, policy)
+}
+
+// Reset mocks base method.
+func (m *MockLoginAttemptRepository) Reset(ctx context.Context, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLoginAttemptRepositoryMockRecorder) Reset(ctx, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLoginAttemptRepository)(nil).Reset), ctx, subject)
+}
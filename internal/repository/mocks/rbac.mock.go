@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/rbac.go -package=repomocks -destination=./webook/internal/repository/mocks/rbac.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACRepository is a mock of RBACRepository interface.
+type MockRBACRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACRepositoryMockRecorder
+}
+
+// MockRBACRepositoryMockRecorder is the mock recorder for MockRBACRepository.
+type MockRBACRepositoryMockRecorder struct {
+	mock *MockRBACRepository
+}
+
+// NewMockRBACRepository creates a new mock instance.
+func NewMockRBACRepository(ctrl *gomock.Controller) *MockRBACRepository {
+	mock := &MockRBACRepository{ctrl: ctrl}
+	mock.recorder = &MockRBACRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACRepository) EXPECT() *MockRBACRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetUserPermissions mocks base method.
+func (m *MockRBACRepository) GetUserPermissions(ctx context.Context, uid int64) (domain.UserPermissions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserPermissions", ctx, uid)
+	ret0, _ := ret[0].(domain.UserPermissions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserPermissions indicates an expected call of GetUserPermissions.
+func (mr *MockRBACRepositoryMockRecorder) GetUserPermissions(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserPermissions", reflect.TypeOf((*MockRBACRepository)(nil).GetUserPermissions), ctx, uid)
+}
+
+// SetUserRoles mocks base method.
+func (m *MockRBACRepository) SetUserRoles(ctx context.Context, uid int64, roles []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, uid, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRoles indicates an expected call of SetUserRoles.
+func (mr *MockRBACRepositoryMockRecorder) SetUserRoles(ctx, uid, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockRBACRepository)(nil).SetUserRoles), ctx, uid, roles)
+}
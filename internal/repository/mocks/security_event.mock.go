@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/security_event.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/security_event.go -package=repomocks -destination=./webook/internal/repository/mocks/security_event.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSecurityEventRepository is a mock of SecurityEventRepository interface.
+type MockSecurityEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecurityEventRepositoryMockRecorder
+}
+
+// MockSecurityEventRepositoryMockRecorder is the mock recorder for MockSecurityEventRepository.
+type MockSecurityEventRepositoryMockRecorder struct {
+	mock *MockSecurityEventRepository
+}
+
+// NewMockSecurityEventRepository creates a new mock instance.
+func NewMockSecurityEventRepository(ctrl *gomock.Controller) *MockSecurityEventRepository {
+	mock := &MockSecurityEventRepository{ctrl: ctrl}
+	mock.recorder = &MockSecurityEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecurityEventRepository) EXPECT() *MockSecurityEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSecurityEventRepository) Create(ctx context.Context, evt domain.SecurityEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSecurityEventRepositoryMockRecorder) Create(ctx, evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSecurityEventRepository)(nil).Create), ctx, evt)
+}
+
+// HasLogin mocks base method.
+func (m *MockSecurityEventRepository) HasLogin(ctx context.Context, uid int64, evt *domain.SecurityEvent) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasLogin", ctx, uid, evt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasLogin indicates an expected call of HasLogin.
+func (mr *MockSecurityEventRepositoryMockRecorder) HasLogin(ctx, uid, evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasLogin", reflect.TypeOf((*MockSecurityEventRepository)(nil).HasLogin), ctx, uid, evt)
+}
+
+// List mocks base method.
+func (m *MockSecurityEventRepository) List(ctx context.Context, uid, cursor int64, limit int) ([]domain.SecurityEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, uid, cursor, limit)
+	ret0, _ := ret[0].([]domain.SecurityEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSecurityEventRepositoryMockRecorder) List(ctx, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSecurityEventRepository)(nil).List), ctx, uid, cursor, limit)
+}
@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/totp.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/totp.go -package=repomocks -destination=./webook/internal/repository/mocks/totp.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTOTPRepository is a mock of TOTPRepository interface.
+type MockTOTPRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTOTPRepositoryMockRecorder
+}
+
+// MockTOTPRepositoryMockRecorder is the mock recorder for MockTOTPRepository.
+type MockTOTPRepositoryMockRecorder struct {
+	mock *MockTOTPRepository
+}
+
+// NewMockTOTPRepository creates a new mock instance.
+func NewMockTOTPRepository(ctrl *gomock.Controller) *MockTOTPRepository {
+	mock := &MockTOTPRepository{ctrl: ctrl}
+	mock.recorder = &MockTOTPRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTOTPRepository) EXPECT() *MockTOTPRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockTOTPRepository) Delete(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTOTPRepositoryMockRecorder) Delete(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTOTPRepository)(nil).Delete), ctx, uid)
+}
+
+// Enable mocks base method.
+func (m *MockTOTPRepository) Enable(ctx context.Context, uid, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockTOTPRepositoryMockRecorder) Enable(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockTOTPRepository)(nil).Enable), ctx, uid, step)
+}
+
+// FindByUid mocks base method.
+func (m *MockTOTPRepository) FindByUid(ctx context.Context, uid int64) (domain.UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].(domain.UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockTOTPRepositoryMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockTOTPRepository)(nil).FindByUid), ctx, uid)
+}
+
+// SavePending mocks base method.
+func (m *MockTOTPRepository) SavePending(ctx context.Context, t domain.UserTOTP, recoveryCodes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePending", ctx, t, recoveryCodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePending indicates an expected call of SavePending.
+func (mr *MockTOTPRepositoryMockRecorder) SavePending(ctx, t, recoveryCodes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePending", reflect.TypeOf((*MockTOTPRepository)(nil).SavePending), ctx, t, recoveryCodes)
+}
+
+// UpdateLastStep mocks base method.
+func (m *MockTOTPRepository) UpdateLastStep(ctx context.Context, uid, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastStep", ctx, uid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastStep indicates an expected call of UpdateLastStep.
+func (mr *MockTOTPRepositoryMockRecorder) UpdateLastStep(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastStep", reflect.TypeOf((*MockTOTPRepository)(nil).UpdateLastStep), ctx, uid, step)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTOTPRepository) UseRecoveryCode(ctx context.Context, uid int64, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, uid, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTOTPRepositoryMockRecorder) UseRecoveryCode(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTOTPRepository)(nil).UseRecoveryCode), ctx, uid, code)
+}
@@ -98,6 +98,49 @@ func (mr *MockUserRepositoryMockRecorder) FindByPhone(ctx, phone any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPhone", reflect.TypeOf((*MockUserRepository)(nil).FindByPhone), ctx, phone)
 }
 
+// FindByWechat mocks base method.
+func (m *MockUserRepository) FindByWechat(ctx context.Context, openId string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByWechat", ctx, openId)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByWechat indicates an expected call of FindByWechat.
+func (mr *MockUserRepositoryMockRecorder) FindByWechat(ctx, openId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechat", reflect.TypeOf((*MockUserRepository)(nil).FindByWechat), ctx, openId)
+}
+
+// UpdateBanned mocks base method.
+func (m *MockUserRepository) UpdateBanned(ctx context.Context, uid int64, banned bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBanned", ctx, uid, banned)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateBanned indicates an expected call of UpdateBanned.
+func (mr *MockUserRepositoryMockRecorder) UpdateBanned(ctx, uid, banned any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBanned", reflect.TypeOf((*MockUserRepository)(nil).UpdateBanned), ctx, uid, banned)
+}
+
+// UpdateEmailVerified mocks base method.
+func (m *MockUserRepository) UpdateEmailVerified(ctx context.Context, uid int64, clearPassword bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmailVerified", ctx, uid, clearPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmailVerified indicates an expected call of UpdateEmailVerified.
+func (mr *MockUserRepositoryMockRecorder) UpdateEmailVerified(ctx, uid, clearPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmailVerified", reflect.TypeOf((*MockUserRepository)(nil).UpdateEmailVerified), ctx, uid, clearPassword)
+}
+
 // UpdateNonZeroFields mocks base method.
 func (m *MockUserRepository) UpdateNonZeroFields(ctx context.Context, user domain.User) error {
 	m.ctrl.T.Helper()
@@ -111,3 +154,17 @@ func (mr *MockUserRepositoryMockRecorder) UpdateNonZeroFields(ctx, user any) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNonZeroFields", reflect.TypeOf((*MockUserRepository)(nil).UpdateNonZeroFields), ctx, user)
 }
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, uid int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, uid, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepositoryMockRecorder) UpdatePassword(ctx, uid, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepository)(nil).UpdatePassword), ctx, uid, password)
+}
@@ -0,0 +1,156 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/user_identity.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/repository/user_identity.go -package=repomocks -destination=./webook/internal/repository/mocks/user_identity.mock.go
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserIdentityRepository is a mock of UserIdentityRepository interface.
+type MockUserIdentityRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserIdentityRepositoryMockRecorder
+}
+
+// MockUserIdentityRepositoryMockRecorder is the mock recorder for MockUserIdentityRepository.
+type MockUserIdentityRepositoryMockRecorder struct {
+	mock *MockUserIdentityRepository
+}
+
+// NewMockUserIdentityRepository creates a new mock instance.
+func NewMockUserIdentityRepository(ctrl *gomock.Controller) *MockUserIdentityRepository {
+	mock := &MockUserIdentityRepository{ctrl: ctrl}
+	mock.recorder = &MockUserIdentityRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserIdentityRepository) EXPECT() *MockUserIdentityRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserIdentityRepository) Create(ctx context.Context, identity domain.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserIdentityRepositoryMockRecorder) Create(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserIdentityRepository)(nil).Create), ctx, identity)
+}
+
+// CreateWithUser mocks base method.
+func (m *MockUserIdentityRepository) CreateWithUser(ctx context.Context, u domain.User, identity domain.UserIdentity) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithUser", ctx, u, identity)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWithUser indicates an expected call of CreateWithUser.
+func (mr *MockUserIdentityRepositoryMockRecorder) CreateWithUser(ctx, u, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithUser", reflect.TypeOf((*MockUserIdentityRepository)(nil).CreateWithUser), ctx, u, identity)
+}
+
+// Delete mocks base method.
+func (m *MockUserIdentityRepository) Delete(ctx context.Context, uid int64, provider string, allowLast bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, provider, allowLast)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserIdentityRepositoryMockRecorder) Delete(ctx, uid, provider, allowLast any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserIdentityRepository)(nil).Delete), ctx, uid, provider, allowLast)
+}
+
+// FindByProviderSubject mocks base method.
+func (m *MockUserIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (domain.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByProviderSubject", ctx, provider, subject)
+	ret0, _ := ret[0].(domain.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByProviderSubject indicates an expected call of FindByProviderSubject.
+func (mr *MockUserIdentityRepositoryMockRecorder) FindByProviderSubject(ctx, provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByProviderSubject", reflect.TypeOf((*MockUserIdentityRepository)(nil).FindByProviderSubject), ctx, provider, subject)
+}
+
+// FindByProviderUnionId mocks base method.
+func (m *MockUserIdentityRepository) FindByProviderUnionId(ctx context.Context, provider, unionId string) (domain.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByProviderUnionId", ctx, provider, unionId)
+	ret0, _ := ret[0].(domain.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByProviderUnionId indicates an expected call of FindByProviderUnionId.
+func (mr *MockUserIdentityRepositoryMockRecorder) FindByProviderUnionId(ctx, provider, unionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByProviderUnionId", reflect.TypeOf((*MockUserIdentityRepository)(nil).FindByProviderUnionId), ctx, provider, unionId)
+}
+
+// FindByUid mocks base method.
+func (m *MockUserIdentityRepository) FindByUid(ctx context.Context, uid int64) ([]domain.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]domain.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockUserIdentityRepositoryMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockUserIdentityRepository)(nil).FindByUid), ctx, uid)
+}
+
+// MigrateWechat mocks base method.
+func (m *MockUserIdentityRepository) MigrateWechat(ctx context.Context, identity domain.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateWechat", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateWechat indicates an expected call of MigrateWechat.
+func (mr *MockUserIdentityRepositoryMockRecorder) MigrateWechat(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateWechat", reflect.TypeOf((*MockUserIdentityRepository)(nil).MigrateWechat), ctx, identity)
+}
+
+// UpdateToken mocks base method.
+func (m *MockUserIdentityRepository) UpdateToken(ctx context.Context, identity domain.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateToken", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateToken indicates an expected call of UpdateToken.
+func (mr *MockUserIdentityRepositoryMockRecorder) UpdateToken(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateToken", reflect.TypeOf((*MockUserIdentityRepository)(nil).UpdateToken), ctx, identity)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+var ErrStaleFencingToken = cache.ErrStaleFencingToken
+
+type RankingRepository interface {
+	// ReplaceTopN token 是计算榜单的任务持有的分布式锁的 fencing token
+	ReplaceTopN(ctx context.Context, arts []domain.Article, token int64) error
+	GetTopN(ctx context.Context) ([]domain.Article, error)
+}
+
+// CachedRankingRepository 热榜只存在缓存里面，先查本地缓存，再查 Redis
+type CachedRankingRepository struct {
+	redis cache.RankingCache
+	local *cache.RankingLocalCache
+	l     logger.LoggerV1
+}
+
+func NewCachedRankingRepository(redis cache.RankingCache,
+	local *cache.RankingLocalCache,
+	l logger.LoggerV1) RankingRepository {
+	return &CachedRankingRepository{
+		redis: redis,
+		local: local,
+		l:     l,
+	}
+}
+
+// ReplaceTopN 先写 Redis，写成功了才更新本地缓存，
+// 避免过期的任务把本地缓存改成和 Redis 不一样
+func (c *CachedRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.Article, token int64) error {
+	err := c.redis.Set(ctx, arts, token)
+	if err != nil {
+		return err
+	}
+	c.local.Set(arts)
+	return nil
+}
+
+func (c *CachedRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	arts, err := c.local.Get()
+	if err == nil {
+		return arts, nil
+	}
+	arts, err = c.redis.Get(ctx)
+	if err == cache.ErrKeyNotExist {
+		// 任务还没有算过榜单
+		return []domain.Article{}, nil
+	}
+	if err != nil {
+		// Redis 出问题了，本地缓存过期了也先用着
+		c.l.Error("查询 Redis 热榜失败", logger.Field{Key: "error", Val: err})
+		return c.local.ForceGet()
+	}
+	c.local.Set(arts)
+	return arts, nil
+}
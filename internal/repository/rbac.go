@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+)
+
+var ErrRoleNotFound = errors.New("角色不存在")
+
+type RBACRepository interface {
+	GetUserPermissions(ctx context.Context, uid int64) (domain.UserPermissions, error)
+	// SetUserRoles 有任何一个角色不存在就返回 ErrRoleNotFound，什么都不改
+	SetUserRoles(ctx context.Context, uid int64, roles []string) error
+}
+
+type CachedRBACRepository struct {
+	dao dao.RBACDAO
+}
+
+func NewCachedRBACRepository(dao dao.RBACDAO) RBACRepository {
+	return &CachedRBACRepository{dao: dao}
+}
+
+func (c *CachedRBACRepository) GetUserPermissions(ctx context.Context, uid int64) (domain.UserPermissions, error) {
+	roles, err := c.dao.FindRolesByUid(ctx, uid)
+	if err != nil {
+		return domain.UserPermissions{}, err
+	}
+	// 普通用户没有角色，不用再查权限
+	res := domain.UserPermissions{
+		Roles:       make([]string, 0, len(roles)),
+		Permissions: []string{},
+	}
+	if len(roles) == 0 {
+		return res, nil
+	}
+	roleIds := make([]int64, 0, len(roles))
+	for _, r := range roles {
+		res.Roles = append(res.Roles, r.Name)
+		roleIds = append(roleIds, r.Id)
+	}
+	perms, err := c.dao.FindPermissions(ctx, roleIds)
+	if err != nil {
+		return domain.UserPermissions{}, err
+	}
+	// 不同角色的权限可能重复
+	seen := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		if !seen[p.Permission] {
+			seen[p.Permission] = true
+			res.Permissions = append(res.Permissions, p.Permission)
+		}
+	}
+	return res, nil
+}
+
+func (c *CachedRBACRepository) SetUserRoles(ctx context.Context, uid int64, roles []string) error {
+	var roleIds []int64
+	if len(roles) > 0 {
+		found, err := c.dao.FindRolesByNames(ctx, roles)
+		if err != nil {
+			return err
+		}
+		ids := make(map[string]int64, len(found))
+		for _, r := range found {
+			ids[r.Name] = r.Id
+		}
+		for _, name := range roles {
+			id, ok := ids[name]
+			if !ok {
+				return ErrRoleNotFound
+			}
+			roleIds = append(roleIds, id)
+		}
+	}
+	return c.dao.SetUserRoles(ctx, uid, roleIds)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedRBACRepository_GetUserPermissions(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) dao.RBACDAO
+
+		wantRes domain.UserPermissions
+		wantErr error
+	}{
+		{
+			name: "多个角色，权限去重",
+			mock: func(ctrl *gomock.Controller) dao.RBACDAO {
+				d := daomocks.NewMockRBACDAO(ctrl)
+				d.EXPECT().FindRolesByUid(gomock.Any(), int64(123)).
+					Return([]dao.Role{{Id: 1, Name: "admin"}, {Id: 2, Name: "moderator"}}, nil)
+				d.EXPECT().FindPermissions(gomock.Any(), []int64{1, 2}).
+					Return([]dao.RolePermission{
+						{RoleId: 1, Permission: "user:read"},
+						{RoleId: 1, Permission: "article:takedown"},
+						{RoleId: 2, Permission: "article:takedown"},
+					}, nil)
+				return d
+			},
+			wantRes: domain.UserPermissions{
+				Roles:       []string{"admin", "moderator"},
+				Permissions: []string{"user:read", "article:takedown"},
+			},
+		},
+		{
+			name: "普通用户",
+			mock: func(ctrl *gomock.Controller) dao.RBACDAO {
+				d := daomocks.NewMockRBACDAO(ctrl)
+				d.EXPECT().FindRolesByUid(gomock.Any(), int64(123)).Return([]dao.Role{}, nil)
+				return d
+			},
+			wantRes: domain.UserPermissions{Roles: []string{}, Permissions: []string{}},
+		},
+		{
+			name: "查询角色失败",
+			mock: func(ctrl *gomock.Controller) dao.RBACDAO {
+				d := daomocks.NewMockRBACDAO(ctrl)
+				d.EXPECT().FindRolesByUid(gomock.Any(), int64(123)).
+					Return(nil, errors.New("db error"))
+				return d
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := NewCachedRBACRepository(tc.mock(ctrl))
+			res, err := repo.GetUserPermissions(context.Background(), 123)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestCachedRBACRepository_SetUserRoles(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) dao.RBACDAO
+		roles []string
+
+		wantErr error
+	}{
+		{
+			name: "设置成功",
+			mock: func(ctrl *gomock.Controller) dao.RBACDAO {
+				d := daomocks.NewMockRBACDAO(ctrl)
+				d.EXPECT().FindRolesByNames(gomock.Any(), []string{"moderator"}).
+					Return([]dao.Role{{Id: 2, Name: "moderator"}}, nil)
+				d.EXPECT().SetUserRoles(gomock.Any(), int64(123), []int64{2}).Return(nil)
+				return d
+			},
+			roles: []string{"moderator"},
+		},
+		{
+			name: "去掉所有角色",
+			mock: func(ctrl *gomock.Controller) dao.RBACDAO {
+				d := daomocks.NewMockRBACDAO(ctrl)
+				d.EXPECT().SetUserRoles(gomock.Any(), int64(123), []int64(nil)).Return(nil)
+				return d
+			},
+		},
+		{
+			name: "角色不存在",
+			mock: func(ctrl *gomock.Controller) dao.RBACDAO {
+				d := daomocks.NewMockRBACDAO(ctrl)
+				d.EXPECT().FindRolesByNames(gomock.Any(), []string{"moderator", "god"}).
+					Return([]dao.Role{{Id: 2, Name: "moderator"}}, nil)
+				return d
+			},
+			roles:   []string{"moderator", "god"},
+			wantErr: ErrRoleNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := NewCachedRBACRepository(tc.mock(ctrl))
+			err := repo.SetUserRoles(context.Background(), 123, tc.roles)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
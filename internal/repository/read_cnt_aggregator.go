@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+// ErrReadCntAggregatorClosed 聚合器已经关闭，不再接收阅读事件
+var ErrReadCntAggregatorClosed = errors.New("阅读数聚合器已经关闭")
+
+type readCntKey struct {
+	biz   string
+	bizId int64
+}
+
+// ReadCntAggregator 在内存里面按照 (biz, bizId) 聚合阅读事件，
+// 攒够 batchSize 个资源或者每隔 interval 批量写一次数据库和缓存。
+// 热点文章一秒钟内的几千次阅读，最终只是一行数据的一次更新。
+// 代价是进程崩溃的时候会丢失还没刷新的阅读数，阅读数本身可以容忍这一点。
+type ReadCntAggregator struct {
+	dao   dao.InteractiveDAO
+	cache cache.InteractiveCache
+	l     logger.LoggerV1
+
+	// 缓冲区里面有多少个不同的资源就触发刷新
+	batchSize int
+	// 最多隔多久刷新一次
+	interval time.Duration
+	// 单次刷新的超时时间
+	flushTimeout time.Duration
+
+	mu     sync.Mutex
+	buffer map[readCntKey]int64
+	closed bool
+
+	// 缓冲区满了，通知后台 goroutine 立刻刷新
+	full chan struct{}
+	// 关闭的时候通知后台 goroutine 退出
+	closing chan struct{}
+	// 后台 goroutine 退出之后关闭
+	done chan struct{}
+}
+
+func NewReadCntAggregator(dao dao.InteractiveDAO,
+	cache cache.InteractiveCache,
+	l logger.LoggerV1) *ReadCntAggregator {
+	return newReadCntAggregator(dao, cache, l, 200, time.Second)
+}
+
+func newReadCntAggregator(dao dao.InteractiveDAO,
+	cache cache.InteractiveCache,
+	l logger.LoggerV1,
+	batchSize int, interval time.Duration) *ReadCntAggregator {
+	a := &ReadCntAggregator{
+		dao:          dao,
+		cache:        cache,
+		l:            l,
+		batchSize:    batchSize,
+		interval:     interval,
+		flushTimeout: time.Second * 3,
+		buffer:       make(map[readCntKey]int64, batchSize),
+		full:         make(chan struct{}, 1),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+// Add 记录一次阅读，只是写到内存里面
+func (a *ReadCntAggregator) Add(biz string, bizId int64) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrReadCntAggregatorClosed
+	}
+	a.buffer[readCntKey{biz: biz, bizId: bizId}]++
+	full := len(a.buffer) >= a.batchSize
+	a.mu.Unlock()
+	if full {
+		select {
+		case a.full <- struct{}{}:
+		default:
+			// 已经有一个刷新信号在排队了
+		}
+	}
+	return nil
+}
+
+// Close 不再接收新的阅读事件，并且把缓冲区里面剩下的数据刷新出去
+func (a *ReadCntAggregator) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+	close(a.closing)
+	// 等后台 goroutine 退出，避免和它并发刷新
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return a.flush(ctx)
+}
+
+func (a *ReadCntAggregator) loop() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.full:
+		case <-a.closing:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), a.flushTimeout)
+		_ = a.flush(ctx)
+		cancel()
+	}
+}
+
+// flush 把缓冲区换出来，然后一次性写数据库，再用一个 pipeline 更新缓存
+func (a *ReadCntAggregator) flush(ctx context.Context) error {
+	a.mu.Lock()
+	if len(a.buffer) == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	buffer := a.buffer
+	a.buffer = make(map[readCntKey]int64, a.batchSize)
+	a.mu.Unlock()
+
+	// map 的遍历顺序是随机的，排好序之后并发的批量写加行锁的顺序一致，不会死锁
+	keys := make([]readCntKey, 0, len(buffer))
+	for key := range buffer {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].biz != keys[j].biz {
+			return keys[i].biz < keys[j].biz
+		}
+		return keys[i].bizId < keys[j].bizId
+	})
+	bizs := make([]string, 0, len(keys))
+	bizIds := make([]int64, 0, len(keys))
+	deltas := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		bizs = append(bizs, key.biz)
+		bizIds = append(bizIds, key.bizId)
+		deltas = append(deltas, buffer[key])
+	}
+	err := a.dao.BatchIncrReadCnt(ctx, bizs, bizIds, deltas)
+	if err != nil {
+		// 数据库出问题的时候不放回缓冲区，免得缓冲区无限增长，丢掉这一批阅读数
+		a.l.Error("批量增加阅读数失败",
+			logger.Field{Key: "size", Val: len(bizs)},
+			logger.Field{Key: "error", Val: err})
+		return err
+	}
+	err = a.cache.BatchIncrReadCntIfPresent(ctx, bizs, bizIds, deltas)
+	if err != nil {
+		a.l.Error("缓存批量增加阅读数失败",
+			logger.Field{Key: "size", Val: len(bizs)},
+			logger.Field{Key: "error", Val: err})
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	cachemocks "gitee.com/geekbang/basic-go/webook/internal/repository/cache/mocks"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReadCntAggregator_FlushBySize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockInteractiveDAO(ctrl)
+	c := cachemocks.NewMockInteractiveCache(ctrl)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var got map[int64]int64
+	d.EXPECT().BatchIncrReadCnt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, bizs []string, bizIds []int64, deltas []int64) error {
+			got = make(map[int64]int64, len(bizIds))
+			for i := range bizIds {
+				assert.Equal(t, "article", bizs[i])
+				got[bizIds[i]] = deltas[i]
+			}
+			return nil
+		})
+	c.EXPECT().BatchIncrReadCntIfPresent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, bizs []string, bizIds []int64, deltas []int64) error {
+			wg.Done()
+			return nil
+		})
+
+	// 时间阈值足够长，只可能是因为攒够了数量才刷新
+	agg := newReadCntAggregator(d, c, logger.NewNopLogger(), 2, time.Hour)
+	require.NoError(t, agg.Add("article", 1))
+	require.NoError(t, agg.Add("article", 1))
+	require.NoError(t, agg.Add("article", 1))
+	require.NoError(t, agg.Add("article", 2))
+	wg.Wait()
+	assert.Equal(t, map[int64]int64{1: 3, 2: 1}, got)
+	require.NoError(t, agg.Close(context.Background()))
+}
+
+func TestReadCntAggregator_Close(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockInteractiveDAO(ctrl)
+	c := cachemocks.NewMockInteractiveCache(ctrl)
+	d.EXPECT().BatchIncrReadCnt(gomock.Any(), []string{"article"}, []int64{1}, []int64{2}).
+		Return(nil)
+	c.EXPECT().BatchIncrReadCntIfPresent(gomock.Any(), []string{"article"}, []int64{1}, []int64{2}).
+		Return(nil)
+
+	agg := newReadCntAggregator(d, c, logger.NewNopLogger(), 100, time.Hour)
+	require.NoError(t, agg.Add("article", 1))
+	require.NoError(t, agg.Add("article", 1))
+	// 关闭的时候把缓冲区里面剩下的刷新出去
+	require.NoError(t, agg.Close(context.Background()))
+	assert.Equal(t, ErrReadCntAggregatorClosed, agg.Add("article", 1))
+	// 重复关闭没有影响
+	require.NoError(t, agg.Close(context.Background()))
+}
+
+func TestReadCntAggregator_FlushSorted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockInteractiveDAO(ctrl)
+	c := cachemocks.NewMockInteractiveCache(ctrl)
+	// 按照 (biz, bizId) 排序，并发刷新的时候加锁顺序一致
+	bizs := []string{"article", "article", "article", "video"}
+	bizIds := []int64{1, 2, 3, 1}
+	deltas := []int64{1, 1, 1, 1}
+	d.EXPECT().BatchIncrReadCnt(gomock.Any(), bizs, bizIds, deltas).Return(nil)
+	c.EXPECT().BatchIncrReadCntIfPresent(gomock.Any(), bizs, bizIds, deltas).Return(nil)
+
+	agg := newReadCntAggregator(d, c, logger.NewNopLogger(), 100, time.Hour)
+	require.NoError(t, agg.Add("video", 1))
+	require.NoError(t, agg.Add("article", 3))
+	require.NoError(t, agg.Add("article", 1))
+	require.NoError(t, agg.Add("article", 2))
+	require.NoError(t, agg.Close(context.Background()))
+}
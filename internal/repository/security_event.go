@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+)
+
+type SecurityEventRepository interface {
+	Create(ctx context.Context, evt domain.SecurityEvent) error
+	List(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.SecurityEvent, error)
+	// HasLogin 用户有没有在这个设备上登录成功过，evt 为 nil 就是任意设备
+	HasLogin(ctx context.Context, uid int64, evt *domain.SecurityEvent) (bool, error)
+}
+
+type CachedSecurityEventRepository struct {
+	dao dao.SecurityEventDAO
+}
+
+func NewCachedSecurityEventRepository(dao dao.SecurityEventDAO) SecurityEventRepository {
+	return &CachedSecurityEventRepository{dao: dao}
+}
+
+func (c *CachedSecurityEventRepository) Create(ctx context.Context, evt domain.SecurityEvent) error {
+	return c.dao.Insert(ctx, c.toEntity(evt))
+}
+
+func (c *CachedSecurityEventRepository) List(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.SecurityEvent, error) {
+	evts, err := c.dao.FindByUid(ctx, uid, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.SecurityEvent, 0, len(evts))
+	for _, evt := range evts {
+		res = append(res, c.toDomain(evt))
+	}
+	return res, nil
+}
+
+func (c *CachedSecurityEventRepository) HasLogin(ctx context.Context, uid int64, evt *domain.SecurityEvent) (bool, error) {
+	device := ""
+	if evt != nil {
+		device = deviceOf(*evt)
+	}
+	return c.dao.HasLogin(ctx, uid, device)
+}
+
+func (c *CachedSecurityEventRepository) toEntity(evt domain.SecurityEvent) dao.SecurityEvent {
+	ua := evt.UserAgent
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	return dao.SecurityEvent{
+		Uid:       evt.Uid,
+		Account:   evt.Account,
+		Type:      evt.Type,
+		Method:    evt.Method,
+		Result:    evt.Result,
+		Detail:    evt.Detail,
+		IP:        evt.IP,
+		UserAgent: ua,
+		Device:    deviceOf(evt),
+		NewDevice: evt.NewDevice,
+	}
+}
+
+func (c *CachedSecurityEventRepository) toDomain(evt dao.SecurityEvent) domain.SecurityEvent {
+	return domain.SecurityEvent{
+		Id:        evt.Id,
+		Uid:       evt.Uid,
+		Account:   evt.Account,
+		Type:      evt.Type,
+		Method:    evt.Method,
+		Result:    evt.Result,
+		Detail:    evt.Detail,
+		IP:        evt.IP,
+		UserAgent: evt.UserAgent,
+		NewDevice: evt.NewDevice,
+		Ctime:     time.UnixMilli(evt.Ctime),
+	}
+}
+
+// deviceOf 优先用设备指纹区分设备，没有的话退化成 UserAgent
+func deviceOf(evt domain.SecurityEvent) string {
+	device := evt.Fingerprint
+	if device == "" {
+		device = evt.UserAgent
+	}
+	sum := sha256.Sum256([]byte(device))
+	return hex.EncodeToString(sum[:])
+}
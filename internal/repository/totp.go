@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/pkg/cryptox"
+)
+
+var (
+	ErrTOTPNotFound       = dao.ErrRecordNotFound
+	ErrTOTPAlreadyEnabled = dao.ErrTOTPAlreadyEnabled
+	// ErrTOTPStepUsed 这个时间步的码已经用过了，防止同一个码被用两次
+	ErrTOTPStepUsed        = errors.New("动态码已经用过了")
+	ErrRecoveryCodeInvalid = errors.New("恢复码不对或者已经用过了")
+)
+
+type TOTPRepository interface {
+	// SavePending 保存还没确认的密钥，recoveryCodes 是明文，只存哈希
+	SavePending(ctx context.Context, t domain.UserTOTP, recoveryCodes []string) error
+	FindByUid(ctx context.Context, uid int64) (domain.UserTOTP, error)
+	Enable(ctx context.Context, uid int64, step int64) error
+	UpdateLastStep(ctx context.Context, uid int64, step int64) error
+	UseRecoveryCode(ctx context.Context, uid int64, code string) error
+	Delete(ctx context.Context, uid int64) error
+}
+
+// CachedTOTPRepository 密钥在这一层加解密，数据库里面只有密文
+type CachedTOTPRepository struct {
+	dao    dao.TOTPDAO
+	cipher *cryptox.AESGCM
+}
+
+func NewCachedTOTPRepository(dao dao.TOTPDAO, cipher *cryptox.AESGCM) TOTPRepository {
+	return &CachedTOTPRepository{
+		dao:    dao,
+		cipher: cipher,
+	}
+}
+
+func (c *CachedTOTPRepository) SavePending(ctx context.Context, t domain.UserTOTP, recoveryCodes []string) error {
+	secret, err := c.cipher.Encrypt([]byte(t.Secret), c.aad(t.Uid))
+	if err != nil {
+		return err
+	}
+	codes := make([]dao.UserRecoveryCode, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		codes = append(codes, dao.UserRecoveryCode{CodeHash: hashRecoveryCode(code)})
+	}
+	return c.dao.UpsertPending(ctx, dao.UserTOTP{
+		Uid:    t.Uid,
+		Secret: secret,
+	}, codes)
+}
+
+func (c *CachedTOTPRepository) FindByUid(ctx context.Context, uid int64) (domain.UserTOTP, error) {
+	t, err := c.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return domain.UserTOTP{}, err
+	}
+	secret, err := c.cipher.Decrypt(t.Secret, c.aad(uid))
+	if err != nil {
+		return domain.UserTOTP{}, err
+	}
+	return domain.UserTOTP{
+		Uid:      t.Uid,
+		Secret:   string(secret),
+		Enabled:  t.Enabled,
+		LastStep: t.LastStep,
+	}, nil
+}
+
+func (c *CachedTOTPRepository) Enable(ctx context.Context, uid int64, step int64) error {
+	return c.dao.Enable(ctx, uid, step)
+}
+
+func (c *CachedTOTPRepository) UpdateLastStep(ctx context.Context, uid int64, step int64) error {
+	err := c.dao.UpdateLastStep(ctx, uid, step)
+	if err == dao.ErrRecordNotFound {
+		return ErrTOTPStepUsed
+	}
+	return err
+}
+
+func (c *CachedTOTPRepository) UseRecoveryCode(ctx context.Context, uid int64, code string) error {
+	err := c.dao.UseRecoveryCode(ctx, uid, hashRecoveryCode(code))
+	if err == dao.ErrRecordNotFound {
+		return ErrRecoveryCodeInvalid
+	}
+	return err
+}
+
+func (c *CachedTOTPRepository) Delete(ctx context.Context, uid int64) error {
+	return c.dao.Delete(ctx, uid)
+}
+
+// aad 密文和用户绑定，挪到别的用户那里解不开
+func (c *CachedTOTPRepository) aad(uid int64) []byte {
+	return []byte(strconv.FormatInt(uid, 10))
+}
+
+// hashRecoveryCode 恢复码是随机生成的，熵足够高，不需要 bcrypt 这种慢哈希
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/cryptox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedTOTPRepository_SecretEncrypted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cipher, err := cryptox.NewAESGCM([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	d := daomocks.NewMockTOTPDAO(ctrl)
+	var stored dao.UserTOTP
+	d.EXPECT().UpsertPending(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, u dao.UserTOTP, codes []dao.UserRecoveryCode) error {
+			stored = u
+			// 恢复码只存哈希
+			assert.Equal(t, []dao.UserRecoveryCode{{CodeHash: hashRecoveryCode("ABCDE23456")}}, codes)
+			return nil
+		})
+	repo := NewCachedTOTPRepository(d, cipher)
+	err = repo.SavePending(context.Background(),
+		domain.UserTOTP{Uid: 123, Secret: "SECRET"}, []string{"ABCDE23456"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored.Secret), "SECRET")
+
+	d.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(stored, nil)
+	u, err := repo.FindByUid(context.Background(), 123)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserTOTP{Uid: 123, Secret: "SECRET"}, u)
+
+	// 密文被挪到别的用户那里解不开
+	d.EXPECT().FindByUid(gomock.Any(), int64(456)).Return(stored, nil)
+	_, err = repo.FindByUid(context.Background(), 456)
+	assert.Error(t, err)
+}
@@ -22,6 +22,11 @@ type UserRepository interface {
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	FindById(ctx context.Context, uid int64) (domain.User, error)
 	FindByWechat(ctx context.Context, openId string) (domain.User, error)
+	UpdateBanned(ctx context.Context, uid int64, banned bool) error
+	// UpdateEmailVerified clearPassword 为 true 的时候顺便清掉密码
+	UpdateEmailVerified(ctx context.Context, uid int64, clearPassword bool) error
+	// UpdatePassword password 是加密之后的
+	UpdatePassword(ctx context.Context, uid int64, password string) error
 }
 
 type CachedUserRepository struct {
@@ -89,18 +94,21 @@ func (repo *CachedUserRepository) FindByEmail(ctx context.Context, email string)
 
 func (repo *CachedUserRepository) toDomain(u dao.User) domain.User {
 	return domain.User{
-		Id:       u.Id,
-		Email:    u.Email.String,
-		Phone:    u.Phone.String,
-		Password: u.Password,
-		AboutMe:  u.AboutMe,
-		Nickname: u.Nickname,
-		Birthday: time.UnixMilli(u.Birthday),
-		Ctime:    time.UnixMilli(u.Ctime),
+		Id:            u.Id,
+		Email:         u.Email.String,
+		EmailVerified: u.EmailVerified,
+		Phone:         u.Phone.String,
+		Password:      u.Password,
+		AboutMe:       u.AboutMe,
+		Nickname:      u.Nickname,
+		Avatar:        u.Avatar,
+		Birthday:      time.UnixMilli(u.Birthday),
+		Ctime:         time.UnixMilli(u.Ctime),
 		WechatInfo: domain.WechatInfo{
 			OpenId:  u.WechatOpenId.String,
 			UnionId: u.WechatUnionId.String,
 		},
+		Banned: u.Banned,
 	}
 }
 
@@ -111,6 +119,7 @@ func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 			String: u.Email,
 			Valid:  u.Email != "",
 		},
+		EmailVerified: u.EmailVerified,
 		Phone: sql.NullString{
 			String: u.Phone,
 			Valid:  u.Phone != "",
@@ -127,6 +136,7 @@ func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 		},
 		AboutMe:  u.AboutMe,
 		Nickname: u.Nickname,
+		Avatar:   u.Avatar,
 	}
 }
 
@@ -135,6 +145,32 @@ func (repo *CachedUserRepository) UpdateNonZeroFields(ctx context.Context,
 	return repo.dao.UpdateById(ctx, repo.toEntity(user))
 }
 
+func (repo *CachedUserRepository) UpdateBanned(ctx context.Context, uid int64, banned bool) error {
+	err := repo.dao.UpdateBanned(ctx, uid, banned)
+	if err != nil {
+		return err
+	}
+	// 缓存里面的用户信息过时了
+	return repo.cache.Del(ctx, uid)
+}
+
+func (repo *CachedUserRepository) UpdateEmailVerified(ctx context.Context, uid int64, clearPassword bool) error {
+	err := repo.dao.UpdateEmailVerified(ctx, uid, clearPassword)
+	if err != nil {
+		return err
+	}
+	return repo.cache.Del(ctx, uid)
+}
+
+func (repo *CachedUserRepository) UpdatePassword(ctx context.Context, uid int64, password string) error {
+	err := repo.dao.UpdatePassword(ctx, uid, password)
+	if err != nil {
+		return err
+	}
+	// 缓存里面有密码，不删掉的话旧密码还能登录
+	return repo.cache.Del(ctx, uid)
+}
+
 func (repo *CachedUserRepository) FindById(ctx context.Context, uid int64) (domain.User, error) {
 	du, err := repo.cache.Get(ctx, uid)
 	// 只要 err 为 nil，就返回
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/pkg/cryptox"
+)
+
+var (
+	ErrDuplicateIdentity = dao.ErrDuplicateIdentity
+	ErrLastIdentity      = dao.ErrLastIdentity
+	ErrIdentityNotFound  = dao.ErrRecordNotFound
+)
+
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity domain.UserIdentity) error
+	// CreateWithUser 第三方登录的新用户，返回用户 id
+	CreateWithUser(ctx context.Context, u domain.User, identity domain.UserIdentity) (int64, error)
+	// MigrateWechat 老的微信用户第一次登录的时候，把 users 表上的微信字段挪过来
+	MigrateWechat(ctx context.Context, identity domain.UserIdentity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (domain.UserIdentity, error)
+	FindByProviderUnionId(ctx context.Context, provider, unionId string) (domain.UserIdentity, error)
+	// UpdateToken 按照 uid 和 provider 更新令牌和用户信息
+	UpdateToken(ctx context.Context, identity domain.UserIdentity) error
+	FindByUid(ctx context.Context, uid int64) ([]domain.UserIdentity, error)
+	Delete(ctx context.Context, uid int64, provider string, allowLast bool) error
+}
+
+// CachedUserIdentityRepository 第三方的令牌在这一层加解密，数据库里面只有密文
+type CachedUserIdentityRepository struct {
+	dao dao.UserIdentityDAO
+	// userCache 缓存的用户信息里面有微信字段，迁移之后要删掉
+	userCache cache.UserCache
+	cipher    *cryptox.AESGCM
+}
+
+func NewCachedUserIdentityRepository(dao dao.UserIdentityDAO,
+	userCache cache.UserCache, cipher *cryptox.AESGCM) UserIdentityRepository {
+	return &CachedUserIdentityRepository{
+		dao:       dao,
+		userCache: userCache,
+		cipher:    cipher,
+	}
+}
+
+func (repo *CachedUserIdentityRepository) Create(ctx context.Context, identity domain.UserIdentity) error {
+	entity, err := repo.toEntity(identity)
+	if err != nil {
+		return err
+	}
+	return repo.dao.Insert(ctx, entity)
+}
+
+func (repo *CachedUserIdentityRepository) CreateWithUser(ctx context.Context,
+	u domain.User, identity domain.UserIdentity) (int64, error) {
+	// 令牌要和用户 id 绑定着加密，插入之后才知道用户 id，所以令牌后面再单独更新
+	tokens := identity
+	identity.AccessToken, identity.RefreshToken = "", ""
+	entity, err := repo.toEntity(identity)
+	if err != nil {
+		return 0, err
+	}
+	// 第三方登录注册的用户只有邮箱、昵称和头像
+	uid, err := repo.dao.InsertWithUser(ctx, dao.User{
+		Email: sql.NullString{
+			String: u.Email,
+			Valid:  u.Email != "",
+		},
+		EmailVerified: u.EmailVerified,
+		Nickname:      u.Nickname,
+		Avatar:        u.Avatar,
+	}, entity)
+	if err != nil || (tokens.AccessToken == "" && tokens.RefreshToken == "") {
+		return uid, err
+	}
+	tokens.Uid = uid
+	return uid, repo.UpdateToken(ctx, tokens)
+}
+
+func (repo *CachedUserIdentityRepository) MigrateWechat(ctx context.Context, identity domain.UserIdentity) error {
+	entity, err := repo.toEntity(identity)
+	if err != nil {
+		return err
+	}
+	err = repo.dao.MigrateWechat(ctx, entity)
+	if err != nil {
+		return err
+	}
+	return repo.userCache.Del(ctx, identity.Uid)
+}
+
+func (repo *CachedUserIdentityRepository) FindByProviderSubject(ctx context.Context,
+	provider, subject string) (domain.UserIdentity, error) {
+	identity, err := repo.dao.FindByProviderSubject(ctx, provider, subject)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	return repo.toDomain(identity)
+}
+
+func (repo *CachedUserIdentityRepository) FindByProviderUnionId(ctx context.Context,
+	provider, unionId string) (domain.UserIdentity, error) {
+	identity, err := repo.dao.FindByProviderUnionId(ctx, provider, unionId)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	return repo.toDomain(identity)
+}
+
+func (repo *CachedUserIdentityRepository) UpdateToken(ctx context.Context, identity domain.UserIdentity) error {
+	entity, err := repo.toEntity(identity)
+	if err != nil {
+		return err
+	}
+	return repo.dao.UpdateToken(ctx, entity)
+}
+
+func (repo *CachedUserIdentityRepository) FindByUid(ctx context.Context, uid int64) ([]domain.UserIdentity, error) {
+	identities, err := repo.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.UserIdentity, 0, len(identities))
+	for _, identity := range identities {
+		ui, err := repo.toDomain(identity)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ui)
+	}
+	return res, nil
+}
+
+func (repo *CachedUserIdentityRepository) Delete(ctx context.Context, uid int64, provider string, allowLast bool) error {
+	return repo.dao.Delete(ctx, uid, provider, allowLast)
+}
+
+func (repo *CachedUserIdentityRepository) toEntity(identity domain.UserIdentity) (dao.UserIdentity, error) {
+	aad := repo.aad(identity.Uid, identity.Provider)
+	accessToken, err := repo.encrypt(identity.AccessToken, aad)
+	if err != nil {
+		return dao.UserIdentity{}, err
+	}
+	refreshToken, err := repo.encrypt(identity.RefreshToken, aad)
+	if err != nil {
+		return dao.UserIdentity{}, err
+	}
+	return dao.UserIdentity{
+		Id:       identity.Id,
+		Uid:      identity.Uid,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UnionId: sql.NullString{
+			String: identity.UnionId,
+			Valid:  identity.UnionId != "",
+		},
+		Profile:      identity.Profile,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenExpiry:  repo.toMilli(identity.TokenExpiry),
+	}, nil
+}
+
+func (repo *CachedUserIdentityRepository) toMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func (repo *CachedUserIdentityRepository) toDomain(identity dao.UserIdentity) (domain.UserIdentity, error) {
+	aad := repo.aad(identity.Uid, identity.Provider)
+	accessToken, err := repo.decrypt(identity.AccessToken, aad)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	refreshToken, err := repo.decrypt(identity.RefreshToken, aad)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	return domain.UserIdentity{
+		Id:       identity.Id,
+		Uid:      identity.Uid,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UnionId:  identity.UnionId.String,
+		Profile:  identity.Profile,
+		Ctime:    time.UnixMilli(identity.Ctime),
+
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenExpiry:  repo.toTime(identity.TokenExpiry),
+	}, nil
+}
+
+// aad 密文和用户、供应商绑定，挪到别的用户或者别的供应商那里解不开
+func (repo *CachedUserIdentityRepository) aad(uid int64, provider string) []byte {
+	return []byte(strconv.FormatInt(uid, 10) + ":" + provider)
+}
+
+// encrypt 没有令牌的时候存空值，不用加密
+func (repo *CachedUserIdentityRepository) encrypt(token string, aad []byte) ([]byte, error) {
+	if token == "" {
+		return nil, nil
+	}
+	return repo.cipher.Encrypt([]byte(token), aad)
+}
+
+func (repo *CachedUserIdentityRepository) decrypt(token []byte, aad []byte) (string, error) {
+	if len(token) == 0 {
+		return "", nil
+	}
+	res, err := repo.cipher.Decrypt(token, aad)
+	return string(res), err
+}
+
+func (repo *CachedUserIdentityRepository) toTime(milli int64) time.Time {
+	if milli == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(milli)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	daomocks "gitee.com/geekbang/basic-go/webook/internal/repository/dao/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/cryptox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedUserIdentityRepository_TokenEncrypted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cipher, err := cryptox.NewAESGCM([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	d := daomocks.NewMockUserIdentityDAO(ctrl)
+	var stored dao.UserIdentity
+	// 新用户插入的时候还不知道 uid，令牌是插入之后再加密更新的
+	d.EXPECT().InsertWithUser(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, u dao.User, identity dao.UserIdentity) (int64, error) {
+			assert.Empty(t, identity.AccessToken)
+			assert.Empty(t, identity.RefreshToken)
+			return 123, nil
+		})
+	d.EXPECT().UpdateToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, identity dao.UserIdentity) error {
+			stored = identity
+			return nil
+		})
+	repo := NewCachedUserIdentityRepository(d, nil, cipher)
+	uid, err := repo.CreateWithUser(context.Background(), domain.User{}, domain.UserIdentity{
+		Provider: "wechat", Subject: "openid",
+		AccessToken: "ACCESS", RefreshToken: "REFRESH",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), uid)
+	assert.Equal(t, int64(123), stored.Uid)
+	assert.NotContains(t, string(stored.AccessToken), "ACCESS")
+	assert.NotContains(t, string(stored.RefreshToken), "REFRESH")
+
+	d.EXPECT().FindByProviderSubject(gomock.Any(), "wechat", "openid").Return(stored, nil)
+	ui, err := repo.FindByProviderSubject(context.Background(), "wechat", "openid")
+	require.NoError(t, err)
+	assert.Equal(t, "ACCESS", ui.AccessToken)
+	assert.Equal(t, "REFRESH", ui.RefreshToken)
+
+	// 密文被挪到别的用户那里解不开
+	moved := stored
+	moved.Uid = 456
+	d.EXPECT().FindByUid(gomock.Any(), int64(456)).Return([]dao.UserIdentity{moved}, nil)
+	_, err = repo.FindByUid(context.Background(), 456)
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+var (
+	ErrArticleNotFound = repository.ErrArticleNotFound
+	// ErrArticleNotPublished 线上库里有，但是已经被撤回或者仅自己可见
+	ErrArticleNotPublished = errors.New("文章未发表")
+)
+
+type ArticleService interface {
+	// Save 保存到制作库，也就是创作者的草稿
+	Save(ctx context.Context, art domain.Article) (int64, error)
+	// Publish 保存并且同步到线上库
+	Publish(ctx context.Context, art domain.Article) (int64, error)
+	Withdraw(ctx context.Context, uid int64, id int64) error
+	// TakeDown 管理员下架文章，读者看不到，作者也不能再编辑
+	TakeDown(ctx context.Context, id int64) error
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	GetById(ctx context.Context, id int64) (domain.Article, error)
+	// GetPubById 读者查看已经发表的文章
+	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	// GetPubByIds 批量查询，只返回已经发表的文章，顺序和 ids 一致
+	GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error)
+	// ListPub 分页查询 start 之后更新过的已发表文章
+	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error)
+}
+
+type articleService struct {
+	repo repository.ArticleRepository
+}
+
+func NewArticleService(repo repository.ArticleRepository) ArticleService {
+	return &articleService{
+		repo: repo,
+	}
+}
+
+func (svc *articleService) Save(ctx context.Context, art domain.Article) (int64, error) {
+	art.Status = domain.ArticleStatusUnpublished
+	if art.Id > 0 {
+		err := svc.repo.Update(ctx, art)
+		return art.Id, err
+	}
+	return svc.repo.Create(ctx, art)
+}
+
+func (svc *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
+	art.Status = domain.ArticleStatusPublished
+	return svc.repo.Sync(ctx, art)
+}
+
+func (svc *articleService) Withdraw(ctx context.Context, uid int64, id int64) error {
+	return svc.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+}
+
+func (svc *articleService) TakeDown(ctx context.Context, id int64) error {
+	art, err := svc.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return svc.repo.SyncStatus(ctx, art.Author.Id, id, domain.ArticleStatusArchived)
+}
+
+func (svc *articleService) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	return svc.repo.GetByAuthor(ctx, uid, offset, limit)
+}
+
+func (svc *articleService) GetById(ctx context.Context, id int64) (domain.Article, error) {
+	return svc.repo.GetById(ctx, id)
+}
+
+func (svc *articleService) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := svc.repo.GetPubById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	if art.Status != domain.ArticleStatusPublished {
+		return domain.Article{}, ErrArticleNotPublished
+	}
+	return art, nil
+}
+
+func (svc *articleService) GetPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	arts, err := svc.repo.GetPubByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	artMap := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		if art.Status == domain.ArticleStatusPublished {
+			artMap[art.Id] = art
+		}
+	}
+	res := make([]domain.Article, 0, len(artMap))
+	for _, id := range ids {
+		if art, ok := artMap[id]; ok {
+			res = append(res, art)
+		}
+	}
+	return res, nil
+}
+
+func (svc *articleService) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error) {
+	return svc.repo.ListPub(ctx, start, offset, limit)
+}
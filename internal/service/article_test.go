@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_articleService_Publish(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.ArticleRepository
+
+		art domain.Article
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "新建并发表成功",
+			mock: func(ctrl *gomock.Controller) repository.ArticleRepository {
+				repo := repomocks.NewMockArticleRepository(ctrl)
+				repo.EXPECT().Sync(gomock.Any(), domain.Article{
+					Title:   "我的标题",
+					Content: "我的内容",
+					Author:  domain.Author{Id: 123},
+					Status:  domain.ArticleStatusPublished,
+				}).Return(int64(1), nil)
+				return repo
+			},
+			art: domain.Article{
+				Title:   "我的标题",
+				Content: "我的内容",
+				Author:  domain.Author{Id: 123},
+			},
+			wantId: 1,
+		},
+		{
+			name: "同步失败",
+			mock: func(ctrl *gomock.Controller) repository.ArticleRepository {
+				repo := repomocks.NewMockArticleRepository(ctrl)
+				repo.EXPECT().Sync(gomock.Any(), domain.Article{
+					Id:      2,
+					Title:   "我的标题",
+					Content: "我的内容",
+					Author:  domain.Author{Id: 123},
+					Status:  domain.ArticleStatusPublished,
+				}).Return(int64(0), errors.New("mock db error"))
+				return repo
+			},
+			art: domain.Article{
+				Id:      2,
+				Title:   "我的标题",
+				Content: "我的内容",
+				Author:  domain.Author{Id: 123},
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewArticleService(tc.mock(ctrl))
+			id, err := svc.Publish(context.Background(), tc.art)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, id)
+		})
+	}
+}
+
+func Test_articleService_Save(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.ArticleRepository
+
+		art domain.Article
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "新建草稿",
+			mock: func(ctrl *gomock.Controller) repository.ArticleRepository {
+				repo := repomocks.NewMockArticleRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), domain.Article{
+					Title:  "我的标题",
+					Author: domain.Author{Id: 123},
+					Status: domain.ArticleStatusUnpublished,
+				}).Return(int64(1), nil)
+				return repo
+			},
+			art: domain.Article{
+				Title:  "我的标题",
+				Author: domain.Author{Id: 123},
+			},
+			wantId: 1,
+		},
+		{
+			name: "修改草稿",
+			mock: func(ctrl *gomock.Controller) repository.ArticleRepository {
+				repo := repomocks.NewMockArticleRepository(ctrl)
+				repo.EXPECT().Update(gomock.Any(), domain.Article{
+					Id:     2,
+					Title:  "我的标题",
+					Author: domain.Author{Id: 123},
+					Status: domain.ArticleStatusUnpublished,
+				}).Return(nil)
+				return repo
+			},
+			art: domain.Article{
+				Id:     2,
+				Title:  "我的标题",
+				Author: domain.Author{Id: 123},
+			},
+			wantId: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewArticleService(tc.mock(ctrl))
+			id, err := svc.Save(context.Background(), tc.art)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, id)
+		})
+	}
+}
+
+func Test_articleService_GetPubById(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.ArticleRepository
+
+		wantArt domain.Article
+		wantErr error
+	}{
+		{
+			name: "查询成功",
+			mock: func(ctrl *gomock.Controller) repository.ArticleRepository {
+				repo := repomocks.NewMockArticleRepository(ctrl)
+				repo.EXPECT().GetPubById(gomock.Any(), int64(1)).
+					Return(domain.Article{Id: 1, Status: domain.ArticleStatusPublished}, nil)
+				return repo
+			},
+			wantArt: domain.Article{Id: 1, Status: domain.ArticleStatusPublished},
+		},
+		{
+			name: "文章已经撤回",
+			mock: func(ctrl *gomock.Controller) repository.ArticleRepository {
+				repo := repomocks.NewMockArticleRepository(ctrl)
+				repo.EXPECT().GetPubById(gomock.Any(), int64(1)).
+					Return(domain.Article{Id: 1, Status: domain.ArticleStatusPrivate}, nil)
+				return repo
+			},
+			wantErr: ErrArticleNotPublished,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewArticleService(tc.mock(ctrl))
+			art, err := svc.GetPubById(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantArt, art)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
 	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
 	"math/rand"
 )
@@ -12,18 +13,22 @@ var ErrCodeSendTooMany = repository.ErrCodeSendTooMany
 
 type CodeService interface {
 	Send(ctx context.Context, biz, phone string) error
+	// SendEmail 验证码发到邮箱，校验也是调用 Verify，phone 传邮箱地址
+	SendEmail(ctx context.Context, biz, addr string) error
 	Verify(ctx context.Context,
 		biz, phone, inputCode string) (bool, error)
 }
 type codeService struct {
-	repo repository.CodeRepository
-	sms  sms.Service
+	repo  repository.CodeRepository
+	sms   sms.Service
+	email email.Service
 }
 
-func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service) CodeService {
+func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service, emailSvc email.Service) CodeService {
 	return &codeService{
-		repo: repo,
-		sms:  smsSvc,
+		repo:  repo,
+		sms:   smsSvc,
+		email: emailSvc,
 	}
 }
 
@@ -38,6 +43,17 @@ func (svc *codeService) Send(ctx context.Context, biz, phone string) error {
 	return svc.sms.Send(ctx, codeTplId, []string{code}, phone)
 }
 
+func (svc *codeService) SendEmail(ctx context.Context, biz, addr string) error {
+	code := svc.generate()
+	// 邮箱地址里面有 @，和手机号不会冲突
+	err := svc.repo.Set(ctx, biz, addr, code)
+	if err != nil {
+		return err
+	}
+	return svc.email.Send(ctx, "webook 验证码",
+		fmt.Sprintf("你的验证码是 %s，十分钟内有效。如果不是你本人操作，请忽略这封邮件。", code), addr)
+}
+
 func (svc *codeService) Verify(ctx context.Context,
 	biz, phone, inputCode string) (bool, error) {
 	ok, err := svc.repo.Verify(ctx, biz, phone, inputCode)
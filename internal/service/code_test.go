@@ -1,10 +1,40 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"regexp"
 	"testing"
+
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	emailmocks "gitee.com/geekbang/basic-go/webook/internal/service/email/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
 )
 
 func TestCodeGenerate(t *testing.T) {
 	t.Log(fmt.Sprintf("%06d", 1))
 }
+
+func TestCodeService_SendEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockCodeRepository(ctrl)
+	emailSvc := emailmocks.NewMockService(ctrl)
+	var code string
+	repo.EXPECT().Set(gomock.Any(), "email_login", "123@qq.com", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, biz, addr, c string) error {
+			code = c
+			return nil
+		})
+	emailSvc.EXPECT().Send(gomock.Any(), "webook 验证码", gomock.Any(), "123@qq.com").
+		DoAndReturn(func(ctx context.Context, subject, content string, to ...string) error {
+			// 邮件里面的就是存下来的验证码
+			assert.Contains(t, content, code)
+			return nil
+		})
+	svc := NewCodeService(repo, nil, emailSvc)
+	err := svc.SendEmail(context.Background(), "email_login", "123@qq.com")
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\d{6}$`), code)
+}
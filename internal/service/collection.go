@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+var (
+	ErrCollectionNotFound  = repository.ErrCollectionNotFound
+	ErrDuplicateCollection = repository.ErrDuplicateCollection
+	ErrCollectItemNotFound = repository.ErrCollectItemNotFound
+	ErrInvalidCollection   = errors.New("收藏夹名字不合法")
+)
+
+// CollectionService 收藏夹管理
+// 收藏和取消收藏会影响收藏数，在 InteractiveService 里面
+type CollectionService interface {
+	Create(ctx context.Context, c domain.Collection) (int64, error)
+	Rename(ctx context.Context, uid int64, id int64, name string) error
+	Delete(ctx context.Context, uid int64, id int64) error
+	List(ctx context.Context, uid int64) ([]domain.Collection, error)
+	MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error
+	ListItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]domain.CollectionItem, error)
+}
+
+type collectionService struct {
+	repo repository.CollectionRepository
+}
+
+func NewCollectionService(repo repository.CollectionRepository) CollectionService {
+	return &collectionService{
+		repo: repo,
+	}
+}
+
+func (svc *collectionService) Create(ctx context.Context, c domain.Collection) (int64, error) {
+	if !svc.validName(c.Name) {
+		return 0, ErrInvalidCollection
+	}
+	return svc.repo.Create(ctx, c)
+}
+
+func (svc *collectionService) Rename(ctx context.Context, uid int64, id int64, name string) error {
+	if !svc.validName(name) {
+		return ErrInvalidCollection
+	}
+	return svc.repo.Rename(ctx, uid, id, name)
+}
+
+func (svc *collectionService) Delete(ctx context.Context, uid int64, id int64) error {
+	return svc.repo.Delete(ctx, uid, id)
+}
+
+func (svc *collectionService) List(ctx context.Context, uid int64) ([]domain.Collection, error) {
+	return svc.repo.List(ctx, uid)
+}
+
+func (svc *collectionService) MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error {
+	return svc.repo.MoveItem(ctx, uid, biz, bizId, cid)
+}
+
+func (svc *collectionService) ListItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]domain.CollectionItem, error) {
+	return svc.repo.ListItems(ctx, uid, cid, offset, limit)
+}
+
+func (svc *collectionService) validName(name string) bool {
+	// 数据库里面是 varchar(128)
+	l := utf8.RuneCountInString(name)
+	return l > 0 && l <= 128
+}
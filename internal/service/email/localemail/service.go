@@ -0,0 +1,19 @@
+package localemail
+
+import (
+	"context"
+	"log"
+)
+
+// Service 本地开发用，邮件内容只打到日志里面
+type Service struct {
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Send(ctx context.Context, subject, content string, to ...string) error {
+	log.Println("邮件", to, subject, content)
+	return nil
+}
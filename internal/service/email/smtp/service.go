@@ -0,0 +1,74 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	netsmtp "net/smtp"
+	"strings"
+	"time"
+)
+
+type Service struct {
+	addr string
+	auth netsmtp.Auth
+	from string
+	// send 测试的时候替换掉，不用真的连 SMTP 服务器
+	send func(addr string, a netsmtp.Auth, from string, to []string, msg []byte) error
+	now  func() time.Time
+}
+
+// NewService addr 是 host:port，from 是发件人的邮箱地址。
+// 用的是 PLAIN 认证，net/smtp 要求服务器支持 STARTTLS，除非连的是 localhost
+func NewService(addr, username, password, from string) *Service {
+	host, _, _ := net.SplitHostPort(addr)
+	return &Service{
+		addr: addr,
+		auth: netsmtp.PlainAuth("", username, password, host),
+		from: from,
+		send: netsmtp.SendMail,
+		now:  time.Now,
+	}
+}
+
+// Send net/smtp 不支持 context，超时靠服务器那边
+func (s *Service) Send(ctx context.Context, subject, content string, to ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := s.message(subject, content, to)
+	if err != nil {
+		return err
+	}
+	return s.send(s.addr, s.auth, s.from, to, msg)
+}
+
+func (s *Service) message(subject, content string, to []string) ([]byte, error) {
+	for _, addr := range append([]string{s.from}, to...) {
+		// 防止往邮件头里面注入别的字段
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("非法的邮箱地址 %q", addr)
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("From: " + s.from + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	// 标题里面有中文，要编码
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	buf.WriteString("Date: " + s.now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(content))
+	// 每行不能超过 76 个字符
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package smtp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/mail"
+	netsmtp "net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Send(t *testing.T) {
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	content := strings.Repeat("你的验证码是 123456，十分钟内有效。", 5)
+	testCases := []struct {
+		name string
+		to   []string
+
+		wantErr bool
+	}{
+		{
+			name: "发送成功",
+			to:   []string{"123@qq.com", "456@qq.com"},
+		},
+		{
+			name:    "邮件头注入",
+			to:      []string{"123@qq.com\r\nBcc: evil@qq.com"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewService("smtp.example.com:587", "user", "password", "noreply@meoying.com")
+			svc.now = func() time.Time {
+				return now
+			}
+			var sent []byte
+			svc.send = func(addr string, a netsmtp.Auth, from string, to []string, msg []byte) error {
+				assert.Equal(t, "smtp.example.com:587", addr)
+				assert.Equal(t, "noreply@meoying.com", from)
+				assert.Equal(t, tc.to, to)
+				sent = msg
+				return nil
+			}
+			err := svc.Send(context.Background(), "webook 验证码", content, tc.to...)
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, sent)
+				return
+			}
+			require.NoError(t, err)
+
+			msg, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(sent)))
+			require.NoError(t, err)
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			require.NoError(t, err)
+			assert.Equal(t, "webook 验证码", subject)
+			assert.Equal(t, "123@qq.com, 456@qq.com", msg.Header.Get("To"))
+			date, err := msg.Header.Date()
+			require.NoError(t, err)
+			assert.True(t, now.Equal(date))
+			body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, msg.Body))
+			require.NoError(t, err)
+			assert.Equal(t, content, string(body))
+		})
+	}
+}
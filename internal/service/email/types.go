@@ -0,0 +1,9 @@
+package email
+
+import "context"
+
+// Service 发送邮件的抽象，和 sms.Service 一样屏蔽不同的发送方式
+type Service interface {
+	// Send content 是纯文本
+	Send(ctx context.Context, subject, content string, to ...string) error
+}
@@ -12,8 +12,17 @@ type InteractiveService interface {
 	Like(ctx context.Context, biz string, bizId int64, uid int64) error
 	CancelLike(ctx context.Context, biz string, bizId int64, uid int64) error
 	Collect(ctx context.Context, biz string, bizId, cid, uid int64) error
+	CancelCollect(ctx context.Context, biz string, bizId, uid int64) error
 
 	Get(ctx context.Context, biz string, id, uid int64) (domain.Interactive, error)
+	// GetByIds 批量版本的 Get，列表页用，每一个 id 都会出现在结果里面
+	GetByIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]domain.Interactive, error)
+	// TopLiked 点赞榜，点赞数从高到低的前 n 个
+	TopLiked(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error)
+	// ListLiked 用户点过赞的资源，cursor 是上一页最后一项的 Id，第一页传 0
+	ListLiked(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error)
+	// ListCollected 用户收藏过的资源，分页方式和 ListLiked 一样
+	ListCollected(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error)
 }
 
 type interactiveService struct {
@@ -22,27 +31,88 @@ type interactiveService struct {
 
 // CancelLike implements [InteractiveService].
 func (i *interactiveService) CancelLike(ctx context.Context, biz string, bizId int64, uid int64) error {
-	panic("unimplemented")
+	return i.repo.DecrLike(ctx, biz, bizId, uid)
 }
 
-// Collect implements [InteractiveService].
+// Collect 收藏到 cid 这个收藏夹，cid 为 0 就是默认收藏夹
 func (i *interactiveService) Collect(ctx context.Context, biz string, bizId int64, cid int64, uid int64) error {
-	panic("unimplemented")
+	return i.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
 }
 
-// Get implements [InteractiveService].
+// CancelCollect implements [InteractiveService].
+func (i *interactiveService) CancelCollect(ctx context.Context, biz string, bizId int64, uid int64) error {
+	return i.repo.DeleteCollectionItem(ctx, biz, bizId, uid)
+}
+
+// Get 查询计数，uid 大于 0 的时候顺便查询这个用户有没有点赞、收藏
 func (i *interactiveService) Get(ctx context.Context, biz string, id int64, uid int64) (domain.Interactive, error) {
-	panic("unimplemented")
+	intr, err := i.repo.Get(ctx, biz, id)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	if uid <= 0 {
+		// 没有登录的读者
+		return intr, nil
+	}
+	intr.Liked, err = i.repo.Liked(ctx, biz, id, uid)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	intr.Collected, err = i.repo.Collected(ctx, biz, id, uid)
+	return intr, err
+}
+
+// GetByIds 查询计数，uid 大于 0 的时候顺便批量查询这个用户有没有点赞、收藏
+func (i *interactiveService) GetByIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]domain.Interactive, error) {
+	if len(ids) == 0 {
+		return map[int64]domain.Interactive{}, nil
+	}
+	intrs, err := i.repo.GetByIds(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	if uid <= 0 {
+		return intrs, nil
+	}
+	liked, err := i.repo.LikedIds(ctx, biz, ids, uid)
+	if err != nil {
+		return nil, err
+	}
+	collected, err := i.repo.CollectedIds(ctx, biz, ids, uid)
+	if err != nil {
+		return nil, err
+	}
+	for id, intr := range intrs {
+		intr.Liked = liked[id]
+		intr.Collected = collected[id]
+		intrs[id] = intr
+	}
+	return intrs, nil
+}
+
+// TopLiked implements [InteractiveService].
+func (i *interactiveService) TopLiked(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error) {
+	return i.repo.TopLiked(ctx, biz, window, n)
+}
+
+// ListLiked implements [InteractiveService].
+func (i *interactiveService) ListLiked(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error) {
+	return i.repo.ListLiked(ctx, biz, uid, cursor, limit)
+}
+
+// ListCollected implements [InteractiveService].
+func (i *interactiveService) ListCollected(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error) {
+	return i.repo.ListCollected(ctx, biz, uid, cursor, limit)
 }
 
 // IncrReadCont implements [InteractiveService].
 func (i *interactiveService) IncrReadCont(ctx context.Context, biz string, bizId int64) error {
-	return i.repo.IncrReadCnt(ctx,biz,bizId)
+	return i.repo.IncrReadCnt(ctx, biz, bizId)
 }
 
 // Like implements [InteractiveService].
 func (i *interactiveService) Like(ctx context.Context, biz string, bizId int64, uid int64) error {
-	panic("unimplemented")
+	return i.repo.IncrLike(ctx, biz, bizId, uid)
 }
 
 func NewInteractiveService(repo repository.InteractiveRepository) InteractiveService {
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+// ReconcileReport 一次核对的结果
+type ReconcileReport struct {
+	Diffs []domain.InteractiveCntDiff
+	// 修复了多少条，只核对不修复的时候是 0
+	Fixed int
+}
+
+// InteractiveReconcileService 核对、修复点赞数和收藏数
+type InteractiveReconcileService interface {
+	// Reconcile fix 为 false 的时候只报告差异，不修改数据
+	Reconcile(ctx context.Context, fix bool) (ReconcileReport, error)
+}
+
+type interactiveReconcileService struct {
+	repo repository.InteractiveReconcileRepository
+	// 每一批核对、修复多少条，控制对数据库的压力
+	batchSize int
+}
+
+func NewInteractiveReconcileService(repo repository.InteractiveReconcileRepository) InteractiveReconcileService {
+	return &interactiveReconcileService{
+		repo:      repo,
+		batchSize: 100,
+	}
+}
+
+// Reconcile 一批一批核对，每一批发现的差异马上修复，
+// 中途失败的时候返回已经核对过的部分
+func (s *interactiveReconcileService) Reconcile(ctx context.Context, fix bool) (ReconcileReport, error) {
+	var report ReconcileReport
+	var startId int64
+	for {
+		diffs, lastId, err := s.repo.FindDiffs(ctx, startId, s.batchSize)
+		if err != nil {
+			return report, err
+		}
+		if lastId == 0 {
+			return report, nil
+		}
+		report.Diffs = append(report.Diffs, diffs...)
+		if fix && len(diffs) > 0 {
+			bizIds := make(map[string][]int64)
+			for _, diff := range diffs {
+				bizIds[diff.Biz] = append(bizIds[diff.Biz], diff.BizId)
+			}
+			for biz, ids := range bizIds {
+				err = s.repo.Fix(ctx, biz, ids)
+				if err != nil {
+					return report, err
+				}
+				report.Fixed += len(ids)
+			}
+		}
+		startId = lastId
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestInteractiveReconcileService_Reconcile(t *testing.T) {
+	diff := domain.InteractiveCntDiff{Biz: "article", BizId: 11, LikeCnt: 3, ActualLikeCnt: 2}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.InteractiveReconcileRepository
+		fix  bool
+
+		wantReport ReconcileReport
+		wantErr    error
+	}{
+		{
+			name: "只报告不修复",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveReconcileRepository {
+				repo := repomocks.NewMockInteractiveReconcileRepository(ctrl)
+				repo.EXPECT().FindDiffs(gomock.Any(), int64(0), 100).
+					Return([]domain.InteractiveCntDiff{diff}, int64(100), nil)
+				repo.EXPECT().FindDiffs(gomock.Any(), int64(100), 100).
+					Return(nil, int64(0), nil)
+				return repo
+			},
+			wantReport: ReconcileReport{Diffs: []domain.InteractiveCntDiff{diff}},
+		},
+		{
+			name: "分批修复",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveReconcileRepository {
+				repo := repomocks.NewMockInteractiveReconcileRepository(ctrl)
+				repo.EXPECT().FindDiffs(gomock.Any(), int64(0), 100).
+					Return([]domain.InteractiveCntDiff{diff}, int64(100), nil)
+				repo.EXPECT().Fix(gomock.Any(), "article", []int64{11}).Return(nil)
+				// 第二批没有差异
+				repo.EXPECT().FindDiffs(gomock.Any(), int64(100), 100).
+					Return(nil, int64(200), nil)
+				repo.EXPECT().FindDiffs(gomock.Any(), int64(200), 100).
+					Return(nil, int64(0), nil)
+				return repo
+			},
+			fix: true,
+			wantReport: ReconcileReport{
+				Diffs: []domain.InteractiveCntDiff{diff},
+				Fixed: 1,
+			},
+		},
+		{
+			name: "修复失败，返回已经核对过的部分",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveReconcileRepository {
+				repo := repomocks.NewMockInteractiveReconcileRepository(ctrl)
+				repo.EXPECT().FindDiffs(gomock.Any(), int64(0), 100).
+					Return([]domain.InteractiveCntDiff{diff}, int64(100), nil)
+				repo.EXPECT().Fix(gomock.Any(), "article", []int64{11}).
+					Return(errors.New("db error"))
+				return repo
+			},
+			fix:        true,
+			wantReport: ReconcileReport{Diffs: []domain.InteractiveCntDiff{diff}},
+			wantErr:    errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewInteractiveReconcileService(tc.mock(ctrl))
+			report, err := svc.Reconcile(context.Background(), tc.fix)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantReport, report)
+		})
+	}
+}
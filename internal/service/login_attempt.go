@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+var ErrLoginLocked = errors.New("登录失败次数太多，暂时锁定")
+
+// LoginAttemptService 邮箱密码登录的防暴力破解，同时按照账号和 IP 统计失败次数
+type LoginAttemptService interface {
+	// Check 登录之前调用，账号或者 IP 被锁定了就返回 ErrLoginLocked
+	Check(ctx context.Context, email, ip string) (domain.LoginAttemptStatus, error)
+	// Failed 密码不对的时候调用，这次失败导致锁定的话，返回的 LockedFor 大于 0
+	Failed(ctx context.Context, email, ip string) (domain.LoginAttemptStatus, error)
+	// Succeeded 登录成功，清掉账号的失败次数。IP 的不清，
+	// 攻击者可能自己有一个能登录的账号
+	Succeeded(ctx context.Context, email string) error
+	// Unlock 管理员解锁
+	Unlock(ctx context.Context, uid int64) error
+	// UnlockByPhone 用户自己用短信验证码解锁，调用方要先校验验证码
+	UnlockByPhone(ctx context.Context, phone string) error
+}
+
+type loginAttemptService struct {
+	repo     repository.LoginAttemptRepository
+	userRepo repository.UserRepository
+
+	accountPolicy domain.LoginLockPolicy
+	// 同一个 IP 后面可能是整个公司，阈值要高很多
+	ipPolicy domain.LoginLockPolicy
+	// 失败这么多次之后要求验证码
+	accountCaptchaThreshold int64
+	ipCaptchaThreshold      int64
+}
+
+func NewLoginAttemptService(repo repository.LoginAttemptRepository,
+	userRepo repository.UserRepository) LoginAttemptService {
+	return &loginAttemptService{
+		repo:     repo,
+		userRepo: userRepo,
+		accountPolicy: domain.LoginLockPolicy{
+			Threshold:       5,
+			Window:          time.Minute * 15,
+			LockDuration:    time.Minute * 15,
+			MaxLockDuration: time.Hour * 24,
+		},
+		ipPolicy: domain.LoginLockPolicy{
+			Threshold:       50,
+			Window:          time.Minute * 15,
+			LockDuration:    time.Minute * 15,
+			MaxLockDuration: time.Hour * 24,
+		},
+		accountCaptchaThreshold: 3,
+		ipCaptchaThreshold:      20,
+	}
+}
+
+func (s *loginAttemptService) Check(ctx context.Context, email, ip string) (domain.LoginAttemptStatus, error) {
+	acc, err := s.repo.Get(ctx, s.accountSubject(email))
+	if err != nil {
+		return domain.LoginAttemptStatus{}, err
+	}
+	ipAttempt, err := s.repo.Get(ctx, s.ipSubject(ip))
+	if err != nil {
+		return domain.LoginAttemptStatus{}, err
+	}
+	status := s.status(acc, ipAttempt)
+	if status.LockedFor > 0 {
+		return status, ErrLoginLocked
+	}
+	return status, nil
+}
+
+func (s *loginAttemptService) Failed(ctx context.Context, email, ip string) (domain.LoginAttemptStatus, error) {
+	acc, err := s.repo.IncrFailure(ctx, s.accountSubject(email), s.accountPolicy)
+	if err != nil {
+		return domain.LoginAttemptStatus{}, err
+	}
+	ipAttempt, err := s.repo.IncrFailure(ctx, s.ipSubject(ip), s.ipPolicy)
+	if err != nil {
+		return domain.LoginAttemptStatus{}, err
+	}
+	return s.status(acc, ipAttempt), nil
+}
+
+func (s *loginAttemptService) Succeeded(ctx context.Context, email string) error {
+	return s.repo.Reset(ctx, s.accountSubject(email))
+}
+
+func (s *loginAttemptService) Unlock(ctx context.Context, uid int64) error {
+	u, err := s.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return s.unlock(ctx, u)
+}
+
+func (s *loginAttemptService) UnlockByPhone(ctx context.Context, phone string) error {
+	u, err := s.userRepo.FindByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	return s.unlock(ctx, u)
+}
+
+func (s *loginAttemptService) unlock(ctx context.Context, u domain.User) error {
+	if u.Email == "" {
+		// 没有邮箱就不可能用密码登录，也就不会被锁
+		return nil
+	}
+	return s.repo.Reset(ctx, s.accountSubject(u.Email))
+}
+
+func (s *loginAttemptService) status(acc, ip domain.LoginAttempt) domain.LoginAttemptStatus {
+	return domain.LoginAttemptStatus{
+		LockedFor: max(acc.LockedFor, ip.LockedFor),
+		CaptchaRequired: acc.LockedFor > 0 || ip.LockedFor > 0 ||
+			acc.Failures >= s.accountCaptchaThreshold ||
+			ip.Failures >= s.ipCaptchaThreshold,
+	}
+}
+
+// accountSubject 邮箱不区分大小写，不然换个大小写就能绕过去
+func (s *loginAttemptService) accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *loginAttemptService) ipSubject(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoginAttemptService_Check(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) repository.LoginAttemptRepository
+		email string
+
+		wantStatus domain.LoginAttemptStatus
+		wantErr    error
+	}{
+		{
+			name: "正常登录，邮箱不区分大小写",
+			mock: func(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				repo.EXPECT().Get(gomock.Any(), "account:a@qq.com").
+					Return(domain.LoginAttempt{Failures: 1}, nil)
+				repo.EXPECT().Get(gomock.Any(), "ip:10.0.0.1").
+					Return(domain.LoginAttempt{Failures: 1}, nil)
+				return repo
+			},
+			email: "A@QQ.com",
+		},
+		{
+			name: "账号失败次数多了，要验证码",
+			mock: func(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				repo.EXPECT().Get(gomock.Any(), "account:a@qq.com").
+					Return(domain.LoginAttempt{Failures: 3}, nil)
+				repo.EXPECT().Get(gomock.Any(), "ip:10.0.0.1").
+					Return(domain.LoginAttempt{}, nil)
+				return repo
+			},
+			email:      "a@qq.com",
+			wantStatus: domain.LoginAttemptStatus{CaptchaRequired: true},
+		},
+		{
+			name: "IP 被锁了",
+			mock: func(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				repo.EXPECT().Get(gomock.Any(), "account:a@qq.com").
+					Return(domain.LoginAttempt{LockedFor: time.Minute}, nil)
+				repo.EXPECT().Get(gomock.Any(), "ip:10.0.0.1").
+					Return(domain.LoginAttempt{LockedFor: time.Minute * 10}, nil)
+				return repo
+			},
+			email:      "a@qq.com",
+			wantStatus: domain.LoginAttemptStatus{LockedFor: time.Minute * 10, CaptchaRequired: true},
+			wantErr:    ErrLoginLocked,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewLoginAttemptService(tc.mock(ctrl), nil)
+			status, err := svc.Check(context.Background(), tc.email, "10.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantStatus, status)
+		})
+	}
+}
+
+func TestLoginAttemptService_UnlockByPhone(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.LoginAttemptRepository, repository.UserRepository)
+
+		wantErr error
+	}{
+		{
+			name: "解锁手机号对应的邮箱",
+			mock: func(ctrl *gomock.Controller) (repository.LoginAttemptRepository, repository.UserRepository) {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{Id: 123, Email: "A@qq.com"}, nil)
+				repo.EXPECT().Reset(gomock.Any(), "account:a@qq.com").Return(nil)
+				return repo, userRepo
+			},
+		},
+		{
+			name: "没有邮箱，不用解锁",
+			mock: func(ctrl *gomock.Controller) (repository.LoginAttemptRepository, repository.UserRepository) {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{Id: 123}, nil)
+				return repo, userRepo
+			},
+		},
+		{
+			name: "手机号没有注册",
+			mock: func(ctrl *gomock.Controller) (repository.LoginAttemptRepository, repository.UserRepository) {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return repo, userRepo
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			svc := NewLoginAttemptService(repo, userRepo)
+			err := svc.UnlockByPhone(context.Background(), "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
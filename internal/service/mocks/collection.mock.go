@@ -0,0 +1,127 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/collection.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/collection.go -package=svcmocks -destination=./webook/internal/service/mocks/collection.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCollectionService is a mock of CollectionService interface.
+type MockCollectionService struct {
+	ctrl     *gomock.Controller
+	recorder *MockCollectionServiceMockRecorder
+}
+
+// MockCollectionServiceMockRecorder is the mock recorder for MockCollectionService.
+type MockCollectionServiceMockRecorder struct {
+	mock *MockCollectionService
+}
+
+// NewMockCollectionService creates a new mock instance.
+func NewMockCollectionService(ctrl *gomock.Controller) *MockCollectionService {
+	mock := &MockCollectionService{ctrl: ctrl}
+	mock.recorder = &MockCollectionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCollectionService) EXPECT() *MockCollectionServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockCollectionService) Create(ctx context.Context, c domain.Collection) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, c)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockCollectionServiceMockRecorder) Create(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCollectionService)(nil).Create), ctx, c)
+}
+
+// Delete mocks base method.
+func (m *MockCollectionService) Delete(ctx context.Context, uid, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCollectionServiceMockRecorder) Delete(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCollectionService)(nil).Delete), ctx, uid, id)
+}
+
+// List mocks base method.
+func (m *MockCollectionService) List(ctx context.Context, uid int64) ([]domain.Collection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, uid)
+	ret0, _ := ret[0].([]domain.Collection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockCollectionServiceMockRecorder) List(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockCollectionService)(nil).List), ctx, uid)
+}
+
+// ListItems mocks base method.
+func (m *MockCollectionService) ListItems(ctx context.Context, uid, cid int64, offset, limit int) ([]domain.CollectionItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListItems", ctx, uid, cid, offset, limit)
+	ret0, _ := ret[0].([]domain.CollectionItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListItems indicates an expected call of ListItems.
+func (mr *MockCollectionServiceMockRecorder) ListItems(ctx, uid, cid, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockCollectionService)(nil).ListItems), ctx, uid, cid, offset, limit)
+}
+
+// MoveItem mocks base method.
+func (m *MockCollectionService) MoveItem(ctx context.Context, uid int64, biz string, bizId, cid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveItem", ctx, uid, biz, bizId, cid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveItem indicates an expected call of MoveItem.
+func (mr *MockCollectionServiceMockRecorder) MoveItem(ctx, uid, biz, bizId, cid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveItem", reflect.TypeOf((*MockCollectionService)(nil).MoveItem), ctx, uid, biz, bizId, cid)
+}
+
+// Rename mocks base method.
+func (m *MockCollectionService) Rename(ctx context.Context, uid, id int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rename", ctx, uid, id, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rename indicates an expected call of Rename.
+func (mr *MockCollectionServiceMockRecorder) Rename(ctx, uid, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rename", reflect.TypeOf((*MockCollectionService)(nil).Rename), ctx, uid, id, name)
+}
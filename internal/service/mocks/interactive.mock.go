@@ -0,0 +1,185 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/interactive.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/interactive.go -package=svcmocks -destination=./webook/internal/service/mocks/interactive.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInteractiveService is a mock of InteractiveService interface.
+type MockInteractiveService struct {
+	ctrl     *gomock.Controller
+	recorder *MockInteractiveServiceMockRecorder
+}
+
+// MockInteractiveServiceMockRecorder is the mock recorder for MockInteractiveService.
+type MockInteractiveServiceMockRecorder struct {
+	mock *MockInteractiveService
+}
+
+// NewMockInteractiveService creates a new mock instance.
+func NewMockInteractiveService(ctrl *gomock.Controller) *MockInteractiveService {
+	mock := &MockInteractiveService{ctrl: ctrl}
+	mock.recorder = &MockInteractiveServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInteractiveService) EXPECT() *MockInteractiveServiceMockRecorder {
+	return m.recorder
+}
+
+// CancelCollect mocks base method.
+func (m *MockInteractiveService) CancelCollect(ctx context.Context, biz string, bizId, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelCollect", ctx, biz, bizId, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelCollect indicates an expected call of CancelCollect.
+func (mr *MockInteractiveServiceMockRecorder) CancelCollect(ctx, biz, bizId, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelCollect", reflect.TypeOf((*MockInteractiveService)(nil).CancelCollect), ctx, biz, bizId, uid)
+}
+
+// CancelLike mocks base method.
+func (m *MockInteractiveService) CancelLike(ctx context.Context, biz string, bizId, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelLike", ctx, biz, bizId, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelLike indicates an expected call of CancelLike.
+func (mr *MockInteractiveServiceMockRecorder) CancelLike(ctx, biz, bizId, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelLike", reflect.TypeOf((*MockInteractiveService)(nil).CancelLike), ctx, biz, bizId, uid)
+}
+
+// Collect mocks base method.
+func (m *MockInteractiveService) Collect(ctx context.Context, biz string, bizId, cid, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Collect", ctx, biz, bizId, cid, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Collect indicates an expected call of Collect.
+func (mr *MockInteractiveServiceMockRecorder) Collect(ctx, biz, bizId, cid, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockInteractiveService)(nil).Collect), ctx, biz, bizId, cid, uid)
+}
+
+// Get mocks base method.
+func (m *MockInteractiveService) Get(ctx context.Context, biz string, id, uid int64) (domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, biz, id, uid)
+	ret0, _ := ret[0].(domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInteractiveServiceMockRecorder) Get(ctx, biz, id, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInteractiveService)(nil).Get), ctx, biz, id, uid)
+}
+
+// GetByIds mocks base method.
+func (m *MockInteractiveService) GetByIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]domain.Interactive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, biz, ids, uid)
+	ret0, _ := ret[0].(map[int64]domain.Interactive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockInteractiveServiceMockRecorder) GetByIds(ctx, biz, ids, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockInteractiveService)(nil).GetByIds), ctx, biz, ids, uid)
+}
+
+// IncrReadCont mocks base method.
+func (m *MockInteractiveService) IncrReadCont(ctx context.Context, biz string, bizId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrReadCont", ctx, biz, bizId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrReadCont indicates an expected call of IncrReadCont.
+func (mr *MockInteractiveServiceMockRecorder) IncrReadCont(ctx, biz, bizId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrReadCont", reflect.TypeOf((*MockInteractiveService)(nil).IncrReadCont), ctx, biz, bizId)
+}
+
+// Like mocks base method.
+func (m *MockInteractiveService) Like(ctx context.Context, biz string, bizId, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Like", ctx, biz, bizId, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Like indicates an expected call of Like.
+func (mr *MockInteractiveServiceMockRecorder) Like(ctx, biz, bizId, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Like", reflect.TypeOf((*MockInteractiveService)(nil).Like), ctx, biz, bizId, uid)
+}
+
+// ListCollected mocks base method.
+func (m *MockInteractiveService) ListCollected(ctx context.Context, biz string, uid, cursor int64, limit int) ([]domain.UserBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCollected", ctx, biz, uid, cursor, limit)
+	ret0, _ := ret[0].([]domain.UserBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCollected indicates an expected call of ListCollected.
+func (mr *MockInteractiveServiceMockRecorder) ListCollected(ctx, biz, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCollected", reflect.TypeOf((*MockInteractiveService)(nil).ListCollected), ctx, biz, uid, cursor, limit)
+}
+
+// ListLiked mocks base method.
+func (m *MockInteractiveService) ListLiked(ctx context.Context, biz string, uid, cursor int64, limit int) ([]domain.UserBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLiked", ctx, biz, uid, cursor, limit)
+	ret0, _ := ret[0].([]domain.UserBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLiked indicates an expected call of ListLiked.
+func (mr *MockInteractiveServiceMockRecorder) ListLiked(ctx, biz, uid, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLiked", reflect.TypeOf((*MockInteractiveService)(nil).ListLiked), ctx, biz, uid, cursor, limit)
+}
+
+// TopLiked mocks base method.
+func (m *MockInteractiveService) TopLiked(ctx context.Context, biz string, window domain.LikeRankWindow, n int) ([]domain.LikeRankItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TopLiked", ctx, biz, window, n)
+	ret0, _ := ret[0].([]domain.LikeRankItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopLiked indicates an expected call of TopLiked.
+func (mr *MockInteractiveServiceMockRecorder) TopLiked(ctx, biz, window, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopLiked", reflect.TypeOf((*MockInteractiveService)(nil).TopLiked), ctx, biz, window, n)
+}
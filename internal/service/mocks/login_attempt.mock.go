@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/login_attempt.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/login_attempt.go -package=svcmocks -destination=./webook/internal/service/mocks/login_attempt.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginAttemptService is a mock of LoginAttemptService interface.
+type MockLoginAttemptService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptServiceMockRecorder
+}
+
+// MockLoginAttemptServiceMockRecorder is the mock recorder for MockLoginAttemptService.
+type MockLoginAttemptServiceMockRecorder struct {
+	mock *MockLoginAttemptService
+}
+
+// NewMockLoginAttemptService creates a new mock instance.
+func NewMockLoginAttemptService(ctrl *gomock.Controller) *MockLoginAttemptService {
+	mock := &MockLoginAttemptService{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptService) EXPECT() *MockLoginAttemptServiceMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockLoginAttemptService) Check(ctx context.Context, email, ip string) (domain.LoginAttemptStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, email, ip)
+	ret0, _ := ret[0].(domain.LoginAttemptStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockLoginAttemptServiceMockRecorder) Check(ctx, email, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockLoginAttemptService)(nil).Check), ctx, email, ip)
+}
+
+// Failed mocks base method.
+func (m *MockLoginAttemptService) Failed(ctx context.Context, email, ip string) (domain.LoginAttemptStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Failed", ctx, email, ip)
+	ret0, _ := ret[0].(domain.LoginAttemptStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Failed indicates an expected call of Failed.
+func (mr *MockLoginAttemptServiceMockRecorder) Failed(ctx, email, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Failed", reflect.TypeOf((*MockLoginAttemptService)(nil).Failed), ctx, email, ip)
+}
+
+// Succeeded mocks base method.
+func (m *MockLoginAttemptService) Succeeded(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Succeeded", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Succeeded indicates an expected call of Succeeded.
+func (mr *MockLoginAttemptServiceMockRecorder) Succeeded(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Succeeded", reflect.TypeOf((*MockLoginAttemptService)(nil).Succeeded), ctx, email)
+}
+
+// Unlock mocks base method.
+func (m *MockLoginAttemptService) Unlock(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockLoginAttemptServiceMockRecorder) Unlock(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockLoginAttemptService)(nil).Unlock), ctx, uid)
+}
+
+// UnlockByPhone mocks base method.
+func (m *MockLoginAttemptService) UnlockByPhone(ctx context.Context, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockByPhone", ctx, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockByPhone indicates an expected call of UnlockByPhone.
+func (mr *MockLoginAttemptServiceMockRecorder) UnlockByPhone(ctx, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockByPhone", reflect.TypeOf((*MockLoginAttemptService)(nil).UnlockByPhone), ctx, phone)
+}
@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/rbac.go -package=svcmocks -destination=./webook/internal/service/mocks/rbac.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACService is a mock of RBACService interface.
+type MockRBACService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACServiceMockRecorder
+}
+
+// MockRBACServiceMockRecorder is the mock recorder for MockRBACService.
+type MockRBACServiceMockRecorder struct {
+	mock *MockRBACService
+}
+
+// NewMockRBACService creates a new mock instance.
+func NewMockRBACService(ctrl *gomock.Controller) *MockRBACService {
+	mock := &MockRBACService{ctrl: ctrl}
+	mock.recorder = &MockRBACServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACService) EXPECT() *MockRBACServiceMockRecorder {
+	return m.recorder
+}
+
+// GetPermissions mocks base method.
+func (m *MockRBACService) GetPermissions(ctx context.Context, uid int64) (domain.UserPermissions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", ctx, uid)
+	ret0, _ := ret[0].(domain.UserPermissions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions.
+func (mr *MockRBACServiceMockRecorder) GetPermissions(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockRBACService)(nil).GetPermissions), ctx, uid)
+}
+
+// SetUserRoles mocks base method.
+func (m *MockRBACService) SetUserRoles(ctx context.Context, uid int64, roles []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, uid, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRoles indicates an expected call of SetUserRoles.
+func (mr *MockRBACServiceMockRecorder) SetUserRoles(ctx, uid, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockRBACService)(nil).SetUserRoles), ctx, uid, roles)
+}
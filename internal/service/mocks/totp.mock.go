@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/totp.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/totp.go -package=svcmocks -destination=./webook/internal/service/mocks/totp.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTOTPService is a mock of TOTPService interface.
+type MockTOTPService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTOTPServiceMockRecorder
+}
+
+// MockTOTPServiceMockRecorder is the mock recorder for MockTOTPService.
+type MockTOTPServiceMockRecorder struct {
+	mock *MockTOTPService
+}
+
+// NewMockTOTPService creates a new mock instance.
+func NewMockTOTPService(ctrl *gomock.Controller) *MockTOTPService {
+	mock := &MockTOTPService{ctrl: ctrl}
+	mock.recorder = &MockTOTPServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTOTPService) EXPECT() *MockTOTPServiceMockRecorder {
+	return m.recorder
+}
+
+// Confirm mocks base method.
+func (m *MockTOTPService) Confirm(ctx context.Context, uid int64, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", ctx, uid, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockTOTPServiceMockRecorder) Confirm(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockTOTPService)(nil).Confirm), ctx, uid, code)
+}
+
+// Disable mocks base method.
+func (m *MockTOTPService) Disable(ctx context.Context, uid int64, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, uid, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockTOTPServiceMockRecorder) Disable(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockTOTPService)(nil).Disable), ctx, uid, code)
+}
+
+// Enabled mocks base method.
+func (m *MockTOTPService) Enabled(ctx context.Context, uid int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enabled", ctx, uid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enabled indicates an expected call of Enabled.
+func (mr *MockTOTPServiceMockRecorder) Enabled(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enabled", reflect.TypeOf((*MockTOTPService)(nil).Enabled), ctx, uid)
+}
+
+// Enroll mocks base method.
+func (m *MockTOTPService) Enroll(ctx context.Context, uid int64) (domain.TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", ctx, uid)
+	ret0, _ := ret[0].(domain.TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockTOTPServiceMockRecorder) Enroll(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockTOTPService)(nil).Enroll), ctx, uid)
+}
+
+// Verify mocks base method.
+func (m *MockTOTPService) Verify(ctx context.Context, uid int64, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, uid, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockTOTPServiceMockRecorder) Verify(ctx, uid, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockTOTPService)(nil).Verify), ctx, uid, code)
+}
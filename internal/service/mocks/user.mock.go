@@ -39,6 +39,34 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockUserService) ChangePassword(ctx context.Context, uid int64, oldPassword, newPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, uid, oldPassword, newPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserServiceMockRecorder) ChangePassword(ctx, uid, oldPassword, newPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserService)(nil).ChangePassword), ctx, uid, oldPassword, newPassword)
+}
+
+// CheckPassword mocks base method.
+func (m *MockUserService) CheckPassword(ctx context.Context, uid int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPassword", ctx, uid, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPassword indicates an expected call of CheckPassword.
+func (mr *MockUserServiceMockRecorder) CheckPassword(ctx, uid, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPassword", reflect.TypeOf((*MockUserService)(nil).CheckPassword), ctx, uid, password)
+}
+
 // FindById mocks base method.
 func (m *MockUserService) FindById(ctx context.Context, uid int64) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +97,22 @@ func (mr *MockUserServiceMockRecorder) FindOrCreate(ctx, phone any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreate", reflect.TypeOf((*MockUserService)(nil).FindOrCreate), ctx, phone)
 }
 
+// FindOrCreateByEmail mocks base method.
+func (m *MockUserService) FindOrCreateByEmail(ctx context.Context, email string) (domain.User, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreateByEmail", ctx, email)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindOrCreateByEmail indicates an expected call of FindOrCreateByEmail.
+func (mr *MockUserServiceMockRecorder) FindOrCreateByEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreateByEmail", reflect.TypeOf((*MockUserService)(nil).FindOrCreateByEmail), ctx, email)
+}
+
 // Login mocks base method.
 func (m *MockUserService) Login(ctx context.Context, email, password string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -84,6 +128,50 @@ func (mr *MockUserServiceMockRecorder) Login(ctx, email, password any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserService)(nil).Login), ctx, email, password)
 }
 
+// ResetPassword mocks base method.
+func (m *MockUserService) ResetPassword(ctx context.Context, phone, newPassword string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, phone, newPassword)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserServiceMockRecorder) ResetPassword(ctx, phone, newPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserService)(nil).ResetPassword), ctx, phone, newPassword)
+}
+
+// ResetPasswordByEmail mocks base method.
+func (m *MockUserService) ResetPasswordByEmail(ctx context.Context, email, newPassword string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPasswordByEmail", ctx, email, newPassword)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPasswordByEmail indicates an expected call of ResetPasswordByEmail.
+func (mr *MockUserServiceMockRecorder) ResetPasswordByEmail(ctx, email, newPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPasswordByEmail", reflect.TypeOf((*MockUserService)(nil).ResetPasswordByEmail), ctx, email, newPassword)
+}
+
+// SetBanned mocks base method.
+func (m *MockUserService) SetBanned(ctx context.Context, uid int64, banned bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBanned", ctx, uid, banned)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBanned indicates an expected call of SetBanned.
+func (mr *MockUserServiceMockRecorder) SetBanned(ctx, uid, banned any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBanned", reflect.TypeOf((*MockUserService)(nil).SetBanned), ctx, uid, banned)
+}
+
 // Signup mocks base method.
 func (m *MockUserService) Signup(ctx context.Context, u domain.User) error {
 	m.ctrl.T.Helper()
@@ -111,3 +199,19 @@ func (mr *MockUserServiceMockRecorder) UpdateNonSensitiveInfo(ctx, user any) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNonSensitiveInfo", reflect.TypeOf((*MockUserService)(nil).UpdateNonSensitiveInfo), ctx, user)
 }
+
+// VerifyEmail mocks base method.
+func (m *MockUserService) VerifyEmail(ctx context.Context, email string) (int64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, email)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUserServiceMockRecorder) VerifyEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUserService)(nil).VerifyEmail), ctx, email)
+}
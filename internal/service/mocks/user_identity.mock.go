@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/user_identity.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/user_identity.go -package=svcmocks -destination=./webook/internal/service/mocks/user_identity.mock.go
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserIdentityService is a mock of UserIdentityService interface.
+type MockUserIdentityService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserIdentityServiceMockRecorder
+}
+
+// MockUserIdentityServiceMockRecorder is the mock recorder for MockUserIdentityService.
+type MockUserIdentityServiceMockRecorder struct {
+	mock *MockUserIdentityService
+}
+
+// NewMockUserIdentityService creates a new mock instance.
+func NewMockUserIdentityService(ctrl *gomock.Controller) *MockUserIdentityService {
+	mock := &MockUserIdentityService{ctrl: ctrl}
+	mock.recorder = &MockUserIdentityServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserIdentityService) EXPECT() *MockUserIdentityServiceMockRecorder {
+	return m.recorder
+}
+
+// AccessToken mocks base method.
+func (m *MockUserIdentityService) AccessToken(ctx context.Context, uid int64, provider string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccessToken", ctx, uid, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AccessToken indicates an expected call of AccessToken.
+func (mr *MockUserIdentityServiceMockRecorder) AccessToken(ctx, uid, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccessToken", reflect.TypeOf((*MockUserIdentityService)(nil).AccessToken), ctx, uid, provider)
+}
+
+// Bind mocks base method.
+func (m *MockUserIdentityService) Bind(ctx context.Context, uid int64, identity domain.OAuth2Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bind", ctx, uid, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Bind indicates an expected call of Bind.
+func (mr *MockUserIdentityServiceMockRecorder) Bind(ctx, uid, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockUserIdentityService)(nil).Bind), ctx, uid, identity)
+}
+
+// FindOrCreate mocks base method.
+func (m *MockUserIdentityService) FindOrCreate(ctx context.Context, identity domain.OAuth2Identity) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreate", ctx, identity)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrCreate indicates an expected call of FindOrCreate.
+func (mr *MockUserIdentityServiceMockRecorder) FindOrCreate(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreate", reflect.TypeOf((*MockUserIdentityService)(nil).FindOrCreate), ctx, identity)
+}
+
+// List mocks base method.
+func (m *MockUserIdentityService) List(ctx context.Context, uid int64) ([]domain.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, uid)
+	ret0, _ := ret[0].([]domain.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUserIdentityServiceMockRecorder) List(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserIdentityService)(nil).List), ctx, uid)
+}
+
+// Unbind mocks base method.
+func (m *MockUserIdentityService) Unbind(ctx context.Context, uid int64, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unbind", ctx, uid, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unbind indicates an expected call of Unbind.
+func (mr *MockUserIdentityServiceMockRecorder) Unbind(ctx, uid, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockUserIdentityService)(nil).Unbind), ctx, uid, provider)
+}
@@ -0,0 +1,100 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/service/oauth2/types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/service/oauth2/types.go -package=oauth2mocks -destination=./webook/internal/service/oauth2/mocks/provider.mock.go
+//
+// Package oauth2mocks is a generated GoMock package.
+package oauth2mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	oauth2 "gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProvider is a mock of Provider interface.
+type MockProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockProviderMockRecorder
+}
+
+// MockProviderMockRecorder is the mock recorder for MockProvider.
+type MockProviderMockRecorder struct {
+	mock *MockProvider
+}
+
+// NewMockProvider creates a new mock instance.
+func NewMockProvider(ctrl *gomock.Controller) *MockProvider {
+	mock := &MockProvider{ctrl: ctrl}
+	mock.recorder = &MockProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
+	return m.recorder
+}
+
+// AuthURL mocks base method.
+func (m *MockProvider) AuthURL(ctx context.Context, req oauth2.AuthRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthURL", ctx, req)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthURL indicates an expected call of AuthURL.
+func (mr *MockProviderMockRecorder) AuthURL(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthURL", reflect.TypeOf((*MockProvider)(nil).AuthURL), ctx, req)
+}
+
+// Exchange mocks base method.
+func (m *MockProvider) Exchange(ctx context.Context, code string, req oauth2.AuthRequest) (oauth2.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exchange", ctx, code, req)
+	ret0, _ := ret[0].(oauth2.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exchange indicates an expected call of Exchange.
+func (mr *MockProviderMockRecorder) Exchange(ctx, code, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exchange", reflect.TypeOf((*MockProvider)(nil).Exchange), ctx, code, req)
+}
+
+// Identity mocks base method.
+func (m *MockProvider) Identity(ctx context.Context, tok oauth2.Token) (domain.OAuth2Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Identity", ctx, tok)
+	ret0, _ := ret[0].(domain.OAuth2Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Identity indicates an expected call of Identity.
+func (mr *MockProviderMockRecorder) Identity(ctx, tok any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Identity", reflect.TypeOf((*MockProvider)(nil).Identity), ctx, tok)
+}
+
+// Name mocks base method.
+func (m *MockProvider) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockProviderMockRecorder) Name() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockProvider)(nil).Name))
+}
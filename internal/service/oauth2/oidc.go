@@ -0,0 +1,421 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCConfig 一个标准的 OpenID Connect 供应商
+type OIDCConfig struct {
+	Name string
+	// Issuer 用来拼 discovery 的地址，也要和 ID token 里面的 iss 一致
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL 一般是 https://你的域名/oauth2/{Name}/callback
+	RedirectURL string
+	// Scopes 为空的时候是 openid email profile
+	Scopes []string
+}
+
+// OIDCProvider 第一次用的时候才去拉 discovery 和 JWKS，
+// 供应商暂时访问不了也不影响启动
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	discovery *discoveryDoc
+	keys      map[string]any
+	// keysFetched 上次拉 JWKS 的时间，碰到不认识的 kid 最多一分钟重新拉一次
+	keysFetched time.Time
+}
+
+func NewOIDCProvider(cfg OIDCConfig, client *http.Client) Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+type discoveryDoc struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *OIDCProvider) AuthURL(ctx context.Context, req AuthRequest) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", req.State)
+	if req.Nonce != "" {
+		q.Set("nonce", req.Nonce)
+	}
+	if req.CodeVerifier != "" {
+		q.Set("code_challenge", CodeChallengeS256(req.CodeVerifier))
+		q.Set("code_challenge_method", "S256")
+	}
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, req AuthRequest) (Token, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	if req.CodeVerifier != "" {
+		form.Set("code_verifier", req.CodeVerifier)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic，按照 RFC 6749 2.3.1 要先做 URL 编码
+		httpReq.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Token{}, err
+	}
+	defer httpResp.Body.Close()
+	var res tokenResponse
+	err = json.NewDecoder(httpResp.Body).Decode(&res)
+	if err != nil {
+		return Token{}, fmt.Errorf("解析 %s 的 token 响应失败 %w", p.cfg.Name, err)
+	}
+	if httpResp.StatusCode != http.StatusOK || res.Error != "" {
+		return Token{}, fmt.Errorf("%s 换 token 失败 %d %s %s",
+			p.cfg.Name, httpResp.StatusCode, res.Error, res.ErrorDescription)
+	}
+	if res.IDToken == "" {
+		return Token{}, fmt.Errorf("%w: %s 没有返回 ID token", ErrInvalidIDToken, p.cfg.Name)
+	}
+	claims, err := p.verifyIDToken(ctx, doc, res.IDToken, req.Nonce)
+	if err != nil {
+		return Token{}, err
+	}
+	tok := Token{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		IDToken:      res.IDToken,
+		Claims:       claims,
+	}
+	if res.ExpiresIn > 0 {
+		tok.Expiry = p.now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+func (p *OIDCProvider) Identity(ctx context.Context, tok Token) (domain.OAuth2Identity, error) {
+	sub, _ := tok.Claims["sub"].(string)
+	if sub == "" {
+		return domain.OAuth2Identity{}, fmt.Errorf("%w: 没有 sub", ErrInvalidIDToken)
+	}
+	claims := tok.Claims
+	// 有些供应商的 ID token 里面只有 sub，邮箱这些要调 userinfo 才有
+	if _, ok := claims["email"]; !ok && tok.AccessToken != "" {
+		doc, err := p.discover(ctx)
+		if err != nil {
+			return domain.OAuth2Identity{}, err
+		}
+		if doc.UserinfoEndpoint != "" {
+			var info map[string]any
+			err = p.getJSON(ctx, doc.UserinfoEndpoint, tok.AccessToken, &info)
+			if err != nil {
+				return domain.OAuth2Identity{}, err
+			}
+			// OIDC Core 5.3.2，不一致的话 userinfo 的内容不能用
+			if info["sub"] != sub {
+				return domain.OAuth2Identity{}, fmt.Errorf("%s 的 userinfo 和 ID token 的 sub 不一致", p.cfg.Name)
+			}
+			claims = maps.Clone(claims)
+			for k, v := range info {
+				if _, ok := claims[k]; !ok {
+					claims[k] = v
+				}
+			}
+		}
+	}
+	profile, err := json.Marshal(claims)
+	if err != nil {
+		return domain.OAuth2Identity{}, err
+	}
+	return domain.OAuth2Identity{
+		Provider:      p.cfg.Name,
+		Subject:       sub,
+		Email:         stringClaim(claims, "email"),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Nickname:      stringClaim(claims, "name"),
+		Avatar:        stringClaim(claims, "picture"),
+		Profile:       string(profile),
+	}, nil
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, doc discoveryDoc,
+	raw string, nonce string) (map[string]any, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, doc, kid)
+	},
+		// 只接受非对称签名，防止拿公钥当 HMAC 密钥伪造 token
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithTimeFunc(p.now),
+		// 容忍一点时钟误差
+		jwt.WithLeeway(time.Minute))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: 没有过期时间", ErrInvalidIDToken)
+	}
+	if nonce != "" && claims["nonce"] != nonce {
+		return nil, fmt.Errorf("%w: nonce 不一致", ErrInvalidIDToken)
+	}
+	// 有多个 aud 的时候，azp 必须是自己
+	if azp, ok := claims["azp"].(string); ok && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: azp 不对", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (discoveryDoc, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return *p.discovery, nil
+	}
+	var doc discoveryDoc
+	err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", "", &doc)
+	if err != nil {
+		return discoveryDoc{}, err
+	}
+	// 防止 discovery 被篡改，指到别人的 issuer 上
+	if doc.Issuer != p.cfg.Issuer {
+		return discoveryDoc{}, fmt.Errorf("%s 的 discovery 里面 issuer 是 %s，和配置的 %s 不一致",
+			p.cfg.Name, doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return discoveryDoc{}, fmt.Errorf("%s 的 discovery 缺少必要的地址", p.cfg.Name)
+	}
+	p.discovery = &doc
+	return doc, nil
+}
+
+func (p *OIDCProvider) publicKey(ctx context.Context, doc discoveryDoc, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+	// 供应商轮换密钥之后会出现新的 kid，要重新拉，但是不能被人拿假的 kid 刷
+	if p.keys != nil && p.now().Sub(p.keysFetched) < time.Minute {
+		return nil, fmt.Errorf("未知的 kid %s", kid)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err := p.getJSON(ctx, doc.JWKSURI, "", &set)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(k)
+		if err != nil {
+			// 不认识的密钥类型跳过，不影响别的密钥
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	p.keys, p.keysFetched = keys, p.now()
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未知的 kid %s", kid)
+}
+
+// lookupKey token 没有 kid 的时候，只有一把密钥才能确定用哪一把
+func lookupKey(keys map[string]any, kid string) (any, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+func parseJWK(k jwk) (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线 %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("不支持的曲线 %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("Ed25519 公钥长度不对")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型 %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("JWK 字段为空")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, accessToken string, val any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 失败，状态码 %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(val)
+}
+
+func stringClaim(claims map[string]any, key string) string {
+	val, _ := claims[key].(string)
+	return val
+}
+
+// boolClaim 有些供应商的 email_verified 是字符串 "true"
+func boolClaim(claims map[string]any, key string) bool {
+	switch val := claims[key].(type) {
+	case bool:
+		return val
+	case string:
+		return val == "true"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,377 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 附录 B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
+
+func TestOIDCProvider_Login(t *testing.T) {
+	testCases := []struct {
+		name string
+		// mutate 修改 fake 签发的 ID token
+		mutate func(claims jwt.MapClaims)
+		// hs256 用 HMAC 签名，模拟攻击者伪造 token
+		hs256 bool
+		// exchange 回调的时候传给 Exchange 的参数
+		exchange func(req AuthRequest) AuthRequest
+
+		assertErr    assert.ErrorAssertionFunc
+		wantIdentity domain.OAuth2Identity
+	}{
+		{
+			name:      "登录成功",
+			assertErr: assert.NoError,
+			wantIdentity: domain.OAuth2Identity{
+				Provider:      "fake",
+				Subject:       "user-1",
+				Email:         "a@example.com",
+				EmailVerified: true,
+				Nickname:      "Tom",
+			},
+		},
+		{
+			name: "ID token 里面没有邮箱，调 userinfo",
+			mutate: func(claims jwt.MapClaims) {
+				delete(claims, "email")
+				delete(claims, "email_verified")
+			},
+			assertErr: assert.NoError,
+			wantIdentity: domain.OAuth2Identity{
+				Provider:      "fake",
+				Subject:       "user-1",
+				Email:         "info@example.com",
+				EmailVerified: true,
+				Nickname:      "Tom",
+				Avatar:        "https://example.com/a.png",
+			},
+		},
+		{
+			name: "code_verifier 对不上",
+			exchange: func(req AuthRequest) AuthRequest {
+				req.CodeVerifier = "stolen-code-without-verifier"
+				return req
+			},
+			assertErr: assert.Error,
+		},
+		{
+			name: "nonce 对不上",
+			exchange: func(req AuthRequest) AuthRequest {
+				req.Nonce = "another-nonce"
+				return req
+			},
+			assertErr: isInvalidIDToken,
+		},
+		{
+			name: "签发给别的应用的",
+			mutate: func(claims jwt.MapClaims) {
+				claims["aud"] = "another-client"
+			},
+			assertErr: isInvalidIDToken,
+		},
+		{
+			name: "issuer 不对",
+			mutate: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://evil.example.com"
+			},
+			assertErr: isInvalidIDToken,
+		},
+		{
+			name: "已经过期",
+			mutate: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+			assertErr: isInvalidIDToken,
+		},
+		{
+			name: "没有过期时间",
+			mutate: func(claims jwt.MapClaims) {
+				delete(claims, "exp")
+			},
+			assertErr: isInvalidIDToken,
+		},
+		{
+			name:      "用 HMAC 伪造的",
+			hs256:     true,
+			assertErr: isInvalidIDToken,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newFakeOIDCServer(t)
+			srv.mutate = tc.mutate
+			srv.hs256 = tc.hs256
+			p := NewOIDCProvider(srv.config(), srv.Client())
+			ctx := context.Background()
+
+			req := AuthRequest{State: "state", CodeVerifier: "verifier-verifier-verifier-verifier-1234", Nonce: "nonce"}
+			authURL, err := p.AuthURL(ctx, req)
+			require.NoError(t, err)
+			code := srv.authorize(t, authURL)
+			if tc.exchange != nil {
+				req = tc.exchange(req)
+			}
+			tok, err := p.Exchange(ctx, code, req)
+			tc.assertErr(t, err)
+			if err != nil {
+				return
+			}
+			identity, err := p.Identity(ctx, tok)
+			require.NoError(t, err)
+			// 原始信息里面有签发时间这些，只看有没有存下来
+			assert.Contains(t, identity.Profile, `"sub":"user-1"`)
+			identity.Profile = ""
+			assert.Equal(t, tc.wantIdentity, identity)
+		})
+	}
+}
+
+func TestOIDCProvider_KeyRotation(t *testing.T) {
+	srv := newFakeOIDCServer(t)
+	p := NewOIDCProvider(srv.config(), srv.Client()).(*OIDCProvider)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+	login := func() error {
+		req := AuthRequest{State: "state", CodeVerifier: "verifier-verifier-verifier-verifier-1234", Nonce: "nonce"}
+		authURL, err := p.AuthURL(ctx, req)
+		require.NoError(t, err)
+		_, err = p.Exchange(ctx, srv.authorize(t, authURL), req)
+		return err
+	}
+	require.NoError(t, login())
+	assert.Equal(t, 1, srv.jwksHits)
+
+	// 供应商换了密钥，一分钟之内不会重新拉 JWKS
+	srv.rotate(t)
+	assert.ErrorIs(t, login(), ErrInvalidIDToken)
+	assert.Equal(t, 1, srv.jwksHits)
+
+	now = now.Add(time.Minute * 2)
+	require.NoError(t, login())
+	assert.Equal(t, 2, srv.jwksHits)
+}
+
+func TestOIDCProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	srv := newFakeOIDCServer(t)
+	cfg := srv.config()
+	srv.issuer = "https://evil.example.com"
+	p := NewOIDCProvider(cfg, srv.Client())
+	_, err := p.AuthURL(context.Background(), AuthRequest{State: "state"})
+	assert.Error(t, err)
+}
+
+func isInvalidIDToken(t assert.TestingT, err error, msgAndArgs ...any) bool {
+	return assert.ErrorIs(t, err, ErrInvalidIDToken, msgAndArgs...)
+}
+
+type fakeGrant struct {
+	challenge string
+	nonce     string
+}
+
+// fakeOIDCServer 进程内的 OIDC 供应商，授权的时候不需要用户操作，直接发 code
+type fakeOIDCServer struct {
+	*httptest.Server
+	// issuer 为空的时候就是 Server.URL
+	issuer string
+	key    *rsa.PrivateKey
+	kid    string
+
+	mutate func(claims jwt.MapClaims)
+	hs256  bool
+
+	mu       sync.Mutex
+	grants   map[string]fakeGrant
+	jwksHits int
+}
+
+func newFakeOIDCServer(t *testing.T) *fakeOIDCServer {
+	srv := &fakeOIDCServer{grants: map[string]fakeGrant{}}
+	srv.rotate(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", srv.discovery)
+	mux.HandleFunc("/authorize", srv.authorizeEndpoint)
+	mux.HandleFunc("/token", srv.token)
+	mux.HandleFunc("/jwks", srv.jwks)
+	mux.HandleFunc("/userinfo", srv.userinfo)
+	srv.Server = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *fakeOIDCServer) config() OIDCConfig {
+	return OIDCConfig{
+		Name:         "fake",
+		Issuer:       s.URL,
+		ClientID:     "webook",
+		ClientSecret: "secret",
+		RedirectURL:  "https://webook.example.com/oauth2/fake/callback",
+	}
+}
+
+// rotate 换一把新的签名密钥，旧的不再出现在 JWKS 里面
+func (s *fakeOIDCServer) rotate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	s.kid = base64.RawURLEncoding.EncodeToString(key.N.Bytes()[:8])
+}
+
+// authorize 模拟用户在供应商那边点了同意，返回回调地址上的 code
+func (s *fakeOIDCServer) authorize(t *testing.T, authURL string) string {
+	client := s.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "state", loc.Query().Get("state"))
+	return loc.Query().Get("code")
+}
+
+func (s *fakeOIDCServer) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := s.issuer
+	if issuer == "" {
+		issuer = s.URL
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                 issuer,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"userinfo_endpoint":      s.URL + "/userinfo",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *fakeOIDCServer) authorizeEndpoint(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != "webook" || q.Get("response_type") != "code" ||
+		q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	code := "code-" + big.NewInt(int64(len(s.grants))).String()
+	s.grants[code] = fakeGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	s.mu.Unlock()
+	redirect := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (s *fakeOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != "webook" || secret != "secret" {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_client"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := r.PostFormValue("code")
+	grant, ok := s.grants[code]
+	// code 只能用一次
+	delete(s.grants, code)
+	if !ok || CodeChallengeS256(r.PostFormValue("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            "webook",
+		"sub":            "user-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          grant.nonce,
+		"email":          "a@example.com",
+		"email_verified": true,
+		"name":           "Tom",
+	}
+	if s.mutate != nil {
+		s.mutate(claims)
+	}
+	var idToken string
+	var err error
+	if s.hs256 {
+		// 拿公钥当 HMAC 的密钥，经典的算法混淆攻击
+		idToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.key.PublicKey.N.Bytes())
+	} else {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = s.kid
+		idToken, err = token.SignedString(s.key)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (s *fakeOIDCServer) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwksHits++
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]any{
+			{
+				"kty": "RSA",
+				"kid": s.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (s *fakeOIDCServer) userinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sub":            "user-1",
+		"email":          "info@example.com",
+		"email_verified": "true",
+		"name":           "Jerry",
+		"picture":        "https://example.com/a.png",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, val any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(val)
+}
@@ -0,0 +1,74 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+)
+
+var (
+	ErrUnknownProvider = errors.New("未知的第三方登录")
+	ErrInvalidIDToken  = errors.New("ID token 无效")
+)
+
+// Provider 一个第三方登录的供应商。
+// 流程是 AuthURL 跳过去，用户授权之后回调带着 code 回来，
+// Exchange 用 code 换 token，Identity 再从 token 里面拿到用户身份
+type Provider interface {
+	// Name 出现在路由 /oauth2/:provider 里面，也是会话的登录方式
+	Name() string
+	AuthURL(ctx context.Context, req AuthRequest) (string, error)
+	// Exchange req 要和 AuthURL 的时候是同一个，PKCE 和 nonce 都靠它核对
+	Exchange(ctx context.Context, code string, req AuthRequest) (Token, error)
+	Identity(ctx context.Context, tok Token) (domain.OAuth2Identity, error)
+}
+
+// TokenRefresher 令牌会过期、可以用 refresh token 续期的供应商实现这个接口
+type TokenRefresher interface {
+	Refresh(ctx context.Context, refreshToken string) (Token, error)
+}
+
+// AuthRequest 一次授权里面跳过去的时候带上、回调的时候要核对的参数，
+// 不支持的供应商直接忽略对应的字段
+type AuthRequest struct {
+	State string
+	// CodeVerifier PKCE 的 code_verifier，跳过去的时候只带它的哈希
+	CodeVerifier string
+	// Nonce 会被放进 ID token 里面，防止 ID token 被重放
+	Nonce string
+}
+
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+	// IDToken OIDC 才有
+	IDToken string
+	// Claims 校验通过的 ID token 里面的字段
+	Claims map[string]any
+	// Extra 各家特有的字段，比如微信的 openid 和 unionid
+	Extra map[string]string
+}
+
+// Providers 按照名字找供应商
+type Providers struct {
+	providers map[string]Provider
+}
+
+func NewProviders(providers ...Provider) *Providers {
+	res := &Providers{providers: make(map[string]Provider, len(providers))}
+	for _, p := range providers {
+		res.providers[p.Name()] = p
+	}
+	return res
+}
+
+func (p *Providers) Get(name string) (Provider, error) {
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
@@ -0,0 +1,83 @@
+package wechat
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+)
+
+// ProviderName 和 ijwt.LoginMethodWechat 保持一致
+const ProviderName = "wechat"
+
+// provider 把微信扫码登录适配成 oauth2.Provider。
+// 微信不支持 PKCE，也没有 ID token，AuthRequest 里面只用到 state
+type provider struct {
+	svc Service
+}
+
+func NewProvider(svc Service) oauth2.Provider {
+	return &provider{svc: svc}
+}
+
+func (p *provider) Name() string {
+	return ProviderName
+}
+
+func (p *provider) AuthURL(ctx context.Context, req oauth2.AuthRequest) (string, error) {
+	return p.svc.AuthURL(ctx, req.State)
+}
+
+func (p *provider) Exchange(ctx context.Context, code string, req oauth2.AuthRequest) (oauth2.Token, error) {
+	tok, err := p.svc.VerifyCode(ctx, code)
+	if err != nil {
+		return oauth2.Token{}, err
+	}
+	return p.toToken(tok), nil
+}
+
+// Refresh access token 两个小时就过期，用 refresh token 换一个新的
+func (p *provider) Refresh(ctx context.Context, refreshToken string) (oauth2.Token, error) {
+	tok, err := p.svc.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		return oauth2.Token{}, err
+	}
+	return p.toToken(tok), nil
+}
+
+func (p *provider) toToken(tok Token) oauth2.Token {
+	return oauth2.Token{
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		Expiry:       tok.Expiry,
+		Extra: map[string]string{
+			"openid":  tok.OpenId,
+			"unionid": tok.UnionId,
+		},
+	}
+}
+
+// Identity 顺便调用 sns/userinfo 拿昵称和头像，新用户注册的时候直接用上
+func (p *provider) Identity(ctx context.Context, tok oauth2.Token) (domain.OAuth2Identity, error) {
+	openId := tok.Extra["openid"]
+	info, err := p.svc.UserInfo(ctx, tok.AccessToken, openId)
+	if err != nil {
+		return domain.OAuth2Identity{}, err
+	}
+	unionId := tok.Extra["unionid"]
+	if unionId == "" {
+		// 换 token 的时候不一定有 unionid，userinfo 里面一定有
+		unionId = info.UnionId
+	}
+	return domain.OAuth2Identity{
+		Provider:     ProviderName,
+		Subject:      openId,
+		UnionId:      unionId,
+		Nickname:     info.Nickname,
+		Avatar:       info.HeadImgURL,
+		Profile:      info.Raw,
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		TokenExpiry:  tok.Expiry,
+	}, nil
+}
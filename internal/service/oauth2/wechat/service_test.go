@@ -0,0 +1,185 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeWechat 本地的假微信，只认 code 和 refresh token 是 "good" 的请求
+func newFakeWechat(t *testing.T) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, val any) {
+		err := json.NewEncoder(w).Encode(val)
+		require.NoError(t, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sns/oauth2/access_token", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "appid", q.Get("appid"))
+		assert.Equal(t, "secret", q.Get("secret"))
+		assert.Equal(t, "authorization_code", q.Get("grant_type"))
+		if q.Get("code") != "good" {
+			writeJSON(w, map[string]any{"errcode": 40029, "errmsg": "invalid code"})
+			return
+		}
+		writeJSON(w, map[string]any{
+			"access_token":  "access-1",
+			"expires_in":    7200,
+			"refresh_token": "refresh-1",
+			"openid":        "openid",
+			"scope":         "snsapi_login",
+		})
+	})
+	mux.HandleFunc("/sns/oauth2/refresh_token", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "refresh_token", q.Get("grant_type"))
+		if q.Get("refresh_token") != "good" {
+			writeJSON(w, map[string]any{"errcode": 40030, "errmsg": "invalid refresh_token"})
+			return
+		}
+		writeJSON(w, map[string]any{
+			"access_token":  "access-2",
+			"expires_in":    7200,
+			"refresh_token": "refresh-2",
+			"openid":        "openid",
+			"scope":         "snsapi_login",
+		})
+	})
+	mux.HandleFunc("/sns/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("access_token") != "access-1" {
+			writeJSON(w, map[string]any{"errcode": 40001, "errmsg": "invalid credential"})
+			return
+		}
+		assert.Equal(t, "openid", q.Get("openid"))
+		writeJSON(w, map[string]any{
+			"openid":     "openid",
+			"nickname":   "Tom",
+			"headimgurl": "https://thirdwx.qlogo.cn/tom.png",
+			"unionid":    "unionid",
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestService(t *testing.T, now time.Time) Service {
+	server := newFakeWechat(t)
+	svc := NewService("appid", "secret", server.URL+"/", logger.NewNopLogger()).(*service)
+	svc.now = func() time.Time {
+		return now
+	}
+	return svc
+}
+
+func TestService_VerifyCode(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	testCases := []struct {
+		name string
+		code string
+
+		wantTok Token
+		wantErr string
+	}{
+		{
+			name: "换到 token",
+			code: "good",
+			wantTok: Token{
+				AccessToken:  "access-1",
+				RefreshToken: "refresh-1",
+				Expiry:       now.Add(2 * time.Hour),
+				OpenId:       "openid",
+				Scope:        "snsapi_login",
+			},
+		},
+		{
+			name:    "授权码不对",
+			code:    "bad",
+			wantErr: "调用微信接口失败 errcode 40029, errmsg invalid code",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := newTestService(t, now)
+			tok, err := svc.VerifyCode(context.Background(), tc.code)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTok, tok)
+		})
+	}
+}
+
+func TestService_RefreshToken(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	svc := newTestService(t, now)
+	tok, err := svc.RefreshToken(context.Background(), "good")
+	require.NoError(t, err)
+	assert.Equal(t, Token{
+		AccessToken:  "access-2",
+		RefreshToken: "refresh-2",
+		Expiry:       now.Add(2 * time.Hour),
+		OpenId:       "openid",
+		Scope:        "snsapi_login",
+	}, tok)
+
+	_, err = svc.RefreshToken(context.Background(), "bad")
+	assert.EqualError(t, err, "调用微信接口失败 errcode 40030, errmsg invalid refresh_token")
+}
+
+func TestService_UserInfo(t *testing.T) {
+	svc := newTestService(t, time.Now())
+	info, err := svc.UserInfo(context.Background(), "access-1", "openid")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"openid":"openid","nickname":"Tom",
+"headimgurl":"https://thirdwx.qlogo.cn/tom.png","unionid":"unionid"}`, info.Raw)
+	info.Raw = ""
+	assert.Equal(t, UserInfo{
+		OpenId:     "openid",
+		Nickname:   "Tom",
+		HeadImgURL: "https://thirdwx.qlogo.cn/tom.png",
+		UnionId:    "unionid",
+	}, info)
+
+	_, err = svc.UserInfo(context.Background(), "expired", "openid")
+	assert.EqualError(t, err, "调用微信接口失败 errcode 40001, errmsg invalid credential")
+}
+
+func TestProvider(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	p := NewProvider(newTestService(t, now))
+	tok, err := p.Exchange(context.Background(), "good", oauth2.AuthRequest{State: "state"})
+	require.NoError(t, err)
+	identity, err := p.Identity(context.Background(), tok)
+	require.NoError(t, err)
+	// 换 token 的时候没有 unionid，用 userinfo 里面的
+	assert.Equal(t, "unionid", identity.UnionId)
+	assert.NotEmpty(t, identity.Profile)
+	assert.Equal(t, ProviderName, identity.Provider)
+	assert.Equal(t, "openid", identity.Subject)
+	assert.Equal(t, "Tom", identity.Nickname)
+	assert.Equal(t, "https://thirdwx.qlogo.cn/tom.png", identity.Avatar)
+	assert.Equal(t, "access-1", identity.AccessToken)
+	assert.Equal(t, "refresh-1", identity.RefreshToken)
+	assert.Equal(t, now.Add(2*time.Hour), identity.TokenExpiry)
+
+	// 过期之后用 refresh token 续期
+	refresher, ok := p.(oauth2.TokenRefresher)
+	require.True(t, ok)
+	tok, err = refresher.Refresh(context.Background(), "good")
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", tok.AccessToken)
+	assert.Equal(t, "refresh-2", tok.RefreshToken)
+	assert.Equal(t, "openid", tok.Extra["openid"])
+}
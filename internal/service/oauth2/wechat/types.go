@@ -4,15 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
+// DefaultBaseURL 微信开放平台接口的地址，测试的时候可以换成本地的假服务
+const DefaultBaseURL = "https://api.weixin.qq.com"
+
 type Service interface {
 	AuthURL(ctx context.Context, state string) (string, error)
-	VerifyCode(ctx context.Context, code string) (domain.WechatInfo, error)
+	// VerifyCode 用授权码换 access token
+	VerifyCode(ctx context.Context, code string) (Token, error)
+	// RefreshToken access token 两个小时就过期，refresh token 可以用三十天
+	RefreshToken(ctx context.Context, refreshToken string) (Token, error)
+	// UserInfo 拿用户的昵称和头像
+	UserInfo(ctx context.Context, accessToken string, openId string) (UserInfo, error)
 }
 
 var redirectURL = url.PathEscape("https://meoying.com/oauth2/wechat/callback")
@@ -20,44 +29,102 @@ var redirectURL = url.PathEscape("https://meoying.com/oauth2/wechat/callback")
 type service struct {
 	appID     string
 	appSecret string
+	baseURL   string
 	client    *http.Client
 	l         logger.LoggerV1
+	now       func() time.Time
 }
 
-func NewService(appID string, appSecret string, l logger.LoggerV1) Service {
+// NewService baseURL 为空的时候用 DefaultBaseURL
+func NewService(appID string, appSecret string, baseURL string, l logger.LoggerV1) Service {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
 	return &service{
 		appID:     appID,
 		appSecret: appSecret,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
 		client:    http.DefaultClient,
+		l:         l,
+		now:       time.Now,
 	}
 }
-func (s *service) VerifyCode(ctx context.Context,
-	code string) (domain.WechatInfo, error) {
-	accessTokenUrl := fmt.Sprintf(`https://api.weixin.qq.com/sns/oauth2/access_token?appid=%s&secret=%s&code=%s&grant_type=authorization_code`,
-		s.appID, s.appSecret, code)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, accessTokenUrl, nil)
+
+func (s *service) VerifyCode(ctx context.Context, code string) (Token, error) {
+	return s.token(ctx, "/sns/oauth2/access_token", url.Values{
+		"appid":      {s.appID},
+		"secret":     {s.appSecret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	})
+}
+
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) (Token, error) {
+	return s.token(ctx, "/sns/oauth2/refresh_token", url.Values{
+		"appid":         {s.appID},
+		"refresh_token": {refreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+}
+
+func (s *service) token(ctx context.Context, path string, params url.Values) (Token, error) {
+	var res Result
+	_, err := s.get(ctx, path, params, &res)
 	if err != nil {
-		return domain.WechatInfo{}, err
+		return Token{}, err
 	}
-	httpResp, err := s.client.Do(req)
+	return Token{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		Expiry:       s.now().Add(time.Duration(res.ExpiresIn) * time.Second),
+		OpenId:       res.OpenId,
+		UnionId:      res.UnionId,
+		Scope:        res.Scope,
+	}, nil
+}
+
+func (s *service) UserInfo(ctx context.Context, accessToken string, openId string) (UserInfo, error) {
+	var res UserInfo
+	raw, err := s.get(ctx, "/sns/userinfo", url.Values{
+		"access_token": {accessToken},
+		"openid":       {openId},
+	}, &res)
 	if err != nil {
-		return domain.WechatInfo{}, err
+		return UserInfo{}, err
 	}
+	res.Raw = string(raw)
+	return res, nil
+}
 
-	var res Result
-	err = json.NewDecoder(httpResp.Body).Decode(&res)
+// get 微信出错的时候 HTTP 状态码也是 200，要看响应里面的 errcode
+func (s *service) get(ctx context.Context, path string, params url.Values, val any) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	var raw json.RawMessage
+	err = json.NewDecoder(httpResp.Body).Decode(&raw)
 	if err != nil {
 		// 转 JSON 为结构体出错
-		return domain.WechatInfo{}, err
+		return nil, err
 	}
-	if res.ErrCode != 0 {
-		return domain.WechatInfo{},
-			fmt.Errorf("调用微信接口失败 errcode %d, errmsg %s", res.ErrCode, res.ErrMsg)
+	var errResp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
 	}
-	return domain.WechatInfo{
-		UnionId: res.UnionId,
-		OpenId:  res.OpenId,
-	}, nil
+	err = json.Unmarshal(raw, &errResp)
+	if err != nil {
+		return nil, err
+	}
+	if errResp.ErrCode != 0 {
+		return nil, fmt.Errorf("调用微信接口失败 errcode %d, errmsg %s", errResp.ErrCode, errResp.ErrMsg)
+	}
+	return raw, json.Unmarshal(raw, val)
 }
 
 func (s *service) AuthURL(ctx context.Context, state string) (string, error) {
@@ -65,6 +132,15 @@ func (s *service) AuthURL(ctx context.Context, state string) (string, error) {
 	return fmt.Sprintf(authURLPattern, s.appID, redirectURL, state), nil
 }
 
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+	OpenId       string
+	UnionId      string
+	Scope        string
+}
+
 type Result struct {
 	AccessToken string `json:"access_token"`
 	// access_token接口调用凭证超时时间，单位（秒）
@@ -82,3 +158,14 @@ type Result struct {
 	ErrCode int    `json:"errcode"`
 	ErrMsg  string `json:"errmsg"`
 }
+
+// UserInfo sns/userinfo 的返回
+type UserInfo struct {
+	OpenId   string `json:"openid"`
+	Nickname string `json:"nickname"`
+	// HeadImgURL 用户头像，没有头像的时候为空
+	HeadImgURL string `json:"headimgurl"`
+	UnionId    string `json:"unionid"`
+	// Raw 微信返回的原始 JSON
+	Raw string `json:"-"`
+}
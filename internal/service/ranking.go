@@ -0,0 +1,136 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+var ErrStaleFencingToken = repository.ErrStaleFencingToken
+
+type RankingService interface {
+	// TopN 计算热榜并且保存起来，token 是计算任务持有的分布式锁的 fencing token
+	TopN(ctx context.Context, token int64) error
+	// GetTopN 查询算好的热榜
+	GetTopN(ctx context.Context) ([]domain.Article, error)
+}
+
+// BatchRankingService 分批查询最近发表的文章和它们的互动数据，
+// 用 Hacker News 的公式算分，取前 n 个
+type BatchRankingService struct {
+	artSvc  ArticleService
+	intrSvc InteractiveService
+	repo    repository.RankingRepository
+	biz     string
+
+	batchSize int
+	n         int
+	// 只看这段时间内更新过的文章，更早的文章分数已经衰减得差不多了
+	window    time.Duration
+	scoreFunc func(intr domain.Interactive, publishTime time.Time) float64
+}
+
+func NewBatchRankingService(artSvc ArticleService,
+	intrSvc InteractiveService,
+	repo repository.RankingRepository) RankingService {
+	return &BatchRankingService{
+		artSvc:    artSvc,
+		intrSvc:   intrSvc,
+		repo:      repo,
+		biz:       "article",
+		batchSize: 100,
+		n:         100,
+		window:    time.Hour * 24 * 7,
+		scoreFunc: hotScore,
+	}
+}
+
+// hotScore (P-1) / (T+2)^1.8，P 是加权之后的互动数，T 是发表了多少个小时。
+// 收藏比点赞更能说明文章有价值，阅读数很容易刷，所以权重很低
+func hotScore(intr domain.Interactive, publishTime time.Time) float64 {
+	p := float64(intr.LikeCnt) + float64(intr.CollectCnt)*2 + float64(intr.ReadCnt)*0.01
+	t := time.Since(publishTime).Hours()
+	return (p - 1) / math.Pow(t+2, 1.8)
+}
+
+func (b *BatchRankingService) TopN(ctx context.Context, token int64) error {
+	arts, err := b.topN(ctx)
+	if err != nil {
+		return err
+	}
+	return b.repo.ReplaceTopN(ctx, arts, token)
+}
+
+func (b *BatchRankingService) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	return b.repo.GetTopN(ctx)
+}
+
+func (b *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error) {
+	start := time.Now().Add(-b.window)
+	h := &scoredArticleHeap{}
+	for offset := 0; ; offset += b.batchSize {
+		arts, err := b.artSvc.ListPub(ctx, start, offset, b.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, 0, len(arts))
+		for _, art := range arts {
+			ids = append(ids, art.Id)
+		}
+		intrs, err := b.intrSvc.GetByIds(ctx, b.biz, ids, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, art := range arts {
+			// 榜单里面只需要摘要
+			art.Content = art.Abstract()
+			// Ctime 是第一次发表的时间
+			item := scoredArticle{art: art, score: b.scoreFunc(intrs[art.Id], art.Ctime)}
+			if h.Len() < b.n {
+				heap.Push(h, item)
+				continue
+			}
+			if item.score > (*h)[0].score {
+				(*h)[0] = item
+				heap.Fix(h, 0)
+			}
+		}
+		if len(arts) < b.batchSize {
+			break
+		}
+	}
+	// 小顶堆，倒着放就是从高到低
+	res := make([]domain.Article, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(scoredArticle).art
+	}
+	return res, nil
+}
+
+type scoredArticle struct {
+	art   domain.Article
+	score float64
+}
+
+// scoredArticleHeap 按照分数排序的小顶堆，堆顶是当前前 n 名里面分数最低的
+type scoredArticleHeap []scoredArticle
+
+func (h scoredArticleHeap) Len() int           { return len(h) }
+func (h scoredArticleHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h scoredArticleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *scoredArticleHeap) Push(x any) {
+	*h = append(*h, x.(scoredArticle))
+}
+
+func (h *scoredArticleHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBatchRankingService_TopN(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository)
+
+		wantErr error
+	}{
+		{
+			name: "分两批算出前两名",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				repo := repomocks.NewMockRankingRepository(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 2).
+					Return([]domain.Article{{Id: 1, Ctime: now}, {Id: 2, Ctime: now}}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}, int64(0)).
+					Return(map[int64]domain.Interactive{1: {LikeCnt: 1}, 2: {LikeCnt: 3}}, nil)
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 2, 2).
+					Return([]domain.Article{{Id: 3, Ctime: now}}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{3}, int64(0)).
+					Return(map[int64]domain.Interactive{3: {LikeCnt: 2}}, nil)
+				repo.EXPECT().ReplaceTopN(gomock.Any(), []domain.Article{
+					{Id: 2, Ctime: now},
+					{Id: 3, Ctime: now},
+				}, int64(12)).Return(nil)
+				return artSvc, intrSvc, repo
+			},
+		},
+		{
+			name: "查询文章失败",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				repo := repomocks.NewMockRankingRepository(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 2).
+					Return(nil, errors.New("db error"))
+				return artSvc, intrSvc, repo
+			},
+			wantErr: errors.New("db error"),
+		},
+		{
+			name: "锁已经被别人拿走了",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				repo := repomocks.NewMockRankingRepository(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 2).
+					Return([]domain.Article{}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{}, int64(0)).
+					Return(map[int64]domain.Interactive{}, nil)
+				repo.EXPECT().ReplaceTopN(gomock.Any(), []domain.Article{}, int64(12)).
+					Return(ErrStaleFencingToken)
+				return artSvc, intrSvc, repo
+			},
+			wantErr: ErrStaleFencingToken,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc, intrSvc, repo := tc.mock(ctrl)
+			svc := NewBatchRankingService(artSvc, intrSvc, repo).(*BatchRankingService)
+			svc.batchSize = 2
+			svc.n = 2
+			// 只看点赞数，方便验证
+			svc.scoreFunc = func(intr domain.Interactive, publishTime time.Time) float64 {
+				return float64(intr.LikeCnt)
+			}
+			err := svc.TopN(context.Background(), 12)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestHotScore(t *testing.T) {
+	now := time.Now()
+	// 同样的互动数，越新的分数越高
+	assert.Greater(t,
+		hotScore(domain.Interactive{LikeCnt: 10}, now),
+		hotScore(domain.Interactive{LikeCnt: 10}, now.Add(-time.Hour*10)))
+	// 同样的发表时间，互动越多分数越高
+	assert.Greater(t,
+		hotScore(domain.Interactive{LikeCnt: 10, CollectCnt: 1}, now),
+		hotScore(domain.Interactive{LikeCnt: 10}, now))
+}
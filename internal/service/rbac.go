@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+var ErrRoleNotFound = repository.ErrRoleNotFound
+
+type RBACService interface {
+	// GetPermissions 签发 access token 的时候调用，结果会放进 token 里面
+	GetPermissions(ctx context.Context, uid int64) (domain.UserPermissions, error)
+	// SetUserRoles 修改之后，已经签发的 access token 里面的权限就过时了，
+	// 要调用方让这些 token 失效
+	SetUserRoles(ctx context.Context, uid int64, roles []string) error
+}
+
+type rbacService struct {
+	repo repository.RBACRepository
+}
+
+func NewRBACService(repo repository.RBACRepository) RBACService {
+	return &rbacService{repo: repo}
+}
+
+func (s *rbacService) GetPermissions(ctx context.Context, uid int64) (domain.UserPermissions, error) {
+	return s.repo.GetUserPermissions(ctx, uid)
+}
+
+func (s *rbacService) SetUserRoles(ctx context.Context, uid int64, roles []string) error {
+	return s.repo.SetUserRoles(ctx, uid, roles)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+// SecurityEventService 登录审计日志
+type SecurityEventService interface {
+	// Record 登录成功的时候顺便判断是不是新设备，是的话通知用户
+	Record(ctx context.Context, evt domain.SecurityEvent) error
+	// List 用户最近的安全事件，最新的在前面
+	List(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.SecurityEvent, error)
+}
+
+// NewDeviceNotifier 用户在新设备上登录的时候通知用户，比如发短信、发邮件
+type NewDeviceNotifier interface {
+	Notify(ctx context.Context, evt domain.SecurityEvent) error
+}
+
+type securityEventService struct {
+	repo     repository.SecurityEventRepository
+	userRepo repository.UserRepository
+	notifier NewDeviceNotifier
+	l        logger.LoggerV1
+}
+
+func NewSecurityEventService(repo repository.SecurityEventRepository,
+	userRepo repository.UserRepository,
+	notifier NewDeviceNotifier,
+	l logger.LoggerV1) SecurityEventService {
+	return &securityEventService{
+		repo:     repo,
+		userRepo: userRepo,
+		notifier: notifier,
+		l:        l,
+	}
+}
+
+func (s *securityEventService) Record(ctx context.Context, evt domain.SecurityEvent) error {
+	if evt.Uid == 0 && evt.Account != "" {
+		// 登录失败的时候不知道是谁，账号存在的话要让这个用户能看到
+		uid, err := s.findUid(ctx, evt)
+		if err != nil {
+			return err
+		}
+		evt.Uid = uid
+	}
+	if evt.Type == domain.SecurityEventLogin && evt.Result == domain.SecurityResultSuccess {
+		isNew, err := s.isNewDevice(ctx, evt)
+		if err != nil {
+			return err
+		}
+		evt.NewDevice = isNew
+	}
+	err := s.repo.Create(ctx, evt)
+	if err != nil {
+		return err
+	}
+	if evt.NewDevice {
+		err = s.notifier.Notify(ctx, evt)
+		if err != nil {
+			// 通知失败不影响登录
+			s.l.Error("新设备登录通知失败",
+				logger.Field{Key: "uid", Val: evt.Uid},
+				logger.Field{Key: "error", Val: err})
+		}
+	}
+	return nil
+}
+
+func (s *securityEventService) List(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.SecurityEvent, error) {
+	return s.repo.List(ctx, uid, cursor, limit)
+}
+
+// findUid 只有短信、密码和邮箱验证码登录的 Account 是手机号或者邮箱，
+// 第三方登录的 Account 是供应商的用户标识，调用方要自己填 Uid
+func (s *securityEventService) findUid(ctx context.Context, evt domain.SecurityEvent) (int64, error) {
+	var u domain.User
+	var err error
+	switch evt.Method {
+	case domain.LoginMethodSMS:
+		u, err = s.userRepo.FindByPhone(ctx, evt.Account)
+	case domain.LoginMethodPassword, domain.LoginMethodEmail:
+		u, err = s.userRepo.FindByEmail(ctx, evt.Account)
+	default:
+		return 0, nil
+	}
+	if err == repository.ErrUserNotFound {
+		return 0, nil
+	}
+	return u.Id, err
+}
+
+// isNewDevice 第一次登录不算新设备，不然每个新注册的用户都会收到通知
+func (s *securityEventService) isNewDevice(ctx context.Context, evt domain.SecurityEvent) (bool, error) {
+	hasAny, err := s.repo.HasLogin(ctx, evt.Uid, nil)
+	if err != nil || !hasAny {
+		return false, err
+	}
+	seen, err := s.repo.HasLogin(ctx, evt.Uid, &evt)
+	return !seen, err
+}
+
+// LoggerNewDeviceNotifier 只打日志，接入短信或者邮件之前先用它
+type LoggerNewDeviceNotifier struct {
+	l logger.LoggerV1
+}
+
+func NewLoggerNewDeviceNotifier(l logger.LoggerV1) NewDeviceNotifier {
+	return &LoggerNewDeviceNotifier{l: l}
+}
+
+func (n *LoggerNewDeviceNotifier) Notify(ctx context.Context, evt domain.SecurityEvent) error {
+	n.l.Info("新设备登录",
+		logger.Field{Key: "uid", Val: evt.Uid},
+		logger.Field{Key: "method", Val: evt.Method},
+		logger.Field{Key: "ip", Val: evt.IP},
+		logger.Field{Key: "userAgent", Val: evt.UserAgent})
+	return nil
+}
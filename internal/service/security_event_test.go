@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSecurityEventService_Record(t *testing.T) {
+	login := domain.SecurityEvent{
+		Uid:       123,
+		Type:      domain.SecurityEventLogin,
+		Method:    "password",
+		Result:    domain.SecurityResultSuccess,
+		UserAgent: "Chrome",
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+			repository.UserRepository, NewDeviceNotifier)
+		evt domain.SecurityEvent
+
+		wantErr error
+	}{
+		{
+			name: "第一次登录，不算新设备",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				repo.EXPECT().HasLogin(gomock.Any(), int64(123), nil).Return(false, nil)
+				repo.EXPECT().Create(gomock.Any(), login).Return(nil)
+				return repo, nil, svcmocks.NewMockNewDeviceNotifier(ctrl)
+			},
+			evt: login,
+		},
+		{
+			name: "登录过的设备",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				repo.EXPECT().HasLogin(gomock.Any(), int64(123), nil).Return(true, nil)
+				repo.EXPECT().HasLogin(gomock.Any(), int64(123), gomock.Any()).Return(true, nil)
+				repo.EXPECT().Create(gomock.Any(), login).Return(nil)
+				return repo, nil, svcmocks.NewMockNewDeviceNotifier(ctrl)
+			},
+			evt: login,
+		},
+		{
+			name: "新设备，通知失败也不影响",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				repo.EXPECT().HasLogin(gomock.Any(), int64(123), nil).Return(true, nil)
+				repo.EXPECT().HasLogin(gomock.Any(), int64(123), gomock.Any()).Return(false, nil)
+				evt := login
+				evt.NewDevice = true
+				repo.EXPECT().Create(gomock.Any(), evt).Return(nil)
+				notifier := svcmocks.NewMockNewDeviceNotifier(ctrl)
+				notifier.EXPECT().Notify(gomock.Any(), evt).Return(errors.New("短信发送失败"))
+				return repo, nil, notifier
+			},
+			evt: login,
+		},
+		{
+			name: "密码错误，按邮箱找到用户",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123}, nil)
+				repo.EXPECT().Create(gomock.Any(), domain.SecurityEvent{
+					Uid:     123,
+					Account: "123@qq.com",
+					Type:    domain.SecurityEventLoginFailed,
+					Method:  "password",
+					Result:  domain.SecurityResultFailure,
+				}).Return(nil)
+				return repo, userRepo, nil
+			},
+			evt: domain.SecurityEvent{
+				Account: "123@qq.com",
+				Type:    domain.SecurityEventLoginFailed,
+				Method:  "password",
+				Result:  domain.SecurityResultFailure,
+			},
+		},
+		{
+			name: "验证码错误，手机号没注册",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().Create(gomock.Any(), domain.SecurityEvent{
+					Account: "15212345678",
+					Type:    domain.SecurityEventLoginFailed,
+					Method:  "sms",
+					Result:  domain.SecurityResultFailure,
+				}).Return(nil)
+				return repo, userRepo, nil
+			},
+			evt: domain.SecurityEvent{
+				Account: "15212345678",
+				Type:    domain.SecurityEventLoginFailed,
+				Method:  "sms",
+				Result:  domain.SecurityResultFailure,
+			},
+		},
+		{
+			name: "第三方登录的账号不是邮箱，不按邮箱找",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), domain.SecurityEvent{
+					Account: "123@qq.com",
+					Type:    domain.SecurityEventLoginFailed,
+					Method:  "google",
+					Result:  domain.SecurityResultFailure,
+				}).Return(nil)
+				return repo, repomocks.NewMockUserRepository(ctrl), nil
+			},
+			evt: domain.SecurityEvent{
+				Account: "123@qq.com",
+				Type:    domain.SecurityEventLoginFailed,
+				Method:  "google",
+				Result:  domain.SecurityResultFailure,
+			},
+		},
+		{
+			name: "写入失败",
+			mock: func(ctrl *gomock.Controller) (repository.SecurityEventRepository,
+				repository.UserRepository, NewDeviceNotifier) {
+				repo := repomocks.NewMockSecurityEventRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+				return repo, nil, nil
+			},
+			evt: domain.SecurityEvent{
+				Uid:    123,
+				Type:   domain.SecurityEventLogout,
+				Result: domain.SecurityResultSuccess,
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo, notifier := tc.mock(ctrl)
+			svc := NewSecurityEventService(repo, userRepo, notifier, logger.NewNopLogger())
+			err := svc.Record(context.Background(), tc.evt)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
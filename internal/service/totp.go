@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/limiter"
+	"gitee.com/geekbang/basic-go/webook/pkg/totp"
+)
+
+var (
+	ErrTOTPNotEnabled     = errors.New("没有开启两步验证")
+	ErrTOTPAlreadyEnabled = repository.ErrTOTPAlreadyEnabled
+	ErrInvalidTOTPCode    = errors.New("动态码或者恢复码不对")
+	ErrTOTPVerifyTooMany  = errors.New("两步验证尝试太频繁")
+)
+
+type TOTPService interface {
+	// Enroll 生成新的密钥和恢复码，用户用动态码 Confirm 之后才生效
+	Enroll(ctx context.Context, uid int64) (domain.TOTPEnrollment, error)
+	Confirm(ctx context.Context, uid int64, code string) error
+	// Disable 关闭之前要再验证一次动态码或者恢复码
+	Disable(ctx context.Context, uid int64, code string) error
+	Enabled(ctx context.Context, uid int64) (bool, error)
+	// Verify 登录的第二步，code 可以是动态码，也可以是恢复码
+	Verify(ctx context.Context, uid int64, code string) error
+}
+
+type totpService struct {
+	repo     repository.TOTPRepository
+	userRepo repository.UserRepository
+	// 限制每个用户验证的次数，6 位数字很容易被穷举
+	limiter limiter.Limiter
+	issuer  string
+	// 允许手机时间前后差几个时间步
+	skew              int
+	recoveryCodeCount int
+	now               func() time.Time
+}
+
+func NewTOTPService(repo repository.TOTPRepository,
+	userRepo repository.UserRepository,
+	limiter limiter.Limiter) TOTPService {
+	return &totpService{
+		repo:              repo,
+		userRepo:          userRepo,
+		limiter:           limiter,
+		issuer:            "webook",
+		skew:              1,
+		recoveryCodeCount: 10,
+		now:               time.Now,
+	}
+}
+
+func (s *totpService) Enroll(ctx context.Context, uid int64) (domain.TOTPEnrollment, error) {
+	u, err := s.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+	codes := make([]string, 0, s.recoveryCodeCount)
+	// 展示的时候中间加个 -，方便用户抄
+	display := make([]string, 0, s.recoveryCodeCount)
+	for i := 0; i < s.recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return domain.TOTPEnrollment{}, err
+		}
+		codes = append(codes, code)
+		display = append(display, code[:5]+"-"+code[5:])
+	}
+	err = s.repo.SavePending(ctx, domain.UserTOTP{Uid: uid, Secret: secret}, codes)
+	if err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+	return domain.TOTPEnrollment{
+		Secret:        secret,
+		URI:           totp.ProvisioningURI(s.issuer, s.account(u), secret),
+		RecoveryCodes: display,
+	}, nil
+}
+
+func (s *totpService) Confirm(ctx context.Context, uid int64, code string) error {
+	if err := s.limit(ctx, uid); err != nil {
+		return err
+	}
+	t, err := s.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if t.Enabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	// 确认的时候只认动态码，证明认证器 App 确实绑好了
+	step, ok, err := totp.Validate(t.Secret, code, s.now(), s.skew)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	err = s.repo.Enable(ctx, uid, step)
+	if err == repository.ErrTOTPNotFound {
+		// 并发确认，别的请求已经确认过了
+		return ErrTOTPAlreadyEnabled
+	}
+	return err
+}
+
+func (s *totpService) Disable(ctx context.Context, uid int64, code string) error {
+	err := s.Verify(ctx, uid, code)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, uid)
+}
+
+func (s *totpService) Enabled(ctx context.Context, uid int64) (bool, error) {
+	t, err := s.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return false, nil
+	}
+	return t.Enabled, err
+}
+
+func (s *totpService) Verify(ctx context.Context, uid int64, code string) error {
+	if err := s.limit(ctx, uid); err != nil {
+		return err
+	}
+	t, err := s.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound || (err == nil && !t.Enabled) {
+		return ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	// 用户照抄带着 - 的恢复码也要认
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	step, ok, err := totp.Validate(t.Secret, code, s.now(), s.skew)
+	if err != nil {
+		return err
+	}
+	if ok {
+		err = s.repo.UpdateLastStep(ctx, uid, step)
+		if err == repository.ErrTOTPStepUsed {
+			return ErrInvalidTOTPCode
+		}
+		return err
+	}
+	err = s.repo.UseRecoveryCode(ctx, uid, code)
+	if err == repository.ErrRecoveryCodeInvalid {
+		return ErrInvalidTOTPCode
+	}
+	return err
+}
+
+func (s *totpService) limit(ctx context.Context, uid int64) error {
+	limited, err := s.limiter.Limit(ctx, fmt.Sprintf("totp:verify:%d", uid))
+	if err != nil {
+		return err
+	}
+	if limited {
+		return ErrTOTPVerifyTooMany
+	}
+	return nil
+}
+
+// account 认证器 App 里面显示的账号
+func (s *totpService) account(u domain.User) string {
+	if u.Email != "" {
+		return u.Email
+	}
+	if u.Phone != "" {
+		return u.Phone
+	}
+	return strconv.FormatInt(u.Id, 10)
+}
+
+// newRecoveryCode 10 个 base32 字符，50 位的熵
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(buf)[:10], nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/limiter"
+	limitermocks "gitee.com/geekbang/basic-go/webook/pkg/limiter/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTotpService_Verify(t *testing.T) {
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(1111111109, 0)
+	step := totp.Step(now)
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+	enabled := domain.UserTOTP{Uid: 123, Secret: secret, Enabled: true}
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter)
+		code string
+
+		wantErr error
+	}{
+		{
+			name: "动态码正确",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").Return(false, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UpdateLastStep(gomock.Any(), int64(123), step).Return(nil)
+				return repo, l
+			},
+			code: code,
+		},
+		{
+			name: "动态码已经用过了",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").Return(false, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UpdateLastStep(gomock.Any(), int64(123), step).
+					Return(repository.ErrTOTPStepUsed)
+				return repo, l
+			},
+			code:    code,
+			wantErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "照抄带 - 的恢复码",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").Return(false, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), int64(123), "ABCDE23456").Return(nil)
+				return repo, l
+			},
+			code: " abcde-23456 ",
+		},
+		{
+			name: "恢复码不对",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").Return(false, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), int64(123), "ABCDE23456").
+					Return(repository.ErrRecoveryCodeInvalid)
+				return repo, l
+			},
+			code:    "ABCDE-23456",
+			wantErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "还没有确认",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").Return(false, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.UserTOTP{Uid: 123, Secret: secret}, nil)
+				return repo, l
+			},
+			code:    code,
+			wantErr: ErrTOTPNotEnabled,
+		},
+		{
+			name: "尝试太频繁",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").Return(true, nil)
+				return repo, l
+			},
+			code:    code,
+			wantErr: ErrTOTPVerifyTooMany,
+		},
+		{
+			name: "限流出错",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, limiter.Limiter) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				l := limitermocks.NewMockLimiter(ctrl)
+				l.EXPECT().Limit(gomock.Any(), "totp:verify:123").
+					Return(false, errors.New("redis error"))
+				return repo, l
+			},
+			code:    code,
+			wantErr: errors.New("redis error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, l := tc.mock(ctrl)
+			svc := NewTOTPService(repo, nil, l).(*totpService)
+			svc.now = func() time.Time { return now }
+			err := svc.Verify(context.Background(), 123, tc.code)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestTotpService_Enroll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockTOTPRepository(ctrl)
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().FindById(gomock.Any(), int64(123)).
+		Return(domain.User{Id: 123, Email: "a@qq.com"}, nil)
+	var saved []string
+	repo.EXPECT().SavePending(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, u domain.UserTOTP, codes []string) error {
+			assert.Equal(t, int64(123), u.Uid)
+			saved = codes
+			return nil
+		})
+	svc := NewTOTPService(repo, userRepo, nil)
+	e, err := svc.Enroll(context.Background(), 123)
+	require.NoError(t, err)
+	assert.Contains(t, e.URI, "otpauth://totp/webook:a@qq.com?")
+	assert.Contains(t, e.URI, "secret="+e.Secret)
+	require.Len(t, e.RecoveryCodes, 10)
+	// 存起来的是去掉 - 的
+	for i, code := range e.RecoveryCodes {
+		assert.Equal(t, saved[i][:5]+"-"+saved[i][5:], code)
+	}
+}
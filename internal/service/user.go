@@ -5,13 +5,17 @@ import (
 	"errors"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
-	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrDuplicateEmail        = repository.ErrDuplicateUser
 	ErrInvalidUserOrPassword = errors.New("用户不存在或者密码不对")
+	ErrUserBanned            = errors.New("用户已经被封号")
+	ErrUserNotFound          = repository.ErrUserNotFound
+	ErrWrongOldPassword      = errors.New("旧密码不对")
+	// ErrNoEmail 密码登录是按照邮箱找用户的，没有邮箱的账号设置了密码也用不了
+	ErrNoEmail = errors.New("账号没有绑定邮箱")
 )
 
 type UserService interface {
@@ -22,7 +26,29 @@ type UserService interface {
 	FindById(ctx context.Context,
 		uid int64) (domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
-	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
+	// FindOrCreateByEmail 邮箱验证码登录，调用方要先校验过验证码，
+	// 所以找到的用户顺便标记为邮箱已验证。
+	// cleared 的含义和 VerifyEmail 一样
+	FindOrCreateByEmail(ctx context.Context, email string) (u domain.User, cleared bool, err error)
+	// VerifyEmail 调用方校验过发到邮箱的验证码之后调用。
+	// 没有验证过邮箱的账号谁都可以注册，密码可能是别人抢注的时候设的，
+	// 所以第一次验证的时候清掉密码，cleared 返回 true，调用方要踢掉这个用户所有的会话
+	VerifyEmail(ctx context.Context, email string) (uid int64, cleared bool, err error)
+	// SetBanned 封号和解封。封号之后所有登录方式都会返回 ErrUserBanned，
+	// 已经登录的会话要调用方自己踢掉
+	SetBanned(ctx context.Context, uid int64, banned bool) error
+	// CheckPassword 已经登录的用户再验证一次身份，
+	// 密码不对或者没有设置过密码都返回 ErrInvalidUserOrPassword
+	CheckPassword(ctx context.Context, uid int64, password string) error
+	// ChangePassword 已经登录的用户改密码，要验证旧密码。
+	// 没有设置过密码的用户只能走 ResetPassword
+	ChangePassword(ctx context.Context, uid int64, oldPassword, newPassword string) error
+	// ResetPassword 忘记密码，调用方要先验证过手机号，返回重置了密码的用户 id。
+	// 账号没有邮箱的时候返回 ErrNoEmail
+	ResetPassword(ctx context.Context, phone string, newPassword string) (int64, error)
+	// ResetPasswordByEmail 忘记密码，调用方要先校验过发到邮箱的验证码，
+	// 所以顺便标记为邮箱已验证
+	ResetPasswordByEmail(ctx context.Context, email string, newPassword string) (int64, error)
 }
 
 type userService struct {
@@ -59,6 +85,75 @@ func (svc *userService) Login(ctx context.Context, email string, password string
 	if err != nil {
 		return domain.User{}, ErrInvalidUserOrPassword
 	}
+	// 密码对了才告诉他被封号了
+	return svc.checkBanned(u)
+}
+
+func (svc *userService) SetBanned(ctx context.Context, uid int64, banned bool) error {
+	return svc.repo.UpdateBanned(ctx, uid, banned)
+}
+
+func (svc *userService) CheckPassword(ctx context.Context, uid int64, password string) error {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	if err != nil {
+		return ErrInvalidUserOrPassword
+	}
+	return nil
+}
+
+func (svc *userService) ChangePassword(ctx context.Context, uid int64, oldPassword, newPassword string) error {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	// 没有设置过密码，CompareHashAndPassword 也会返回 error
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(oldPassword))
+	if err != nil {
+		return ErrWrongOldPassword
+	}
+	return svc.updatePassword(ctx, uid, newPassword)
+}
+
+func (svc *userService) ResetPassword(ctx context.Context, phone string, newPassword string) (int64, error) {
+	u, err := svc.repo.FindByPhone(ctx, phone)
+	if err != nil {
+		return 0, err
+	}
+	if u.Email == "" {
+		return 0, ErrNoEmail
+	}
+	return u.Id, svc.updatePassword(ctx, u.Id, newPassword)
+}
+
+func (svc *userService) ResetPasswordByEmail(ctx context.Context, email string, newPassword string) (int64, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	err = svc.updatePassword(ctx, u.Id, newPassword)
+	if err != nil || u.EmailVerified {
+		return u.Id, err
+	}
+	// 密码刚刚重置过，不用清
+	return u.Id, svc.repo.UpdateEmailVerified(ctx, u.Id, false)
+}
+
+func (svc *userService) updatePassword(ctx context.Context, uid int64, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return svc.repo.UpdatePassword(ctx, uid, string(hash))
+}
+
+func (svc *userService) checkBanned(u domain.User) (domain.User, error) {
+	if u.Banned {
+		return domain.User{}, ErrUserBanned
+	}
 	return u, nil
 }
 
@@ -80,7 +175,10 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.
 		// 有两种情况
 		// err == nil, u 是可用的
 		// err != nil，系统错误，
-		return u, err
+		if err != nil {
+			return domain.User{}, err
+		}
+		return svc.checkBanned(u)
 	}
 	// 用户没找到
 	err = svc.repo.Create(ctx, domain.User{
@@ -96,20 +194,50 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.
 	return svc.repo.FindByPhone(ctx, phone)
 }
 
-func (svc *userService) FindOrCreateByWechat(ctx context.Context, wechatInfo domain.WechatInfo) (domain.User, error) {
-	u, err := svc.repo.FindByWechat(ctx, wechatInfo.OpenId)
-	if err != repository.ErrUserNotFound {
-		return u, err
+func (svc *userService) FindOrCreateByEmail(ctx context.Context, email string) (domain.User, bool, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	switch err {
+	case nil:
+		cleared, err := svc.markEmailVerified(ctx, u)
+		if err != nil {
+			return domain.User{}, false, err
+		}
+		u.EmailVerified = true
+		if cleared {
+			u.Password = ""
+		}
+		u, err = svc.checkBanned(u)
+		return u, cleared, err
+	case repository.ErrUserNotFound:
+	default:
+		return domain.User{}, false, err
 	}
-	// 这边就是意味着是一个新用户
-	// JSON 格式的 wechatInfo
-	zap.L().Info("新用户", zap.Any("wechatInfo", wechatInfo))
-	//svc.logger.Info("新用户", zap.Any("wechatInfo", wechatInfo))
 	err = svc.repo.Create(ctx, domain.User{
-		WechatInfo: wechatInfo,
+		Email:         email,
+		EmailVerified: true,
 	})
+	// 和手机号一样，唯一索引冲突说明别的请求已经创建好了
 	if err != nil && err != repository.ErrDuplicateUser {
-		return domain.User{}, err
+		return domain.User{}, false, err
+	}
+	u, err = svc.repo.FindByEmail(ctx, email)
+	return u, false, err
+}
+
+func (svc *userService) VerifyEmail(ctx context.Context, email string) (int64, bool, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return 0, false, err
+	}
+	cleared, err := svc.markEmailVerified(ctx, u)
+	return u.Id, cleared, err
+}
+
+// markEmailVerified 第一次验证邮箱的时候，有密码就清掉，返回是不是清掉了
+func (svc *userService) markEmailVerified(ctx context.Context, u domain.User) (bool, error) {
+	if u.EmailVerified {
+		return false, nil
 	}
-	return svc.repo.FindByWechat(ctx, wechatInfo.OpenId)
+	clearPassword := u.Password != ""
+	return clearPassword, svc.repo.UpdateEmailVerified(ctx, u.Id, clearPassword)
 }
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/wechat"
+)
+
+var (
+	ErrIdentityNotFound     = repository.ErrIdentityNotFound
+	ErrIdentityBoundToOther = errors.New("第三方账号已经绑定了别的用户")
+	ErrProviderAlreadyBound = errors.New("已经绑定过这个平台的账号")
+	// ErrLastLoginMethod 解绑之后用户就登录不了了
+	ErrLastLoginMethod = errors.New("这是唯一的登录方式，不能解绑")
+	// ErrProviderTokenExpired 供应商的令牌过期了，又没办法刷新，只能让用户重新授权
+	ErrProviderTokenExpired = errors.New("第三方令牌已经过期")
+)
+
+// tokenExpiryLeeway 令牌快过期的时候就提前刷新，免得拿到手调用的时候刚好过期
+const tokenExpiryLeeway = time.Minute
+
+// UserIdentityService 用户绑定的第三方账号
+type UserIdentityService interface {
+	// FindOrCreate 第三方登录。先按照第三方账号找，找不到再按照供应商验证过的邮箱找，
+	// 都找不到就注册一个新用户。被封号的时候返回 ErrUserBanned，返回的用户只有 Id
+	FindOrCreate(ctx context.Context, identity domain.OAuth2Identity) (domain.User, error)
+	// Bind 已经登录的用户绑定第三方账号，重复绑定同一个账号不算错
+	Bind(ctx context.Context, uid int64, identity domain.OAuth2Identity) error
+	// Unbind 解绑之后用户必须还有别的登录方式，不然返回 ErrLastLoginMethod
+	Unbind(ctx context.Context, uid int64, provider string) error
+	List(ctx context.Context, uid int64) ([]domain.UserIdentity, error)
+	// AccessToken 替用户调用供应商接口用的令牌，过期了就用 refresh token 换一个新的存起来。
+	// 没有绑定返回 ErrIdentityNotFound，过期了又刷新不了返回 ErrProviderTokenExpired
+	AccessToken(ctx context.Context, uid int64, provider string) (string, error)
+}
+
+type userIdentityService struct {
+	repo      repository.UserIdentityRepository
+	userRepo  repository.UserRepository
+	providers *oauth2.Providers
+}
+
+func NewUserIdentityService(repo repository.UserIdentityRepository,
+	userRepo repository.UserRepository,
+	providers *oauth2.Providers) UserIdentityService {
+	return &userIdentityService{
+		repo:      repo,
+		userRepo:  userRepo,
+		providers: providers,
+	}
+}
+
+func (svc *userIdentityService) FindOrCreate(ctx context.Context, identity domain.OAuth2Identity) (domain.User, error) {
+	ui, err := svc.findIdentity(ctx, identity)
+	switch err {
+	case nil:
+		// 按照 unionid 找到的可能是同一个开放平台下面别的应用的账号，
+		// 令牌是按照应用发的，不能混着存
+		if ui.Subject == identity.Subject {
+			update := svc.toIdentity(ui.Uid, identity)
+			update.Id = ui.Id
+			err = svc.repo.UpdateToken(ctx, update)
+			if err != nil {
+				return domain.User{}, err
+			}
+		}
+		return svc.findUser(ctx, ui.Uid)
+	case repository.ErrIdentityNotFound:
+	default:
+		return domain.User{}, err
+	}
+	if identity.Provider == wechat.ProviderName {
+		u, err := svc.userRepo.FindByWechat(ctx, identity.Subject)
+		switch err {
+		case nil:
+			err = svc.migrateWechat(ctx, u)
+			if err != nil {
+				return domain.User{}, err
+			}
+			return svc.checkBanned(u)
+		case repository.ErrUserNotFound:
+		default:
+			return domain.User{}, err
+		}
+	}
+	// 只信任供应商验证过的邮箱，不然谁都可以声称自己是某个邮箱的主人，直接登进别人的账号
+	verified := identity.Email != "" && identity.EmailVerified
+	if verified {
+		u, err := svc.userRepo.FindByEmail(ctx, identity.Email)
+		switch {
+		case err == nil && u.EmailVerified:
+			// 顺便绑定上，下次直接按照第三方账号找
+			err = svc.repo.Create(ctx, svc.toIdentity(u.Id, identity))
+			if err != nil && err != repository.ErrDuplicateIdentity {
+				return domain.User{}, err
+			}
+			return svc.checkBanned(u)
+		case err == nil:
+			// 这个邮箱注册的账号自己还没有验证过邮箱，可能是别人抢注的，
+			// 不能自动合并。另外注册一个账号，想合并的话登录之后再绑定
+			verified = false
+		case err == repository.ErrUserNotFound:
+		default:
+			return domain.User{}, err
+		}
+	}
+	u := domain.User{Nickname: identity.Nickname, Avatar: identity.Avatar}
+	if verified {
+		u.Email = identity.Email
+		u.EmailVerified = true
+	}
+	uid, err := svc.repo.CreateWithUser(ctx, u, svc.toIdentity(0, identity))
+	if err == repository.ErrDuplicateIdentity {
+		// 同一个人的回调并发进来，别的请求已经注册好了
+		ui, err = svc.repo.FindByProviderSubject(ctx, identity.Provider, identity.Subject)
+		uid = ui.Uid
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	return svc.findUser(ctx, uid)
+}
+
+func (svc *userIdentityService) Bind(ctx context.Context, uid int64, identity domain.OAuth2Identity) error {
+	if identity.Provider == wechat.ProviderName {
+		// 以前的微信用户，openid 还在 users 表上，不挪过来的话会被绑到两个人身上
+		u, err := svc.userRepo.FindByWechat(ctx, identity.Subject)
+		switch err {
+		case nil:
+			if u.Id != uid {
+				return ErrIdentityBoundToOther
+			}
+			return svc.migrateWechat(ctx, u)
+		case repository.ErrUserNotFound:
+		default:
+			return err
+		}
+	}
+	if identity.UnionId != "" {
+		// 用户在同一个开放平台下面别的应用里面绑定过
+		ui, err := svc.repo.FindByProviderUnionId(ctx, identity.Provider, identity.UnionId)
+		switch err {
+		case nil:
+			if ui.Uid == uid {
+				return nil
+			}
+			return ErrIdentityBoundToOther
+		case repository.ErrIdentityNotFound:
+		default:
+			return err
+		}
+	}
+	err := svc.repo.Create(ctx, svc.toIdentity(uid, identity))
+	if err != repository.ErrDuplicateIdentity {
+		return err
+	}
+	// 两个唯一索引，看看是哪个冲突了
+	ui, err := svc.repo.FindByProviderSubject(ctx, identity.Provider, identity.Subject)
+	switch err {
+	case nil:
+		if ui.Uid == uid {
+			return nil
+		}
+		return ErrIdentityBoundToOther
+	case repository.ErrIdentityNotFound:
+		return ErrProviderAlreadyBound
+	default:
+		return err
+	}
+}
+
+func (svc *userIdentityService) Unbind(ctx context.Context, uid int64, provider string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if provider == wechat.ProviderName && u.WechatInfo.OpenId != "" {
+		err = svc.migrateWechat(ctx, u)
+		if err != nil {
+			return err
+		}
+	}
+	// 有邮箱就能用密码或者邮箱验证码登录，有手机号就能用短信登录，第三方账号可以全部解绑
+	hasOther := u.Email != "" || u.Phone != ""
+	err = svc.repo.Delete(ctx, uid, provider, hasOther)
+	if err == repository.ErrLastIdentity {
+		return ErrLastLoginMethod
+	}
+	return err
+}
+
+func (svc *userIdentityService) List(ctx context.Context, uid int64) ([]domain.UserIdentity, error) {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if u.WechatInfo.OpenId != "" {
+		err = svc.migrateWechat(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return svc.repo.FindByUid(ctx, uid)
+}
+
+func (svc *userIdentityService) AccessToken(ctx context.Context, uid int64, provider string) (string, error) {
+	identities, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+	var ui domain.UserIdentity
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			ui = identity
+			break
+		}
+	}
+	if ui.Id == 0 {
+		return "", ErrIdentityNotFound
+	}
+	if ui.AccessToken != "" &&
+		(ui.TokenExpiry.IsZero() || time.Now().Add(tokenExpiryLeeway).Before(ui.TokenExpiry)) {
+		return ui.AccessToken, nil
+	}
+	p, err := svc.providers.Get(provider)
+	if err != nil {
+		return "", err
+	}
+	refresher, ok := p.(oauth2.TokenRefresher)
+	if !ok || ui.RefreshToken == "" {
+		return "", ErrProviderTokenExpired
+	}
+	tok, err := refresher.Refresh(ctx, ui.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+	ui.AccessToken = tok.AccessToken
+	ui.TokenExpiry = tok.Expiry
+	// 有些供应商刷新的时候不换 refresh token
+	if tok.RefreshToken != "" {
+		ui.RefreshToken = tok.RefreshToken
+	}
+	err = svc.repo.UpdateToken(ctx, ui)
+	if err != nil {
+		return "", err
+	}
+	return ui.AccessToken, nil
+}
+
+// findIdentity 有 unionid 的时候优先按照 unionid 找，
+// 这样同一个人从微信开放平台下面的不同应用登录进来都是同一个用户
+func (svc *userIdentityService) findIdentity(ctx context.Context,
+	identity domain.OAuth2Identity) (domain.UserIdentity, error) {
+	if identity.UnionId != "" {
+		ui, err := svc.repo.FindByProviderUnionId(ctx, identity.Provider, identity.UnionId)
+		if err != repository.ErrIdentityNotFound {
+			return ui, err
+		}
+	}
+	return svc.repo.FindByProviderSubject(ctx, identity.Provider, identity.Subject)
+}
+
+// migrateWechat 老的微信用户，openid 存在 users 表上，挪到 user_identities 里面
+func (svc *userIdentityService) migrateWechat(ctx context.Context, u domain.User) error {
+	err := svc.repo.MigrateWechat(ctx, domain.UserIdentity{
+		Uid:      u.Id,
+		Provider: wechat.ProviderName,
+		Subject:  u.WechatInfo.OpenId,
+		UnionId:  u.WechatInfo.UnionId,
+	})
+	if err == repository.ErrDuplicateIdentity {
+		// 别的请求已经挪过了
+		return nil
+	}
+	return err
+}
+
+func (svc *userIdentityService) findUser(ctx context.Context, uid int64) (domain.User, error) {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return svc.checkBanned(u)
+}
+
+func (svc *userIdentityService) checkBanned(u domain.User) (domain.User, error) {
+	if u.Banned {
+		// 带上 Id，调用方要把登录失败记到这个用户名下
+		return domain.User{Id: u.Id}, ErrUserBanned
+	}
+	return u, nil
+}
+
+func (svc *userIdentityService) toIdentity(uid int64, identity domain.OAuth2Identity) domain.UserIdentity {
+	return domain.UserIdentity{
+		Uid:      uid,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UnionId:  identity.UnionId,
+		Profile:  identity.Profile,
+
+		AccessToken:  identity.AccessToken,
+		RefreshToken: identity.RefreshToken,
+		TokenExpiry:  identity.TokenExpiry,
+	}
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	oauth2mocks "gitee.com/geekbang/basic-go/webook/internal/service/oauth2/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestUserIdentityService_FindOrCreate(t *testing.T) {
+	google := domain.OAuth2Identity{
+		Provider:      "google",
+		Subject:       "sub-1",
+		Email:         "123@qq.com",
+		EmailVerified: true,
+		Nickname:      "Tom",
+	}
+	googleIdentity := domain.UserIdentity{Provider: "google", Subject: "sub-1"}
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository)
+		identity domain.OAuth2Identity
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "已经绑定过",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{Id: 1, Uid: 123, Provider: "google", Subject: "sub-1"}, nil)
+				repo.EXPECT().UpdateToken(gomock.Any(), domain.UserIdentity{
+					Id: 1, Uid: 123, Provider: "google", Subject: "sub-1",
+				}).Return(nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				return repo, userRepo
+			},
+			identity: google,
+			wantUser: domain.User{Id: 123},
+		},
+		{
+			name: "微信优先按照 unionid 找，更新令牌",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderUnionId(gomock.Any(), "wechat", "unionid").
+					Return(domain.UserIdentity{Id: 1, Uid: 123, Provider: "wechat",
+						Subject: "openid", UnionId: "unionid"}, nil)
+				repo.EXPECT().UpdateToken(gomock.Any(), domain.UserIdentity{
+					Id: 1, Uid: 123, Provider: "wechat", Subject: "openid", UnionId: "unionid",
+					AccessToken: "access", RefreshToken: "refresh",
+				}).Return(nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				return repo, userRepo
+			},
+			identity: domain.OAuth2Identity{Provider: "wechat", Subject: "openid", UnionId: "unionid",
+				AccessToken: "access", RefreshToken: "refresh"},
+			wantUser: domain.User{Id: 123},
+		},
+		{
+			name: "别的微信应用绑定过，令牌不能混着存",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderUnionId(gomock.Any(), "wechat", "unionid").
+					Return(domain.UserIdentity{Id: 1, Uid: 123, Provider: "wechat",
+						Subject: "other-openid", UnionId: "unionid"}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				return repo, userRepo
+			},
+			identity: domain.OAuth2Identity{Provider: "wechat", Subject: "openid", UnionId: "unionid",
+				AccessToken: "access", RefreshToken: "refresh"},
+			wantUser: domain.User{Id: 123},
+		},
+		{
+			name: "老的微信用户，挪到新表",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "wechat", "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				u := domain.User{Id: 123, WechatInfo: domain.WechatInfo{OpenId: "openid", UnionId: "unionid"}}
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").Return(u, nil)
+				repo.EXPECT().MigrateWechat(gomock.Any(), domain.UserIdentity{
+					Uid: 123, Provider: "wechat", Subject: "openid", UnionId: "unionid",
+				}).Return(nil)
+				return repo, userRepo
+			},
+			identity: domain.OAuth2Identity{Provider: "wechat", Subject: "openid"},
+			wantUser: domain.User{Id: 123, WechatInfo: domain.WechatInfo{OpenId: "openid", UnionId: "unionid"}},
+		},
+		{
+			name: "验证过的邮箱，绑定到已有用户",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true}, nil)
+				repo.EXPECT().Create(gomock.Any(), domain.UserIdentity{
+					Uid: 123, Provider: "google", Subject: "sub-1",
+				}).Return(nil)
+				return repo, userRepo
+			},
+			identity: google,
+			wantUser: domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true},
+		},
+		{
+			name: "已有用户自己没验证过邮箱，不合并，注册新用户",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				// 邮箱已经被占用了，新用户不带邮箱
+				repo.EXPECT().CreateWithUser(gomock.Any(),
+					domain.User{Nickname: "Tom"}, googleIdentity).
+					Return(int64(124), nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(124)).
+					Return(domain.User{Id: 124, Nickname: "Tom"}, nil)
+				return repo, userRepo
+			},
+			identity: google,
+			wantUser: domain.User{Id: 124, Nickname: "Tom"},
+		},
+		{
+			name: "没验证过的邮箱，注册新用户",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(),
+					domain.User{Nickname: "Tom", Avatar: "https://example.com/tom.png"}, googleIdentity).
+					Return(int64(124), nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(124)).
+					Return(domain.User{Id: 124, Nickname: "Tom"}, nil)
+				return repo, userRepo
+			},
+			identity: domain.OAuth2Identity{Provider: "google", Subject: "sub-1",
+				Email: "123@qq.com", Nickname: "Tom", Avatar: "https://example.com/tom.png"},
+			wantUser: domain.User{Id: 124, Nickname: "Tom"},
+		},
+		{
+			name: "并发注册，别的请求已经注册好了",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(),
+					domain.User{Nickname: "Tom", Email: "123@qq.com", EmailVerified: true}, googleIdentity).
+					Return(int64(0), repository.ErrDuplicateIdentity)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{Uid: 124}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(124)).Return(domain.User{Id: 124}, nil)
+				return repo, userRepo
+			},
+			identity: google,
+			wantUser: domain.User{Id: 124},
+		},
+		{
+			name: "被封号了",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{Uid: 123}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Banned: true}, nil)
+				return repo, userRepo
+			},
+			identity: google,
+			// 调用方要把登录失败记到这个用户名下
+			wantUser: domain.User{Id: 123},
+			wantErr:  ErrUserBanned,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			svc := NewUserIdentityService(repo, userRepo, nil)
+			u, err := svc.FindOrCreate(context.Background(), tc.identity)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestUserIdentityService_Bind(t *testing.T) {
+	google := domain.OAuth2Identity{Provider: "google", Subject: "sub-1"}
+	googleIdentity := domain.UserIdentity{Uid: 123, Provider: "google", Subject: "sub-1"}
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository)
+		identity domain.OAuth2Identity
+
+		wantErr error
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), googleIdentity).Return(nil)
+				return repo, nil
+			},
+			identity: google,
+		},
+		{
+			name: "重复绑定同一个账号",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), googleIdentity).Return(repository.ErrDuplicateIdentity)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(googleIdentity, nil)
+				return repo, nil
+			},
+			identity: google,
+		},
+		{
+			name: "第三方账号绑定了别人",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), googleIdentity).Return(repository.ErrDuplicateIdentity)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{Uid: 124}, nil)
+				return repo, nil
+			},
+			identity: google,
+			wantErr:  ErrIdentityBoundToOther,
+		},
+		{
+			name: "已经绑定过这个平台的别的账号",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), googleIdentity).Return(repository.ErrDuplicateIdentity)
+				repo.EXPECT().FindByProviderSubject(gomock.Any(), "google", "sub-1").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				return repo, nil
+			},
+			identity: google,
+			wantErr:  ErrProviderAlreadyBound,
+		},
+		{
+			name: "同一个开放平台下面别的应用绑定了别人",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().FindByProviderUnionId(gomock.Any(), "wechat", "unionid").
+					Return(domain.UserIdentity{Uid: 124, Subject: "other-openid"}, nil)
+				return repo, userRepo
+			},
+			identity: domain.OAuth2Identity{Provider: "wechat", Subject: "openid", UnionId: "unionid"},
+			wantErr:  ErrIdentityBoundToOther,
+		},
+		{
+			name: "老的微信用户是别人",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").
+					Return(domain.User{Id: 124, WechatInfo: domain.WechatInfo{OpenId: "openid"}}, nil)
+				return nil, userRepo
+			},
+			identity: domain.OAuth2Identity{Provider: "wechat", Subject: "openid"},
+			wantErr:  ErrIdentityBoundToOther,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			svc := NewUserIdentityService(repo, userRepo, nil)
+			err := svc.Bind(context.Background(), 123, tc.identity)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserIdentityService_Unbind(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository)
+
+		wantErr error
+	}{
+		{
+			name: "有邮箱，可以用邮箱验证码登录，能解绑最后一个",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(123), "google", true).Return(nil)
+				return repo, userRepo
+			},
+		},
+		{
+			name: "没有邮箱和手机号，不能解绑最后一个",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Nickname: "Tom"}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(123), "google", false).
+					Return(repository.ErrLastIdentity)
+				return repo, userRepo
+			},
+			wantErr: ErrLastLoginMethod,
+		},
+		{
+			name: "没有绑定过",
+			mock: func(ctrl *gomock.Controller) (repository.UserIdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "15212345678"}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(123), "google", true).
+					Return(repository.ErrIdentityNotFound)
+				return repo, userRepo
+			},
+			wantErr: ErrIdentityNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			svc := NewUserIdentityService(repo, userRepo, nil)
+			err := svc.Unbind(context.Background(), 123, "google")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserIdentityService_AccessToken(t *testing.T) {
+	now := time.Now()
+	expired := domain.UserIdentity{Id: 1, Uid: 123, Provider: "fake", Subject: "sub-1",
+		AccessToken: "access-1", RefreshToken: "refresh-1", TokenExpiry: now.Add(-time.Minute)}
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) repository.UserIdentityRepository
+		provider func(ctrl *gomock.Controller) oauth2.Provider
+
+		wantToken string
+		wantErr   error
+	}{
+		{
+			name: "没有过期，直接用",
+			mock: func(ctrl *gomock.Controller) repository.UserIdentityRepository {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				valid := expired
+				valid.TokenExpiry = now.Add(time.Hour)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return([]domain.UserIdentity{valid}, nil)
+				return repo
+			},
+			provider:  newFakeRefresher(oauth2.Token{}),
+			wantToken: "access-1",
+		},
+		{
+			name: "过期了，刷新之后存起来",
+			mock: func(ctrl *gomock.Controller) repository.UserIdentityRepository {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return([]domain.UserIdentity{expired}, nil)
+				refreshed := expired
+				refreshed.AccessToken = "access-2"
+				refreshed.RefreshToken = "refresh-2"
+				refreshed.TokenExpiry = now.Add(2 * time.Hour)
+				repo.EXPECT().UpdateToken(gomock.Any(), refreshed).Return(nil)
+				return repo
+			},
+			provider: newFakeRefresher(oauth2.Token{
+				AccessToken: "access-2", RefreshToken: "refresh-2", Expiry: now.Add(2 * time.Hour),
+			}),
+			wantToken: "access-2",
+		},
+		{
+			name: "过期了，供应商不支持刷新",
+			mock: func(ctrl *gomock.Controller) repository.UserIdentityRepository {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return([]domain.UserIdentity{expired}, nil)
+				return repo
+			},
+			provider: func(ctrl *gomock.Controller) oauth2.Provider {
+				p := oauth2mocks.NewMockProvider(ctrl)
+				p.EXPECT().Name().Return("fake").AnyTimes()
+				return p
+			},
+			wantErr: ErrProviderTokenExpired,
+		},
+		{
+			name: "没有绑定",
+			mock: func(ctrl *gomock.Controller) repository.UserIdentityRepository {
+				repo := repomocks.NewMockUserIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(nil, nil)
+				return repo
+			},
+			provider: newFakeRefresher(oauth2.Token{}),
+			wantErr:  ErrIdentityNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserIdentityService(tc.mock(ctrl), nil, oauth2.NewProviders(tc.provider(ctrl)))
+			token, err := svc.AccessToken(context.Background(), 123, "fake")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantToken, token)
+		})
+	}
+}
+
+// fakeRefresher 支持刷新令牌的供应商，只认 refresh-1
+type fakeRefresher struct {
+	oauth2.Provider
+	tok oauth2.Token
+}
+
+func newFakeRefresher(tok oauth2.Token) func(ctrl *gomock.Controller) oauth2.Provider {
+	return func(ctrl *gomock.Controller) oauth2.Provider {
+		p := oauth2mocks.NewMockProvider(ctrl)
+		p.EXPECT().Name().Return("fake").AnyTimes()
+		return &fakeRefresher{Provider: p, tok: tok}
+	}
+}
+
+func (f *fakeRefresher) Refresh(ctx context.Context, refreshToken string) (oauth2.Token, error) {
+	if refreshToken != "refresh-1" {
+		return oauth2.Token{}, errors.New("invalid refresh token")
+	}
+	return f.tok, nil
+}
@@ -125,3 +125,354 @@ func Test_userService_Login(t *testing.T) {
 		})
 	}
 }
+
+func Test_userService_ChangePassword(t *testing.T) {
+	// 123456#hello 加密之后的
+	const hash = "$2a$10$.l0JHmM7a2PdJ.A9gsmVyerEDlp1WhxsglC34S4UJH4TuHhWY7Tfq"
+	testCases := []struct {
+		name        string
+		mock        func(ctrl *gomock.Controller) repository.UserRepository
+		oldPassword string
+
+		wantErr error
+	}{
+		{
+			name: "修改成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Password: hash}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(123), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, uid int64, password string) error {
+						// 存的是新密码加密之后的
+						return bcrypt.CompareHashAndPassword([]byte(password), []byte("hello#world456"))
+					})
+				return repo
+			},
+			oldPassword: "123456#hello",
+		},
+		{
+			name: "旧密码不对",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Password: hash}, nil)
+				return repo
+			},
+			oldPassword: "123456#world",
+			wantErr:     ErrWrongOldPassword,
+		},
+		{
+			name: "没有设置过密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "15212345678"}, nil)
+				return repo
+			},
+			oldPassword: "",
+			wantErr:     ErrWrongOldPassword,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			err := svc.ChangePassword(context.Background(), 123, tc.oldPassword, "hello#world456")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_userService_CheckPassword(t *testing.T) {
+	// 123456#hello 加密之后的
+	const hash = "$2a$10$.l0JHmM7a2PdJ.A9gsmVyerEDlp1WhxsglC34S4UJH4TuHhWY7Tfq"
+	testCases := []struct {
+		name     string
+		user     domain.User
+		password string
+
+		wantErr error
+	}{
+		{
+			name:     "密码对了",
+			user:     domain.User{Id: 123, Password: hash},
+			password: "123456#hello",
+		},
+		{
+			name:     "密码不对",
+			user:     domain.User{Id: 123, Password: hash},
+			password: "123456#world",
+			wantErr:  ErrInvalidUserOrPassword,
+		},
+		{
+			name:    "没有设置过密码",
+			user:    domain.User{Id: 123, Phone: "15212345678"},
+			wantErr: ErrInvalidUserOrPassword,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := repomocks.NewMockUserRepository(ctrl)
+			repo.EXPECT().FindById(gomock.Any(), int64(123)).Return(tc.user, nil)
+			svc := NewUserService(repo)
+			err := svc.CheckPassword(context.Background(), 123, tc.password)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_userService_ResetPassword(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantUid int64
+		wantErr error
+	}{
+		{
+			name: "重置成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{Id: 123, Phone: "15212345678", Email: "123@qq.com"}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(123), gomock.Any()).Return(nil)
+				return repo
+			},
+			wantUid: 123,
+		},
+		{
+			name: "账号没有邮箱，设置了密码也登录不了",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{Id: 123, Phone: "15212345678"}, nil)
+				return repo
+			},
+			wantErr: ErrNoEmail,
+		},
+		{
+			name: "手机号没有注册",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return repo
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			uid, err := svc.ResetPassword(context.Background(), "15212345678", "hello#world456")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUid, uid)
+		})
+	}
+}
+
+func Test_userService_ResetPasswordByEmail(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantUid int64
+		wantErr error
+	}{
+		{
+			name: "重置成功，顺便标记邮箱已验证",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(123), gomock.Any()).Return(nil)
+				repo.EXPECT().UpdateEmailVerified(gomock.Any(), int64(123), false).Return(nil)
+				return repo
+			},
+			wantUid: 123,
+		},
+		{
+			name: "邮箱已经验证过",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(123), gomock.Any()).Return(nil)
+				return repo
+			},
+			wantUid: 123,
+		},
+		{
+			name: "邮箱没有注册",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return repo
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			uid, err := svc.ResetPasswordByEmail(context.Background(), "123@qq.com", "hello#world456")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUid, uid)
+		})
+	}
+}
+
+func Test_userService_FindOrCreateByEmail(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantUser    domain.User
+		wantCleared bool
+		wantErr     error
+	}{
+		{
+			name: "老用户，顺便标记邮箱已验证",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				repo.EXPECT().UpdateEmailVerified(gomock.Any(), int64(123), false).Return(nil)
+				return repo
+			},
+			wantUser: domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true},
+		},
+		{
+			name: "没验证过邮箱的账号设置了密码，可能是抢注的，清掉密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", Password: "hash"}, nil)
+				repo.EXPECT().UpdateEmailVerified(gomock.Any(), int64(123), true).Return(nil)
+				return repo
+			},
+			wantUser:    domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true},
+			wantCleared: true,
+		},
+		{
+			name: "验证过邮箱的账号，密码不动",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", Password: "hash", EmailVerified: true}, nil)
+				return repo
+			},
+			wantUser: domain.User{Id: 123, Email: "123@qq.com", Password: "hash", EmailVerified: true},
+		},
+		{
+			name: "新用户",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().Create(gomock.Any(), domain.User{Email: "123@qq.com", EmailVerified: true}).
+					Return(nil)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true}, nil)
+				return repo
+			},
+			wantUser: domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true},
+		},
+		{
+			name: "被封号了",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", EmailVerified: true, Banned: true}, nil)
+				return repo
+			},
+			wantErr: ErrUserBanned,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			u, cleared, err := svc.FindOrCreateByEmail(context.Background(), "123@qq.com")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+			assert.Equal(t, tc.wantCleared, cleared)
+		})
+	}
+}
+
+func Test_userService_VerifyEmail(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantUid     int64
+		wantCleared bool
+		wantErr     error
+	}{
+		{
+			name: "没有设置过密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				repo.EXPECT().UpdateEmailVerified(gomock.Any(), int64(123), false).Return(nil)
+				return repo
+			},
+			wantUid: 123,
+		},
+		{
+			name: "第一次验证，清掉之前设置的密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", Password: "hash"}, nil)
+				repo.EXPECT().UpdateEmailVerified(gomock.Any(), int64(123), true).Return(nil)
+				return repo
+			},
+			wantUid:     123,
+			wantCleared: true,
+		},
+		{
+			name: "已经验证过了",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123, Email: "123@qq.com", Password: "hash", EmailVerified: true}, nil)
+				return repo
+			},
+			wantUid: 123,
+		},
+		{
+			name: "邮箱没有注册",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return repo
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			uid, cleared, err := svc.VerifyEmail(context.Background(), "123@qq.com")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUid, uid)
+			assert.Equal(t, tc.wantCleared, cleared)
+		})
+	}
+}
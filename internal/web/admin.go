@@ -0,0 +1,245 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 管理后台，每个接口都要求对应的权限
+type AdminHandler struct {
+	userSvc    service.UserService
+	artSvc     service.ArticleService
+	rbacSvc    service.RBACService
+	attemptSvc service.LoginAttemptService
+	jwtHdl     ijwt.Handler
+	perms      *middleware.PermissionMiddlewareBuilder
+	l          logger.LoggerV1
+}
+
+func NewAdminHandler(userSvc service.UserService,
+	artSvc service.ArticleService,
+	rbacSvc service.RBACService,
+	attemptSvc service.LoginAttemptService,
+	jwtHdl ijwt.Handler,
+	l logger.LoggerV1) *AdminHandler {
+	return &AdminHandler{
+		userSvc:    userSvc,
+		artSvc:     artSvc,
+		rbacSvc:    rbacSvc,
+		attemptSvc: attemptSvc,
+		jwtHdl:     jwtHdl,
+		perms:      middleware.NewPermissionMiddlewareBuilder(l),
+		l:          l,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry) {
+	g := server.Group("/admin")
+	g.GET("/users/:id", h.perms.RequirePermission(domain.PermissionUserRead), h.UserDetail)
+	g.POST("/users/ban", h.perms.RequirePermission(domain.PermissionUserBan), h.BanUser)
+	g.POST("/users/unlock", h.perms.RequirePermission(domain.PermissionUserBan), h.UnlockLogin)
+	g.POST("/users/roles", h.perms.RequirePermission(domain.PermissionRoleManage), h.SetUserRoles)
+	g.POST("/articles/takedown",
+		h.perms.RequirePermission(domain.PermissionArticleTakedown), h.TakeDownArticle)
+}
+
+func (h *AdminHandler) UserDetail(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "参数错误"})
+		return
+	}
+	u, err := h.userSvc.FindById(ctx, uid)
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "用户不存在"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("查询用户失败",
+			logger.Field{Key: "uid", Val: uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	perms, err := h.rbacSvc.GetPermissions(ctx, uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("查询用户的角色失败",
+			logger.Field{Key: "uid", Val: uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: AdminUserVO{
+			Id:       u.Id,
+			Email:    u.Email,
+			Phone:    u.Phone,
+			Nickname: u.Nickname,
+			Banned:   u.Banned,
+			Roles:    perms.Roles,
+			Ctime:    u.Ctime.Format(time.DateTime),
+		},
+	})
+}
+
+// BanUser 封号的同时踢掉这个用户所有的会话，解封不会恢复会话
+func (h *AdminHandler) BanUser(ctx *gin.Context) {
+	type Req struct {
+		Uid int64 `json:"uid"`
+		// true 是封号，false 是解封
+		Ban bool `json:"ban"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	if req.Ban && req.Uid == uc.Uid {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "不能封禁自己"})
+		return
+	}
+	err := h.userSvc.SetBanned(ctx, req.Uid, req.Ban)
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "用户不存在"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("封号失败",
+			logger.Field{Key: "uid", Val: req.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	if req.Ban {
+		err = h.jwtHdl.RevokeAllSessions(ctx, req.Uid)
+		if err != nil {
+			// 已经封号了，只是会话没踢掉，重试是安全的
+			ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+			h.l.Error("封号之后退出会话失败",
+				logger.Field{Key: "uid", Val: req.Uid},
+				logger.Field{Key: "error", Val: err})
+			return
+		}
+	}
+	h.l.Info("管理员修改封号状态",
+		logger.Field{Key: "operator", Val: uc.Uid},
+		logger.Field{Key: "uid", Val: req.Uid},
+		logger.Field{Key: "ban", Val: req.Ban})
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+// UnlockLogin 解除登录失败太多次导致的锁定
+func (h *AdminHandler) UnlockLogin(ctx *gin.Context) {
+	type Req struct {
+		Uid int64 `json:"uid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.attemptSvc.Unlock(ctx, req.Uid)
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "用户不存在"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("解锁登录失败",
+			logger.Field{Key: "uid", Val: req.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	h.l.Info("管理员解锁登录",
+		logger.Field{Key: "operator", Val: uc.Uid},
+		logger.Field{Key: "uid", Val: req.Uid})
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+// SetUserRoles 整个替换用户的角色，传空数组就是去掉所有角色
+func (h *AdminHandler) SetUserRoles(ctx *gin.Context) {
+	type Req struct {
+		Uid   int64    `json:"uid"`
+		Roles []string `json:"roles"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	_, err := h.userSvc.FindById(ctx, req.Uid)
+	if err == nil {
+		err = h.rbacSvc.SetUserRoles(ctx, req.Uid, req.Roles)
+	}
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "用户不存在"})
+		return
+	case service.ErrRoleNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "角色不存在"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("修改用户角色失败",
+			logger.Field{Key: "uid", Val: req.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	err = h.jwtHdl.InvalidateClaims(ctx, req.Uid)
+	if err != nil {
+		// 旧 token 过期之前权限还是旧的，重试是安全的
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("修改角色之后作废 token 失败",
+			logger.Field{Key: "uid", Val: req.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	h.l.Info("管理员修改用户角色",
+		logger.Field{Key: "operator", Val: uc.Uid},
+		logger.Field{Key: "uid", Val: req.Uid},
+		logger.Field{Key: "roles", Val: req.Roles})
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+func (h *AdminHandler) TakeDownArticle(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.artSvc.TakeDown(ctx, req.Id)
+	switch {
+	case err == nil:
+	case err == service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "文章不存在"})
+		return
+	case isIllegalArticleStatus(err):
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "文章当前状态不能下架"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		h.l.Error("下架文章失败",
+			logger.Field{Key: "aid", Val: req.Id},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	h.l.Info("管理员下架文章",
+		logger.Field{Key: "operator", Val: uc.Uid},
+		logger.Field{Key: "aid", Val: req.Id})
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
@@ -0,0 +1,216 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAdminHandler_BanUser(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler)
+		perms []string
+		body  string
+
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "封号并且踢掉所有会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().SetBanned(gomock.Any(), int64(456), true).Return(nil)
+				jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(456)).Return(nil)
+				return userSvc, jwtHdl
+			},
+			perms:    []string{domain.PermissionUserBan},
+			body:     `{"uid":456,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "解封不动会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().SetBanned(gomock.Any(), int64(456), false).Return(nil)
+				return userSvc, jwtHdl
+			},
+			perms:    []string{domain.PermissionUserBan},
+			body:     `{"uid":456,"ban":false}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().SetBanned(gomock.Any(), int64(456), true).
+					Return(service.ErrUserNotFound)
+				return userSvc, jwtHdl
+			},
+			perms:    []string{domain.PermissionUserBan},
+			body:     `{"uid":456,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "用户不存在"},
+		},
+		{
+			name: "踢会话失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().SetBanned(gomock.Any(), int64(456), true).Return(nil)
+				jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(456)).
+					Return(errors.New("redis error"))
+				return userSvc, jwtHdl
+			},
+			perms:    []string{domain.PermissionUserBan},
+			body:     `{"uid":456,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+		{
+			name: "不能封自己",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				return svcmocks.NewMockUserService(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			perms:    []string{domain.PermissionUserBan},
+			body:     `{"uid":123,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "不能封禁自己"},
+		},
+		{
+			name: "没有封号权限",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				return svcmocks.NewMockUserService(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			perms:    []string{domain.PermissionUserRead},
+			body:     `{"uid":456,"ban":true}`,
+			wantCode: http.StatusForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewAdminHandler(userSvc, nil, nil, nil, jwtHdl, logger.NewNopLogger())
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123, Permissions: tc.perms})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/users/ban",
+				bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if recorder.Code != http.StatusOK {
+				return
+			}
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestAdminHandler_SetUserRoles(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.RBACService, ijwt.Handler)
+		body string
+
+		wantRes Result
+	}{
+		{
+			name: "修改角色之后作废旧的 token",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.RBACService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				rbacSvc := svcmocks.NewMockRBACService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(456)).Return(domain.User{Id: 456}, nil)
+				rbacSvc.EXPECT().SetUserRoles(gomock.Any(), int64(456), []string{"moderator"}).Return(nil)
+				jwtHdl.EXPECT().InvalidateClaims(gomock.Any(), int64(456)).Return(nil)
+				return userSvc, rbacSvc, jwtHdl
+			},
+			body:    `{"uid":456,"roles":["moderator"]}`,
+			wantRes: Result{Msg: "OK"},
+		},
+		{
+			name: "角色不存在",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.RBACService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				rbacSvc := svcmocks.NewMockRBACService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(456)).Return(domain.User{Id: 456}, nil)
+				rbacSvc.EXPECT().SetUserRoles(gomock.Any(), int64(456), []string{"god"}).
+					Return(service.ErrRoleNotFound)
+				return userSvc, rbacSvc, jwtHdl
+			},
+			body:    `{"uid":456,"roles":["god"]}`,
+			wantRes: Result{Code: 4, Msg: "角色不存在"},
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.RBACService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				rbacSvc := svcmocks.NewMockRBACService(ctrl)
+				jwtHdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(456)).
+					Return(domain.User{}, service.ErrUserNotFound)
+				return userSvc, rbacSvc, jwtHdl
+			},
+			body:    `{"uid":456,"roles":["moderator"]}`,
+			wantRes: Result{Code: 4, Msg: "用户不存在"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, rbacSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewAdminHandler(userSvc, nil, rbacSvc, nil, jwtHdl, logger.NewNopLogger())
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123,
+					Permissions: []string{domain.PermissionRoleManage}})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/users/roles",
+				bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,581 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+var _ Handler = (*ArticleHandler)(nil)
+
+// likeRankingSize 点赞榜返回多少篇文章
+const likeRankingSize = 100
+
+type ArticleHandler struct {
+	svc        service.ArticleService
+	intrSvc    service.InteractiveService
+	rankingSvc service.RankingService
+	l          logger.LoggerV1
+	biz        string
+}
+
+func NewArticleHandler(svc service.ArticleService,
+	intrSvc service.InteractiveService,
+	rankingSvc service.RankingService,
+	l logger.LoggerV1) *ArticleHandler {
+	return &ArticleHandler{
+		svc:        svc,
+		intrSvc:    intrSvc,
+		rankingSvc: rankingSvc,
+		l:          l,
+		biz:        "article",
+	}
+}
+
+func (h *ArticleHandler) RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry) {
+	g := server.Group("/articles")
+	// 创作者接口
+	g.POST("/edit", h.Edit)
+	g.POST("/publish", h.Publish)
+	g.POST("/withdraw", h.Withdraw)
+	g.POST("/list", h.List)
+	g.GET("/detail/:id", h.Detail)
+
+	// 读者接口，登录了的读者能看到自己有没有点赞、收藏
+	routes.Optional(g).GET("/pub/:id", h.PubDetail)
+	// 榜单
+	pub := routes.Public(g)
+	pub.GET("/ranking/likes", h.LikeRanking)
+	pub.GET("/ranking/hot", h.HotRanking)
+
+	// 点赞和取消点赞
+	g.POST("/like", h.Like)
+	// 收藏和取消收藏
+	g.POST("/collect", h.Collect)
+
+	// 我点过赞、收藏过的文章，路径放在用户下面
+	server.GET("/users/likes", h.UserLikes)
+	server.GET("/users/collections", h.UserCollections)
+}
+
+// Edit 接收 Article 输入，返回一个 ID，文章的 ID
+func (h *ArticleHandler) Edit(ctx *gin.Context) {
+	var req ArticleReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	id, err := h.svc.Save(ctx, req.toDomain(uc.Uid))
+	if h.isIllegalStatus(err) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章当前状态不允许该操作",
+		})
+		h.l.Warn("非法的文章状态迁移",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("保存文章数据失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: id,
+	})
+}
+
+func (h *ArticleHandler) Publish(ctx *gin.Context) {
+	var req ArticleReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	id, err := h.svc.Publish(ctx, req.toDomain(uc.Uid))
+	if h.isIllegalStatus(err) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章当前状态不允许该操作",
+		})
+		h.l.Warn("非法的文章状态迁移",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("发表文章失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: id,
+	})
+}
+
+func (h *ArticleHandler) Withdraw(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.svc.Withdraw(ctx, uc.Uid, req.Id)
+	if h.isIllegalStatus(err) {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章当前状态不允许该操作",
+		})
+		h.l.Warn("非法的文章状态迁移",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("撤回文章失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "aid", Val: req.Id},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// List 创作者查看自己的文章列表
+func (h *ArticleHandler) List(ctx *gin.Context) {
+	var req ListReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	// 限制一下，防止一次性查太多
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	arts, err := h.svc.GetByAuthor(ctx, uc.Uid, req.Offset, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查找文章列表失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ids := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		ids = append(ids, art.Id)
+	}
+	intrs, err := h.intrSvc.GetByIds(ctx, h.biz, ids, uc.Uid)
+	if err != nil {
+		// 互动数据查不到，不影响列表本身
+		h.l.Error("批量查询互动数据失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+	}
+	res := make([]ArticleVO, 0, len(arts))
+	for _, art := range arts {
+		// 列表页不需要返回内容，只要摘要
+		vo := h.toVO(art)
+		vo.Content = ""
+		intr := intrs[art.Id]
+		vo.ReadCnt = intr.ReadCnt
+		vo.LikeCnt = intr.LikeCnt
+		vo.CollectCnt = intr.CollectCnt
+		vo.Liked = intr.Liked
+		vo.Collected = intr.Collected
+		res = append(res, vo)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// Detail 创作者查看自己的文章详情
+func (h *ArticleHandler) Detail(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	art, err := h.svc.GetById(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询文章失败",
+			logger.Field{Key: "aid", Val: id},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	if art.Author.Id != uc.Uid {
+		// 有人在搞鬼，看别人的草稿
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "输入有误",
+		})
+		h.l.Error("非法访问文章，创作者 ID 不匹配",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "aid", Val: id})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: h.toVO(art),
+	})
+}
+
+// PubDetail 读者查看已发表的文章
+func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	art, err := h.svc.GetPubById(ctx, id)
+	switch err {
+	case nil:
+	case service.ErrArticleNotFound, service.ErrArticleNotPublished:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章不存在",
+		})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询已发表文章失败",
+			logger.Field{Key: "aid", Val: id},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+
+	// 增加阅读计数，不需要阻塞读者
+	go func() {
+		er := h.intrSvc.IncrReadCont(context.Background(), h.biz, id)
+		if er != nil {
+			h.l.Error("增加阅读计数失败",
+				logger.Field{Key: "aid", Val: id},
+				logger.Field{Key: "error", Val: er})
+		}
+	}()
+
+	vo := h.toVO(art)
+	// 没有登录的读者 uid 是 0，不查点赞、收藏状态
+	var uid int64
+	if val, ok := ctx.Get("user"); ok {
+		uid = val.(ijwt.UserClaims).Uid
+	}
+	intr, err := h.intrSvc.Get(ctx, h.biz, id, uid)
+	if err != nil {
+		// 拿不到计数也不影响读者看文章
+		h.l.Error("查询文章的互动数据失败",
+			logger.Field{Key: "aid", Val: id},
+			logger.Field{Key: "error", Val: err})
+	}
+	vo.ReadCnt = intr.ReadCnt
+	vo.LikeCnt = intr.LikeCnt
+	vo.CollectCnt = intr.CollectCnt
+	vo.Liked = intr.Liked
+	vo.Collected = intr.Collected
+	ctx.JSON(http.StatusOK, Result{
+		Data: vo,
+	})
+}
+
+// Like 点赞和取消点赞，重复操作是幂等的
+func (h *ArticleHandler) Like(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// true 是点赞，false 是取消点赞
+		Like bool `json:"like"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	var err error
+	if req.Like {
+		err = h.intrSvc.Like(ctx, h.biz, req.Id, uc.Uid)
+	} else {
+		err = h.intrSvc.CancelLike(ctx, h.biz, req.Id, uc.Uid)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("点赞/取消点赞失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "aid", Val: req.Id},
+			logger.Field{Key: "like", Val: req.Like},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// Collect 收藏到指定的收藏夹，或者取消收藏
+func (h *ArticleHandler) Collect(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// 收藏夹 ID，0 是默认收藏夹
+		Cid int64 `json:"cid"`
+		// true 是收藏，false 是取消收藏
+		Collect bool `json:"collect"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	var err error
+	if req.Collect {
+		err = h.intrSvc.Collect(ctx, h.biz, req.Id, req.Cid, uc.Uid)
+	} else {
+		err = h.intrSvc.CancelCollect(ctx, h.biz, req.Id, uc.Uid)
+	}
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "收藏夹不存在",
+		})
+	case service.ErrDuplicateCollection:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "已经收藏过了",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("收藏/取消收藏失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "aid", Val: req.Id},
+			logger.Field{Key: "cid", Val: req.Cid},
+			logger.Field{Key: "error", Val: err})
+	}
+}
+
+// LikeRanking 点赞榜，window 可以是 24h、7d 和 all，默认是 24h
+func (h *ArticleHandler) LikeRanking(ctx *gin.Context) {
+	window := domain.LikeRankWindow(ctx.DefaultQuery("window", string(domain.LikeRankWindowDay)))
+	if !window.Valid() {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	items, err := h.intrSvc.TopLiked(ctx, h.biz, window, likeRankingSize)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询点赞榜失败",
+			logger.Field{Key: "window", Val: window},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.BizId)
+	}
+	// 已经撤回、删除的文章不会在结果里面
+	arts, err := h.svc.GetPubByIds(ctx, ids)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询点赞榜文章失败",
+			logger.Field{Key: "window", Val: window},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	likeCnts := make(map[int64]int64, len(items))
+	for _, item := range items {
+		likeCnts[item.BizId] = item.LikeCnt
+	}
+	res := make([]ArticleVO, 0, len(arts))
+	for _, art := range arts {
+		vo := h.toVO(art)
+		vo.Content = ""
+		// 窗口内的点赞数
+		vo.LikeCnt = likeCnts[art.Id]
+		res = append(res, vo)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// HotRanking 热榜，由定时任务计算好，这里直接返回
+func (h *ArticleHandler) HotRanking(ctx *gin.Context) {
+	arts, err := h.rankingSvc.GetTopN(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询热榜失败", logger.Field{Key: "error", Val: err})
+		return
+	}
+	res := make([]ArticleVO, 0, len(arts))
+	for _, art := range arts {
+		// 热榜里面存的内容就是摘要
+		vo := h.toVO(art)
+		vo.Content = ""
+		res = append(res, vo)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// UserLikes 我点过赞的文章，按照第一次点赞的时间倒序。
+// 取消之后再点赞复用的是原来的记录，不会排到前面
+func (h *ArticleHandler) UserLikes(ctx *gin.Context) {
+	h.listUserArticles(ctx, "查询点赞列表失败", h.intrSvc.ListLiked)
+}
+
+// UserCollections 我收藏过的文章，最近收藏的在前面
+func (h *ArticleHandler) UserCollections(ctx *gin.Context) {
+	h.listUserArticles(ctx, "查询收藏列表失败", h.intrSvc.ListCollected)
+}
+
+func (h *ArticleHandler) listUserArticles(ctx *gin.Context, errMsg string,
+	list func(ctx context.Context, biz string, uid int64, cursor int64, limit int) ([]domain.UserBiz, error)) {
+	var req UserArticlesReq
+	if err := ctx.BindQuery(&req); err != nil {
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	items, err := list(ctx, h.biz, uc.Uid, req.Cursor, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error(errMsg,
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.BizId)
+	}
+	// 已经撤回、删除的文章不会在结果里面
+	arts, err := h.svc.GetPubByIds(ctx, ids)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error(errMsg,
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	artMap := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		artMap[art.Id] = art
+	}
+	res := UserArticlesVO{
+		Articles: make([]UserArticleVO, 0, len(items)),
+		Cursor:   req.Cursor,
+		// 不够一页就是没有更多了，游标是按照点赞、收藏记录算的，和文章有没有被过滤掉无关
+		HasMore: len(items) == req.Limit,
+	}
+	for _, item := range items {
+		res.Cursor = item.Id
+		art, ok := artMap[item.BizId]
+		if !ok {
+			continue
+		}
+		vo := h.toVO(art)
+		vo.Content = ""
+		res.Articles = append(res.Articles, UserArticleVO{
+			ArticleVO:  vo,
+			ActionTime: item.Utime.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// isIllegalStatus 状态机拒绝的操作，是用户的问题，不是系统错误
+func (h *ArticleHandler) isIllegalStatus(err error) bool {
+	return isIllegalArticleStatus(err)
+}
+
+func isIllegalArticleStatus(err error) bool {
+	var stErr *domain.ArticleStatusTransitionError
+	return errors.As(err, &stErr)
+}
+
+func (h *ArticleHandler) toVO(art domain.Article) ArticleVO {
+	return ArticleVO{
+		Id:         art.Id,
+		Title:      art.Title,
+		Abstract:   art.Abstract(),
+		Content:    art.Content,
+		AuthorId:   art.Author.Id,
+		AuthorName: art.Author.Name,
+		Status:     art.Status.ToUint8(),
+		Ctime:      art.Ctime.Format(time.DateTime),
+		Utime:      art.Utime.Format(time.DateTime),
+	}
+}
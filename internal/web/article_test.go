@@ -0,0 +1,107 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestArticleHandler_UserLikes(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService)
+		url  string
+
+		wantRes UserArticlesVO
+	}{
+		{
+			name: "第一页，撤回的文章被过滤掉",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().ListLiked(gomock.Any(), "article", int64(123), int64(0), 2).
+					Return([]domain.UserBiz{
+						{Id: 20, Biz: "article", BizId: 2, Utime: now},
+						{Id: 10, Biz: "article", BizId: 1, Utime: now},
+					}, nil)
+				artSvc.EXPECT().GetPubByIds(gomock.Any(), []int64{2, 1}).
+					Return([]domain.Article{{Id: 2, Title: "标题2", Ctime: now, Utime: now}}, nil)
+				return artSvc, intrSvc
+			},
+			url: "/users/likes?limit=2",
+			wantRes: UserArticlesVO{
+				Articles: []UserArticleVO{
+					{
+						ArticleVO: ArticleVO{
+							Id:    2,
+							Title: "标题2",
+							Ctime: now.Format(time.DateTime),
+							Utime: now.Format(time.DateTime),
+						},
+						ActionTime: now.Format(time.DateTime),
+					},
+				},
+				Cursor:  10,
+				HasMore: true,
+			},
+		},
+		{
+			name: "最后一页",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().ListLiked(gomock.Any(), "article", int64(123), int64(10), 20).
+					Return([]domain.UserBiz{}, nil)
+				artSvc.EXPECT().GetPubByIds(gomock.Any(), []int64{}).
+					Return([]domain.Article{}, nil)
+				return artSvc, intrSvc
+			},
+			url: "/users/likes?cursor=10",
+			wantRes: UserArticlesVO{
+				Articles: []UserArticleVO{},
+				Cursor:   10,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc, intrSvc := tc.mock(ctrl)
+			hdl := NewArticleHandler(artSvc, intrSvc, nil, logger.NewNopLogger())
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res struct {
+				Code int            `json:"code"`
+				Data UserArticlesVO `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, 0, res.Code)
+			assert.Equal(t, tc.wantRes, res.Data)
+		})
+	}
+}
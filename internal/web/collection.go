@@ -0,0 +1,259 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+var _ Handler = (*CollectionHandler)(nil)
+
+// CollectionHandler 收藏夹管理，收藏和取消收藏在具体的业务里面，比如说 ArticleHandler
+type CollectionHandler struct {
+	svc service.CollectionService
+	l   logger.LoggerV1
+}
+
+func NewCollectionHandler(svc service.CollectionService, l logger.LoggerV1) *CollectionHandler {
+	return &CollectionHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *CollectionHandler) RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry) {
+	g := server.Group("/collections")
+	g.POST("/create", h.Create)
+	g.POST("/rename", h.Rename)
+	g.POST("/delete", h.Delete)
+	g.GET("/list", h.List)
+
+	// 收藏夹里面的内容
+	g.POST("/items", h.ListItems)
+	g.POST("/items/move", h.MoveItem)
+}
+
+func (h *CollectionHandler) Create(ctx *gin.Context) {
+	type Req struct {
+		Name string `json:"name"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	id, err := h.svc.Create(ctx, domain.Collection{
+		Uid:  uc.Uid,
+		Name: req.Name,
+	})
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrInvalidCollection:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "收藏夹名字不能为空，并且不能超过 128 个字",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("创建收藏夹失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+	}
+}
+
+func (h *CollectionHandler) Rename(ctx *gin.Context) {
+	type Req struct {
+		Id   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.svc.Rename(ctx, uc.Uid, req.Id, req.Name)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrInvalidCollection:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "收藏夹名字不能为空，并且不能超过 128 个字",
+		})
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "收藏夹不存在",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("重命名收藏夹失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "cid", Val: req.Id},
+			logger.Field{Key: "error", Val: err})
+	}
+}
+
+// Delete 删除收藏夹，里面的收藏也会一起删掉
+func (h *CollectionHandler) Delete(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.svc.Delete(ctx, uc.Uid, req.Id)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "收藏夹不存在",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("删除收藏夹失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "cid", Val: req.Id},
+			logger.Field{Key: "error", Val: err})
+	}
+}
+
+func (h *CollectionHandler) List(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	colls, err := h.svc.List(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询收藏夹列表失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	res := make([]CollectionVO, 0, len(colls))
+	for _, c := range colls {
+		res = append(res, CollectionVO{
+			Id:    c.Id,
+			Name:  c.Name,
+			Ctime: c.Ctime.Format(time.DateTime),
+			Utime: c.Utime.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// ListItems 分页查询收藏夹里面的收藏
+func (h *CollectionHandler) ListItems(ctx *gin.Context) {
+	type Req struct {
+		// 收藏夹 ID，0 是默认收藏夹
+		Cid    int64 `json:"cid"`
+		Offset int   `json:"offset"`
+		Limit  int   `json:"limit"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	items, err := h.svc.ListItems(ctx, uc.Uid, req.Cid, req.Offset, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询收藏夹内容失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "cid", Val: req.Cid},
+			logger.Field{Key: "error", Val: err})
+		return
+	}
+	res := make([]CollectionItemVO, 0, len(items))
+	for _, item := range items {
+		res = append(res, CollectionItemVO{
+			Biz:   item.Biz,
+			BizId: item.BizId,
+			Cid:   item.Cid,
+			Ctime: item.Ctime.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// MoveItem 把一条收藏挪到另外一个收藏夹
+func (h *CollectionHandler) MoveItem(ctx *gin.Context) {
+	type Req struct {
+		Biz   string `json:"biz"`
+		BizId int64  `json:"bizId"`
+		// 目标收藏夹
+		Cid int64 `json:"cid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Biz == "" {
+		req.Biz = "article"
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.svc.MoveItem(ctx, uc.Uid, req.Biz, req.BizId, req.Cid)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "收藏夹不存在",
+		})
+	case service.ErrCollectItemNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "没有收藏过",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("移动收藏失败",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "biz_id", Val: req.BizId},
+			logger.Field{Key: "cid", Val: req.Cid},
+			logger.Field{Key: "error", Val: err})
+	}
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"net/http"
+
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler 公开 access token 的公钥，别的服务拿它校验 token，不需要共享密钥
+type JWKSHandler struct {
+	keys *ijwt.KeyRing
+}
+
+func NewJWKSHandler(keys *ijwt.KeyRings) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys.Access,
+	}
+}
+
+func (h *JWKSHandler) RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry) {
+	routes.Public(&server.RouterGroup).GET("/.well-known/jwks.json", h.JWKS)
+}
+
+// JWKS 按照 RFC 7517 的格式返回，不包在 Result 里面
+func (h *JWKSHandler) JWKS(ctx *gin.Context) {
+	// 轮换密钥的时候，新密钥要提前加进来，保证别的服务缓存过期之前就能拿到
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,200 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKid = errors.New("未知的 kid")
+	ErrRetiredKey = errors.New("密钥已经退役")
+)
+
+// Key 一把签名密钥
+type Key struct {
+	Kid string
+	// Method 支持 HS512、RS256 和 EdDSA，为空的时候是 HS512
+	Method jwt.SigningMethod
+	// Secret HS512 的密钥
+	Secret []byte
+	// PrivateKey RS256 用 *rsa.PrivateKey，EdDSA 用 ed25519.PrivateKey，
+	// 公钥会通过 JWKS 公开出去，别的服务不需要知道密钥就能校验 token
+	PrivateKey crypto.Signer
+	// Retired 退役之后不再用来校验，用它签发的 token 马上全部失效
+	Retired bool
+}
+
+type ringKey struct {
+	Key
+	signKey   any
+	verifyKey any
+}
+
+func newRingKey(key Key) (ringKey, error) {
+	if key.Kid == "" {
+		return ringKey{}, errors.New("kid 不能为空")
+	}
+	if key.Method == nil {
+		key.Method = jwt.SigningMethodHS512
+	}
+	rk := ringKey{Key: key}
+	switch key.Method {
+	case jwt.SigningMethodHS512:
+		if len(key.Secret) == 0 {
+			return ringKey{}, fmt.Errorf("密钥 %s 为空", key.Kid)
+		}
+		rk.signKey, rk.verifyKey = key.Secret, key.Secret
+	case jwt.SigningMethodRS256:
+		pk, ok := key.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return ringKey{}, fmt.Errorf("密钥 %s 不是 RSA 私钥", key.Kid)
+		}
+		rk.signKey, rk.verifyKey = pk, &pk.PublicKey
+	case jwt.SigningMethodEdDSA:
+		pk, ok := key.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			return ringKey{}, fmt.Errorf("密钥 %s 不是 Ed25519 私钥", key.Kid)
+		}
+		rk.signKey, rk.verifyKey = pk, pk.Public()
+	default:
+		return ringKey{}, fmt.Errorf("密钥 %s 的算法 %s 不支持", key.Kid, key.Method.Alg())
+	}
+	return rk, nil
+}
+
+// KeyRing 一组用 kid 区分的密钥。
+// 签名永远用当前密钥，并且把 kid 写进 token 头部；校验的时候按照 kid 找密钥。
+// 轮换的时候先加新密钥并切过去，旧密钥签发的 token 过期之后再把旧密钥退役，
+// 这样用户不会被踢下线
+type KeyRing struct {
+	active ringKey
+	keys   map[string]ringKey
+	// 环里面所有密钥用到的算法，其它算法的 token 一律拒绝
+	methods []string
+}
+
+func NewKeyRing(active string, keys []Key) (*KeyRing, error) {
+	ring := &KeyRing{
+		keys: make(map[string]ringKey, len(keys)),
+	}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		rk, err := newRingKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ring.keys[rk.Kid]; ok {
+			return nil, fmt.Errorf("kid %s 重复", rk.Kid)
+		}
+		ring.keys[rk.Kid] = rk
+		if alg := rk.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			ring.methods = append(ring.methods, alg)
+		}
+	}
+	key, ok := ring.keys[active]
+	if !ok {
+		return nil, fmt.Errorf("当前密钥 %s 不存在", active)
+	}
+	if key.Retired {
+		return nil, fmt.Errorf("当前密钥 %s 已经退役", active)
+	}
+	ring.active = key
+	return ring, nil
+}
+
+// Sign 用当前密钥签名
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.active.Method, claims)
+	token.Header["kid"] = r.active.Kid
+	return token.SignedString(r.active.signKey)
+}
+
+// Parse 解析并且校验 token，token 过期、签名不对、kid 未知或者已经退役都会返回 error
+func (r *KeyRing) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, r.keyFunc,
+		jwt.WithValidMethods(r.methods))
+}
+
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKid
+	}
+	if key.Retired {
+		return nil, ErrRetiredKey
+	}
+	// 算法必须和 kid 对应的密钥一致，
+	// 否则有人可以把 RSA 公钥当成 HMAC 密钥来伪造 token
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.verifyKey, nil
+}
+
+// JWK 见 RFC 7517，只放校验签名需要的字段
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA 公钥
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 公钥
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 没有退役的非对称密钥的公钥，HMAC 的密钥不能公开
+func (r *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if key.Retired {
+			continue
+		}
+		jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// KeyRings 不同用途的 token 用不同的密钥，
+// 避免 refresh token 被拿来当 access token 用
+type KeyRings struct {
+	// Access 别的服务要校验 access token，适合用非对称密钥
+	Access  *KeyRing
+	Refresh *KeyRing
+	// State 第三方登录时保护 state 的 cookie
+	State *KeyRing
+	// TwoFactor 密码对了、还没有通过两步验证时的临时 token
+	TwoFactor *KeyRing
+}
@@ -0,0 +1,182 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRing_Rotate(t *testing.T) {
+	oldKey := Key{Kid: "old", Secret: []byte("old-secret")}
+	newKey := Key{Kid: "new", Secret: []byte("new-secret")}
+	oldRing, err := NewKeyRing("old", []Key{oldKey})
+	require.NoError(t, err)
+	oldToken, err := oldRing.Sign(UserClaims{Uid: 123, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		active string
+		keys   []Key
+		token  string
+
+		wantUid int64
+		wantErr error
+	}{
+		{
+			name:    "切换到新密钥之后，旧 token 还能用",
+			active:  "new",
+			keys:    []Key{oldKey, newKey},
+			token:   oldToken,
+			wantUid: 123,
+		},
+		{
+			name:   "旧密钥退役之后，旧 token 失效",
+			active: "new",
+			keys: []Key{
+				{Kid: "old", Secret: oldKey.Secret, Retired: true},
+				newKey,
+			},
+			token:   oldToken,
+			wantErr: ErrRetiredKey,
+		},
+		{
+			name:    "旧密钥被删掉了",
+			active:  "new",
+			keys:    []Key{newKey},
+			token:   oldToken,
+			wantErr: ErrUnknownKid,
+		},
+		{
+			name:    "kid 相同但是密钥不同，比如拿 access token 当 refresh token",
+			active:  "old",
+			keys:    []Key{{Kid: "old", Secret: []byte("refresh-secret")}},
+			token:   oldToken,
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ring, err := NewKeyRing(tc.active, tc.keys)
+			require.NoError(t, err)
+			var uc UserClaims
+			_, err = ring.Parse(tc.token, &uc)
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantUid, uc.Uid)
+		})
+	}
+}
+
+func TestKeyRing_Sign(t *testing.T) {
+	ring, err := NewKeyRing("new", []Key{
+		{Kid: "old", Secret: []byte("old-secret")},
+		{Kid: "new", Secret: []byte("new-secret")},
+	})
+	require.NoError(t, err)
+	tokenStr, err := ring.Sign(UserClaims{Uid: 123})
+	require.NoError(t, err)
+	var uc UserClaims
+	token, err := ring.Parse(tokenStr, &uc)
+	require.NoError(t, err)
+	// 永远用当前密钥签名
+	assert.Equal(t, "new", token.Header["kid"])
+}
+
+func TestNewKeyRing(t *testing.T) {
+	_, err := NewKeyRing("none", []Key{{Kid: "old", Secret: []byte("old-secret")}})
+	assert.Error(t, err)
+	_, err = NewKeyRing("old", []Key{{Kid: "old", Secret: []byte("old-secret"), Retired: true}})
+	assert.Error(t, err)
+	_, err = NewKeyRing("old", []Key{
+		{Kid: "old", Secret: []byte("old-secret")},
+		{Kid: "old", Secret: []byte("new-secret")},
+	})
+	assert.Error(t, err)
+}
+
+func TestKeyRing_Asymmetric(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := []Key{
+		{Kid: "hmac", Secret: []byte("secret")},
+		{Kid: "rsa", Method: jwt.SigningMethodRS256, PrivateKey: rsaKey},
+		{Kid: "ed", Method: jwt.SigningMethodEdDSA, PrivateKey: edKey},
+	}
+	for _, active := range []string{"hmac", "rsa", "ed"} {
+		t.Run(active, func(t *testing.T) {
+			ring, err := NewKeyRing(active, keys)
+			require.NoError(t, err)
+			tokenStr, err := ring.Sign(UserClaims{Uid: 123})
+			require.NoError(t, err)
+			var uc UserClaims
+			_, err = ring.Parse(tokenStr, &uc)
+			require.NoError(t, err)
+			assert.Equal(t, int64(123), uc.Uid)
+		})
+	}
+
+	// 头部声称是 HS512，但是 kid 对应的是 RSA 密钥
+	ring, err := NewKeyRing("rsa", keys)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS512, UserClaims{Uid: 456})
+	forged.Header["kid"] = "rsa"
+	forgedStr, err := forged.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ring.Parse(forgedStr, &UserClaims{})
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestKeyRing_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ring, err := NewKeyRing("rsa", []Key{
+		{Kid: "hmac", Secret: []byte("secret")},
+		{Kid: "old", Method: jwt.SigningMethodEdDSA, PrivateKey: edKey, Retired: true},
+		{Kid: "ed", Method: jwt.SigningMethodEdDSA, PrivateKey: edKey},
+		{Kid: "rsa", Method: jwt.SigningMethodRS256, PrivateKey: rsaKey},
+	})
+	require.NoError(t, err)
+
+	// HMAC 的密钥和退役的密钥都不能出现
+	set := ring.JWKS()
+	require.Len(t, set.Keys, 2)
+	ed, rsaJWK := set.Keys[0], set.Keys[1]
+	assert.Equal(t, JWK{
+		Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+		X: base64.RawURLEncoding.EncodeToString(edPub),
+	}, ed)
+	assert.Equal(t, "RSA", rsaJWK.Kty)
+	assert.Equal(t, "RS256", rsaJWK.Alg)
+
+	// 别的服务只拿 JWKS 里面的公钥就能校验 token
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	require.NoError(t, err)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	tokenStr, err := ring.Sign(UserClaims{Uid: 123})
+	require.NoError(t, err)
+	var uc UserClaims
+	_, err = jwt.ParseWithClaims(tokenStr, &uc, func(token *jwt.Token) (interface{}, error) {
+		return pub, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), uc.Uid)
+}
@@ -0,0 +1,26 @@
+-- 每个会话只有最新的 refresh token 有效。
+-- 拿着已经用过的 refresh token 来刷新，说明 token 很可能被偷了，
+-- 分不清哪边是真正的用户，只能把整个会话都踢掉。
+-- 例外是刚刚被换掉的那个 refresh token：多个标签页同时刷新是正常的，
+-- 宽限期内还能用，拿到的是当前最新的 refresh token，不会再轮换一次
+local vals = redis.call("HMGET", KEYS[1], "uid", "rt", "prev_rt", "prev_rt_until")
+if vals[1] ~= ARGV[1] then
+    -- 会话已经退出或者过期了
+    return {0, ""}
+end
+local now = tonumber(ARGV[4])
+if vals[2] ~= ARGV[2] then
+    if vals[3] == ARGV[2] and now <= tonumber(vals[4]) then
+        redis.call("HSET", KEYS[1], "last_seen", ARGV[4])
+        return {2, vals[2]}
+    end
+    redis.call("DEL", KEYS[1])
+    redis.call("SREM", KEYS[2], ARGV[6])
+    return {-1, ""}
+end
+redis.call("HSET", KEYS[1], "rt", ARGV[3], "last_seen", ARGV[4],
+        "prev_rt", ARGV[2], "prev_rt_until", now + tonumber(ARGV[7]))
+-- 新的 refresh token 又能用一段时间，会话也跟着续期
+redis.call("PEXPIRE", KEYS[1], ARGV[5])
+redis.call("PEXPIRE", KEYS[2], ARGV[5])
+return {1, ARGV[3]}
@@ -0,0 +1,13 @@
+-- 会话还在，并且确实属于这个用户，才更新最后活跃时间。
+-- 不能先查再写，否则会把刚刚被踢掉的会话又写回去
+local uid = redis.call("HGET", KEYS[1], "uid")
+if uid ~= ARGV[1] then
+    return 0
+end
+-- 用户的角色改过了，token 里面的权限已经过时，要刷新 token
+local version = tonumber(redis.call("GET", KEYS[2]) or "0")
+if version > tonumber(ARGV[3]) then
+    return -1
+end
+redis.call("HSET", KEYS[1], "last_seen", ARGV[2])
+return 1
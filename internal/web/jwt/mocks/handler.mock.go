@@ -0,0 +1,255 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/web/jwt/types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./webook/internal/web/jwt/types.go -package=jwtmocks -destination=./webook/internal/web/jwt/mocks/handler.mock.go
+//
+// Package jwtmocks is a generated GoMock package.
+package jwtmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	jwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	gin "github.com/gin-gonic/gin"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockHandler is a mock of Handler interface.
+type MockHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockHandlerMockRecorder
+}
+
+// MockHandlerMockRecorder is the mock recorder for MockHandler.
+type MockHandlerMockRecorder struct {
+	mock *MockHandler
+}
+
+// NewMockHandler creates a new mock instance.
+func NewMockHandler(ctrl *gomock.Controller) *MockHandler {
+	mock := &MockHandler{ctrl: ctrl}
+	mock.recorder = &MockHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
+	return m.recorder
+}
+
+// CheckSession mocks base method.
+func (m *MockHandler) CheckSession(ctx *gin.Context, uc jwt.UserClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSession", ctx, uc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSession indicates an expected call of CheckSession.
+func (mr *MockHandlerMockRecorder) CheckSession(ctx, uc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSession", reflect.TypeOf((*MockHandler)(nil).CheckSession), ctx, uc)
+}
+
+// ClearToken mocks base method.
+func (m *MockHandler) ClearToken(ctx *gin.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearToken", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearToken indicates an expected call of ClearToken.
+func (mr *MockHandlerMockRecorder) ClearToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearToken", reflect.TypeOf((*MockHandler)(nil).ClearToken), ctx)
+}
+
+// ExtractToken mocks base method.
+func (m *MockHandler) ExtractToken(ctx *gin.Context) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractToken", ctx)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ExtractToken indicates an expected call of ExtractToken.
+func (mr *MockHandlerMockRecorder) ExtractToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractToken", reflect.TypeOf((*MockHandler)(nil).ExtractToken), ctx)
+}
+
+// InvalidateClaims mocks base method.
+func (m *MockHandler) InvalidateClaims(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateClaims", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateClaims indicates an expected call of InvalidateClaims.
+func (mr *MockHandlerMockRecorder) InvalidateClaims(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateClaims", reflect.TypeOf((*MockHandler)(nil).InvalidateClaims), ctx, uid)
+}
+
+// ListSessions mocks base method.
+func (m *MockHandler) ListSessions(ctx context.Context, uid int64) ([]jwt.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, uid)
+	ret0, _ := ret[0].([]jwt.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockHandlerMockRecorder) ListSessions(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockHandler)(nil).ListSessions), ctx, uid)
+}
+
+// RefreshTokens mocks base method.
+func (m *MockHandler) RefreshTokens(ctx *gin.Context, rc jwt.RefreshClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokens", ctx, rc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RefreshTokens indicates an expected call of RefreshTokens.
+func (mr *MockHandlerMockRecorder) RefreshTokens(ctx, rc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokens", reflect.TypeOf((*MockHandler)(nil).RefreshTokens), ctx, rc)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockHandler) RevokeAllSessions(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockHandlerMockRecorder) RevokeAllSessions(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockHandler)(nil).RevokeAllSessions), ctx, uid)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockHandler) RevokeOtherSessions(ctx context.Context, uid int64, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", ctx, uid, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockHandlerMockRecorder) RevokeOtherSessions(ctx, uid, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockHandler)(nil).RevokeOtherSessions), ctx, uid, ssid)
+}
+
+// RevokeSession mocks base method.
+func (m *MockHandler) RevokeSession(ctx context.Context, uid int64, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, uid, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockHandlerMockRecorder) RevokeSession(ctx, uid, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockHandler)(nil).RevokeSession), ctx, uid, ssid)
+}
+
+// SetJWTToken mocks base method.
+func (m *MockHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetJWTToken", ctx, uid, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetJWTToken indicates an expected call of SetJWTToken.
+func (mr *MockHandlerMockRecorder) SetJWTToken(ctx, uid, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetJWTToken", reflect.TypeOf((*MockHandler)(nil).SetJWTToken), ctx, uid, ssid)
+}
+
+// SetLoginToken mocks base method.
+func (m *MockHandler) SetLoginToken(ctx *gin.Context, uid int64, method string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLoginToken", ctx, uid, method)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLoginToken indicates an expected call of SetLoginToken.
+func (mr *MockHandlerMockRecorder) SetLoginToken(ctx, uid, method any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLoginToken", reflect.TypeOf((*MockHandler)(nil).SetLoginToken), ctx, uid, method)
+}
+
+// SetTwoFactorToken mocks base method.
+func (m *MockHandler) SetTwoFactorToken(ctx *gin.Context, uid int64, method string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTwoFactorToken", ctx, uid, method)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTwoFactorToken indicates an expected call of SetTwoFactorToken.
+func (mr *MockHandlerMockRecorder) SetTwoFactorToken(ctx, uid, method any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTwoFactorToken", reflect.TypeOf((*MockHandler)(nil).SetTwoFactorToken), ctx, uid, method)
+}
+
+// VerifyAccessToken mocks base method.
+func (m *MockHandler) VerifyAccessToken(tokenStr string) (jwt.UserClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyAccessToken", tokenStr)
+	ret0, _ := ret[0].(jwt.UserClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAccessToken indicates an expected call of VerifyAccessToken.
+func (mr *MockHandlerMockRecorder) VerifyAccessToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAccessToken", reflect.TypeOf((*MockHandler)(nil).VerifyAccessToken), tokenStr)
+}
+
+// VerifyRefreshToken mocks base method.
+func (m *MockHandler) VerifyRefreshToken(tokenStr string) (jwt.RefreshClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyRefreshToken", tokenStr)
+	ret0, _ := ret[0].(jwt.RefreshClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyRefreshToken indicates an expected call of VerifyRefreshToken.
+func (mr *MockHandlerMockRecorder) VerifyRefreshToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyRefreshToken", reflect.TypeOf((*MockHandler)(nil).VerifyRefreshToken), tokenStr)
+}
+
+// VerifyTwoFactorToken mocks base method.
+func (m *MockHandler) VerifyTwoFactorToken(tokenStr string) (jwt.TwoFactorClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyTwoFactorToken", tokenStr)
+	ret0, _ := ret[0].(jwt.TwoFactorClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyTwoFactorToken indicates an expected call of VerifyTwoFactorToken.
+func (mr *MockHandlerMockRecorder) VerifyTwoFactorToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTwoFactorToken", reflect.TypeOf((*MockHandler)(nil).VerifyTwoFactorToken), tokenStr)
+}
@@ -2,7 +2,7 @@ package jwt
 
 import (
 	"errors"
-	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -12,25 +12,50 @@ import (
 )
 
 type RedisJWTHandler struct {
-	client        redis.Cmdable
-	signingMethod jwt.SigningMethod
-	rcExpiration  time.Duration
+	client  redis.Cmdable
+	keys    *KeyRings
+	rbacSvc service.RBACService
+	// refresh token 的有效期，也是会话的有效期
+	rcExpiration time.Duration
+	// 两步验证要在这段时间内完成
+	tfExpiration time.Duration
 }
 
-func NewRedisJWTHandler(client redis.Cmdable) Handler {
+func NewRedisJWTHandler(client redis.Cmdable, keys *KeyRings,
+	rbacSvc service.RBACService) Handler {
 	return &RedisJWTHandler{
-		client:        client,
-		signingMethod: jwt.SigningMethodHS512,
-		rcExpiration:  time.Hour * 24 * 7,
+		client:       client,
+		keys:         keys,
+		rbacSvc:      rbacSvc,
+		rcExpiration: time.Hour * 24 * 7,
+		tfExpiration: time.Minute * 5,
 	}
 }
 
-func (h *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
-	cnt, err := h.client.Exists(ctx, fmt.Sprintf("users:ssid:%s", ssid)).Result()
+func (h *RedisJWTHandler) VerifyAccessToken(tokenStr string) (UserClaims, error) {
+	var uc UserClaims
+	err := h.verify(h.keys.Access, tokenStr, &uc)
+	return uc, err
+}
+
+func (h *RedisJWTHandler) VerifyRefreshToken(tokenStr string) (RefreshClaims, error) {
+	var rc RefreshClaims
+	err := h.verify(h.keys.Refresh, tokenStr, &rc)
+	return rc, err
+}
+
+func (h *RedisJWTHandler) VerifyTwoFactorToken(tokenStr string) (TwoFactorClaims, error) {
+	var tc TwoFactorClaims
+	err := h.verify(h.keys.TwoFactor, tokenStr, &tc)
+	return tc, err
+}
+
+func (h *RedisJWTHandler) verify(ring *KeyRing, tokenStr string, claims jwt.Claims) error {
+	token, err := ring.Parse(tokenStr, claims)
 	if err != nil {
 		return err
 	}
-	if cnt > 0 {
+	if token == nil || !token.Valid {
 		return errors.New("token 无效")
 	}
 	return nil
@@ -52,9 +77,14 @@ func (h *RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
 
 var _ Handler = &RedisJWTHandler{}
 
-func (h *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
+func (h *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64, method string) error {
 	ssid := uuid.New().String()
-	err := h.setRefreshToken(ctx, uid, ssid)
+	jti := uuid.New().String()
+	err := h.createSession(ctx, uid, ssid, method, jti)
+	if err != nil {
+		return err
+	}
+	err = h.setRefreshToken(ctx, uid, ssid, jti)
 	if err != nil {
 		return err
 	}
@@ -65,24 +95,44 @@ func (h *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
 	ctx.Header("x-jwt-token", "")
 	ctx.Header("x-refresh-token", "")
 	uc := ctx.MustGet("user").(UserClaims)
-
-	return h.client.Set(ctx,
-		fmt.Sprintf("users:ssid:%s", uc.Ssid),
-		"", h.rcExpiration).Err()
+	err := h.RevokeSession(ctx, uc.Uid, uc.Ssid)
+	if err == ErrSessionNotFound {
+		// 已经被别的设备踢掉了
+		return nil
+	}
+	return err
 }
 
 func (h *RedisJWTHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string) error {
+	// 先拿版本再查权限：查权限的时候角色正好被改了，
+	// 拿到的就是旧版本，这个 token 马上会被判定为过时
+	version, err := h.claimsVersion(ctx, uid)
+	if err != nil {
+		return err
+	}
+	perms, err := h.rbacSvc.GetPermissions(ctx, uid)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	// 记下签发时的设备信息，要不要校验看 middleware.TokenBindingPolicy
 	uc := UserClaims{
-		Uid:       uid,
-		Ssid:      ssid,
-		UserAgent: ctx.GetHeader("User-Agent"),
+		Uid:         uid,
+		Ssid:        ssid,
+		UserAgent:   ctx.GetHeader("User-Agent"),
+		IP:          ctx.ClientIP(),
+		Fingerprint: ctx.GetHeader(FingerprintHeader),
+		// 权限放进 token 里面，校验权限的时候不用查数据库
+		Roles:         perms.Roles,
+		Permissions:   perms.Permissions,
+		ClaimsVersion: version,
 		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(now),
 			// 1 分钟过期
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute * 30)),
 		},
 	}
-	token := jwt.NewWithClaims(h.signingMethod, uc)
-	tokenStr, err := token.SignedString(JWTKey)
+	tokenStr, err := h.keys.Access.Sign(uc)
 	if err != nil {
 		return err
 	}
@@ -90,16 +140,33 @@ func (h *RedisJWTHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string)
 	return nil
 }
 
-func (h *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid string) error {
+func (h *RedisJWTHandler) SetTwoFactorToken(ctx *gin.Context, uid int64, method string) error {
+	tc := TwoFactorClaims{
+		Uid:    uid,
+		Method: method,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.tfExpiration)),
+		},
+	}
+	tokenStr, err := h.keys.TwoFactor.Sign(tc)
+	if err != nil {
+		return err
+	}
+	ctx.Header("x-2fa-token", tokenStr)
+	return nil
+}
+
+func (h *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid, jti string) error {
 	rc := RefreshClaims{
 		Uid:  uid,
 		Ssid: ssid,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// 每次刷新都换一个 jti，旧的 refresh token 就作废了
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.rcExpiration)),
 		},
 	}
-	token := jwt.NewWithClaims(h.signingMethod, rc)
-	tokenStr, err := token.SignedString(RCJWTKey)
+	tokenStr, err := h.keys.Refresh.Sign(rc)
 	if err != nil {
 		return err
 	}
@@ -107,18 +174,43 @@ func (h *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid stri
 	return nil
 }
 
-var JWTKey = []byte("k6CswdUm77WKcbM68UQUuxVsHSpTCwgK")
-var RCJWTKey = []byte("k6CswdUm77WKcbM68UQUuxVsHSpTCwgA")
-
 type RefreshClaims struct {
 	jwt.RegisteredClaims
 	Uid  int64
 	Ssid string
 }
 
+// TwoFactorClaims 只能拿来完成两步验证，不能当 access token 用
+type TwoFactorClaims struct {
+	jwt.RegisteredClaims
+	Uid int64
+	// Method 第一步的登录方式
+	Method string
+}
+
 type UserClaims struct {
 	jwt.RegisteredClaims
-	Uid       int64
-	Ssid      string
-	UserAgent string
+	Uid         int64
+	Ssid        string
+	UserAgent   string
+	IP          string
+	Fingerprint string
+
+	Roles       []string
+	Permissions []string
+	// ClaimsVersion 签发时角色的版本，角色改了之后这个 token 就过时了
+	ClaimsVersion int64
 }
+
+// HasPermission 有没有 perm 权限
+func (uc UserClaims) HasPermission(perm string) bool {
+	for _, p := range uc.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// FingerprintHeader 前端算出来的设备指纹
+const FingerprintHeader = "X-Device-Fingerprint"
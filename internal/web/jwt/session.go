@@ -0,0 +1,252 @@
+package jwt
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrSessionNotFound = errors.New("会话不存在")
+	ErrSessionRevoked  = errors.New("会话已经退出")
+	// ErrClaimsStale 角色改过了，access token 里面的权限过时了，要用 refresh token 换一个新的
+	ErrClaimsStale = errors.New("token 里面的权限已经过时")
+	// ErrRefreshTokenReused 用过的 refresh token 又被拿来刷新，整个会话已经被踢掉了
+	ErrRefreshTokenReused = errors.New("refresh token 被重复使用")
+)
+
+var (
+	//go:embed lua/touch_session.lua
+	luaTouchSession string
+	//go:embed lua/rotate_refresh_token.lua
+	luaRotateRefreshToken string
+)
+
+// 登录方式，和安全事件里面的一致
+const (
+	LoginMethodPassword = domain.LoginMethodPassword
+	LoginMethodSMS      = domain.LoginMethodSMS
+	LoginMethodEmail    = domain.LoginMethodEmail
+	LoginMethodWechat   = domain.LoginMethodWechat
+)
+
+// Session 一次登录就是一个会话，用 ssid 标识，
+// access token 和 refresh token 里面都带着 ssid
+type Session struct {
+	Ssid        string
+	Uid         int64
+	UserAgent   string
+	IP          string
+	LoginMethod string
+	Ctime       time.Time
+	LastSeen    time.Time
+}
+
+// 每个会话一个 hash，过期时间和 refresh token 一样；
+// 另外用一个 set 记录用户有哪些会话，列出和批量退出的时候用
+func sessionKey(ssid string) string {
+	return fmt.Sprintf("users:session:%s", ssid)
+}
+
+func userSessionsKey(uid int64) string {
+	return fmt.Sprintf("users:sessions:%d", uid)
+}
+
+// claimsVersionKey 用户的角色每改一次就加一，
+// 版本比它小的 access token 都不能再用
+func claimsVersionKey(uid int64) string {
+	return fmt.Sprintf("users:claims_version:%d", uid)
+}
+
+// rt 字段记录这个会话当前有效的 refresh token 的 jti
+func (h *RedisJWTHandler) createSession(ctx *gin.Context, uid int64, ssid, method, jti string) error {
+	now := time.Now().UnixMilli()
+	key := sessionKey(ssid)
+	pipe := h.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		"uid", uid,
+		"ua", ctx.GetHeader("User-Agent"),
+		"ip", ctx.ClientIP(),
+		"method", method,
+		"rt", jti,
+		"ctime", now,
+		"last_seen", now)
+	pipe.Expire(ctx, key, h.rcExpiration)
+	pipe.SAdd(ctx, userSessionsKey(uid), ssid)
+	// 最后一个会话过期之后，整个 set 也跟着过期
+	pipe.Expire(ctx, userSessionsKey(uid), h.rcExpiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// CheckSession 会话还在就顺便更新最后活跃时间
+func (h *RedisJWTHandler) CheckSession(ctx *gin.Context, uc UserClaims) error {
+	res, err := h.client.Eval(ctx, luaTouchSession,
+		[]string{sessionKey(uc.Ssid), claimsVersionKey(uc.Uid)},
+		uc.Uid, time.Now().UnixMilli(), uc.ClaimsVersion).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+		return ErrSessionRevoked
+	case -1:
+		return ErrClaimsStale
+	}
+	return nil
+}
+
+func (h *RedisJWTHandler) InvalidateClaims(ctx context.Context, uid int64) error {
+	return h.client.Incr(ctx, claimsVersionKey(uid)).Err()
+}
+
+func (h *RedisJWTHandler) claimsVersion(ctx context.Context, uid int64) (int64, error) {
+	version, err := h.client.Get(ctx, claimsVersionKey(uid)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+// refreshGracePeriod 刚被换掉的 refresh token 还能用的时间，
+// 多个标签页同时刷新的时候，慢的那个不会被当成 token 被偷了
+const refreshGracePeriod = 5 * time.Second
+
+// RefreshTokens 轮换 refresh token：旧的作废，重新签发 refresh token 和 access token
+func (h *RedisJWTHandler) RefreshTokens(ctx *gin.Context, rc RefreshClaims) error {
+	jti := uuid.New().String()
+	res, err := h.client.Eval(ctx, luaRotateRefreshToken,
+		[]string{sessionKey(rc.Ssid), userSessionsKey(rc.Uid)},
+		rc.Uid, rc.ID, jti, time.Now().UnixMilli(), h.rcExpiration.Milliseconds(), rc.Ssid,
+		refreshGracePeriod.Milliseconds()).Slice()
+	if err != nil {
+		return err
+	}
+	if len(res) != 2 {
+		return fmt.Errorf("轮换 refresh token 的返回值不对 %v", res)
+	}
+	code, _ := res[0].(int64)
+	switch code {
+	case 0:
+		return ErrSessionRevoked
+	case -1:
+		return ErrRefreshTokenReused
+	}
+	// 宽限期内拿到的是别的请求刚刚换好的 jti
+	jti, _ = res[1].(string)
+	err = h.setRefreshToken(ctx, rc.Uid, rc.Ssid, jti)
+	if err != nil {
+		return err
+	}
+	return h.SetJWTToken(ctx, rc.Uid, rc.Ssid)
+}
+
+// ListSessions 按照最后活跃时间倒序
+func (h *RedisJWTHandler) ListSessions(ctx context.Context, uid int64) ([]Session, error) {
+	ssids, err := h.client.SMembers(ctx, userSessionsKey(uid)).Result()
+	if err != nil {
+		return nil, err
+	}
+	pipe := h.client.Pipeline()
+	for _, ssid := range ssids {
+		pipe.HGetAll(ctx, sessionKey(ssid))
+	}
+	cmds, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Session, 0, len(ssids))
+	var expired []any
+	for i, cmd := range cmds {
+		vals, err := cmd.(*redis.MapStringStringCmd).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) == 0 {
+			expired = append(expired, ssids[i])
+			continue
+		}
+		res = append(res, toSession(ssids[i], vals))
+	}
+	if len(expired) > 0 {
+		// 过期的会话顺手清理掉，失败了也不影响结果
+		_ = h.client.SRem(ctx, userSessionsKey(uid), expired...).Err()
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastSeen.After(res[j].LastSeen)
+	})
+	return res, nil
+}
+
+func toSession(ssid string, vals map[string]string) Session {
+	uid, _ := strconv.ParseInt(vals["uid"], 10, 64)
+	ctime, _ := strconv.ParseInt(vals["ctime"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(vals["last_seen"], 10, 64)
+	return Session{
+		Ssid:        ssid,
+		Uid:         uid,
+		UserAgent:   vals["ua"],
+		IP:          vals["ip"],
+		LoginMethod: vals["method"],
+		Ctime:       time.UnixMilli(ctime),
+		LastSeen:    time.UnixMilli(lastSeen),
+	}
+}
+
+// RevokeSession 只能退出自己的会话
+func (h *RedisJWTHandler) RevokeSession(ctx context.Context, uid int64, ssid string) error {
+	cnt, err := h.client.SRem(ctx, userSessionsKey(uid), ssid).Result()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return ErrSessionNotFound
+	}
+	return h.client.Del(ctx, sessionKey(ssid)).Err()
+}
+
+// RevokeOtherSessions 退出除了 ssid 之外的所有会话
+func (h *RedisJWTHandler) RevokeOtherSessions(ctx context.Context, uid int64, ssid string) error {
+	return h.revokeSessions(ctx, uid, ssid)
+}
+
+func (h *RedisJWTHandler) RevokeAllSessions(ctx context.Context, uid int64) error {
+	// ssid 不会是空字符串
+	return h.revokeSessions(ctx, uid, "")
+}
+
+func (h *RedisJWTHandler) revokeSessions(ctx context.Context, uid int64, ssid string) error {
+	ssids, err := h.client.SMembers(ctx, userSessionsKey(uid)).Result()
+	if err != nil {
+		return err
+	}
+	others := make([]string, 0, len(ssids))
+	for _, s := range ssids {
+		if s != ssid {
+			others = append(others, s)
+		}
+	}
+	if len(others) == 0 {
+		return nil
+	}
+	members := make([]any, 0, len(others))
+	keys := make([]string, 0, len(others))
+	for _, s := range others {
+		members = append(members, s)
+		keys = append(keys, sessionKey(s))
+	}
+	pipe := h.client.TxPipeline()
+	pipe.SRem(ctx, userSessionsKey(uid), members...)
+	pipe.Del(ctx, keys...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache/redismocks"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRedisJWTHandler_RefreshTokens(t *testing.T) {
+	rc := RefreshClaims{
+		Uid:              123,
+		Ssid:             "ssid",
+		RegisteredClaims: jwt.RegisteredClaims{ID: "old"},
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		// wantJti 为空的时候，要求换了一个新的 jti
+		wantJti string
+		wantErr error
+	}{
+		{
+			name: "轮换成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return mockRotate(ctrl, 1)
+			},
+		},
+		{
+			name: "别的标签页刚刚刷新过，宽限期内拿到最新的 refresh token",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return mockRotate(ctrl, 2)
+			},
+			wantJti: "current",
+		},
+		{
+			name: "旧的 refresh token 被重复使用",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return mockRotate(ctrl, -1)
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+		{
+			name: "会话已经退出",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return mockRotate(ctrl, 0)
+			},
+			wantErr: ErrSessionRevoked,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			rbacSvc := svcmocks.NewMockRBACService(ctrl)
+			rbacSvc.EXPECT().GetPermissions(gomock.Any(), int64(123)).
+				Return(domain.UserPermissions{
+					Roles:       []string{domain.RoleModerator},
+					Permissions: []string{domain.PermissionArticleTakedown},
+				}, nil).AnyTimes()
+			hdl := NewRedisJWTHandler(tc.mock(ctrl), testKeyRings(t), rbacSvc)
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/users/refresh_token", nil)
+
+			err := hdl.RefreshTokens(ctx, rc)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				assert.Empty(t, recorder.Header().Get("x-refresh-token"))
+				assert.Empty(t, recorder.Header().Get("x-jwt-token"))
+				return
+			}
+			newRc, err := hdl.VerifyRefreshToken(recorder.Header().Get("x-refresh-token"))
+			require.NoError(t, err)
+			// 还是同一个会话，但是换了一个 jti
+			assert.Equal(t, rc.Ssid, newRc.Ssid)
+			assert.NotEqual(t, rc.ID, newRc.ID)
+			if tc.wantJti != "" {
+				assert.Equal(t, tc.wantJti, newRc.ID)
+			}
+			uc, err := hdl.VerifyAccessToken(recorder.Header().Get("x-jwt-token"))
+			require.NoError(t, err)
+			assert.Equal(t, rc.Ssid, uc.Ssid)
+			// 轮换的时候按最新的角色重新签发
+			assert.Equal(t, int64(2), uc.ClaimsVersion)
+			assert.True(t, uc.HasPermission(domain.PermissionArticleTakedown))
+		})
+	}
+}
+
+func mockRotate(ctrl *gomock.Controller, val int64) redis.Cmdable {
+	cmd := redismocks.NewMockCmdable(ctrl)
+	res := redis.NewCmd(context.Background())
+	cmd.EXPECT().Eval(gomock.Any(), luaRotateRefreshToken,
+		[]string{"users:session:ssid", "users:sessions:123"},
+		int64(123), "old", gomock.Any(), gomock.Any(), int64(604800000), "ssid", int64(5000)).
+		DoAndReturn(func(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd {
+			switch val {
+			case 1:
+				// 轮换成功，返回新的 jti
+				res.SetVal([]any{val, args[2]})
+			case 2:
+				res.SetVal([]any{val, "current"})
+			default:
+				res.SetVal([]any{val, ""})
+			}
+			return res
+		})
+	if val > 0 {
+		version := redis.NewStringCmd(context.Background())
+		version.SetVal("2")
+		cmd.EXPECT().Get(gomock.Any(), "users:claims_version:123").Return(version)
+	}
+	return cmd
+}
+
+func testKeyRings(t *testing.T) *KeyRings {
+	newRing := func(secret string) *KeyRing {
+		ring, err := NewKeyRing("test", []Key{{Kid: "test", Secret: []byte(secret)}})
+		require.NoError(t, err)
+		return ring
+	}
+	return &KeyRings{
+		Access:    newRing("access"),
+		Refresh:   newRing("refresh"),
+		State:     newRing("state"),
+		TwoFactor: newRing("two_factor"),
+	}
+}
@@ -1,11 +1,37 @@
 package jwt
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
 
 type Handler interface {
 	ClearToken(ctx *gin.Context) error
 	ExtractToken(ctx *gin.Context) string
-	SetLoginToken(ctx *gin.Context, uid int64) error
+	// SetLoginToken 登录成功，创建会话并且设置 access token 和 refresh token，
+	// method 是登录方式，取值见 LoginMethodXXX，第三方登录是供应商的名字
+	SetLoginToken(ctx *gin.Context, uid int64, method string) error
 	SetJWTToken(ctx *gin.Context, uid int64, ssid string) error
-	CheckSession(ctx *gin.Context, ssid string) error
+	// CheckSession 会话被退出了，或者 token 里面的权限过时了，就返回 error
+	CheckSession(ctx *gin.Context, uc UserClaims) error
+	// VerifyAccessToken 校验签名和过期时间，不检查会话是否已经退出
+	VerifyAccessToken(tokenStr string) (UserClaims, error)
+	VerifyRefreshToken(tokenStr string) (RefreshClaims, error)
+	// RefreshTokens 用校验过的 refresh token 换一对新的 token，旧的 refresh token 作废。
+	// 旧的 refresh token 再被用一次会返回 ErrRefreshTokenReused
+	RefreshTokens(ctx *gin.Context, rc RefreshClaims) error
+	// SetTwoFactorToken 密码或者邮箱验证码对了但是还要两步验证，只签发一个短时间有效的临时 token，
+	// 不创建会话。method 是第一步的登录方式，两步验证通过之后按照这个方式登录
+	SetTwoFactorToken(ctx *gin.Context, uid int64, method string) error
+	VerifyTwoFactorToken(tokenStr string) (TwoFactorClaims, error)
+
+	ListSessions(ctx context.Context, uid int64) ([]Session, error)
+	RevokeSession(ctx context.Context, uid int64, ssid string) error
+	RevokeOtherSessions(ctx context.Context, uid int64, ssid string) error
+	// RevokeAllSessions 封号、重置密码的时候用
+	RevokeAllSessions(ctx context.Context, uid int64) error
+	// InvalidateClaims 用户的角色改了，之前签发的 access token 全部作废，
+	// 前端要用 refresh token 换新的
+	InvalidateClaims(ctx context.Context, uid int64) error
 }
@@ -3,66 +3,55 @@ package middleware
 import (
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"net/http"
 )
 
 type LoginJWTMiddlewareBuilder struct {
 	ijwt.Handler
+	routes *RouteAuthRegistry
 }
 
-func NewLoginJWTMiddlewareBuilder(hdl ijwt.Handler) *LoginJWTMiddlewareBuilder {
+func NewLoginJWTMiddlewareBuilder(hdl ijwt.Handler, routes *RouteAuthRegistry) *LoginJWTMiddlewareBuilder {
 	return &LoginJWTMiddlewareBuilder{
 		Handler: hdl,
+		routes:  routes,
 	}
 }
 
 func (m *LoginJWTMiddlewareBuilder) CheckLogin() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		path := ctx.Request.URL.Path
-		if path == "/users/signup" ||
-			path == "/users/login" ||
-			path == "/users/login_sms/code/send" ||
-			path == "/users/login_sms" ||
-			path == "/oauth2/wechat/authurl" ||
-			path == "/oauth2/wechat/callback" {
+		// 没有命中任何路由的时候 FullPath 是空字符串，按照需要登录处理
+		level := m.routes.Level(ctx.Request.Method, ctx.FullPath())
+		if level == AuthPublic {
 			// 不需要登录校验
 			return
 		}
-		tokenStr := m.ExtractToken(ctx)
-		var uc ijwt.UserClaims
-		token, err := jwt.ParseWithClaims(tokenStr, &uc, func(token *jwt.Token) (interface{}, error) {
-			return ijwt.JWTKey, nil
-		})
-		if err != nil {
-			// token 不对，token 是伪造的
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		if token == nil || !token.Valid {
-			// 在这里发现 access_token 过期了，生成一个新的 access_token
-
-			// token 解析出来了，但是 token 可能是非法的，或者过期了的
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-
-		// 这里看
-		err = m.CheckSession(ctx, uc.Ssid)
+		uc, err := m.check(ctx)
 		if err != nil {
+			if level == AuthOptional {
+				// 当作没有登录
+				return
+			}
 			// token 无效或者 redis 有问题
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-
-		// 可以兼容 Redis 异常的情况
-		// 做好监控，监控有没有 error
-		//if cnt > 0 {
-		//	// token 无效或者 redis 有问题
-		//	ctx.AbortWithStatus(http.StatusUnauthorized)
-		//	return
-		//}
-
 		ctx.Set("user", uc)
 	}
 }
+
+func (m *LoginJWTMiddlewareBuilder) check(ctx *gin.Context) (ijwt.UserClaims, error) {
+	tokenStr := m.ExtractToken(ctx)
+	uc, err := m.VerifyAccessToken(tokenStr)
+	if err != nil {
+		// token 是伪造的、过期了，或者签名的密钥已经退役
+		return ijwt.UserClaims{}, err
+	}
+	// 可以兼容 Redis 异常的情况
+	// 做好监控，监控有没有 error
+	err = m.CheckSession(ctx, uc)
+	if err != nil {
+		return ijwt.UserClaims{}, err
+	}
+	return uc, nil
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoginJWTMiddlewareBuilder_CheckLogin(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) ijwt.Handler
+		method string
+		path   string
+
+		wantCode int
+		// 空字符串表示没有登录信息
+		wantUser string
+	}{
+		{
+			name: "公开的路由，不看 token",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			method:   http.MethodPost,
+			path:     "/users/login",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "Any 注册的公开路由",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			method:   http.MethodPut,
+			path:     "/oauth2/wechat/callback",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "可选登录，没有 token",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("")
+				hdl.EXPECT().VerifyAccessToken("").Return(ijwt.UserClaims{}, errors.New("token 无效"))
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/articles/pub/1",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "可选登录，带了 token",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(ijwt.UserClaims{Uid: 123, Ssid: "ssid"}, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), ijwt.UserClaims{Uid: 123, Ssid: "ssid"}).Return(nil)
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/articles/pub/1",
+			wantCode: http.StatusOK,
+			wantUser: "123",
+		},
+		{
+			name: "必须登录，会话已经退出",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(ijwt.UserClaims{Uid: 123, Ssid: "ssid"}, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), ijwt.UserClaims{Uid: 123, Ssid: "ssid"}).Return(ijwt.ErrSessionRevoked)
+				return hdl
+			},
+			method:   http.MethodPost,
+			path:     "/articles/edit",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "没有声明的路由默认要登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("")
+				hdl.EXPECT().VerifyAccessToken("").Return(ijwt.UserClaims{}, errors.New("token 无效"))
+				return hdl
+			},
+			method:   http.MethodGet,
+			path:     "/not/found",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			routes := NewRouteAuthRegistry()
+			server := gin.New()
+			server.Use(NewLoginJWTMiddlewareBuilder(tc.mock(ctrl), routes).CheckLogin())
+			hdl := func(ctx *gin.Context) {
+				val, ok := ctx.Get("user")
+				if ok {
+					ctx.String(http.StatusOK, "%d", val.(ijwt.UserClaims).Uid)
+					return
+				}
+				ctx.String(http.StatusOK, "")
+			}
+			routes.Public(server.Group("/users")).POST("/login", hdl)
+			routes.Public(server.Group("/oauth2/wechat")).Any("/callback", hdl)
+			routes.Optional(server.Group("/articles")).GET("/pub/:id", hdl)
+			server.POST("/articles/edit", hdl)
+
+			req, err := http.NewRequest(tc.method, tc.path, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, tc.wantUser, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionMiddlewareBuilder 按照 access token 里面的权限做访问控制，
+// 要放在 CheckLogin 后面
+type PermissionMiddlewareBuilder struct {
+	l logger.LoggerV1
+}
+
+func NewPermissionMiddlewareBuilder(l logger.LoggerV1) *PermissionMiddlewareBuilder {
+	return &PermissionMiddlewareBuilder{l: l}
+}
+
+// RequirePermission 必须同时有 perms 里面的所有权限，否则返回 403
+func (b *PermissionMiddlewareBuilder) RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, ok := ctx.Get("user")
+		if !ok {
+			// 路由被声明成了不需要登录
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		uc := val.(ijwt.UserClaims)
+		for _, perm := range perms {
+			if !uc.HasPermission(perm) {
+				b.l.Warn("没有权限",
+					logger.Field{Key: "uid", Val: uc.Uid},
+					logger.Field{Key: "path", Val: ctx.FullPath()},
+					logger.Field{Key: "permission", Val: perm})
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+	}
+}
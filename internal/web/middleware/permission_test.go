@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionMiddlewareBuilder_RequirePermission(t *testing.T) {
+	testCases := []struct {
+		name  string
+		user  *ijwt.UserClaims
+		perms []string
+
+		wantCode int
+	}{
+		{
+			name: "有全部权限",
+			user: &ijwt.UserClaims{Uid: 123,
+				Permissions: []string{domain.PermissionUserRead, domain.PermissionUserBan}},
+			perms:    []string{domain.PermissionUserRead, domain.PermissionUserBan},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "少一个权限",
+			user: &ijwt.UserClaims{Uid: 123,
+				Permissions: []string{domain.PermissionUserRead}},
+			perms:    []string{domain.PermissionUserRead, domain.PermissionUserBan},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "普通用户",
+			user:     &ijwt.UserClaims{Uid: 123},
+			perms:    []string{domain.PermissionUserRead},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "没有登录",
+			perms:    []string{domain.PermissionUserRead},
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.user != nil {
+					ctx.Set("user", *tc.user)
+				}
+			})
+			builder := NewPermissionMiddlewareBuilder(logger.NewNopLogger())
+			server.GET("/admin/test", builder.RequirePermission(tc.perms...), func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "OK")
+			})
+			req, err := http.NewRequest(http.MethodGet, "/admin/test", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthLevel 路由对登录的要求
+type AuthLevel uint8
+
+const (
+	// AuthRequired 必须登录，没有声明的路由都是这个级别
+	AuthRequired AuthLevel = iota
+	// AuthOptional 登录了就解析出用户信息，没登录或者 token 无效也放行
+	AuthOptional
+	// AuthPublic 完全不看 token
+	AuthPublic
+)
+
+// anyMethod Any 注册的路由，所有方法都是一样的级别
+const anyMethod = "*"
+
+// RouteAuthRegistry handler 注册路由的时候顺便声明登录要求，
+// CheckLogin 按照命中的路由模板，也就是 ctx.FullPath()，来查
+type RouteAuthRegistry struct {
+	routes map[string]AuthLevel
+}
+
+func NewRouteAuthRegistry() *RouteAuthRegistry {
+	return &RouteAuthRegistry{
+		routes: make(map[string]AuthLevel),
+	}
+}
+
+// Public 在返回的 AuthRoutes 上注册的路由不需要登录
+func (r *RouteAuthRegistry) Public(g *gin.RouterGroup) AuthRoutes {
+	return AuthRoutes{group: g, registry: r, level: AuthPublic}
+}
+
+// Optional 在返回的 AuthRoutes 上注册的路由可以登录也可以不登录
+func (r *RouteAuthRegistry) Optional(g *gin.RouterGroup) AuthRoutes {
+	return AuthRoutes{group: g, registry: r, level: AuthOptional}
+}
+
+// Level fullPath 是路由模板，例如 /articles/pub/:id
+func (r *RouteAuthRegistry) Level(method, fullPath string) AuthLevel {
+	if level, ok := r.routes[r.key(method, fullPath)]; ok {
+		return level
+	}
+	return r.routes[r.key(anyMethod, fullPath)]
+}
+
+func (r *RouteAuthRegistry) set(method, fullPath string, level AuthLevel) {
+	r.routes[r.key(method, fullPath)] = level
+}
+
+func (r *RouteAuthRegistry) key(method, fullPath string) string {
+	return method + " " + fullPath
+}
+
+// AuthRoutes 用法和 gin.RouterGroup 一样，只是注册的时候会记下登录要求
+type AuthRoutes struct {
+	group    *gin.RouterGroup
+	registry *RouteAuthRegistry
+	level    AuthLevel
+}
+
+func (a AuthRoutes) Handle(method, relativePath string, handlers ...gin.HandlerFunc) {
+	a.group.Handle(method, relativePath, handlers...)
+	a.registry.set(method, joinPaths(a.group.BasePath(), relativePath), a.level)
+}
+
+func (a AuthRoutes) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	a.Handle("GET", relativePath, handlers...)
+}
+
+func (a AuthRoutes) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	a.Handle("POST", relativePath, handlers...)
+}
+
+func (a AuthRoutes) Any(relativePath string, handlers ...gin.HandlerFunc) {
+	a.group.Any(relativePath, handlers...)
+	a.registry.set(anyMethod, joinPaths(a.group.BasePath(), relativePath), a.level)
+}
+
+// joinPaths 和 gin 拼接路由的规则保持一致，结尾的 / 要保留
+func joinPaths(absolutePath, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+	finalPath := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenBindingAction token 和请求对不上的时候怎么处理
+type TokenBindingAction string
+
+const (
+	// TokenBindingLog 只记录日志，默认值
+	TokenBindingLog TokenBindingAction = "log"
+	// TokenBindingReject 当作 token 被偷了，直接返回 401，要求重新登录
+	TokenBindingReject TokenBindingAction = "reject"
+	// TokenBindingStepUp 返回 403 并且带上 x-step-up-required 头部，
+	// 前端要让用户调用 TokenBindingPolicy.StepUpPath 再验证一次身份，换一个绑定当前设备的 token
+	TokenBindingStepUp TokenBindingAction = "step_up"
+)
+
+type TokenBindingRoute struct {
+	// Prefix 路径前缀，多个前缀都匹配的时候用最长的那个
+	Prefix string
+	Action TokenBindingAction
+}
+
+// TokenBindingPolicy 哪些设备信息要和 token 签发时的一致，全部不开就是不校验
+type TokenBindingPolicy struct {
+	UserAgent bool
+	// IPv4PrefixBits 大于 0 的时候比较 IP 的前多少位，例如 24 表示同一个 C 段。
+	// 手机网络下 IP 经常变，不建议比较整个 IP
+	IPv4PrefixBits int
+	IPv6PrefixBits int
+	// Fingerprint 比较 ijwt.FingerprintHeader 头部
+	Fingerprint bool
+	Routes      []TokenBindingRoute
+	// StepUpPath 再验证身份的接口，它本身不校验，不然收到 step_up 之后永远过不去
+	StepUpPath string
+}
+
+func (p TokenBindingPolicy) enabled() bool {
+	return p.UserAgent || p.IPv4PrefixBits > 0 || p.IPv6PrefixBits > 0 || p.Fingerprint
+}
+
+// TokenBindingMiddlewareBuilder 要放在 CheckLogin 后面，
+// 检查 access token 是不是在签发它的设备上使用
+type TokenBindingMiddlewareBuilder struct {
+	policy TokenBindingPolicy
+	l      logger.LoggerV1
+}
+
+func NewTokenBindingMiddlewareBuilder(policy TokenBindingPolicy,
+	l logger.LoggerV1) *TokenBindingMiddlewareBuilder {
+	routes := make([]TokenBindingRoute, len(policy.Routes))
+	copy(routes, policy.Routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+	policy.Routes = routes
+	return &TokenBindingMiddlewareBuilder{
+		policy: policy,
+		l:      l,
+	}
+}
+
+func (b *TokenBindingMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !b.policy.enabled() {
+			return
+		}
+		val, ok := ctx.Get("user")
+		if !ok {
+			// 不需要登录的接口
+			return
+		}
+		path := ctx.Request.URL.Path
+		if b.policy.StepUpPath != "" && path == b.policy.StepUpPath {
+			return
+		}
+		uc := val.(ijwt.UserClaims)
+		mismatches := b.mismatches(ctx, uc)
+		if len(mismatches) == 0 {
+			return
+		}
+		action := b.action(path)
+		b.l.Warn("token 和请求的设备信息对不上",
+			logger.Field{Key: "uid", Val: uc.Uid},
+			logger.Field{Key: "ssid", Val: uc.Ssid},
+			logger.Field{Key: "path", Val: path},
+			logger.Field{Key: "mismatches", Val: mismatches},
+			logger.Field{Key: "ip", Val: ctx.ClientIP()},
+			logger.Field{Key: "action", Val: action})
+		switch action {
+		case TokenBindingReject:
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+		case TokenBindingStepUp:
+			ctx.Header("x-step-up-required", "token_binding")
+			ctx.AbortWithStatus(http.StatusForbidden)
+		}
+	}
+}
+
+func (b *TokenBindingMiddlewareBuilder) action(path string) TokenBindingAction {
+	for _, r := range b.policy.Routes {
+		if strings.HasPrefix(path, r.Prefix) {
+			return r.Action
+		}
+	}
+	return TokenBindingLog
+}
+
+// mismatches 返回对不上的字段。token 里面没有记录的字段不比较，
+// 兼容开启这个功能之前签发的 token
+func (b *TokenBindingMiddlewareBuilder) mismatches(ctx *gin.Context, uc ijwt.UserClaims) []string {
+	var res []string
+	if b.policy.UserAgent && uc.UserAgent != "" &&
+		uc.UserAgent != ctx.GetHeader("User-Agent") {
+		res = append(res, "userAgent")
+	}
+	if (b.policy.IPv4PrefixBits > 0 || b.policy.IPv6PrefixBits > 0) &&
+		uc.IP != "" && !b.samePrefix(uc.IP, ctx.ClientIP()) {
+		res = append(res, "ip")
+	}
+	if b.policy.Fingerprint && uc.Fingerprint != "" &&
+		uc.Fingerprint != ctx.GetHeader(ijwt.FingerprintHeader) {
+		res = append(res, "fingerprint")
+	}
+	return res
+}
+
+func (b *TokenBindingMiddlewareBuilder) samePrefix(ip1, ip2 string) bool {
+	addr1, err := netip.ParseAddr(ip1)
+	if err != nil {
+		return false
+	}
+	addr2, err := netip.ParseAddr(ip2)
+	if err != nil {
+		return false
+	}
+	addr1, addr2 = addr1.Unmap(), addr2.Unmap()
+	if addr1.Is4() != addr2.Is4() {
+		return false
+	}
+	bits := b.policy.IPv6PrefixBits
+	if addr1.Is4() {
+		bits = b.policy.IPv4PrefixBits
+	}
+	if bits <= 0 {
+		// 只配置了另外一种 IP 的前缀
+		return true
+	}
+	p1, err := addr1.Prefix(bits)
+	if err != nil {
+		return false
+	}
+	return p1.Contains(addr2)
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBindingMiddlewareBuilder_Build(t *testing.T) {
+	policy := TokenBindingPolicy{
+		UserAgent:      true,
+		IPv4PrefixBits: 24,
+		Fingerprint:    true,
+		Routes: []TokenBindingRoute{
+			{Prefix: "/users/", Action: TokenBindingStepUp},
+			{Prefix: "/users/sessions", Action: TokenBindingReject},
+		},
+		StepUpPath: "/users/step_up",
+	}
+	uc := ijwt.UserClaims{
+		Uid:         123,
+		UserAgent:   "Chrome",
+		IP:          "192.168.1.10",
+		Fingerprint: "fp",
+	}
+	testCases := []struct {
+		name        string
+		policy      TokenBindingPolicy
+		uc          ijwt.UserClaims
+		path        string
+		userAgent   string
+		ip          string
+		fingerprint string
+
+		wantCode   int
+		wantStepUp bool
+	}{
+		{
+			name:        "同一个设备，IP 在同一个网段",
+			policy:      policy,
+			uc:          uc,
+			path:        "/users/sessions",
+			userAgent:   "Chrome",
+			ip:          "192.168.1.20",
+			fingerprint: "fp",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "User-Agent 对不上，最长前缀要求拒绝",
+			policy:      policy,
+			uc:          uc,
+			path:        "/users/sessions",
+			userAgent:   "curl",
+			ip:          "192.168.1.10",
+			fingerprint: "fp",
+			wantCode:    http.StatusUnauthorized,
+		},
+		{
+			name:        "IP 换了网段，要求再验证一次",
+			policy:      policy,
+			uc:          uc,
+			path:        "/users/edit",
+			userAgent:   "Chrome",
+			ip:          "10.0.0.1",
+			fingerprint: "fp",
+			wantCode:    http.StatusForbidden,
+			wantStepUp:  true,
+		},
+		{
+			name:        "再验证身份的接口本身不校验",
+			policy:      policy,
+			uc:          uc,
+			path:        "/users/step_up",
+			userAgent:   "Chrome",
+			ip:          "10.0.0.1",
+			fingerprint: "fp",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "指纹对不上，没有配置的路由只记录日志",
+			policy:      policy,
+			uc:          uc,
+			path:        "/articles/edit",
+			userAgent:   "Chrome",
+			ip:          "192.168.1.10",
+			fingerprint: "other",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:      "旧 token 没有记录 IP 和指纹，不比较",
+			policy:    policy,
+			uc:        ijwt.UserClaims{Uid: 123, UserAgent: "Chrome"},
+			path:      "/users/edit",
+			userAgent: "Chrome",
+			ip:        "10.0.0.1",
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "旧 token 没有记录 User-Agent，不比较",
+			policy:    policy,
+			uc:        ijwt.UserClaims{Uid: 123},
+			path:      "/users/sessions",
+			userAgent: "curl",
+			ip:        "10.0.0.1",
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "没有开启",
+			policy:    TokenBindingPolicy{Routes: policy.Routes},
+			uc:        uc,
+			path:      "/users/sessions",
+			userAgent: "curl",
+			ip:        "10.0.0.1",
+			wantCode:  http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", tc.uc)
+			})
+			server.Use(NewTokenBindingMiddlewareBuilder(tc.policy, logger.NewNopLogger()).Build())
+			server.Any("/*path", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, tc.path, nil)
+			require.NoError(t, err)
+			req.Header.Set("User-Agent", tc.userAgent)
+			req.Header.Set(ijwt.FingerprintHeader, tc.fingerprint)
+			req.RemoteAddr = tc.ip + ":12345"
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantStepUp, recorder.Header().Get("x-step-up-required") != "")
+		})
+	}
+}
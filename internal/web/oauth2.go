@@ -0,0 +1,308 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// OAuth2Handler 所有第三方登录共用，路由里面的 :provider 决定用哪个供应商
+type OAuth2Handler struct {
+	providers   *oauth2.Providers
+	identitySvc service.UserIdentityService
+	eventSvc    service.SecurityEventService
+	totpSvc     service.TOTPService
+	ijwt.Handler
+	stateKeys       *ijwt.KeyRing
+	stateCookieName string
+}
+
+func NewOAuth2Handler(providers *oauth2.Providers,
+	hdl ijwt.Handler,
+	keys *ijwt.KeyRings,
+	identitySvc service.UserIdentityService,
+	eventSvc service.SecurityEventService,
+	totpSvc service.TOTPService) *OAuth2Handler {
+	return &OAuth2Handler{
+		providers:       providers,
+		identitySvc:     identitySvc,
+		eventSvc:        eventSvc,
+		totpSvc:         totpSvc,
+		stateKeys:       keys.State,
+		stateCookieName: "jwt-state",
+		Handler:         hdl,
+	}
+}
+
+func (o *OAuth2Handler) RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry) {
+	ug := server.Group("/oauth2")
+	g := routes.Public(ug)
+	g.GET("/:provider/authurl", o.AuthURL)
+	g.Any("/:provider/callback", o.Callback)
+	// 下面这些要登录
+	ug.GET("/identities", o.Identities)
+	ug.GET("/:provider/bindurl", o.BindURL)
+	ug.POST("/:provider/unbind", o.Unbind)
+}
+
+// AuthURL 生成 state、PKCE 的 code_verifier 和 nonce，
+// 签名之后放进 cookie，回调的时候拿出来核对
+func (o *OAuth2Handler) AuthURL(ctx *gin.Context) {
+	o.authURL(ctx, 0)
+}
+
+// BindURL 和 AuthURL 一样，只是回调的时候绑定到当前用户上，而不是登录
+func (o *OAuth2Handler) BindURL(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	o.authURL(ctx, uc.Uid)
+}
+
+func (o *OAuth2Handler) authURL(ctx *gin.Context, bindUid int64) {
+	provider, err := o.providers.Get(ctx.Param("provider"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "不支持的登录方式"})
+		return
+	}
+	req, err := newAuthRequest()
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	val, err := provider.AuthURL(ctx, req)
+	if err != nil {
+		zap.L().Error("构造跳转 URL 失败", zap.String("provider", provider.Name()), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "构造跳转URL失败"})
+		return
+	}
+	err = o.setStateCookie(ctx, provider.Name(), req, bindUid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "服务器异常"})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Data: val})
+}
+
+func (o *OAuth2Handler) Callback(ctx *gin.Context) {
+	provider, err := o.providers.Get(ctx.Param("provider"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "不支持的登录方式"})
+		return
+	}
+	sc, err := o.verifyState(ctx, provider.Name())
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "非法请求"})
+		return
+	}
+	tok, err := provider.Exchange(ctx, ctx.Query("code"), oauth2.AuthRequest{
+		State:        sc.State,
+		CodeVerifier: sc.CodeVerifier,
+		Nonce:        sc.Nonce,
+	})
+	if err != nil {
+		zap.L().Warn("第三方登录换 token 失败", zap.String("provider", provider.Name()), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "授权码有误"})
+		return
+	}
+	identity, err := provider.Identity(ctx, tok)
+	if err != nil {
+		zap.L().Error("获取第三方用户信息失败", zap.String("provider", provider.Name()), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	if sc.BindUid != 0 {
+		o.bind(ctx, sc.BindUid, identity)
+		return
+	}
+	u, err := o.identitySvc.FindOrCreate(ctx, identity)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrUserBanned):
+		recordSecurityEvent(ctx, o.eventSvc, domain.SecurityEvent{
+			Uid:     u.Id,
+			Account: identity.Subject,
+			Type:    domain.SecurityEventLoginFailed,
+			Method:  provider.Name(),
+			Result:  domain.SecurityResultFailure,
+			Detail:  "banned",
+		})
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "账号已经被封禁"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	// 第三方账号被盗也不能绕过两步验证
+	pending, err := startTwoFactor(ctx, o.totpSvc, o.Handler, o.eventSvc, u.Id, provider.Name())
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	if pending {
+		return
+	}
+	// 第三方登录的登录方式就是供应商的名字
+	err = o.SetLoginToken(ctx, u.Id, provider.Name())
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	recordSecurityEvent(ctx, o.eventSvc, domain.SecurityEvent{
+		Uid:    u.Id,
+		Type:   domain.SecurityEventLogin,
+		Method: provider.Name(),
+		Result: domain.SecurityResultSuccess,
+	})
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+func (o *OAuth2Handler) bind(ctx *gin.Context, uid int64, identity domain.OAuth2Identity) {
+	err := o.identitySvc.Bind(ctx, uid, identity)
+	evt := domain.SecurityEvent{
+		Uid:    uid,
+		Type:   domain.SecurityEventOAuthBind,
+		Method: identity.Provider,
+		Result: domain.SecurityResultSuccess,
+		Detail: "bind",
+	}
+	if err != nil {
+		evt.Result = domain.SecurityResultFailure
+	}
+	recordSecurityEvent(ctx, o.eventSvc, evt)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "绑定成功"})
+	case service.ErrIdentityBoundToOther:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "这个账号已经绑定了别的用户"})
+	case service.ErrProviderAlreadyBound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "已经绑定过这个平台的账号，请先解绑"})
+	default:
+		zap.L().Error("绑定第三方账号失败", zap.Int64("uid", uid),
+			zap.String("provider", identity.Provider), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+	}
+}
+
+func (o *OAuth2Handler) Unbind(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	provider := ctx.Param("provider")
+	err := o.identitySvc.Unbind(ctx, uc.Uid, provider)
+	switch err {
+	case nil:
+		recordSecurityEvent(ctx, o.eventSvc, domain.SecurityEvent{
+			Uid:    uc.Uid,
+			Type:   domain.SecurityEventOAuthBind,
+			Method: provider,
+			Result: domain.SecurityResultSuccess,
+			Detail: "unbind",
+		})
+		ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+	case service.ErrIdentityNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "没有绑定这个平台的账号"})
+	case service.ErrLastLoginMethod:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "这是唯一的登录方式，请先绑定邮箱或者手机号"})
+	default:
+		zap.L().Error("解绑第三方账号失败", zap.Int64("uid", uc.Uid),
+			zap.String("provider", provider), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+	}
+}
+
+func (o *OAuth2Handler) Identities(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	identities, err := o.identitySvc.List(ctx, uc.Uid)
+	if err != nil {
+		zap.L().Error("查询第三方账号失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	res := make([]UserIdentityVO, 0, len(identities))
+	for _, identity := range identities {
+		res = append(res, UserIdentityVO{
+			Provider: identity.Provider,
+			Ctime:    identity.Ctime.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{Data: res})
+}
+
+func newAuthRequest() (oauth2.AuthRequest, error) {
+	var req oauth2.AuthRequest
+	var err error
+	for _, s := range []*string{&req.State, &req.CodeVerifier, &req.Nonce} {
+		*s, err = oauth2.NewRandomString()
+		if err != nil {
+			return oauth2.AuthRequest{}, err
+		}
+	}
+	return req, nil
+}
+
+// verifyState url 里面的 state 要和 cookie 里面的一致，
+// 而且 cookie 必须是给这个供应商签发的
+func (o *OAuth2Handler) verifyState(ctx *gin.Context, provider string) (StateClaims, error) {
+	ck, err := ctx.Cookie(o.stateCookieName)
+	if err != nil {
+		return StateClaims{}, fmt.Errorf("无法获得 cookie %w", err)
+	}
+	var sc StateClaims
+	_, err = o.stateKeys.Parse(ck, &sc)
+	if err != nil {
+		return StateClaims{}, fmt.Errorf("解析 token 失败 %w", err)
+	}
+	if sc.Provider != provider || ctx.Query("state") != sc.State {
+		// state 不匹配，有人搞你
+		return StateClaims{}, errors.New("state 不匹配")
+	}
+	// 用过一次就作废
+	ctx.SetCookie(o.stateCookieName, "", -1, o.callbackPath(provider), "", false, true)
+	return sc, nil
+}
+
+func (o *OAuth2Handler) setStateCookie(ctx *gin.Context, provider string,
+	req oauth2.AuthRequest, bindUid int64) error {
+	const expiration = time.Minute * 10
+	claims := StateClaims{
+		Provider:     provider,
+		BindUid:      bindUid,
+		State:        req.State,
+		CodeVerifier: req.CodeVerifier,
+		Nonce:        req.Nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
+		},
+	}
+	tokenStr, err := o.stateKeys.Sign(claims)
+	if err != nil {
+		return err
+	}
+	// 只有回调的时候才带上这个 cookie
+	ctx.SetCookie(o.stateCookieName, tokenStr, int(expiration.Seconds()),
+		o.callbackPath(provider), "", false, true)
+	return nil
+}
+
+func (o *OAuth2Handler) callbackPath(provider string) string {
+	return "/oauth2/" + provider + "/callback"
+}
+
+// StateClaims 跳转到第三方之前生成的参数，签名之后放在 cookie 里面。
+// cookie 是 HttpOnly 的，而且只会带到回调的地址上
+type StateClaims struct {
+	jwt.RegisteredClaims
+	Provider string
+	// BindUid 不为 0 的时候回调是绑定到这个用户上，而不是登录
+	BindUid      int64
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
@@ -0,0 +1,361 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	oauth2mocks "gitee.com/geekbang/basic-go/webook/internal/service/oauth2/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOAuth2Handler_Login(t *testing.T) {
+	identity := domain.OAuth2Identity{
+		Provider:      "fake",
+		Subject:       "user-1",
+		Email:         "a@example.com",
+		EmailVerified: true,
+	}
+	testCases := []struct {
+		name string
+		// mock 的时候 req 已经是 authurl 那一步生成的了
+		mock func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+			service.UserIdentityService, ijwt.Handler)
+		// callback 回调的供应商
+		callback string
+		// state 回调地址上带的 state
+		state func(req oauth2.AuthRequest) string
+
+		// totpEnabled 登录的用户有没有开启两步验证
+		totpEnabled bool
+
+		wantBody string
+		// wantEvtUid 不为 0 的时候，记录的安全事件都要落在这个用户名下
+		wantEvtUid int64
+	}{
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+				service.UserIdentityService, ijwt.Handler) {
+				p := newMockProvider(ctrl, "fake", req)
+				p.EXPECT().Exchange(gomock.Any(), "code", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, code string, r oauth2.AuthRequest) (oauth2.Token, error) {
+						// PKCE 和 nonce 要原样带回来
+						assert.Equal(t, *req, r)
+						return oauth2.Token{AccessToken: "access"}, nil
+					})
+				p.EXPECT().Identity(gomock.Any(), oauth2.Token{AccessToken: "access"}).Return(identity, nil)
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().FindOrCreate(gomock.Any(), identity).Return(domain.User{Id: 123}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), "fake").Return(nil)
+				return p, identitySvc, hdl
+			},
+			callback: "fake",
+			state: func(req oauth2.AuthRequest) string {
+				return req.State
+			},
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+		{
+			name: "开启了两步验证，只发临时 token",
+			mock: func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+				service.UserIdentityService, ijwt.Handler) {
+				p := newMockProvider(ctrl, "fake", req)
+				p.EXPECT().Exchange(gomock.Any(), "code", gomock.Any()).Return(oauth2.Token{}, nil)
+				p.EXPECT().Identity(gomock.Any(), oauth2.Token{}).Return(identity, nil)
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().FindOrCreate(gomock.Any(), identity).Return(domain.User{Id: 123}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetTwoFactorToken(gomock.Any(), int64(123), "fake").Return(nil)
+				return p, identitySvc, hdl
+			},
+			callback: "fake",
+			state: func(req oauth2.AuthRequest) string {
+				return req.State
+			},
+			totpEnabled: true,
+			wantBody:    `{"code":0,"msg":"需要两步验证","data":{"twoFactorRequired":true}}`,
+			wantEvtUid:  123,
+		},
+		{
+			name: "state 不对",
+			mock: func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+				service.UserIdentityService, ijwt.Handler) {
+				return newMockProvider(ctrl, "fake", req), nil, nil
+			},
+			callback: "fake",
+			state: func(req oauth2.AuthRequest) string {
+				return "forged"
+			},
+			wantBody: `{"code":4,"msg":"非法请求","data":null}`,
+		},
+		{
+			name: "别的供应商的 state",
+			mock: func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+				service.UserIdentityService, ijwt.Handler) {
+				return newMockProvider(ctrl, "fake", req), nil, nil
+			},
+			callback: "other",
+			state: func(req oauth2.AuthRequest) string {
+				return req.State
+			},
+			wantBody: `{"code":4,"msg":"非法请求","data":null}`,
+		},
+		{
+			name: "被封号了",
+			mock: func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+				service.UserIdentityService, ijwt.Handler) {
+				p := newMockProvider(ctrl, "fake", req)
+				p.EXPECT().Exchange(gomock.Any(), "code", gomock.Any()).Return(oauth2.Token{}, nil)
+				p.EXPECT().Identity(gomock.Any(), oauth2.Token{}).Return(identity, nil)
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().FindOrCreate(gomock.Any(), identity).
+					Return(domain.User{Id: 123}, service.ErrUserBanned)
+				return p, identitySvc, nil
+			},
+			callback: "fake",
+			state: func(req oauth2.AuthRequest) string {
+				return req.State
+			},
+			wantBody:   `{"code":4,"msg":"账号已经被封禁","data":null}`,
+			wantEvtUid: 123,
+		},
+		{
+			name: "授权码不对",
+			mock: func(ctrl *gomock.Controller, req *oauth2.AuthRequest) (oauth2.Provider,
+				service.UserIdentityService, ijwt.Handler) {
+				p := newMockProvider(ctrl, "fake", req)
+				p.EXPECT().Exchange(gomock.Any(), "code", gomock.Any()).
+					Return(oauth2.Token{}, errors.New("invalid_grant"))
+				return p, nil, nil
+			},
+			callback: "fake",
+			state: func(req oauth2.AuthRequest) string {
+				return req.State
+			},
+			wantBody: `{"code":4,"msg":"授权码有误","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			var authReq oauth2.AuthRequest
+			p, identitySvc, jwtHdl := tc.mock(ctrl, &authReq)
+			other := oauth2mocks.NewMockProvider(ctrl)
+			other.EXPECT().Name().Return("other").AnyTimes()
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, evt domain.SecurityEvent) error {
+					if tc.wantEvtUid != 0 {
+						assert.Equal(t, tc.wantEvtUid, evt.Uid)
+					}
+					return nil
+				}).AnyTimes()
+			totpSvc := svcmocks.NewMockTOTPService(ctrl)
+			totpSvc.EXPECT().Enabled(gomock.Any(), gomock.Any()).Return(tc.totpEnabled, nil).AnyTimes()
+			hdl := NewOAuth2Handler(oauth2.NewProviders(p, other), jwtHdl, newStateKeyRings(t), identitySvc, eventSvc, totpSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodGet, "/oauth2/fake/authurl", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			require.Equal(t, `{"code":0,"msg":"","data":"https://fake.example.com/authorize"}`,
+				recorder.Body.String())
+			cookies := recorder.Result().Cookies()
+			require.Len(t, cookies, 1)
+			assert.Equal(t, "/oauth2/fake/callback", cookies[0].Path)
+			assert.True(t, cookies[0].HttpOnly)
+
+			req, err = http.NewRequest(http.MethodGet,
+				"/oauth2/"+tc.callback+"/callback?code=code&state="+tc.state(authReq), nil)
+			require.NoError(t, err)
+			req.AddCookie(cookies[0])
+			recorder = httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestOAuth2Handler_Bind(t *testing.T) {
+	identity := domain.OAuth2Identity{Provider: "fake", Subject: "user-1"}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) service.UserIdentityService
+
+		wantBody string
+		wantEvt  domain.SecurityEvent
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) service.UserIdentityService {
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().Bind(gomock.Any(), int64(123), identity).Return(nil)
+				return identitySvc
+			},
+			wantBody: `{"code":0,"msg":"绑定成功","data":null}`,
+			wantEvt: domain.SecurityEvent{Uid: 123, Type: domain.SecurityEventOAuthBind,
+				Method: "fake", Result: domain.SecurityResultSuccess, Detail: "bind"},
+		},
+		{
+			name: "已经绑定了别人",
+			mock: func(ctrl *gomock.Controller) service.UserIdentityService {
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().Bind(gomock.Any(), int64(123), identity).
+					Return(service.ErrIdentityBoundToOther)
+				return identitySvc
+			},
+			wantBody: `{"code":4,"msg":"这个账号已经绑定了别的用户","data":null}`,
+			wantEvt: domain.SecurityEvent{Uid: 123, Type: domain.SecurityEventOAuthBind,
+				Method: "fake", Result: domain.SecurityResultFailure, Detail: "bind"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			var authReq oauth2.AuthRequest
+			p := newMockProvider(ctrl, "fake", &authReq)
+			p.EXPECT().Exchange(gomock.Any(), "code", gomock.Any()).Return(oauth2.Token{}, nil)
+			p.EXPECT().Identity(gomock.Any(), oauth2.Token{}).Return(identity, nil)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, evt domain.SecurityEvent) error {
+					evt.IP, evt.UserAgent = "", ""
+					assert.Equal(t, tc.wantEvt, evt)
+					return nil
+				})
+			hdl := NewOAuth2Handler(oauth2.NewProviders(p), nil, newStateKeyRings(t), tc.mock(ctrl), eventSvc, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				// 回调是公开的，绑定给谁只看 cookie，这里只给 bindurl 设置登录态
+				if ctx.FullPath() == "/oauth2/:provider/bindurl" {
+					ctx.Set("user", ijwt.UserClaims{Uid: 123})
+				}
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodGet, "/oauth2/fake/bindurl", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			cookies := recorder.Result().Cookies()
+			require.Len(t, cookies, 1)
+
+			req, err = http.NewRequest(http.MethodGet,
+				"/oauth2/fake/callback?code=code&state="+authReq.State, nil)
+			require.NoError(t, err)
+			req.AddCookie(cookies[0])
+			recorder = httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestOAuth2Handler_Unbind(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserIdentityService, service.SecurityEventService)
+
+		wantBody string
+	}{
+		{
+			name: "解绑成功",
+			mock: func(ctrl *gomock.Controller) (service.UserIdentityService, service.SecurityEventService) {
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().Unbind(gomock.Any(), int64(123), "google").Return(nil)
+				eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+				eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil)
+				return identitySvc, eventSvc
+			},
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+		{
+			name: "唯一的登录方式",
+			mock: func(ctrl *gomock.Controller) (service.UserIdentityService, service.SecurityEventService) {
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().Unbind(gomock.Any(), int64(123), "google").
+					Return(service.ErrLastLoginMethod)
+				return identitySvc, nil
+			},
+			wantBody: `{"code":4,"msg":"这是唯一的登录方式，请先绑定邮箱或者手机号","data":null}`,
+		},
+		{
+			name: "没有绑定",
+			mock: func(ctrl *gomock.Controller) (service.UserIdentityService, service.SecurityEventService) {
+				identitySvc := svcmocks.NewMockUserIdentityService(ctrl)
+				identitySvc.EXPECT().Unbind(gomock.Any(), int64(123), "google").
+					Return(service.ErrIdentityNotFound)
+				return identitySvc, nil
+			},
+			wantBody: `{"code":4,"msg":"没有绑定这个平台的账号","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			identitySvc, eventSvc := tc.mock(ctrl)
+			hdl := NewOAuth2Handler(oauth2.NewProviders(), nil, newStateKeyRings(t), identitySvc, eventSvc, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, "/oauth2/google/unbind", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestOAuth2Handler_UnknownProvider(t *testing.T) {
+	hdl := NewOAuth2Handler(oauth2.NewProviders(), nil, newStateKeyRings(t), nil, nil, nil)
+	server := gin.Default()
+	hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+	req, err := http.NewRequest(http.MethodGet, "/oauth2/unknown/authurl", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	assert.Equal(t, `{"code":4,"msg":"不支持的登录方式","data":null}`, recorder.Body.String())
+}
+
+// newMockProvider AuthURL 的时候把生成的参数记到 req 里面
+func newMockProvider(ctrl *gomock.Controller, name string, req *oauth2.AuthRequest) *oauth2mocks.MockProvider {
+	p := oauth2mocks.NewMockProvider(ctrl)
+	p.EXPECT().Name().Return(name).AnyTimes()
+	p.EXPECT().AuthURL(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, r oauth2.AuthRequest) (string, error) {
+			*req = r
+			return "https://fake.example.com/authorize", nil
+		})
+	return p
+}
+
+func newStateKeyRings(t *testing.T) *ijwt.KeyRings {
+	ring, err := ijwt.NewKeyRing("k1", []ijwt.Key{{Kid: "k1", Secret: []byte("state-secret")}})
+	require.NoError(t, err)
+	return &ijwt.KeyRings{State: ring}
+}
@@ -1,7 +1,11 @@
 package web
 
-import "github.com/gin-gonic/gin"
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"github.com/gin-gonic/gin"
+)
 
 type Handler interface {
-	RegisterRoutes(server *gin.Engine)
+	// RegisterRoutes 不需要登录的路由通过 routes 注册，其它路由默认要求登录
+	RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry)
 }
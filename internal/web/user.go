@@ -1,16 +1,20 @@
 package web
 
 import (
+	"context"
+	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -19,49 +23,104 @@ const (
 	// 和上面比起来，用 ` 看起来就比较清爽
 	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
 	bizLogin             = "login"
+	// bizUnlock 登录失败太多次被锁定之后，用短信验证码解锁
+	bizUnlock = "unlock"
+	// bizResetPassword 忘记密码，用短信或者邮箱验证码重置
+	bizResetPassword = "reset_password"
+	// bizVerifyEmail 注册之后验证邮箱
+	bizVerifyEmail = "verify_email"
+	// bizEmailLogin 邮箱验证码登录
+	bizEmailLogin = "email_login"
 )
 
+// StepUpPath 再验证身份的接口，token 绑定的中间件不校验它
+const StepUpPath = "/users/step_up"
+
 type UserHandler struct {
 	ijwt.Handler
 	emailRexExp    *regexp.Regexp
 	passwordRexExp *regexp.Regexp
 	svc            service.UserService
 	codeSvc        service.CodeService
+	totpSvc        service.TOTPService
+	attemptSvc     service.LoginAttemptService
+	eventSvc       service.SecurityEventService
 }
 
 func NewUserHandler(svc service.UserService,
 	hdl ijwt.Handler,
-	codeSvc service.CodeService) *UserHandler {
+	codeSvc service.CodeService,
+	totpSvc service.TOTPService,
+	attemptSvc service.LoginAttemptService,
+	eventSvc service.SecurityEventService) *UserHandler {
 	return &UserHandler{
 		emailRexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
 		passwordRexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
 		svc:            svc,
 		codeSvc:        codeSvc,
+		totpSvc:        totpSvc,
+		attemptSvc:     attemptSvc,
+		eventSvc:       eventSvc,
 		Handler:        hdl,
 	}
 }
 
-func (h *UserHandler) RegisterRoutes(server *gin.Engine) {
+func (h *UserHandler) RegisterRoutes(server *gin.Engine, routes *middleware.RouteAuthRegistry) {
 	// REST 风格
 	//server.POST("/user", h.SignUp)
 	//server.PUT("/user", h.SignUp)
 	//server.GET("/users/:username", h.Profile)
 	ug := server.Group("/users")
+	pub := routes.Public(ug)
 	// POST /users/signup
-	ug.POST("/signup", h.SignUp)
+	pub.POST("/signup", h.SignUp)
 	// POST /users/login
 	//ug.POST("/login", h.Login)
-	ug.POST("/login", h.LoginJWT)
+	pub.POST("/login", h.LoginJWT)
+	// 带的是两步验证的临时 token，自己校验
+	pub.POST("/login/2fa", h.LoginTwoFactor)
 	ug.POST("/logout", h.LogoutJWT)
 	// POST /users/edit
 	ug.POST("/edit", h.Edit)
 	// GET /users/profile
 	ug.GET("/profile", h.Profile)
-	ug.GET("/refresh_token", h.RefreshToken)
+	// 带的是 refresh token，自己校验
+	pub.GET("/refresh_token", h.RefreshToken)
 
 	// 手机验证码登录相关功能
-	ug.POST("/login_sms/code/send", h.SendSMSLoginCode)
-	ug.POST("/login_sms", h.LoginSMS)
+	pub.POST("/login_sms/code/send", h.SendSMSLoginCode)
+	pub.POST("/login_sms", h.LoginSMS)
+	// 邮箱验证码登录，不需要密码
+	pub.POST("/login_email/code/send", h.SendEmailLoginCode)
+	pub.POST("/login_email", h.LoginEmail)
+	// 注册的时候会自动发一次邮箱验证码，收不到可以重发
+	pub.POST("/email/verify/code/send", h.SendVerifyEmailCode)
+	pub.POST("/email/verify", h.VerifyEmail)
+	// 登录失败太多次被锁定之后，用短信验证码解锁
+	pub.POST("/login/unlock/code/send", h.SendUnlockCode)
+	pub.POST("/login/unlock", h.UnlockLogin)
+
+	// 修改密码要旧密码，忘记密码用短信或者邮箱验证码重置
+	ug.POST("/password", h.ChangePassword)
+	pub.POST("/password/reset/code/send", h.SendResetPasswordCode)
+	pub.POST("/password/reset", h.ResetPassword)
+	pub.POST("/password/reset_email/code/send", h.SendResetPasswordEmailCode)
+	pub.POST("/password/reset_email", h.ResetPasswordByEmail)
+
+	// 多设备会话管理
+	ug.GET("/sessions", h.ListSessions)
+	ug.DELETE("/sessions/:ssid", h.RevokeSession)
+	ug.POST("/sessions/logout_others", h.LogoutOtherSessions)
+	// 最近的登录记录
+	ug.GET("/security/events", h.SecurityEvents)
+
+	// token 绑定的设备对不上的时候再验证一次身份，路径要和 StepUpPath 一致
+	ug.POST("/step_up", h.StepUp)
+
+	// 两步验证
+	ug.POST("/2fa/totp/enroll", h.EnrollTOTP)
+	ug.POST("/2fa/totp/confirm", h.ConfirmTOTP)
+	ug.POST("/2fa/totp/disable", h.DisableTOTP)
 }
 
 func (h *UserHandler) LoginSMS(ctx *gin.Context) {
@@ -89,6 +148,13 @@ func (h *UserHandler) LoginSMS(ctx *gin.Context) {
 		return
 	}
 	if !ok {
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Account: req.Phone,
+			Type:    domain.SecurityEventLoginFailed,
+			Method:  ijwt.LoginMethodSMS,
+			Result:  domain.SecurityResultFailure,
+			Detail:  "invalid_code",
+		})
 		ctx.JSON(http.StatusOK, Result{
 			Code: 4,
 			Msg:  "验证码不对，请重新输入",
@@ -96,6 +162,20 @@ func (h *UserHandler) LoginSMS(ctx *gin.Context) {
 		return
 	}
 	u, err := h.svc.FindOrCreate(ctx, req.Phone)
+	if err == service.ErrUserBanned {
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Account: req.Phone,
+			Type:    domain.SecurityEventLoginFailed,
+			Method:  ijwt.LoginMethodSMS,
+			Result:  domain.SecurityResultFailure,
+			Detail:  "banned",
+		})
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "账号已经被封禁",
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5,
@@ -103,11 +183,21 @@ func (h *UserHandler) LoginSMS(ctx *gin.Context) {
 		})
 		return
 	}
-	err = h.SetLoginToken(ctx, u.Id)
+	// 手机号可能被补卡冒用，短信验证码只能算一个因素
+	pending, err := startTwoFactor(ctx, h.totpSvc, h.Handler, h.eventSvc, u.Id, ijwt.LoginMethodSMS)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	if pending {
+		return
+	}
+	err = h.SetLoginToken(ctx, u.Id, ijwt.LoginMethodSMS)
 	if err != nil {
 		ctx.String(http.StatusOK, "系统错误")
 		return
 	}
+	h.recordLogin(ctx, u.Id, ijwt.LoginMethodSMS)
 	ctx.JSON(http.StatusOK, Result{
 		Msg: "登录成功",
 	})
@@ -154,6 +244,202 @@ func (h *UserHandler) SendSMSLoginCode(ctx *gin.Context) {
 	}
 }
 
+func (h *UserHandler) SendEmailLoginCode(ctx *gin.Context) {
+	h.sendEmailCode(ctx, bizEmailLogin)
+}
+
+func (h *UserHandler) SendVerifyEmailCode(ctx *gin.Context) {
+	h.sendEmailCode(ctx, bizVerifyEmail)
+}
+
+func (h *UserHandler) SendResetPasswordEmailCode(ctx *gin.Context) {
+	h.sendEmailCode(ctx, bizResetPassword)
+}
+
+func (h *UserHandler) sendEmailCode(ctx *gin.Context, biz string) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	isEmail, err := h.emailRexExp.MatchString(req.Email)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	if !isEmail {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "非法邮箱格式"})
+		return
+	}
+	err = h.codeSvc.SendEmail(ctx, biz, req.Email)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "发送成功"})
+	case service.ErrCodeSendTooMany:
+		zap.L().Warn("频繁发送邮箱验证码", zap.String("biz", biz))
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "邮件发送太频繁，请稍后再试"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("发送邮箱验证码失败", zap.String("biz", biz), zap.Error(err))
+	}
+}
+
+// LoginEmail 和短信登录一样，邮箱没有注册过就直接注册。
+// 验证码只有六位，和密码登录共用失败次数，开了两步验证的也要走第二步
+func (h *UserHandler) LoginEmail(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	ip := ctx.ClientIP()
+	status, err := h.attemptSvc.Check(ctx, req.Email, ip)
+	switch err {
+	case nil:
+		h.setLoginAttemptHeaders(ctx, status)
+	case service.ErrLoginLocked:
+		h.recordEmailLoginFailed(ctx, req.Email, "locked")
+		h.emailLoginLocked(ctx, status)
+		return
+	default:
+		// redis 出了问题，保守一点，不让登录
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("检查登录失败次数失败", zap.Error(err))
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizEmailLogin, req.Email, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统异常"})
+		zap.L().Error("邮箱验证码验证失败", zap.Error(err))
+		return
+	}
+	if !ok {
+		h.recordEmailLoginFailed(ctx, req.Email, "invalid_code")
+		status, err = h.attemptSvc.Failed(ctx, req.Email, ip)
+		if err != nil {
+			zap.L().Error("记录登录失败次数失败", zap.Error(err))
+		}
+		if status.LockedFor > 0 {
+			zap.L().Warn("登录失败次数太多，锁定", zap.String("ip", ip))
+			h.emailLoginLocked(ctx, status)
+			return
+		}
+		h.setLoginAttemptHeaders(ctx, status)
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对，请重新输入"})
+		return
+	}
+	u, cleared, err := h.svc.FindOrCreateByEmail(ctx, req.Email)
+	switch err {
+	case nil:
+	case service.ErrUserBanned:
+		h.recordEmailLoginFailed(ctx, req.Email, "banned")
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "账号已经被封禁"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("邮箱登录查找用户失败", zap.Error(err))
+		return
+	}
+	// 和密码登录一样，两步验证之前就清掉
+	err = h.attemptSvc.Succeeded(ctx, req.Email)
+	if err != nil {
+		zap.L().Error("清除登录失败次数失败", zap.Int64("uid", u.Id), zap.Error(err))
+	}
+	if cleared && !h.revokeAfterEmailClaimed(ctx, u.Id) {
+		return
+	}
+	pending, err := startTwoFactor(ctx, h.totpSvc, h.Handler, h.eventSvc, u.Id, ijwt.LoginMethodEmail)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	if pending {
+		return
+	}
+	err = h.SetLoginToken(ctx, u.Id, ijwt.LoginMethodEmail)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	h.recordLogin(ctx, u.Id, ijwt.LoginMethodEmail)
+	ctx.JSON(http.StatusOK, Result{Msg: "登录成功"})
+}
+
+func (h *UserHandler) emailLoginLocked(ctx *gin.Context, status domain.LoginAttemptStatus) {
+	h.setLoginAttemptHeaders(ctx, status)
+	minutes := int(math.Ceil(status.LockedFor.Minutes()))
+	ctx.JSON(http.StatusOK, Result{
+		Code: 4,
+		Msg:  fmt.Sprintf("登录失败次数太多，请 %d 分钟后再试，或者用短信验证码解锁", minutes),
+	})
+}
+
+func (h *UserHandler) recordEmailLoginFailed(ctx *gin.Context, email, detail string) {
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Account: email,
+		Type:    domain.SecurityEventLoginFailed,
+		Method:  ijwt.LoginMethodEmail,
+		Result:  domain.SecurityResultFailure,
+		Detail:  detail,
+	})
+}
+
+func (h *UserHandler) VerifyEmail(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizVerifyEmail, req.Email, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统异常"})
+		zap.L().Error("邮箱验证码验证失败", zap.Error(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对，请重新输入"})
+		return
+	}
+	uid, cleared, err := h.svc.VerifyEmail(ctx, req.Email)
+	switch err {
+	case nil:
+		if !cleared {
+			ctx.JSON(http.StatusOK, Result{Msg: "邮箱验证成功"})
+			return
+		}
+		if h.revokeAfterEmailClaimed(ctx, uid) {
+			ctx.JSON(http.StatusOK, Result{Msg: "邮箱验证成功，之前设置的密码已经失效，请用邮箱验证码重新设置密码"})
+		}
+	case service.ErrUserNotFound:
+		// 能收到验证码说明邮箱是他的，告诉他没有注册也没关系
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "邮箱没有注册"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("验证邮箱失败", zap.Error(err))
+	}
+}
+
+// revokeAfterEmailClaimed 没验证过的邮箱第一次被验证，之前的密码已经清掉了，
+// 抢注的人可能已经登录着，要把所有会话都踢掉。返回 false 的时候已经写好了响应
+func (h *UserHandler) revokeAfterEmailClaimed(ctx *gin.Context, uid int64) bool {
+	zap.L().Warn("验证邮箱的时候清掉了之前设置的密码", zap.Int64("uid", uid))
+	err := h.RevokeAllSessions(ctx, uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("验证邮箱之后踢掉所有会话失败", zap.Int64("uid", uid), zap.Error(err))
+		return false
+	}
+	return true
+}
+
 func (h *UserHandler) SignUp(ctx *gin.Context) {
 	type SignUpReq struct {
 		Email           string `json:"email"`
@@ -197,6 +483,11 @@ func (h *UserHandler) SignUp(ctx *gin.Context) {
 	})
 	switch err {
 	case nil:
+		// 发送失败不影响注册，用户可以自己重发
+		err = h.codeSvc.SendEmail(ctx, bizVerifyEmail, req.Email)
+		if err != nil {
+			zap.L().Error("注册之后发送邮箱验证码失败", zap.Error(err))
+		}
 		ctx.String(http.StatusOK, "注册成功")
 	case service.ErrDuplicateEmail:
 		ctx.String(http.StatusOK, "邮箱冲突，请换一个")
@@ -214,22 +505,458 @@ func (h *UserHandler) LoginJWT(ctx *gin.Context) {
 	if err := ctx.Bind(&req); err != nil {
 		return
 	}
+	ip := ctx.ClientIP()
+	status, err := h.attemptSvc.Check(ctx, req.Email, ip)
+	switch err {
+	case nil:
+		h.setLoginAttemptHeaders(ctx, status)
+	case service.ErrLoginLocked:
+		h.recordLoginFailed(ctx, req.Email, "locked")
+		h.loginLocked(ctx, status)
+		return
+	default:
+		// redis 出了问题，保守一点，不让登录
+		ctx.String(http.StatusOK, "系统错误")
+		zap.L().Error("检查登录失败次数失败", zap.Error(err))
+		return
+	}
 	u, err := h.svc.Login(ctx, req.Email, req.Password)
 	switch err {
 	case nil:
-		err = h.SetLoginToken(ctx, u.Id)
+		// 两步验证之前就清掉，密码已经证明了不是在猜密码
+		err = h.attemptSvc.Succeeded(ctx, req.Email)
+		if err != nil {
+			zap.L().Error("清除登录失败次数失败", zap.Int64("uid", u.Id), zap.Error(err))
+		}
+		pending, err := startTwoFactor(ctx, h.totpSvc, h.Handler, h.eventSvc, u.Id, ijwt.LoginMethodPassword)
 		if err != nil {
 			ctx.String(http.StatusOK, "系统错误")
 			return
 		}
+		if pending {
+			return
+		}
+		err = h.SetLoginToken(ctx, u.Id, ijwt.LoginMethodPassword)
+		if err != nil {
+			ctx.String(http.StatusOK, "系统错误")
+			return
+		}
+		h.recordLogin(ctx, u.Id, ijwt.LoginMethodPassword)
 		ctx.String(http.StatusOK, "登录成功")
 	case service.ErrInvalidUserOrPassword:
+		h.recordLoginFailed(ctx, req.Email, "wrong_password")
+		status, err = h.attemptSvc.Failed(ctx, req.Email, ip)
+		if err != nil {
+			zap.L().Error("记录登录失败次数失败", zap.Error(err))
+		}
+		if status.LockedFor > 0 {
+			zap.L().Warn("登录失败次数太多，锁定", zap.String("ip", ip))
+			h.loginLocked(ctx, status)
+			return
+		}
+		h.setLoginAttemptHeaders(ctx, status)
 		ctx.String(http.StatusOK, "用户名或者密码不对")
+	case service.ErrUserBanned:
+		h.recordLoginFailed(ctx, req.Email, "banned")
+		ctx.String(http.StatusOK, "账号已经被封禁")
 	default:
 		ctx.String(http.StatusOK, "系统错误")
 	}
 }
 
+func (h *UserHandler) loginLocked(ctx *gin.Context, status domain.LoginAttemptStatus) {
+	h.setLoginAttemptHeaders(ctx, status)
+	minutes := int(math.Ceil(status.LockedFor.Minutes()))
+	ctx.String(http.StatusOK, "登录失败次数太多，请 %d 分钟后再试，或者用短信验证码解锁", minutes)
+}
+
+// setLoginAttemptHeaders x-captcha-required 告诉前端要先让用户过验证码，
+// Retry-After 是还要锁定多少秒
+func (h *UserHandler) setLoginAttemptHeaders(ctx *gin.Context, status domain.LoginAttemptStatus) {
+	if status.CaptchaRequired {
+		ctx.Header("x-captcha-required", "true")
+	}
+	if status.LockedFor > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(status.LockedFor.Seconds()))))
+	}
+}
+
+func (h *UserHandler) SendUnlockCode(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Phone == "" {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "请输入手机号码"})
+		return
+	}
+	err := h.codeSvc.Send(ctx, bizUnlock, req.Phone)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "发送成功"})
+	case service.ErrCodeSendTooMany:
+		zap.L().Warn("频繁发送解锁验证码")
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "短信发送太频繁，请稍后再试"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("发送解锁验证码失败", zap.Error(err))
+	}
+}
+
+// UnlockLogin 手机号绑定的账号解锁，IP 的锁定不会解除
+func (h *UserHandler) UnlockLogin(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizUnlock, req.Phone, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统异常"})
+		zap.L().Error("解锁验证码验证失败", zap.Error(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对，请重新输入"})
+		return
+	}
+	err = h.attemptSvc.UnlockByPhone(ctx, req.Phone)
+	switch err {
+	case nil, service.ErrUserNotFound:
+		// 手机号没有注册也返回成功，不暴露手机号有没有注册
+		ctx.JSON(http.StatusOK, Result{Msg: "解锁成功"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("解锁失败", zap.Error(err))
+	}
+}
+
+// ChangePassword 改完密码之后，别的设备都要重新登录
+// StepUp 开启了两步验证的用动态码，没有开启的用密码。
+// 验证通过之后重新签发 access token，绑定到当前的设备上
+func (h *UserHandler) StepUp(ctx *gin.Context) {
+	type Req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	enabled, err := h.totpSvc.Enabled(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("查询两步验证失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		return
+	}
+	method := ijwt.LoginMethodPassword
+	if enabled {
+		method = "totp"
+		err = h.totpSvc.Verify(ctx, uc.Uid, req.Code)
+	} else {
+		err = h.svc.CheckPassword(ctx, uc.Uid, req.Password)
+	}
+	switch err {
+	case nil:
+	case service.ErrInvalidTOTPCode, service.ErrInvalidUserOrPassword:
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Uid:    uc.Uid,
+			Type:   domain.SecurityEventStepUp,
+			Method: method,
+			Result: domain.SecurityResultFailure,
+		})
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证失败"})
+		return
+	case service.ErrTOTPVerifyTooMany:
+		zap.L().Warn("两步验证尝试太频繁", zap.Int64("uid", uc.Uid))
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "尝试太频繁，请稍后再试"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("再验证身份失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		return
+	}
+	// 新的 access token 记下的是当前请求的设备信息
+	err = h.SetJWTToken(ctx, uc.Uid, uc.Ssid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Uid:    uc.Uid,
+		Type:   domain.SecurityEventStepUp,
+		Method: method,
+		Result: domain.SecurityResultSuccess,
+	})
+	ctx.JSON(http.StatusOK, Result{Msg: "验证成功"})
+}
+
+func (h *UserHandler) ChangePassword(ctx *gin.Context) {
+	type Req struct {
+		OldPassword     string `json:"oldPassword"`
+		NewPassword     string `json:"newPassword"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if !h.checkNewPassword(ctx, req.NewPassword, req.ConfirmPassword) {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.svc.ChangePassword(ctx, uc.Uid, req.OldPassword, req.NewPassword)
+	switch err {
+	case nil:
+	case service.ErrWrongOldPassword:
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Uid:    uc.Uid,
+			Type:   domain.SecurityEventPasswordChange,
+			Result: domain.SecurityResultFailure,
+			Detail: "wrong_password",
+		})
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "旧密码不对"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("修改密码失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		return
+	}
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Uid:    uc.Uid,
+		Type:   domain.SecurityEventPasswordChange,
+		Result: domain.SecurityResultSuccess,
+	})
+	err = h.RevokeOtherSessions(ctx, uc.Uid, uc.Ssid)
+	if err != nil {
+		// 密码已经改了，别的设备没踢掉只记日志
+		zap.L().Error("修改密码之后退出其它设备失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "密码修改成功"})
+}
+
+func (h *UserHandler) SendResetPasswordCode(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Phone == "" {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "请输入手机号码"})
+		return
+	}
+	err := h.codeSvc.Send(ctx, bizResetPassword, req.Phone)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "发送成功"})
+	case service.ErrCodeSendTooMany:
+		zap.L().Warn("频繁发送重置密码验证码")
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "短信发送太频繁，请稍后再试"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("发送重置密码验证码失败", zap.Error(err))
+	}
+}
+
+// ResetPassword 重置密码之后所有设备都要重新登录，包括发起重置的这个
+func (h *UserHandler) ResetPassword(ctx *gin.Context) {
+	type Req struct {
+		Phone           string `json:"phone"`
+		Code            string `json:"code"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	// 先校验密码格式，不然验证码白白用掉了
+	if !h.checkNewPassword(ctx, req.Password, req.ConfirmPassword) {
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizResetPassword, req.Phone, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统异常"})
+		zap.L().Error("重置密码验证码验证失败", zap.Error(err))
+		return
+	}
+	if !ok {
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Account: req.Phone,
+			Type:    domain.SecurityEventPasswordReset,
+			Method:  ijwt.LoginMethodSMS,
+			Result:  domain.SecurityResultFailure,
+			Detail:  "invalid_code",
+		})
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对，请重新输入"})
+		return
+	}
+	uid, err := h.svc.ResetPassword(ctx, req.Phone, req.Password)
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		// 验证码能收到，说明手机号就是他的，告诉他没有注册也没关系
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "手机号没有注册"})
+		return
+	case service.ErrNoEmail:
+		// 密码登录要填邮箱，没有邮箱的话设置了密码也用不了
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "账号没有绑定邮箱，不能用密码登录，请直接用短信验证码登录"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("重置密码失败", zap.Error(err))
+		return
+	}
+	h.afterResetPassword(ctx, uid, req.Phone, ijwt.LoginMethodSMS)
+}
+
+// ResetPasswordByEmail 和 ResetPassword 一样，只是验证码发到邮箱
+func (h *UserHandler) ResetPasswordByEmail(ctx *gin.Context) {
+	type Req struct {
+		Email           string `json:"email"`
+		Code            string `json:"code"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if !h.checkNewPassword(ctx, req.Password, req.ConfirmPassword) {
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizResetPassword, req.Email, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统异常"})
+		zap.L().Error("重置密码邮箱验证码验证失败", zap.Error(err))
+		return
+	}
+	if !ok {
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Account: req.Email,
+			Type:    domain.SecurityEventPasswordReset,
+			Method:  ijwt.LoginMethodEmail,
+			Result:  domain.SecurityResultFailure,
+			Detail:  "invalid_code",
+		})
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对，请重新输入"})
+		return
+	}
+	uid, err := h.svc.ResetPasswordByEmail(ctx, req.Email, req.Password)
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "邮箱没有注册"})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("重置密码失败", zap.Error(err))
+		return
+	}
+	h.afterResetPassword(ctx, uid, req.Email, ijwt.LoginMethodEmail)
+}
+
+// afterResetPassword 记录安全事件，踢掉所有会话
+func (h *UserHandler) afterResetPassword(ctx *gin.Context, uid int64, account string, method string) {
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Uid:     uid,
+		Account: account,
+		Type:    domain.SecurityEventPasswordReset,
+		Method:  method,
+		Result:  domain.SecurityResultSuccess,
+	})
+	err := h.RevokeAllSessions(ctx, uid)
+	if err != nil {
+		// 密码已经重置了，这里失败的话偷密码的人还能继续用，要告警
+		zap.L().Error("重置密码之后踢掉所有会话失败", zap.Int64("uid", uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "密码重置成功，请重新登录"})
+}
+
+// checkNewPassword 校验不通过的时候已经写好了响应
+func (h *UserHandler) checkNewPassword(ctx *gin.Context, password, confirmPassword string) bool {
+	if password != confirmPassword {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "两次输入密码不对"})
+		return false
+	}
+	isPassword, err := h.passwordRexExp.MatchString(password)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return false
+	}
+	if !isPassword {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "密码必须包含字母、数字、特殊字符，并且不少于八位"})
+		return false
+	}
+	return true
+}
+
+// LoginTwoFactor 密码、短信、邮箱验证码和第三方登录的第二步，Authorization 里面是 x-2fa-token，
+// code 是动态码或者恢复码
+func (h *UserHandler) LoginTwoFactor(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	tc, err := h.VerifyTwoFactorToken(h.ExtractToken(ctx))
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	method := tc.Method
+	if method == "" {
+		// 升级之前签发的临时 token 只有密码登录
+		method = ijwt.LoginMethodPassword
+	}
+	err = h.totpSvc.Verify(ctx, tc.Uid, req.Code)
+	switch err {
+	case nil:
+	case service.ErrInvalidTOTPCode:
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Uid:    tc.Uid,
+			Type:   domain.SecurityEventLoginFailed,
+			Method: method,
+			Result: domain.SecurityResultFailure,
+			Detail: "invalid_2fa_code",
+		})
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对"})
+		return
+	case service.ErrTOTPVerifyTooMany:
+		zap.L().Warn("两步验证尝试太频繁", zap.Int64("uid", tc.Uid))
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "尝试太频繁，请稍后再试"})
+		return
+	case service.ErrTOTPNotEnabled:
+		// 拿到临时 token 之后两步验证被关掉了，重新登录就行
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("两步验证失败", zap.Int64("uid", tc.Uid), zap.Error(err))
+		return
+	}
+	err = h.SetLoginToken(ctx, tc.Uid, method)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	h.recordLogin(ctx, tc.Uid, method)
+	ctx.JSON(http.StatusOK, Result{Msg: "登录成功"})
+}
+
 //func (h *UserHandler) Logout(ctx *gin.Context) {
 //	sess := sessions.Default(ctx)
 //	sess.Options(sessions.Options{
@@ -328,47 +1055,58 @@ func (h *UserHandler) Profile(ctx *gin.Context) {
 		return
 	}
 	type User struct {
-		Nickname string `json:"nickname"`
-		Email    string `json:"email"`
-		AboutMe  string `json:"aboutMe"`
-		Birthday string `json:"birthday"`
+		Nickname      string `json:"nickname"`
+		Avatar        string `json:"avatar"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"emailVerified"`
+		AboutMe       string `json:"aboutMe"`
+		Birthday      string `json:"birthday"`
 	}
 	ctx.JSON(http.StatusOK, User{
-		Nickname: u.Nickname,
-		Email:    u.Email,
-		AboutMe:  u.AboutMe,
-		Birthday: u.Birthday.Format(time.DateOnly),
+		Nickname:      u.Nickname,
+		Avatar:        u.Avatar,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		AboutMe:       u.AboutMe,
+		Birthday:      u.Birthday.Format(time.DateOnly),
 	})
 }
 
 func (h *UserHandler) RefreshToken(ctx *gin.Context) {
 	// 约定，前端在 Authorization 里面带上这个 refresh_token
 	tokenStr := h.ExtractToken(ctx)
-	var rc ijwt.RefreshClaims
-	token, err := jwt.ParseWithClaims(tokenStr, &rc, func(token *jwt.Token) (interface{}, error) {
-		return ijwt.RCJWTKey, nil
-	})
+	rc, err := h.VerifyRefreshToken(tokenStr)
 	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	if token == nil || !token.Valid {
-		ctx.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
 
-	err = h.CheckSession(ctx, rc.Ssid)
-	if err != nil {
-		// token 无效或者 redis 有问题
-		ctx.AbortWithStatus(http.StatusUnauthorized)
-		return
+	// 每次刷新都会拿到新的 refresh token，前端要把旧的换掉
+	err = h.RefreshTokens(ctx, rc)
+	if err == ijwt.ErrRefreshTokenReused {
+		// 安全事件：refresh token 很可能被偷了，整个会话已经被踢掉
+		zap.L().Warn("refresh token 被重复使用",
+			zap.Int64("uid", rc.Uid),
+			zap.String("ssid", rc.Ssid),
+			zap.String("ip", ctx.ClientIP()),
+			zap.String("userAgent", ctx.GetHeader("User-Agent")))
+		h.recordEvent(ctx, domain.SecurityEvent{
+			Uid:    rc.Uid,
+			Type:   domain.SecurityEventRefresh,
+			Result: domain.SecurityResultFailure,
+			Detail: "refresh_token_reused",
+		})
 	}
-
-	err = h.SetJWTToken(ctx, rc.Uid, rc.Ssid)
 	if err != nil {
+		// token 无效或者 redis 有问题
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Uid:    rc.Uid,
+		Type:   domain.SecurityEventRefresh,
+		Result: domain.SecurityResultSuccess,
+	})
 	ctx.JSON(http.StatusOK, Result{
 		Msg: "OK",
 	})
@@ -380,5 +1118,221 @@ func (h *UserHandler) LogoutJWT(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
 		return
 	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Uid:    uc.Uid,
+		Type:   domain.SecurityEventLogout,
+		Result: domain.SecurityResultSuccess,
+	})
 	ctx.JSON(http.StatusOK, Result{Msg: "退出登录成功"})
 }
+
+func (h *UserHandler) ListSessions(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	sessions, err := h.Handler.ListSessions(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("查询会话失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		return
+	}
+	res := make([]SessionVO, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, SessionVO{
+			Ssid:        s.Ssid,
+			UserAgent:   s.UserAgent,
+			IP:          s.IP,
+			LoginMethod: s.LoginMethod,
+			Ctime:       s.Ctime.Format(time.DateTime),
+			LastSeen:    s.LastSeen.Format(time.DateTime),
+			Current:     s.Ssid == uc.Ssid,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{Data: res})
+}
+
+// RevokeSession 踢掉某个设备，踢掉当前设备就相当于退出登录
+func (h *UserHandler) RevokeSession(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.Handler.RevokeSession(ctx, uc.Uid, ctx.Param("ssid"))
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+	case ijwt.ErrSessionNotFound:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "会话不存在"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("退出会话失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+	}
+}
+
+func (h *UserHandler) LogoutOtherSessions(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.RevokeOtherSessions(ctx, uc.Uid, uc.Ssid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("退出其它设备失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+// EnrollTOTP 开始绑定认证器 App，恢复码只在这里展示一次。
+// 要调用 ConfirmTOTP 确认之后才会生效，重复调用会换一个新的密钥
+func (h *UserHandler) EnrollTOTP(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	e, err := h.totpSvc.Enroll(ctx, uc.Uid)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Data: TOTPEnrollmentVO{
+			Secret:        e.Secret,
+			URI:           e.URI,
+			RecoveryCodes: e.RecoveryCodes,
+		}})
+	case service.ErrTOTPAlreadyEnabled:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "已经开启了两步验证"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("开启两步验证失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+	}
+}
+
+func (h *UserHandler) ConfirmTOTP(ctx *gin.Context) {
+	h.handleTOTPCode(ctx, "确认两步验证失败", h.totpSvc.Confirm)
+}
+
+func (h *UserHandler) DisableTOTP(ctx *gin.Context) {
+	h.handleTOTPCode(ctx, "关闭两步验证失败", h.totpSvc.Disable)
+}
+
+func (h *UserHandler) handleTOTPCode(ctx *gin.Context, errMsg string,
+	fn func(ctx context.Context, uid int64, code string) error) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := fn(ctx, uc.Uid, req.Code)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+	case service.ErrInvalidTOTPCode:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码不对"})
+	case service.ErrTOTPVerifyTooMany:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "尝试太频繁，请稍后再试"})
+	case service.ErrTOTPNotEnabled:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "没有开启两步验证"})
+	case service.ErrTOTPAlreadyEnabled:
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "已经开启了两步验证"})
+	default:
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error(errMsg, zap.Int64("uid", uc.Uid), zap.Error(err))
+	}
+}
+
+// SecurityEvents 最近的登录、刷新、退出记录，游标分页
+func (h *UserHandler) SecurityEvents(ctx *gin.Context) {
+	var req SecurityEventsReq
+	if err := ctx.BindQuery(&req); err != nil {
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	evts, err := h.eventSvc.List(ctx, uc.Uid, req.Cursor, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		zap.L().Error("查询安全事件失败", zap.Int64("uid", uc.Uid), zap.Error(err))
+		return
+	}
+	res := SecurityEventsVO{
+		Events: make([]SecurityEventVO, 0, len(evts)),
+		Cursor: req.Cursor,
+		// 拿满了一页就认为还有
+		HasMore: len(evts) == req.Limit,
+	}
+	for _, evt := range evts {
+		res.Events = append(res.Events, SecurityEventVO{
+			Type:      evt.Type,
+			Method:    evt.Method,
+			Result:    evt.Result,
+			Detail:    evt.Detail,
+			IP:        evt.IP,
+			UserAgent: evt.UserAgent,
+			NewDevice: evt.NewDevice,
+			Ctime:     evt.Ctime.Format(time.DateTime),
+		})
+		res.Cursor = evt.Id
+	}
+	ctx.JSON(http.StatusOK, Result{Data: res})
+}
+
+func (h *UserHandler) recordLogin(ctx *gin.Context, uid int64, method string) {
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Uid:    uid,
+		Type:   domain.SecurityEventLogin,
+		Method: method,
+		Result: domain.SecurityResultSuccess,
+	})
+}
+
+func (h *UserHandler) recordLoginFailed(ctx *gin.Context, email, detail string) {
+	h.recordEvent(ctx, domain.SecurityEvent{
+		Account: email,
+		Type:    domain.SecurityEventLoginFailed,
+		Method:  ijwt.LoginMethodPassword,
+		Result:  domain.SecurityResultFailure,
+		Detail:  detail,
+	})
+}
+
+func (h *UserHandler) recordEvent(ctx *gin.Context, evt domain.SecurityEvent) {
+	recordSecurityEvent(ctx, h.eventSvc, evt)
+}
+
+// recordSecurityEvent 补上请求里面的设备信息再记下来，失败了只打日志，不影响业务
+// startTwoFactor 第一步登录通过之后调用。开启了两步验证的话只签发临时 token，
+// 写好要求两步验证的响应，返回 true，前端带着 x-2fa-token 调用 /users/login/2fa。
+// 返回 error 的时候没有写响应
+func startTwoFactor(ctx *gin.Context, totpSvc service.TOTPService, hdl ijwt.Handler,
+	eventSvc service.SecurityEventService, uid int64, method string) (bool, error) {
+	enabled, err := totpSvc.Enabled(ctx, uid)
+	if err != nil {
+		zap.L().Error("查询两步验证失败", zap.Int64("uid", uid), zap.Error(err))
+		return false, err
+	}
+	if !enabled {
+		return false, nil
+	}
+	err = hdl.SetTwoFactorToken(ctx, uid, method)
+	if err != nil {
+		return false, err
+	}
+	recordSecurityEvent(ctx, eventSvc, domain.SecurityEvent{
+		Uid:    uid,
+		Type:   domain.SecurityEventTwoFactorChallenge,
+		Method: method,
+		Result: domain.SecurityResultSuccess,
+	})
+	ctx.JSON(http.StatusOK, Result{
+		Msg:  "需要两步验证",
+		Data: LoginVO{TwoFactorRequired: true},
+	})
+	return true, nil
+}
+
+func recordSecurityEvent(ctx *gin.Context, svc service.SecurityEventService, evt domain.SecurityEvent) {
+	evt.IP = ctx.ClientIP()
+	evt.UserAgent = ctx.GetHeader("User-Agent")
+	evt.Fingerprint = ctx.GetHeader(ijwt.FingerprintHeader)
+	err := svc.Record(ctx, evt)
+	if err != nil {
+		zap.L().Error("记录安全事件失败",
+			zap.String("type", evt.Type),
+			zap.Int64("uid", evt.Uid),
+			zap.Error(err))
+	}
+}
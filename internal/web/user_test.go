@@ -3,10 +3,14 @@ package web
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -14,6 +18,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestUserHandler_SignUp(t *testing.T) {
@@ -39,6 +44,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 					Password: "hello#world123",
 				}).Return(nil)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().SendEmail(gomock.Any(), "verify_email", "123@qq.com").Return(nil)
 				return userSvc, codeSvc
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
@@ -207,11 +213,11 @@ func TestUserHandler_SignUp(t *testing.T) {
 
 			// 构造 handler
 			userSvc, codeSvc := tc.mock(ctrl)
-			hdl := NewUserHandler(userSvc, codeSvc)
+			hdl := NewUserHandler(userSvc, nil, codeSvc, nil, nil, nil)
 
 			// 准备服务器，注册路由
 			server := gin.Default()
-			hdl.RegisterRoutes(server)
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
 
 			// 准备Req和记录的 recorder
 			req := tc.reqBuilder(t)
@@ -227,6 +233,192 @@ func TestUserHandler_SignUp(t *testing.T) {
 	}
 }
 
+func TestUserHandler_Sessions(t *testing.T) {
+	ctime := time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)
+	lastSeen := time.Date(2026, 10, 2, 9, 30, 0, 0, time.Local)
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) ijwt.Handler
+		method string
+		url    string
+
+		wantBody string
+	}{
+		{
+			name: "列出会话，标记当前设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ListSessions(gomock.Any(), int64(123)).Return([]ijwt.Session{
+					{Ssid: "other", UserAgent: "iPhone", IP: "10.0.0.2", LoginMethod: ijwt.LoginMethodSMS,
+						Ctime: ctime, LastSeen: lastSeen},
+					{Ssid: "current", UserAgent: "Chrome", IP: "10.0.0.1", LoginMethod: ijwt.LoginMethodPassword,
+						Ctime: ctime, LastSeen: ctime},
+				}, nil)
+				return hdl
+			},
+			method: http.MethodGet,
+			url:    "/users/sessions",
+			wantBody: `{"code":0,"msg":"","data":[` +
+				`{"ssid":"other","userAgent":"iPhone","ip":"10.0.0.2","loginMethod":"sms","ctime":"2026-10-01 08:00:00","lastSeen":"2026-10-02 09:30:00","current":false},` +
+				`{"ssid":"current","userAgent":"Chrome","ip":"10.0.0.1","loginMethod":"password","ctime":"2026-10-01 08:00:00","lastSeen":"2026-10-01 08:00:00","current":true}]}`,
+		},
+		{
+			name: "踢掉别的设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeSession(gomock.Any(), int64(123), "other").Return(nil)
+				return hdl
+			},
+			method:   http.MethodDelete,
+			url:      "/users/sessions/other",
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+		{
+			name: "会话不存在，或者不是自己的",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeSession(gomock.Any(), int64(123), "someone").
+					Return(ijwt.ErrSessionNotFound)
+				return hdl
+			},
+			method:   http.MethodDelete,
+			url:      "/users/sessions/someone",
+			wantBody: `{"code":4,"msg":"会话不存在","data":null}`,
+		},
+		{
+			name: "退出其它设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeOtherSessions(gomock.Any(), int64(123), "current").Return(nil)
+				return hdl
+			},
+			method:   http.MethodPost,
+			url:      "/users/sessions/logout_others",
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+		{
+			name: "退出其它设备失败",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeOtherSessions(gomock.Any(), int64(123), "current").
+					Return(errors.New("redis error"))
+				return hdl
+			},
+			method:   http.MethodPost,
+			url:      "/users/sessions/logout_others",
+			wantBody: `{"code":5,"msg":"系统错误","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			hdl := NewUserHandler(nil, tc.mock(ctrl), nil, nil, nil, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123, Ssid: "current"})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_StepUp(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, ijwt.Handler)
+		body string
+
+		wantBody string
+	}{
+		{
+			name: "没有开启两步验证，用密码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				userSvc.EXPECT().CheckPassword(gomock.Any(), int64(123), "hello#world123").Return(nil)
+				// 同一个会话，换一个绑定当前设备的 access token
+				hdl.EXPECT().SetJWTToken(gomock.Any(), int64(123), "current").Return(nil)
+				return userSvc, totpSvc, hdl
+			},
+			body:     `{"password":"hello#world123"}`,
+			wantBody: `{"code":0,"msg":"验证成功","data":null}`,
+		},
+		{
+			name: "开启了两步验证，要用动态码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, ijwt.Handler) {
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(true, nil)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "287082").Return(nil)
+				hdl.EXPECT().SetJWTToken(gomock.Any(), int64(123), "current").Return(nil)
+				return nil, totpSvc, hdl
+			},
+			body:     `{"password":"hello#world123","code":"287082"}`,
+			wantBody: `{"code":0,"msg":"验证成功","data":null}`,
+		},
+		{
+			name: "密码不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				userSvc.EXPECT().CheckPassword(gomock.Any(), int64(123), "wrong").
+					Return(service.ErrInvalidUserOrPassword)
+				return userSvc, totpSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			body:     `{"password":"wrong"}`,
+			wantBody: `{"code":4,"msg":"验证失败","data":null}`,
+		},
+		{
+			name: "动态码尝试太频繁",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, ijwt.Handler) {
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(true, nil)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "000000").
+					Return(service.ErrTOTPVerifyTooMany)
+				return nil, totpSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			body:     `{"code":"000000"}`,
+			wantBody: `{"code":4,"msg":"尝试太频繁，请稍后再试","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, totpSvc, jwtHdl := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, jwtHdl, nil, totpSvc, nil, eventSvc)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123, Ssid: "current"})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, StepUpPath, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
 func TestEmailPattern(t *testing.T) {
 	testCases := []struct {
 		name  string
@@ -251,7 +443,7 @@ func TestEmailPattern(t *testing.T) {
 		},
 	}
 
-	h := NewUserHandler(nil, nil)
+	h := NewUserHandler(nil, nil, nil, nil, nil, nil)
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -290,3 +482,870 @@ func TestMock(t *testing.T) {
 	})
 	t.Log(err)
 }
+
+func TestUserHandler_TwoFactorLogin(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler)
+		url  string
+		body string
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "开启了两步验证，只发临时 token",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{Id: 123}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				attemptSvc.EXPECT().Succeeded(gomock.Any(), "123@qq.com").Return(nil)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(true, nil)
+				hdl.EXPECT().SetTwoFactorToken(gomock.Any(), int64(123), "password").Return(nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			url:      "/users/login",
+			body:     `{"email":"123@qq.com","password":"hello#world123"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"需要两步验证","data":{"twoFactorRequired":true}}`,
+		},
+		{
+			name: "没有开启两步验证，直接登录",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{Id: 123}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				attemptSvc.EXPECT().Succeeded(gomock.Any(), "123@qq.com").Return(nil)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), ijwt.LoginMethodPassword).Return(nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			url:      "/users/login",
+			body:     `{"email":"123@qq.com","password":"hello#world123"}`,
+			wantCode: http.StatusOK,
+			wantBody: "登录成功",
+		},
+		{
+			name: "第二步成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("2fa")
+				hdl.EXPECT().VerifyTwoFactorToken("2fa").Return(ijwt.TwoFactorClaims{Uid: 123}, nil)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "287082").Return(nil)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), ijwt.LoginMethodPassword).Return(nil)
+				return nil, totpSvc, nil, hdl
+			},
+			url:      "/users/login/2fa",
+			body:     `{"code":"287082"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "邮箱验证码登录的第二步，按照邮箱登录记录",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("2fa")
+				hdl.EXPECT().VerifyTwoFactorToken("2fa").
+					Return(ijwt.TwoFactorClaims{Uid: 123, Method: ijwt.LoginMethodEmail}, nil)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "287082").Return(nil)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), ijwt.LoginMethodEmail).Return(nil)
+				return nil, totpSvc, nil, hdl
+			},
+			url:      "/users/login/2fa",
+			body:     `{"code":"287082"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "动态码不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("2fa")
+				hdl.EXPECT().VerifyTwoFactorToken("2fa").Return(ijwt.TwoFactorClaims{Uid: 123}, nil)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "000000").
+					Return(service.ErrInvalidTOTPCode)
+				return nil, totpSvc, nil, hdl
+			},
+			url:      "/users/login/2fa",
+			body:     `{"code":"000000"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"code":4,"msg":"验证码不对","data":null}`,
+		},
+		{
+			name: "临时 token 无效",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("access")
+				hdl.EXPECT().VerifyTwoFactorToken("access").
+					Return(ijwt.TwoFactorClaims{}, errors.New("token 无效"))
+				return nil, nil, nil, hdl
+			},
+			url:      "/users/login/2fa",
+			body:     `{"code":"287082"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, totpSvc, attemptSvc, jwtHdl := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, jwtHdl, nil, totpSvc, attemptSvc, eventSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_LoginSMS(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.TOTPService, ijwt.Handler)
+
+		wantBody string
+	}{
+		{
+			name: "开启了两步验证，只发临时 token",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.TOTPService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizLogin, "13812345678", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreate(gomock.Any(), "13812345678").Return(domain.User{Id: 123}, nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(true, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetTwoFactorToken(gomock.Any(), int64(123), ijwt.LoginMethodSMS).Return(nil)
+				return userSvc, codeSvc, totpSvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"需要两步验证","data":{"twoFactorRequired":true}}`,
+		},
+		{
+			name: "没有开启两步验证，直接登录",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.TOTPService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizLogin, "13812345678", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreate(gomock.Any(), "13812345678").Return(domain.User{Id: 123}, nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), ijwt.LoginMethodSMS).Return(nil)
+				return userSvc, codeSvc, totpSvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "查询两步验证失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.TOTPService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizLogin, "13812345678", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreate(gomock.Any(), "13812345678").Return(domain.User{Id: 123}, nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, errors.New("db 错误"))
+				return userSvc, codeSvc, totpSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			wantBody: `{"code":5,"msg":"系统错误","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc, totpSvc, jwtHdl := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, jwtHdl, codeSvc, totpSvc, nil, eventSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login_sms",
+				bytes.NewReader([]byte(`{"phone":"13812345678","code":"123456"}`)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_Password(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler)
+		url  string
+		body string
+
+		wantBody string
+	}{
+		{
+			name: "修改密码，踢掉别的设备",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ChangePassword(gomock.Any(), int64(123), "hello#world123", "hello#world456").
+					Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeOtherSessions(gomock.Any(), int64(123), "current").Return(nil)
+				return userSvc, nil, hdl
+			},
+			url: "/users/password",
+			body: `{"oldPassword":"hello#world123","newPassword":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":0,"msg":"密码修改成功","data":null}`,
+		},
+		{
+			name: "旧密码不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ChangePassword(gomock.Any(), int64(123), "hello#world", "hello#world456").
+					Return(service.ErrWrongOldPassword)
+				return userSvc, nil, nil
+			},
+			url: "/users/password",
+			body: `{"oldPassword":"hello#world","newPassword":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":4,"msg":"旧密码不对","data":null}`,
+		},
+		{
+			name: "新密码格式不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				return nil, nil, nil
+			},
+			url:      "/users/password",
+			body:     `{"oldPassword":"hello#world123","newPassword":"hello","confirmPassword":"hello"}`,
+			wantBody: `{"code":4,"msg":"密码必须包含字母、数字、特殊字符，并且不少于八位","data":null}`,
+		},
+		{
+			name: "发送重置密码验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Send(gomock.Any(), "reset_password", "15212345678").Return(nil)
+				return nil, codeSvc, nil
+			},
+			url:      "/users/password/reset/code/send",
+			body:     `{"phone":"15212345678"}`,
+			wantBody: `{"code":0,"msg":"发送成功","data":null}`,
+		},
+		{
+			name: "重置密码，踢掉所有设备",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "reset_password", "15212345678", "123456").
+					Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ResetPassword(gomock.Any(), "15212345678", "hello#world456").
+					Return(int64(123), nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+				return userSvc, codeSvc, hdl
+			},
+			url: "/users/password/reset",
+			body: `{"phone":"15212345678","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":0,"msg":"密码重置成功，请重新登录","data":null}`,
+		},
+		{
+			name: "重置密码，验证码不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "reset_password", "15212345678", "123456").
+					Return(false, nil)
+				return nil, codeSvc, nil
+			},
+			url: "/users/password/reset",
+			body: `{"phone":"15212345678","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":4,"msg":"验证码不对，请重新输入","data":null}`,
+		},
+		{
+			name: "重置密码，两次密码不一样，验证码不会被用掉",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				return nil, nil, nil
+			},
+			url: "/users/password/reset",
+			body: `{"phone":"15212345678","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world789"}`,
+			wantBody: `{"code":4,"msg":"两次输入密码不对","data":null}`,
+		},
+		{
+			name: "重置密码，账号没有邮箱",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "reset_password", "15212345678", "123456").
+					Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ResetPassword(gomock.Any(), "15212345678", "hello#world456").
+					Return(int64(0), service.ErrNoEmail)
+				return userSvc, codeSvc, nil
+			},
+			url: "/users/password/reset",
+			body: `{"phone":"15212345678","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":4,"msg":"账号没有绑定邮箱，不能用密码登录，请直接用短信验证码登录","data":null}`,
+		},
+		{
+			name: "发送重置密码的邮箱验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().SendEmail(gomock.Any(), "reset_password", "123@qq.com").Return(nil)
+				return nil, codeSvc, nil
+			},
+			url:      "/users/password/reset_email/code/send",
+			body:     `{"email":"123@qq.com"}`,
+			wantBody: `{"code":0,"msg":"发送成功","data":null}`,
+		},
+		{
+			name: "用邮箱重置密码，踢掉所有设备",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "reset_password", "123@qq.com", "123456").
+					Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ResetPasswordByEmail(gomock.Any(), "123@qq.com", "hello#world456").
+					Return(int64(123), nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+				return userSvc, codeSvc, hdl
+			},
+			url: "/users/password/reset_email",
+			body: `{"email":"123@qq.com","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":0,"msg":"密码重置成功，请重新登录","data":null}`,
+		},
+		{
+			name: "用邮箱重置密码，邮箱没有注册",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "reset_password", "123@qq.com", "123456").
+					Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ResetPasswordByEmail(gomock.Any(), "123@qq.com", "hello#world456").
+					Return(int64(0), service.ErrUserNotFound)
+				return userSvc, codeSvc, nil
+			},
+			url: "/users/password/reset_email",
+			body: `{"email":"123@qq.com","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":4,"msg":"邮箱没有注册","data":null}`,
+		},
+		{
+			name: "重置密码，踢掉会话失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "reset_password", "15212345678", "123456").
+					Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ResetPassword(gomock.Any(), "15212345678", "hello#world456").
+					Return(int64(123), nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(errors.New("redis error"))
+				return userSvc, codeSvc, hdl
+			},
+			url: "/users/password/reset",
+			body: `{"phone":"15212345678","code":"123456","password":"hello#world456",` +
+				`"confirmPassword":"hello#world456"}`,
+			wantBody: `{"code":5,"msg":"系统错误","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc, jwtHdl := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, jwtHdl, codeSvc, nil, nil, eventSvc)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123, Ssid: "current"})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_EmailCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler)
+		url  string
+		body string
+
+		wantBody string
+	}{
+		{
+			name: "发送登录验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().SendEmail(gomock.Any(), "email_login", "123@qq.com").Return(nil)
+				return nil, codeSvc, nil
+			},
+			url:      "/users/login_email/code/send",
+			body:     `{"email":"123@qq.com"}`,
+			wantBody: `{"code":0,"msg":"发送成功","data":null}`,
+		},
+		{
+			name: "邮箱格式不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				return nil, nil, nil
+			},
+			url:      "/users/login_email/code/send",
+			body:     `{"email":"123"}`,
+			wantBody: `{"code":4,"msg":"非法邮箱格式","data":null}`,
+		},
+		{
+			name: "发送太频繁",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().SendEmail(gomock.Any(), "verify_email", "123@qq.com").
+					Return(service.ErrCodeSendTooMany)
+				return nil, codeSvc, nil
+			},
+			url:      "/users/email/verify/code/send",
+			body:     `{"email":"123@qq.com"}`,
+			wantBody: `{"code":4,"msg":"邮件发送太频繁，请稍后再试","data":null}`,
+		},
+		{
+			name: "验证邮箱",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "verify_email", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().VerifyEmail(gomock.Any(), "123@qq.com").Return(int64(123), false, nil)
+				return userSvc, codeSvc, nil
+			},
+			url:      "/users/email/verify",
+			body:     `{"email":"123@qq.com","code":"123456"}`,
+			wantBody: `{"code":0,"msg":"邮箱验证成功","data":null}`,
+		},
+		{
+			name: "验证邮箱，清掉了之前设置的密码，踢掉所有会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "verify_email", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().VerifyEmail(gomock.Any(), "123@qq.com").Return(int64(123), true, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+				return userSvc, codeSvc, hdl
+			},
+			url:      "/users/email/verify",
+			body:     `{"email":"123@qq.com","code":"123456"}`,
+			wantBody: `{"code":0,"msg":"邮箱验证成功，之前设置的密码已经失效，请用邮箱验证码重新设置密码","data":null}`,
+		},
+		{
+			name: "验证邮箱，邮箱没有注册",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "verify_email", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().VerifyEmail(gomock.Any(), "123@qq.com").Return(int64(0), false, service.ErrUserNotFound)
+				return userSvc, codeSvc, nil
+			},
+			url:      "/users/email/verify",
+			body:     `{"email":"123@qq.com","code":"123456"}`,
+			wantBody: `{"code":4,"msg":"邮箱没有注册","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc, jwtHdl := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, jwtHdl, codeSvc, nil, nil, eventSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_LoginEmail(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+			service.TOTPService, service.LoginAttemptService, ijwt.Handler)
+
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "email_login", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreateByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123}, false, nil)
+				attemptSvc.EXPECT().Succeeded(gomock.Any(), "123@qq.com").Return(nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), "email").Return(nil)
+				return userSvc, codeSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "开启了两步验证，只发临时 token",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "email_login", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreateByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123}, false, nil)
+				attemptSvc.EXPECT().Succeeded(gomock.Any(), "123@qq.com").Return(nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(true, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetTwoFactorToken(gomock.Any(), int64(123), "email").Return(nil)
+				return userSvc, codeSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"需要两步验证","data":{"twoFactorRequired":true}}`,
+		},
+		{
+			name: "清掉了抢注的密码，先踢掉别的会话",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "email_login", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreateByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{Id: 123}, true, nil)
+				attemptSvc.EXPECT().Succeeded(gomock.Any(), "123@qq.com").Return(nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				gomock.InOrder(
+					hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil),
+					hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), "email").Return(nil),
+				)
+				return userSvc, codeSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "验证码不对，记录失败次数",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "email_login", "123@qq.com", "123456").Return(false, nil)
+				attemptSvc.EXPECT().Failed(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				return nil, codeSvc, nil, attemptSvc, nil
+			},
+			wantBody: `{"code":4,"msg":"验证码不对，请重新输入","data":null}`,
+		},
+		{
+			name: "这次失败触发锁定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "email_login", "123@qq.com", "123456").Return(false, nil)
+				attemptSvc.EXPECT().Failed(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{LockedFor: time.Minute * 15}, nil)
+				return nil, codeSvc, nil, attemptSvc, nil
+			},
+			wantBody:       `{"code":4,"msg":"登录失败次数太多，请 15 分钟后再试，或者用短信验证码解锁","data":null}`,
+			wantRetryAfter: "900",
+		},
+		{
+			name: "已经被锁定，不校验验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{LockedFor: time.Second * 90}, service.ErrLoginLocked)
+				return nil, nil, nil, attemptSvc, nil
+			},
+			wantBody:       `{"code":4,"msg":"登录失败次数太多，请 2 分钟后再试，或者用短信验证码解锁","data":null}`,
+			wantRetryAfter: "90",
+		},
+		{
+			name: "被封号了",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService,
+				service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), "email_login", "123@qq.com", "123456").Return(true, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindOrCreateByEmail(gomock.Any(), "123@qq.com").
+					Return(domain.User{}, false, service.ErrUserBanned)
+				return userSvc, codeSvc, nil, attemptSvc, nil
+			},
+			wantBody: `{"code":4,"msg":"账号已经被封禁","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc, totpSvc, attemptSvc, jwtHdl := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, jwtHdl, codeSvc, totpSvc, attemptSvc, eventSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login_email",
+				bytes.NewReader([]byte(`{"email":"123@qq.com","code":"123456"}`)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantRetryAfter, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestUserHandler_LoginJWTAttempts(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService)
+
+		wantBody       string
+		wantCaptcha    string
+		wantRetryAfter string
+	}{
+		{
+			name: "已经被锁定，不校验密码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{LockedFor: time.Second * 90, CaptchaRequired: true},
+						service.ErrLoginLocked)
+				return svcmocks.NewMockUserService(ctrl), attemptSvc
+			},
+			wantBody:       "登录失败次数太多，请 2 分钟后再试，或者用短信验证码解锁",
+			wantCaptcha:    "true",
+			wantRetryAfter: "90",
+		},
+		{
+			name: "密码不对，要求验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, nil)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				attemptSvc.EXPECT().Failed(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{CaptchaRequired: true}, nil)
+				return userSvc, attemptSvc
+			},
+			wantBody:    "用户名或者密码不对",
+			wantCaptcha: "true",
+		},
+		{
+			name: "这次失败触发锁定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{CaptchaRequired: true}, nil)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				attemptSvc.EXPECT().Failed(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{LockedFor: time.Minute * 15, CaptchaRequired: true}, nil)
+				return userSvc, attemptSvc
+			},
+			wantBody:       "登录失败次数太多，请 15 分钟后再试，或者用短信验证码解锁",
+			wantCaptcha:    "true",
+			wantRetryAfter: "900",
+		},
+		{
+			name: "检查失败次数出错，不让登录",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(domain.LoginAttemptStatus{}, errors.New("redis error"))
+				return svcmocks.NewMockUserService(ctrl), attemptSvc
+			},
+			wantBody: "系统错误",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, attemptSvc := tc.mock(ctrl)
+			eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+			eventSvc.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl := NewUserHandler(userSvc, nil, nil, nil, attemptSvc, eventSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login",
+				bytes.NewReader([]byte(`{"email":"123@qq.com","password":"hello#world123"}`)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantCaptcha, recorder.Header().Get("x-captcha-required"))
+			assert.Equal(t, tc.wantRetryAfter, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestUserHandler_SecurityEvents(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) service.SecurityEventService
+		url  string
+
+		wantCode int
+		wantRes  SecurityEventsVO
+	}{
+		{
+			name: "第一页",
+			mock: func(ctrl *gomock.Controller) service.SecurityEventService {
+				eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+				eventSvc.EXPECT().List(gomock.Any(), int64(123), int64(0), 2).
+					Return([]domain.SecurityEvent{
+						{Id: 20, Uid: 123, Type: domain.SecurityEventLogin, Method: ijwt.LoginMethodSMS,
+							Result: domain.SecurityResultSuccess, IP: "1.2.3.4", UserAgent: "Chrome",
+							NewDevice: true, Ctime: now},
+						{Id: 10, Uid: 123, Type: domain.SecurityEventLoginFailed, Method: ijwt.LoginMethodPassword,
+							Result: domain.SecurityResultFailure, Detail: "wrong_password", Ctime: now},
+					}, nil)
+				return eventSvc
+			},
+			url: "/users/security/events?limit=2",
+			wantRes: SecurityEventsVO{
+				Events: []SecurityEventVO{
+					{Type: domain.SecurityEventLogin, Method: ijwt.LoginMethodSMS,
+						Result: domain.SecurityResultSuccess, IP: "1.2.3.4", UserAgent: "Chrome",
+						NewDevice: true, Ctime: now.Format(time.DateTime)},
+					{Type: domain.SecurityEventLoginFailed, Method: ijwt.LoginMethodPassword,
+						Result: domain.SecurityResultFailure, Detail: "wrong_password",
+						Ctime: now.Format(time.DateTime)},
+				},
+				Cursor:  10,
+				HasMore: true,
+			},
+		},
+		{
+			name: "最后一页，limit 不合法用默认值",
+			mock: func(ctrl *gomock.Controller) service.SecurityEventService {
+				eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+				eventSvc.EXPECT().List(gomock.Any(), int64(123), int64(10), 20).
+					Return([]domain.SecurityEvent{}, nil)
+				return eventSvc
+			},
+			url: "/users/security/events?cursor=10&limit=1000",
+			wantRes: SecurityEventsVO{
+				Events: []SecurityEventVO{},
+				Cursor: 10,
+			},
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) service.SecurityEventService {
+				eventSvc := svcmocks.NewMockSecurityEventService(ctrl)
+				eventSvc.EXPECT().List(gomock.Any(), int64(123), int64(0), 20).
+					Return(nil, errors.New("db error"))
+				return eventSvc
+			},
+			url:      "/users/security/events",
+			wantCode: 5,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			hdl := NewUserHandler(nil, nil, nil, nil, nil, tc.mock(ctrl))
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server, middleware.NewRouteAuthRegistry())
+
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res struct {
+				Code int              `json:"code"`
+				Data SecurityEventsVO `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantCode, res.Code)
+			assert.Equal(t, tc.wantRes, res.Data)
+		})
+	}
+}
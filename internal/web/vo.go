@@ -0,0 +1,145 @@
+package web
+
+import "gitee.com/geekbang/basic-go/webook/internal/domain"
+
+// ArticleReq 创作者编辑、发表文章的请求
+type ArticleReq struct {
+	Id      int64  `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func (req ArticleReq) toDomain(uid int64) domain.Article {
+	return domain.Article{
+		Id:      req.Id,
+		Title:   req.Title,
+		Content: req.Content,
+		Author: domain.Author{
+			Id: uid,
+		},
+	}
+}
+
+type ListReq struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type ArticleVO struct {
+	Id       int64  `json:"id"`
+	Title    string `json:"title"`
+	Abstract string `json:"abstract"`
+	Content  string `json:"content"`
+	// 要把作者信息带出去
+	AuthorId   int64  `json:"authorId"`
+	AuthorName string `json:"authorName"`
+	Status     uint8  `json:"status"`
+	Ctime      string `json:"ctime"`
+	Utime      string `json:"utime"`
+
+	// 互动数据，读者详情和列表页会返回
+	ReadCnt    int64 `json:"readCnt"`
+	LikeCnt    int64 `json:"likeCnt"`
+	CollectCnt int64 `json:"collectCnt"`
+	Liked      bool  `json:"liked"`
+	Collected  bool  `json:"collected"`
+}
+
+// UserArticlesReq 游标分页
+type UserArticlesReq struct {
+	// 上一页返回的 cursor，第一页传 0
+	Cursor int64 `form:"cursor"`
+	Limit  int   `form:"limit"`
+}
+
+// UserArticlesVO 我点过赞、收藏过的文章
+type UserArticlesVO struct {
+	Articles []UserArticleVO `json:"articles"`
+	// 下一页的游标
+	Cursor  int64 `json:"cursor"`
+	HasMore bool  `json:"hasMore"`
+}
+
+type UserArticleVO struct {
+	ArticleVO
+	// 点赞或者收藏的时间
+	ActionTime string `json:"actionTime"`
+}
+
+type CollectionVO struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Ctime string `json:"ctime"`
+	Utime string `json:"utime"`
+}
+
+type CollectionItemVO struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	Cid   int64  `json:"cid"`
+	Ctime string `json:"ctime"`
+}
+
+// SessionVO 一个登录设备
+type SessionVO struct {
+	Ssid        string `json:"ssid"`
+	UserAgent   string `json:"userAgent"`
+	IP          string `json:"ip"`
+	LoginMethod string `json:"loginMethod"`
+	Ctime       string `json:"ctime"`
+	LastSeen    string `json:"lastSeen"`
+	// Current 是不是发起请求的这个设备
+	Current bool `json:"current"`
+}
+
+// AdminUserVO 管理后台看到的用户
+type AdminUserVO struct {
+	Id       int64    `json:"id"`
+	Email    string   `json:"email"`
+	Phone    string   `json:"phone"`
+	Nickname string   `json:"nickname"`
+	Banned   bool     `json:"banned"`
+	Roles    []string `json:"roles"`
+	Ctime    string   `json:"ctime"`
+}
+
+// LoginVO 密码登录的结果，需要两步验证的时候还没有登录成功
+type LoginVO struct {
+	TwoFactorRequired bool `json:"twoFactorRequired"`
+}
+
+type TOTPEnrollmentVO struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type SecurityEventVO struct {
+	Type      string `json:"type"`
+	Method    string `json:"method"`
+	Result    string `json:"result"`
+	Detail    string `json:"detail"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+	NewDevice bool   `json:"newDevice"`
+	Ctime     string `json:"ctime"`
+}
+
+// SecurityEventsReq 游标是上一页最后一条事件的 id
+type SecurityEventsReq struct {
+	// 上一页返回的 cursor，第一页传 0
+	Cursor int64 `form:"cursor"`
+	Limit  int   `form:"limit"`
+}
+
+type SecurityEventsVO struct {
+	Events  []SecurityEventVO `json:"events"`
+	Cursor  int64             `json:"cursor"`
+	HasMore bool              `json:"hasMore"`
+}
+
+type UserIdentityVO struct {
+	Provider string `json:"provider"`
+	// Ctime 绑定的时间
+	Ctime string `json:"ctime"`
+}
@@ -0,0 +1,31 @@
+package ioc
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"gitee.com/geekbang/basic-go/webook/pkg/cryptox"
+	"github.com/spf13/viper"
+)
+
+// InitCipher 加密存在数据库里面的敏感数据，目前是两步验证的密钥和第三方登录的令牌，
+// 换 key 之前要先把这些密文重新加密一遍
+func InitCipher() *cryptox.AESGCM {
+	type Config struct {
+		EncryptionKey string `yaml:"encryptionKey"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("crypto", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+	if err != nil {
+		panic(fmt.Errorf("crypto.encryptionKey 不是 base64 %w", err))
+	}
+	c, err := cryptox.NewAESGCM(key)
+	if err != nil {
+		panic(fmt.Errorf("crypto.encryptionKey 配置有误 %w", err))
+	}
+	return c
+}
@@ -0,0 +1,33 @@
+package ioc
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email/localemail"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email/smtp"
+	"github.com/spf13/viper"
+	"os"
+)
+
+func InitEmailService() email.Service {
+	return localemail.NewService()
+	// 如果有需要，就可以用这个
+	//return initSMTPEmailService()
+}
+
+func initSMTPEmailService() email.Service {
+	type Config struct {
+		Addr     string `yaml:"addr"`
+		Username string `yaml:"username"`
+		From     string `yaml:"from"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("email.smtp", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	password, ok := os.LookupEnv("SMTP_PASSWORD")
+	if !ok {
+		panic("找不到环境变量 SMTP_PASSWORD")
+	}
+	return smtp.NewService(cfg.Addr, cfg.Username, password, cfg.From)
+}
@@ -0,0 +1,50 @@
+package ioc
+
+import (
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/job"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/redislock"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+func InitRedisLockClient(cmd redis.Cmdable) *redislock.Client {
+	return redislock.NewClient(cmd)
+}
+
+func InitRankingJob(svc service.RankingService,
+	client *redislock.Client,
+	l logger.LoggerV1) *job.RankingJob {
+	return job.NewRankingJob(svc, client, time.Second*30, l)
+}
+
+func InitInteractiveReconcileJob(svc service.InteractiveReconcileService,
+	client *redislock.Client,
+	l logger.LoggerV1) *job.InteractiveReconcileJob {
+	return job.NewInteractiveReconcileJob(svc, client, time.Minute*30, l)
+}
+
+func InitScheduler(l logger.LoggerV1,
+	rankingJob *job.RankingJob,
+	reconcileJob *job.InteractiveReconcileJob) *job.Scheduler {
+	s := job.NewScheduler(l)
+	// 本地缓存一分钟过期，算得比这个更频繁也没有意义
+	s.Add(rankingJob, time.Minute*3)
+
+	type Config struct {
+		// 不配置就不定时核对，可以用 reconcile-interactive 子命令手动跑
+		Interval time.Duration `yaml:"interval"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("job.interactiveReconcile", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Interval > 0 {
+		s.Add(reconcileJob, cfg.Interval)
+	}
+	return s
+}
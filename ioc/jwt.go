@@ -0,0 +1,108 @@
+package ioc
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+type jwtKeyConfig struct {
+	Kid string `yaml:"kid"`
+	// Alg HS512、RS256 或者 EdDSA，默认 HS512
+	Alg    string `yaml:"alg"`
+	Secret string `yaml:"secret"`
+	// PrivateKey 和 PrivateKeyFile 二选一，都是 PEM 格式的私钥
+	PrivateKey     string `yaml:"privateKey"`
+	PrivateKeyFile string `yaml:"privateKeyFile"`
+	Retired        bool   `yaml:"retired"`
+}
+
+type jwtKeyRingConfig struct {
+	// Active 签名用的 kid
+	Active string         `yaml:"active"`
+	Keys   []jwtKeyConfig `yaml:"keys"`
+}
+
+func InitJWTKeyRings() *ijwt.KeyRings {
+	type Config struct {
+		Access  jwtKeyRingConfig `yaml:"access"`
+		Refresh jwtKeyRingConfig `yaml:"refresh"`
+		State   jwtKeyRingConfig `yaml:"state"`
+		// TwoFactor 两步验证的临时 token
+		TwoFactor jwtKeyRingConfig `yaml:"twoFactor"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("jwt", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return &ijwt.KeyRings{
+		Access:    newJWTKeyRing("access", cfg.Access),
+		Refresh:   newJWTKeyRing("refresh", cfg.Refresh),
+		State:     newJWTKeyRing("state", cfg.State),
+		TwoFactor: newJWTKeyRing("twoFactor", cfg.TwoFactor),
+	}
+}
+
+func newJWTKeyRing(name string, cfg jwtKeyRingConfig) *ijwt.KeyRing {
+	keys := make([]ijwt.Key, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		key, err := newJWTKey(k)
+		if err != nil {
+			panic(fmt.Errorf("jwt.%s 的密钥 %s 配置有误 %w", name, k.Kid, err))
+		}
+		keys = append(keys, key)
+	}
+	ring, err := ijwt.NewKeyRing(cfg.Active, keys)
+	if err != nil {
+		panic(fmt.Errorf("jwt.%s 配置有误 %w", name, err))
+	}
+	return ring
+}
+
+func newJWTKey(cfg jwtKeyConfig) (ijwt.Key, error) {
+	key := ijwt.Key{
+		Kid:     cfg.Kid,
+		Retired: cfg.Retired,
+	}
+	if cfg.Alg == "" || cfg.Alg == jwt.SigningMethodHS512.Alg() {
+		key.Method = jwt.SigningMethodHS512
+		key.Secret = []byte(cfg.Secret)
+		return key, nil
+	}
+	pem := []byte(cfg.PrivateKey)
+	if cfg.PrivateKeyFile != "" {
+		var err error
+		pem, err = os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return ijwt.Key{}, err
+		}
+	}
+	var (
+		pk  crypto.PrivateKey
+		err error
+	)
+	switch cfg.Alg {
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+		pk, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case jwt.SigningMethodEdDSA.Alg():
+		key.Method = jwt.SigningMethodEdDSA
+		pk, err = jwt.ParseEdPrivateKeyFromPEM(pem)
+	default:
+		return ijwt.Key{}, fmt.Errorf("不支持的算法 %s", cfg.Alg)
+	}
+	if err != nil {
+		return ijwt.Key{}, err
+	}
+	signer, ok := pk.(crypto.Signer)
+	if !ok {
+		return ijwt.Key{}, fmt.Errorf("私钥类型 %T 不对", pk)
+	}
+	key.PrivateKey = signer
+	return key, nil
+}
@@ -0,0 +1,40 @@
+package ioc
+
+import (
+	"net/http"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/wechat"
+	"github.com/spf13/viper"
+)
+
+// InitOAuth2Providers 微信之外的供应商都走标准的 OIDC，在 oauth2.oidc 下面配置
+func InitOAuth2Providers(wechatSvc wechat.Service) *oauth2.Providers {
+	type Config struct {
+		Name         string   `yaml:"name"`
+		Issuer       string   `yaml:"issuer"`
+		ClientID     string   `yaml:"clientId"`
+		ClientSecret string   `yaml:"clientSecret"`
+		RedirectURL  string   `yaml:"redirectURL"`
+		Scopes       []string `yaml:"scopes"`
+	}
+	var cfgs []Config
+	err := viper.UnmarshalKey("oauth2.oidc", &cfgs)
+	if err != nil {
+		panic(err)
+	}
+	client := &http.Client{Timeout: time.Second * 10}
+	providers := []oauth2.Provider{wechat.NewProvider(wechatSvc)}
+	for _, cfg := range cfgs {
+		providers = append(providers, oauth2.NewOIDCProvider(oauth2.OIDCConfig{
+			Name:         cfg.Name,
+			Issuer:       cfg.Issuer,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		}, client))
+	}
+	return oauth2.NewProviders(providers...)
+}
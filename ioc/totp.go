@@ -0,0 +1,18 @@
+package ioc
+
+import (
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitTOTPService(repo repository.TOTPRepository,
+	userRepo repository.UserRepository,
+	redisClient redis.Cmdable) service.TOTPService {
+	// 每个用户 5 分钟之内最多验证 5 次
+	l := limiter.NewRedisSlidingWindowLimiter(redisClient, time.Minute*5, 5)
+	return service.NewTOTPService(repo, userRepo, l)
+}
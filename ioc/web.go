@@ -2,6 +2,7 @@ package ioc
 
 import (
 	"context"
+	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
@@ -11,6 +12,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
 	"strings"
 	"time"
 )
@@ -26,25 +28,37 @@ import (
 //}
 
 func InitWebServer(mdls []gin.HandlerFunc,
-	userHdl *web.UserHandler, wechatHdl *web.OAuth2WechatHandler) *gin.Engine {
+	userHdl *web.UserHandler,
+	artHdl *web.ArticleHandler,
+	collHdl *web.CollectionHandler,
+	oauth2Hdl *web.OAuth2Handler,
+	jwksHdl *web.JWKSHandler,
+	adminHdl *web.AdminHandler,
+	routes *middleware.RouteAuthRegistry) *gin.Engine {
 	server := gin.Default()
 	server.Use(mdls...)
-	userHdl.RegisterRoutes(server)
-	wechatHdl.RegisterRoutes(server)
+	userHdl.RegisterRoutes(server, routes)
+	artHdl.RegisterRoutes(server, routes)
+	collHdl.RegisterRoutes(server, routes)
+	oauth2Hdl.RegisterRoutes(server, routes)
+	jwksHdl.RegisterRoutes(server, routes)
+	adminHdl.RegisterRoutes(server, routes)
 	return server
 }
 
 func InitGinMiddlewares(redisClient redis.Cmdable,
-	hdl ijwt.Handler, l logger.LoggerV1) []gin.HandlerFunc {
+	hdl ijwt.Handler, routes *middleware.RouteAuthRegistry,
+	l logger.LoggerV1) []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		cors.New(cors.Config{
 			//AllowAllOrigins: true,
 			//AllowOrigins:     []string{"http://localhost:3000"},
 			AllowCredentials: true,
 
-			AllowHeaders: []string{"Content-Type", "Authorization"},
+			AllowHeaders: []string{"Content-Type", "Authorization", ijwt.FingerprintHeader},
 			// 这个是允许前端访问你的后端响应中带的头部
-			ExposeHeaders: []string{"x-jwt-token", "x-refresh-token"},
+			ExposeHeaders: []string{"x-jwt-token", "x-refresh-token", "x-step-up-required", "x-2fa-token",
+				"x-captcha-required", "Retry-After"},
 			//AllowHeaders: []string{"content-type"},
 			//AllowMethods: []string{"POST"},
 			AllowOriginFunc: func(origin string) bool {
@@ -63,6 +77,47 @@ func InitGinMiddlewares(redisClient redis.Cmdable,
 		middleware.NewLogMiddlewareBuilder(func(ctx context.Context, al middleware.AccessLog) {
 			l.Debug("", logger.Field{Key: "req", Val: al})
 		}).AllowReqBody().AllowRespBody().Build(),
-		middleware.NewLoginJWTMiddlewareBuilder(hdl).CheckLogin(),
+		middleware.NewLoginJWTMiddlewareBuilder(hdl, routes).CheckLogin(),
+		middleware.NewTokenBindingMiddlewareBuilder(initTokenBindingPolicy(), l).Build(),
 	}
 }
+
+// initTokenBindingPolicy 没有配置的时候不做校验
+func initTokenBindingPolicy() middleware.TokenBindingPolicy {
+	type Route struct {
+		Prefix string `yaml:"prefix"`
+		Action string `yaml:"action"`
+	}
+	type Config struct {
+		UserAgent      bool    `yaml:"userAgent"`
+		IPv4PrefixBits int     `yaml:"ipv4PrefixBits"`
+		IPv6PrefixBits int     `yaml:"ipv6PrefixBits"`
+		Fingerprint    bool    `yaml:"fingerprint"`
+		Routes         []Route `yaml:"routes"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("tokenBinding", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	policy := middleware.TokenBindingPolicy{
+		UserAgent:      cfg.UserAgent,
+		IPv4PrefixBits: cfg.IPv4PrefixBits,
+		IPv6PrefixBits: cfg.IPv6PrefixBits,
+		Fingerprint:    cfg.Fingerprint,
+		StepUpPath:     web.StepUpPath,
+	}
+	for _, r := range cfg.Routes {
+		action := middleware.TokenBindingAction(r.Action)
+		switch action {
+		case middleware.TokenBindingLog, middleware.TokenBindingReject, middleware.TokenBindingStepUp:
+		default:
+			panic(fmt.Errorf("tokenBinding 路由 %s 的 action %s 不对", r.Prefix, r.Action))
+		}
+		policy.Routes = append(policy.Routes, middleware.TokenBindingRoute{
+			Prefix: r.Prefix,
+			Action: action,
+		})
+	}
+	return policy
+}
@@ -3,6 +3,7 @@ package ioc
 import (
 	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/wechat"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/spf13/viper"
 	"os"
 )
 
@@ -15,5 +16,7 @@ func InitWechatService(l logger.LoggerV1) wechat.Service {
 	if !ok {
 		panic("找不到环境变量 WECHAT_APP_SECRET")
 	}
-	return wechat.NewService(appID, appSecret, l)
+	// 不配置就用微信官方的地址
+	baseURL := viper.GetString("wechat.baseURL")
+	return wechat.NewService(appID, appSecret, baseURL, l)
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/pflag"
@@ -10,16 +12,53 @@ import (
 	"go.uber.org/zap"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
 	initViperV1()
 	initLogger()
-	server := InitWebServer()
+	if pflag.Arg(0) == cmdReconcileInteractive {
+		runReconcileInteractive()
+		return
+	}
+	if pflag.Arg(0) == cmdGrantRole {
+		runGrantRole()
+		return
+	}
+	app := InitApp()
+	server := app.server
 	server.GET("/hello", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "hello，启动成功了！")
 	})
-	server.Run(":8080")
+	app.scheduler.Start()
+	srv := &http.Server{
+		Addr:    ":8080",
+		Handler: server,
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	// 优雅退出：先停止接收新请求，再把内存里面攒着的数据刷新出去
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("关闭 web 服务器失败", err)
+	}
+	app.scheduler.Stop(ctx)
+	if err := app.readCntAggregator.Close(ctx); err != nil {
+		log.Println("刷新阅读数失败", err)
+	}
 }
 
 func initLogger() {
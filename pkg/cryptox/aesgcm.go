@@ -0,0 +1,47 @@
+// Package cryptox 加密敏感字段，比如两步验证的密钥
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+var ErrInvalidCiphertext = errors.New("密文不对")
+
+// AESGCM 密文的格式是 nonce + 加密结果，每次加密都用新的随机 nonce
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM key 的长度是 16、24 或者 32 字节
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{aead: aead}, nil
+}
+
+// Encrypt aad 不加密，但是解密的时候要传一样的，
+// 用来把密文和它所属的数据绑定起来，比如用户 id，防止密文被挪到别的行
+func (a *AESGCM) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func (a *AESGCM) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	size := a.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrInvalidCiphertext
+	}
+	return a.aead.Open(nil, ciphertext[:size], ciphertext[size:], aad)
+}
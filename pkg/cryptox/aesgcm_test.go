@@ -0,0 +1,27 @@
+package cryptox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAESGCM(t *testing.T) {
+	c, err := NewAESGCM([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	enc, err := c.Encrypt([]byte("secret"), []byte("123"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(enc), "secret")
+
+	dec, err := c.Decrypt(enc, []byte("123"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(dec))
+
+	// 挪到别的用户那里就解不开
+	_, err = c.Decrypt(enc, []byte("456"))
+	assert.Error(t, err)
+
+	_, err = c.Decrypt([]byte("short"), []byte("123"))
+	assert.Equal(t, ErrInvalidCiphertext, err)
+}
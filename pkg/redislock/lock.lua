@@ -0,0 +1,14 @@
+-- 锁
+local key = KEYS[1]
+-- fencing token 的计数器
+local tokenKey = KEYS[2]
+
+local val = ARGV[1]
+local expiration = ARGV[2]
+
+if redis.call("SET", key, val, "NX", "PX", expiration) then
+    -- 每次加锁成功，token 都比上一次大
+    return redis.call("INCR", tokenKey)
+else
+    return 0
+end
@@ -0,0 +1,138 @@
+package redislock
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lock.lua
+	luaLock string
+	//go:embed refresh.lua
+	luaRefresh string
+	//go:embed unlock.lua
+	luaUnlock string
+)
+
+var (
+	// ErrFailedToPreemptLock 锁被别人拿着
+	ErrFailedToPreemptLock = errors.New("redislock: 抢锁失败")
+	// ErrLockNotHold 锁已经过期，或者被别人拿走了
+	ErrLockNotHold = errors.New("redislock: 没有持有锁")
+)
+
+// Client 基于 Redis 的分布式锁
+type Client struct {
+	cmd redis.Cmdable
+}
+
+func NewClient(cmd redis.Cmdable) *Client {
+	return &Client{cmd: cmd}
+}
+
+// TryLock 尝试加锁一次，锁被别人拿着的时候返回 ErrFailedToPreemptLock
+func (c *Client) TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
+	val := uuid.New().String()
+	// 两个 key 必须落在同一个 slot 上，不然 Redis Cluster 会报 CROSSSLOT
+	key = "{" + key + "}"
+	token, err := c.cmd.Eval(ctx, luaLock, []string{key, c.tokenKey(key)},
+		val, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, ErrFailedToPreemptLock
+	}
+	return &Lock{
+		cmd:        c.cmd,
+		key:        key,
+		value:      val,
+		token:      token,
+		expiration: expiration,
+		unlock:     make(chan struct{}),
+	}, nil
+}
+
+func (c *Client) tokenKey(key string) string {
+	return key + ":fencing_token"
+}
+
+// Lock 一把已经拿到手的锁
+type Lock struct {
+	cmd redis.Cmdable
+	key string
+	// 用来确认锁还是自己的
+	value      string
+	token      int64
+	expiration time.Duration
+
+	unlock     chan struct{}
+	unlockOnce sync.Once
+}
+
+// Token 单调递增的 fencing token。
+// 锁可能在持有者不知情的情况下过期（比如 GC 停顿），
+// 被保护的资源应该拒绝比它见过的 token 更小的写入
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Refresh 续约一次，把过期时间重置为加锁时的 expiration
+func (l *Lock) Refresh(ctx context.Context) error {
+	res, err := l.cmd.Eval(ctx, luaRefresh, []string{l.key},
+		l.value, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHold
+	}
+	return nil
+}
+
+// AutoRefresh 每隔 interval 续约一次，每次续约的超时时间是 timeout。
+// 超时了会立刻重试，其它错误或者调用了 Unlock 就返回，所以它会一直阻塞，要在单独的 goroutine 里面调用
+func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	retry := make(chan struct{}, 1)
+	for {
+		select {
+		case <-ticker.C:
+		case <-retry:
+		case <-l.unlock:
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := l.Refresh(ctx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			retry <- struct{}{}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Unlock 释放锁，同时让 AutoRefresh 退出
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.unlockOnce.Do(func() {
+		close(l.unlock)
+	})
+	res, err := l.cmd.Eval(ctx, luaUnlock, []string{l.key}, l.value).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHold
+	}
+	return nil
+}
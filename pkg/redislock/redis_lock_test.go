@@ -0,0 +1,110 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache/redismocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestClient_TryLock(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantToken int64
+		wantErr   error
+	}{
+		{
+			name: "加锁成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(3))
+				cmd.EXPECT().Eval(gomock.Any(), luaLock,
+					[]string{"{job}", "{job}:fencing_token"},
+					gomock.Any(), gomock.Any()).Return(res)
+				return cmd
+			},
+			wantToken: 3,
+		},
+		{
+			name: "锁被别人拿着",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().Eval(gomock.Any(), luaLock,
+					[]string{"{job}", "{job}:fencing_token"},
+					gomock.Any(), gomock.Any()).Return(res)
+				return cmd
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+		{
+			name: "redis 错误",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(errors.New("redis error"))
+				cmd.EXPECT().Eval(gomock.Any(), luaLock,
+					[]string{"{job}", "{job}:fencing_token"},
+					gomock.Any(), gomock.Any()).Return(res)
+				return cmd
+			},
+			wantErr: errors.New("redis error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewClient(tc.mock(ctrl))
+			l, err := c.TryLock(context.Background(), "job", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantToken, l.Token())
+		})
+	}
+}
+
+func TestLock_Unlock(t *testing.T) {
+	testCases := []struct {
+		name string
+		val  int64
+
+		wantErr error
+	}{
+		{
+			name: "解锁成功",
+			val:  1,
+		},
+		{
+			name:    "锁已经不是自己的",
+			val:     0,
+			wantErr: ErrLockNotHold,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := redismocks.NewMockCmdable(ctrl)
+			res := redis.NewCmd(context.Background())
+			res.SetVal(tc.val)
+			cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{"job"}, "abc").Return(res)
+			l := &Lock{cmd: cmd, key: "job", value: "abc", unlock: make(chan struct{})}
+			err := l.Unlock(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			// AutoRefresh 在解锁之后直接返回
+			assert.NoError(t, l.AutoRefresh(time.Hour, time.Second))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录 B 的测试向量，SHA1 的密钥是 "12345678901234567890"，取后 6 位
+func TestCode(t *testing.T) {
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tc := range testCases {
+		code, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	prev, err := Code(secret, Step(now)-1)
+	require.NoError(t, err)
+
+	// 手机慢了一个时间步
+	step, ok, err := Validate(secret, prev, now, 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	_, ok, err = Validate(secret, prev, now, 0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = Validate(secret, "12345", now, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	u, err := url.Parse(ProvisioningURI("webook", "a@qq.com", "ABC"))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/webook:a@qq.com", u.Path)
+	assert.Equal(t, "ABC", u.Query().Get("secret"))
+	assert.Equal(t, "webook", u.Query().Get("issuer"))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gitee.com/geekbang/basic-go/webook/internal/job"
+	"gitee.com/geekbang/basic-go/webook/pkg/redislock"
+	"github.com/spf13/pflag"
+)
+
+// 子命令的名字，比如 ./webook --config=config/dev.yaml reconcile-interactive --fix
+const cmdReconcileInteractive = "reconcile-interactive"
+
+var reconcileFix = pflag.Bool("fix", false,
+	cmdReconcileInteractive+" 子命令：修复对不上的点赞数和收藏数，不加就只报告")
+
+// runReconcileInteractive 核对一遍点赞数和收藏数，把对不上的打印出来
+func runReconcileInteractive() {
+	if *reconcileFix {
+		// 修复的时候和定时任务互斥，免得两边同时改同一批计数
+		lock := lockReconcile()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := lock.Unlock(ctx); err != nil {
+				log.Println("释放核对任务的锁失败", err)
+			}
+		}()
+	}
+	svc := InitInteractiveReconcileService()
+	report, err := svc.Reconcile(context.Background(), *reconcileFix)
+	for _, diff := range report.Diffs {
+		log.Printf("%s:%d like_cnt %d => %d, collect_cnt %d => %d\n",
+			diff.Biz, diff.BizId,
+			diff.LikeCnt, diff.ActualLikeCnt,
+			diff.CollectCnt, diff.ActualCollectCnt)
+	}
+	log.Printf("差异 %d 条，修复 %d 条\n", len(report.Diffs), report.Fixed)
+	if err != nil {
+		log.Fatalln("核对中断", err)
+	}
+}
+
+// lockReconcile 拿不到锁就直接退出，拿到了就一直续约到 Unlock
+func lockReconcile() *redislock.Lock {
+	const expiration = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	lock, err := InitRedisLockClient().TryLock(ctx, job.InteractiveReconcileLockKey, expiration)
+	if errors.Is(err, redislock.ErrFailedToPreemptLock) {
+		log.Fatalln("别的实例正在核对互动计数，稍后再试")
+	}
+	if err != nil {
+		log.Fatalln("抢核对任务的锁失败", err)
+	}
+	go func() {
+		if er := lock.AutoRefresh(expiration/2, time.Second); er != nil {
+			log.Println("核对任务的锁续约失败", er)
+		}
+	}()
+	return lock
+}
@@ -9,38 +9,133 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	"gitee.com/geekbang/basic-go/webook/ioc"
-	"github.com/gin-gonic/gin"
+	"gitee.com/geekbang/basic-go/webook/pkg/redislock"
 	"github.com/google/wire"
 )
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	wire.Build(
 		// 第三方依赖
 		ioc.InitRedis, ioc.InitDB,
 		ioc.InitLogger,
 		// DAO 部分
 		dao.NewUserDAO,
+		dao.NewArticleGORMDAO,
+		dao.NewGORMInteractiveDAO,
+		dao.NewGORMCollectionDAO,
+		dao.NewGORMRBACDAO,
+		dao.NewGORMTOTPDAO,
+		dao.NewGORMSecurityEventDAO,
+		dao.NewGORMUserIdentityDAO,
 
 		// cache 部分
 		cache.NewCodeCache, cache.NewUserCache,
+		cache.NewInteractiveRedisCache,
+		cache.NewLoginAttemptRedisCache,
+		cache.NewLikeRankRedisCache,
+		cache.NewRankingRedisCache,
+		cache.NewRankingLocalCache,
 
 		// repository 部分
 		repository.NewCachedUserRepository,
 		repository.NewCodeRepository,
+		repository.NewCachedArticleRepository,
+		repository.NewReadCntAggregator,
+		repository.NewCachedInteractiveRepository,
+		repository.NewCachedCollectionRepository,
+		repository.NewCachedRBACRepository,
+		repository.NewCachedTOTPRepository,
+		repository.NewCachedLoginAttemptRepository,
+		repository.NewCachedSecurityEventRepository,
+		repository.NewCachedUserIdentityRepository,
+		ioc.InitCipher,
+		repository.NewCachedRankingRepository,
+		repository.NewCachedInteractiveReconcileRepository,
 
 		// Service 部分
 		ioc.InitSMSService,
+		ioc.InitEmailService,
 		ioc.InitWechatService,
+		ioc.InitOAuth2Providers,
 		service.NewUserService,
 		service.NewCodeService,
+		service.NewArticleService,
+		service.NewInteractiveService,
+		service.NewCollectionService,
+		service.NewRBACService,
+		ioc.InitTOTPService,
+		service.NewLoginAttemptService,
+		service.NewSecurityEventService,
+		service.NewUserIdentityService,
+		service.NewLoggerNewDeviceNotifier,
+		service.NewBatchRankingService,
+		service.NewInteractiveReconcileService,
 
 		// handler 部分
 		web.NewUserHandler,
+		web.NewArticleHandler,
+		web.NewCollectionHandler,
+		ioc.InitJWTKeyRings,
 		ijwt.NewRedisJWTHandler,
-		web.NewOAuth2WechatHandler,
+		web.NewOAuth2Handler,
+		web.NewJWKSHandler,
+		web.NewAdminHandler,
+		middleware.NewRouteAuthRegistry,
 		ioc.InitGinMiddlewares,
 		ioc.InitWebServer,
+
+		// 定时任务
+		ioc.InitRedisLockClient,
+		ioc.InitRankingJob,
+		ioc.InitInteractiveReconcileJob,
+		ioc.InitScheduler,
+
+		wire.Struct(new(App), "*"),
+	)
+	return new(App)
+}
+
+// InitInteractiveReconcileService reconcile-interactive 子命令用
+func InitInteractiveReconcileService() service.InteractiveReconcileService {
+	wire.Build(
+		ioc.InitRedis, ioc.InitDB,
+		ioc.InitLogger,
+		dao.NewGORMInteractiveDAO,
+		cache.NewInteractiveRedisCache,
+		repository.NewCachedInteractiveReconcileRepository,
+		service.NewInteractiveReconcileService,
+	)
+	return nil
+}
+
+// InitRedisLockClient reconcile-interactive 子命令用，修复的时候要和定时任务抢同一把锁
+func InitRedisLockClient() *redislock.Client {
+	wire.Build(ioc.InitRedis, ioc.InitRedisLockClient)
+	return nil
+}
+
+// InitRBACService grant-role 子命令用
+func InitRBACService() service.RBACService {
+	wire.Build(
+		ioc.InitDB, ioc.InitLogger,
+		dao.NewGORMRBACDAO,
+		repository.NewCachedRBACRepository,
+		service.NewRBACService,
+	)
+	return nil
+}
+
+// InitJWTHandler grant-role 子命令用，改完角色要作废用户手上的 access token
+func InitJWTHandler() ijwt.Handler {
+	wire.Build(
+		ioc.InitRedis, ioc.InitDB, ioc.InitLogger,
+		ioc.InitJWTKeyRings,
+		dao.NewGORMRBACDAO,
+		repository.NewCachedRBACRepository,
+		service.NewRBACService,
+		ijwt.NewRedisJWTHandler,
 	)
-	return gin.Default()
+	return nil
 }
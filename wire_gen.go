@@ -13,8 +13,9 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	"gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	"gitee.com/geekbang/basic-go/webook/ioc"
-	"github.com/gin-gonic/gin"
+	"gitee.com/geekbang/basic-go/webook/pkg/redislock"
 )
 
 import (
@@ -23,12 +24,17 @@ import (
 
 // Injectors from wire.go:
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	cmdable := ioc.InitRedis()
-	handler := jwt.NewRedisJWTHandler(cmdable)
+	keyRings := ioc.InitJWTKeyRings()
 	loggerV1 := ioc.InitLogger()
-	v := ioc.InitGinMiddlewares(cmdable, handler, loggerV1)
 	db := ioc.InitDB(loggerV1)
+	rbacDAO := dao.NewGORMRBACDAO(db)
+	rbacRepository := repository.NewCachedRBACRepository(rbacDAO)
+	rbacService := service.NewRBACService(rbacRepository)
+	handler := jwt.NewRedisJWTHandler(cmdable, keyRings, rbacService)
+	routeAuthRegistry := middleware.NewRouteAuthRegistry()
+	v := ioc.InitGinMiddlewares(cmdable, handler, routeAuthRegistry, loggerV1)
 	userDAO := dao.NewUserDAO(db)
 	userCache := cache.NewUserCache(cmdable)
 	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
@@ -36,10 +42,99 @@ func InitWebServer() *gin.Engine {
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
 	smsService := ioc.InitSMSService()
-	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, handler, codeService)
+	emailService := ioc.InitEmailService()
+	codeService := service.NewCodeService(codeRepository, smsService, emailService)
+	totpDAO := dao.NewGORMTOTPDAO(db)
+	aesgcm := ioc.InitCipher()
+	totpRepository := repository.NewCachedTOTPRepository(totpDAO, aesgcm)
+	totpService := ioc.InitTOTPService(totpRepository, userRepository, cmdable)
+	loginAttemptCache := cache.NewLoginAttemptRedisCache(cmdable)
+	loginAttemptRepository := repository.NewCachedLoginAttemptRepository(loginAttemptCache)
+	loginAttemptService := service.NewLoginAttemptService(loginAttemptRepository, userRepository)
+	securityEventDAO := dao.NewGORMSecurityEventDAO(db)
+	securityEventRepository := repository.NewCachedSecurityEventRepository(securityEventDAO)
+	newDeviceNotifier := service.NewLoggerNewDeviceNotifier(loggerV1)
+	securityEventService := service.NewSecurityEventService(securityEventRepository, userRepository, newDeviceNotifier, loggerV1)
+	userHandler := web.NewUserHandler(userService, handler, codeService, totpService, loginAttemptService, securityEventService)
+	articleDAO := dao.NewArticleGORMDAO(db)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, userRepository, loggerV1)
+	articleService := service.NewArticleService(articleRepository)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	readCntAggregator := repository.NewReadCntAggregator(interactiveDAO, interactiveCache, loggerV1)
+	likeRankCache := cache.NewLikeRankRedisCache(cmdable)
+	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, loggerV1, interactiveCache, readCntAggregator, likeRankCache)
+	interactiveService := service.NewInteractiveService(interactiveRepository)
+	rankingCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingCache, rankingLocalCache, loggerV1)
+	rankingService := service.NewBatchRankingService(articleService, interactiveService, rankingRepository)
+	articleHandler := web.NewArticleHandler(articleService, interactiveService, rankingService, loggerV1)
+	collectionDAO := dao.NewGORMCollectionDAO(db)
+	collectionRepository := repository.NewCachedCollectionRepository(collectionDAO, interactiveCache, loggerV1)
+	collectionService := service.NewCollectionService(collectionRepository)
+	collectionHandler := web.NewCollectionHandler(collectionService, loggerV1)
 	wechatService := ioc.InitWechatService(loggerV1)
-	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, handler, userService)
-	engine := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler)
-	return engine
+	providers := ioc.InitOAuth2Providers(wechatService)
+	userIdentityDAO := dao.NewGORMUserIdentityDAO(db)
+	userIdentityRepository := repository.NewCachedUserIdentityRepository(userIdentityDAO, userCache, aesgcm)
+	userIdentityService := service.NewUserIdentityService(userIdentityRepository, userRepository, providers)
+	oAuth2Handler := web.NewOAuth2Handler(providers, handler, keyRings, userIdentityService, securityEventService, totpService)
+	jwksHandler := web.NewJWKSHandler(keyRings)
+	adminHandler := web.NewAdminHandler(userService, articleService, rbacService, loginAttemptService, handler, loggerV1)
+	engine := ioc.InitWebServer(v, userHandler, articleHandler, collectionHandler, oAuth2Handler, jwksHandler, adminHandler, routeAuthRegistry)
+	client := ioc.InitRedisLockClient(cmdable)
+	rankingJob := ioc.InitRankingJob(rankingService, client, loggerV1)
+	interactiveReconcileRepository := repository.NewCachedInteractiveReconcileRepository(interactiveDAO, interactiveCache, loggerV1)
+	interactiveReconcileService := service.NewInteractiveReconcileService(interactiveReconcileRepository)
+	interactiveReconcileJob := ioc.InitInteractiveReconcileJob(interactiveReconcileService, client, loggerV1)
+	scheduler := ioc.InitScheduler(loggerV1, rankingJob, interactiveReconcileJob)
+	app := &App{
+		server:            engine,
+		readCntAggregator: readCntAggregator,
+		scheduler:         scheduler,
+	}
+	return app
+}
+
+// InitInteractiveReconcileService reconcile-interactive 子命令用
+func InitInteractiveReconcileService() service.InteractiveReconcileService {
+	cmdable := ioc.InitRedis()
+	loggerV1 := ioc.InitLogger()
+	db := ioc.InitDB(loggerV1)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	interactiveReconcileRepository := repository.NewCachedInteractiveReconcileRepository(interactiveDAO, interactiveCache, loggerV1)
+	interactiveReconcileService := service.NewInteractiveReconcileService(interactiveReconcileRepository)
+	return interactiveReconcileService
+}
+
+// InitRedisLockClient reconcile-interactive 子命令用，修复的时候要和定时任务抢同一把锁
+func InitRedisLockClient() *redislock.Client {
+	cmdable := ioc.InitRedis()
+	client := ioc.InitRedisLockClient(cmdable)
+	return client
+}
+
+// InitRBACService grant-role 子命令用
+func InitRBACService() service.RBACService {
+	loggerV1 := ioc.InitLogger()
+	db := ioc.InitDB(loggerV1)
+	rbacDAO := dao.NewGORMRBACDAO(db)
+	rbacRepository := repository.NewCachedRBACRepository(rbacDAO)
+	rbacService := service.NewRBACService(rbacRepository)
+	return rbacService
+}
+
+// InitJWTHandler grant-role 子命令用，改完角色要作废用户手上的 access token
+func InitJWTHandler() jwt.Handler {
+	cmdable := ioc.InitRedis()
+	keyRings := ioc.InitJWTKeyRings()
+	loggerV1 := ioc.InitLogger()
+	db := ioc.InitDB(loggerV1)
+	rbacDAO := dao.NewGORMRBACDAO(db)
+	rbacRepository := repository.NewCachedRBACRepository(rbacDAO)
+	rbacService := service.NewRBACService(rbacRepository)
+	handler := jwt.NewRedisJWTHandler(cmdable, keyRings, rbacService)
+	return handler
 }